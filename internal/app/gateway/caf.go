@@ -6,7 +6,6 @@ import (
 
 	"github.com/apidome/gateway/internal/pkg/configs"
 	"github.com/apidome/gateway/internal/pkg/middleman"
-	"github.com/apidome/gateway/internal/pkg/proxy"
 )
 
 var config *configs.Configuration
@@ -21,9 +20,11 @@ func Start() {
 		log.Panicln("Could not load configuration correctly:", err)
 	}
 
-	var prx proxy.Proxy
-
-	proxy.InitProxy(&prx, config.In.Targets[0].GetURL())
+	// Create a proxy and a set of middlewares for each target.
+	routes, err := buildTargetRoutes(config.In.Targets)
+	if err != nil {
+		log.Panicln("Could not set up targets:", err)
+	}
 
 	var reverseProxy middleman.Middleman
 
@@ -31,9 +32,27 @@ func Start() {
 		":"+config.Out.Port,
 		middlewareErrorHandler)
 
-	requestProxying(&reverseProxy, &prx)
+	// Request client certificates if any target requires them
+	tlsConfig, err := listenerTLSConfig(config)
+	if err != nil {
+		log.Panicln("Could not set up client authentication:", err)
+	}
+
+	reverseProxy.SetTLSConfig(tlsConfig)
+
+	// Let internal clients speak HTTP/2 without TLS
+	if config.Out.H2C && !config.Out.SSL {
+		err = reverseProxy.EnableH2C()
+		if err != nil {
+			log.Panicln("Could not enable h2c:", err)
+		}
+	}
+
+	// Log all incoming requests' routes
+	reverseProxy.All("/.*", middleman.RouteLogger())
 
-	responseProxying(&reverseProxy, &prx)
+	// Hand each request to the target it is routed to
+	reverseProxy.All("/.*", targetDispatcher(routes))
 
 	reverseProxy.All("/.*", defaultMiddleware())
 
@@ -0,0 +1,64 @@
+package caf
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+
+	"github.com/apidome/gateway/internal/pkg/configs"
+	"github.com/apidome/gateway/internal/pkg/proxy"
+	"github.com/pkg/errors"
+)
+
+// listenerTLSConfig creates the TLS configuration of the listener, which
+// requests client certificates if any target requires them.
+// The certificates are only requested, so the targets that do not require
+// them stay reachable, and the targets that do reject the requests without
+// them.
+func listenerTLSConfig(config *configs.Configuration) (*tls.Config, error) {
+	clientAuth := false
+	for _, target := range config.In.Targets {
+		clientAuth = clientAuth || target.ClientAuth
+	}
+
+	if !clientAuth {
+		return nil, nil
+	}
+
+	if !config.Out.SSL {
+		return nil, errors.New("client certificates require ssl")
+	}
+
+	if config.Out.ClientCAPath == "" {
+		return nil, errors.New("client certificates require a clientCaPath")
+	}
+
+	bundle, err := ioutil.ReadFile(config.Out.ClientCAPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read client CA bundle")
+	}
+
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(bundle) {
+		return nil, errors.New("no certificates were found in client CA bundle " + config.Out.ClientCAPath)
+	}
+
+	return &tls.Config{
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		ClientCAs:  clientCAs,
+	}, nil
+}
+
+// newClientAuth creates the ClientAuth that authorizes the clients of a
+// target by their certificates
+func newClientAuth(target configs.Target) *proxy.ClientAuth {
+	certificates := target.ClientCertificates
+
+	return proxy.NewClientAuth(certificates.Subjects,
+		certificates.SANs,
+		proxy.ClientIdentityHeaders{
+			Subject:     certificates.SubjectHeader,
+			SANs:        certificates.SANsHeader,
+			Certificate: certificates.CertificateHeader,
+		})
+}
@@ -0,0 +1,57 @@
+package caf
+
+import (
+	"github.com/apidome/gateway/internal/pkg/configs"
+	"github.com/apidome/gateway/internal/pkg/middleman"
+	"github.com/apidome/gateway/internal/pkg/proxymiddlewares"
+	"github.com/apidome/gateway/internal/pkg/validators/formparser"
+	"github.com/pkg/errors"
+)
+
+// addFormParsing creates a ParseForm middleware for an endpoint, which
+// parses its form bodies and enforces the constraints of their uploads. It
+// should be added after the body is read.
+func addFormParsing(mm *middleman.Middleman, api configs.API, endpoint *configs.Endpoint) error {
+	parser, err := newFormParser(api, endpoint)
+	if err != nil {
+		return errors.Wrap(err, "failed to create form parser for endpoint - "+endpoint.Path)
+	}
+
+	addEndpointMiddleware(mm, endpoint, proxymiddlewares.ParseForm(endpoint.Path,
+		endpoint.Method,
+		parser,
+		api.Validator))
+
+	return nil
+}
+
+// newFormParser creates the parser of the form bodies of an endpoint with
+// the endpoint's upload constraints
+func newFormParser(api configs.API, endpoint *configs.Endpoint) (*formparser.Parser, error) {
+	parser := formparser.NewParser()
+
+	parser.SetExhaustive(api.Validator.Exhaustive)
+	parser.SetMaxParts(endpoint.Form.MaxParts)
+
+	err := parser.SetFileConstraints("", formparser.FileConstraints{
+		MaxSize:      endpoint.Form.MaxFileSize,
+		ContentTypes: endpoint.Form.ContentTypes,
+		Filename:     endpoint.Form.Filename,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for field, file := range endpoint.Form.Files {
+		err = parser.SetFileConstraints(field, formparser.FileConstraints{
+			MaxSize:      file.MaxSize,
+			ContentTypes: file.ContentTypes,
+			Filename:     file.Filename,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return parser, nil
+}
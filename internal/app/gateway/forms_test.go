@@ -0,0 +1,130 @@
+package caf
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/apidome/gateway/internal/pkg/configs"
+)
+
+const succeed = "V"
+const failed = "X"
+
+// newTestUpstream starts a server that answers requests with the handler and
+// returns it with a target that points to it
+func newTestUpstream(t *testing.T, handler http.HandlerFunc) (*httptest.Server, configs.Target) {
+	upstream := httptest.NewServer(handler)
+
+	address, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to start an upstream: %v", failed, err)
+	}
+
+	host, port, err := net.SplitHostPort(address.Host)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to start an upstream: %v", failed, err)
+	}
+
+	return upstream, configs.Target{Host: host, Port: port}
+}
+
+func TestFormParsing(t *testing.T) {
+	schema := `{"type": "object", "properties": {"id": {"type": "integer"}}, "required": ["id"]}`
+
+	testCases := []struct {
+		description string
+		path        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{
+			"a json body to an endpoint without a form block",
+			"/items",
+			"application/json",
+			`{"id": 5}`,
+			http.StatusOK,
+		},
+		{
+			"a url-encoded body to an endpoint without a form block",
+			"/items",
+			"application/x-www-form-urlencoded",
+			"id=5",
+			http.StatusBadRequest,
+		},
+		{
+			"a multipart body to an endpoint without a form block",
+			"/items",
+			"multipart/form-data; boundary=b",
+			"--b\r\nContent-Disposition: form-data; name=\"id\"\r\n\r\n5\r\n--b--\r\n",
+			http.StatusBadRequest,
+		},
+		{
+			"a valid url-encoded body to an endpoint with a form block",
+			"/uploads",
+			"application/x-www-form-urlencoded",
+			"id=5",
+			http.StatusOK,
+		},
+		{
+			"an invalid url-encoded body to an endpoint with a form block",
+			"/uploads",
+			"application/x-www-form-urlencoded",
+			"id=five",
+			http.StatusUnprocessableEntity,
+		},
+		{
+			"a json body to an endpoint with a form block",
+			"/uploads",
+			"application/json",
+			`{"id": 5}`,
+			http.StatusOK,
+		},
+	}
+
+	t.Log("Given the need to test which endpoints accept form bodies")
+	{
+		upstream, target := newTestUpstream(t, func(res http.ResponseWriter, req *http.Request) {
+			res.WriteHeader(http.StatusOK)
+		})
+		defer upstream.Close()
+
+		target.Apis = []configs.API{
+			{
+				Type:    configs.TypeRest,
+				Version: "draft-07",
+				Endpoints: []*configs.Endpoint{
+					{Path: "/items", Method: "POST", Schema: schema},
+					{Path: "/uploads", Method: "POST", Schema: schema, Form: &configs.Form{}},
+				},
+			},
+		}
+
+		route, err := newTargetRoute(target)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to set up the target: %v", failed, err)
+		}
+		t.Logf("\t%s\tShould be able to set up the target", succeed)
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When sending %s", index, testCase.description)
+			{
+				req := httptest.NewRequest("POST", testCase.path, strings.NewReader(testCase.body))
+				req.Header.Set("Content-Type", testCase.contentType)
+
+				res := httptest.NewRecorder()
+				route.mm.ServeHTTP(res, req)
+
+				if res.Code != testCase.status {
+					t.Errorf("\t%s\tShould answer %d: got %d", failed, testCase.status, res.Code)
+				} else {
+					t.Logf("\t%s\tShould answer %d", succeed, testCase.status)
+				}
+			}
+		}
+	}
+}
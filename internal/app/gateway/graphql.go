@@ -0,0 +1,67 @@
+package caf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/apidome/gateway/internal/pkg/configs"
+	"github.com/apidome/gateway/internal/pkg/validators/graphqlvalidator"
+	"github.com/pkg/errors"
+)
+
+// newGraphQLValidator creates the validator of a GRAPHQL api with the
+// api's limits and persisted queries
+func newGraphQLValidator(api configs.API) (*graphqlvalidator.GraphQLValidator, error) {
+	graphQLValidator := graphqlvalidator.NewGraphQLValidator()
+
+	graphQLValidator.SetExhaustive(api.Validator.Exhaustive)
+	graphQLValidator.SetLimits(graphqlvalidator.Limits{
+		MaxDepth:      api.GraphQL.MaxDepth,
+		MaxAliases:    api.GraphQL.MaxAliases,
+		MaxComplexity: api.GraphQL.MaxComplexity,
+	})
+
+	if api.GraphQL.PersistedQueries != "" {
+		queries, err := readPersistedQueries(api.GraphQL.PersistedQueries)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read persisted queries "+api.GraphQL.PersistedQueries)
+		}
+
+		graphQLValidator.SetPersistedQueries(queries)
+	}
+
+	return graphQLValidator, nil
+}
+
+// readPersistedQueries reads a file of persisted queries, which is either
+// an object of queries by their ids or an array of queries, whose ids are
+// their sha256 hashes
+func readPersistedQueries(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var queries map[string]string
+
+	if json.Unmarshal(data, &queries) == nil {
+		return queries, nil
+	}
+
+	var list []string
+
+	err = json.Unmarshal(data, &list)
+	if err != nil {
+		return nil, errors.New("expected an object of queries by their ids or an array of queries")
+	}
+
+	queries = make(map[string]string, len(list))
+	for _, query := range list {
+		hash := sha256.Sum256([]byte(query))
+		queries[hex.EncodeToString(hash[:])] = query
+	}
+
+	return queries, nil
+}
@@ -0,0 +1,44 @@
+package caf
+
+import (
+	"io/ioutil"
+
+	"github.com/apidome/gateway/internal/pkg/configs"
+	"github.com/apidome/gateway/internal/pkg/validators/protovalidator"
+	"github.com/pkg/errors"
+)
+
+// newProtoValidator creates the validator of a GRPC api from the api's
+// descriptor set
+func newProtoValidator(api configs.API) (*protovalidator.ProtoValidator, error) {
+	if api.DescriptorSet == "" {
+		return nil, errors.New("GRPC api has no descriptor set")
+	}
+
+	descriptorSet, err := ioutil.ReadFile(api.DescriptorSet)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read descriptor set "+api.DescriptorSet)
+	}
+
+	protoValidator, err := protovalidator.NewProtoValidator(descriptorSet, api.Version)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load descriptor set "+api.DescriptorSet)
+	}
+
+	protoValidator.SetExhaustive(api.Validator.Exhaustive)
+	protoValidator.SetSchemaSources(api.Schemas.Directories, api.Schemas.Mappings)
+
+	return protoValidator, nil
+}
+
+// hasGRPCApis returns true if some of the target's apis are GRPC apis,
+// whose clients may speak gRPC-Web
+func hasGRPCApis(target configs.Target) bool {
+	for _, api := range target.Apis {
+		if api.Type == configs.TypeGRPC {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,42 @@
+package caf
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/apidome/gateway/internal/pkg/configs"
+	"github.com/apidome/gateway/internal/pkg/openapi"
+	"github.com/pkg/errors"
+)
+
+// loadOpenAPIEndpoints derives the endpoints of the target's OPENAPI apis
+// from their OpenAPI documents and adds them to the apis
+func loadOpenAPIEndpoints(target configs.Target) error {
+	for index, api := range target.Apis {
+		if api.Type != configs.TypeOpenAPI {
+			continue
+		}
+
+		if api.Spec == "" {
+			return errors.New("OpenAPI api number " + strconv.Itoa(index) +
+				" of target " + target.GetURL() + " has no spec")
+		}
+
+		document, err := openapi.LoadDocument(api.Spec)
+		if err != nil {
+			return errors.Wrap(err, "failed to load OpenAPI document "+api.Spec)
+		}
+
+		endpoints, err := document.Endpoints()
+		if err != nil {
+			return errors.Wrap(err, "failed to derive endpoints from OpenAPI document "+api.Spec)
+		}
+
+		target.Apis[index].Endpoints = append(api.Endpoints, endpoints...)
+
+		log.Print("[Proxy DEBUG]: Loaded " + strconv.Itoa(len(endpoints)) +
+			" endpoints from OpenAPI document - " + api.Spec)
+	}
+
+	return nil
+}
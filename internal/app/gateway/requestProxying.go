@@ -1,23 +1,58 @@
 package caf
 
 import (
+	"github.com/apidome/gateway/internal/pkg/configs"
 	"github.com/apidome/gateway/internal/pkg/middleman"
-	"github.com/apidome/gateway/internal/pkg/proxy"
+	"github.com/apidome/gateway/internal/pkg/proxymiddlewares"
+	"github.com/apidome/gateway/internal/pkg/validators"
 )
 
-// requestProxying assembles all client request middlewares
-func requestProxying(reverseProxy *middleman.Middleman, pr *proxy.Proxy) {
-	// Log all incoming requests' routes
-	reverseProxy.All("/.*", middleman.RouteLogger())
+// requestProxying assembles all client request middlewares of a target, and
+// returns the validators of the target's apis by their index
+func requestProxying(reverseProxy *middleman.Middleman, target configs.Target) ([]validators.Validator, error) {
+	// Only clients with allowed certificates may reach a target that
+	// requires client certificates
+	if target.ClientAuth {
+		reverseProxy.All("/.*", proxymiddlewares.AuthorizeClient(newClientAuth(target)))
+	}
 
-	// Read variables from the request path and parameters from the
-	// request query
-	reverseProxy.All("/.*", middleman.VariablesReader())
+	// Read parameters from the request query (variables from the request
+	// path are extracted by the middleman according to the endpoints' paths)
 	reverseProxy.All("/.*", middleman.ParametersReader())
 
-	// Read the request body and store it in store["reqeustBody"]
-	// for all middlewares to use
-	reverseProxy.All("/.*", middleman.BodyReader())
+	// Limit the size of the request bodies before anything reads them. The
+	// limits of the endpoints come first, since only the first limit that
+	// matches a request applies.
+	for _, api := range target.Apis {
+		for _, endpoint := range api.Endpoints {
+			if endpoint.MaxBodySize != 0 {
+				addEndpointMiddleware(reverseProxy, endpoint, middleman.BodyLimiter(endpoint.MaxBodySize))
+			}
+		}
+	}
 
-	addValidationMiddlewares(reverseProxy, config.In.Targets)
+	reverseProxy.All("/.*", middleman.BodyLimiter(target.MaxBodySize))
+
+	// The messages of gRPC-Web requests are decoded before they are
+	// validated
+	if hasGRPCApis(target) {
+		reverseProxy.All("/.*", proxymiddlewares.DecodeGRPCWeb())
+	}
+
+	// The bodies of the requests to endpoints with a schema are read by
+	// their validation middlewares, and other bodies are streamed to the
+	// target.
+	apiValidators, err := addValidationMiddlewares(reverseProxy, target)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prepare the validation of the WebSocket messages of the endpoints
+	// whose connections are upgraded
+	err = addMessageValidationMiddlewares(reverseProxy, target, apiValidators)
+	if err != nil {
+		return nil, err
+	}
+
+	return apiValidators, nil
 }
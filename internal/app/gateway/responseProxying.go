@@ -1,15 +1,54 @@
 package caf
 
 import (
+	"github.com/apidome/gateway/internal/pkg/configs"
 	"github.com/apidome/gateway/internal/pkg/middleman"
 	"github.com/apidome/gateway/internal/pkg/proxy"
 	"github.com/apidome/gateway/internal/pkg/proxymiddlewares"
+	"github.com/apidome/gateway/internal/pkg/validators"
 )
 
-// responseProxying assembles all target response middlewares
-func responseProxying(reverseProxy *middleman.Middleman, pr *proxy.Proxy) {
+// responseProxying assembles all target response middlewares. The
+// responses are validated by the validators of the target's apis, by their
+// index.
+func responseProxying(reverseProxy *middleman.Middleman, pr *proxy.Proxy,
+	target configs.Target, apiValidators []validators.Validator) error {
 	reverseProxy.All("/.*", proxymiddlewares.CreateRequest(pr))
+
+	// gRPC-Web requests are sent to the target as gRPC requests
+	if hasGRPCApis(target) {
+		reverseProxy.All("/.*", proxymiddlewares.TranslateGRPCWeb())
+	}
+
+	// Tell the target about the client
+	forwarding, err := proxy.NewForwarding(target.Forwarding.XForwarded,
+		target.Forwarding.Forwarded,
+		target.Forwarding.TrustedProxies)
+	if err != nil {
+		return err
+	}
+
+	reverseProxy.All("/.*", proxymiddlewares.SetForwardedHeaders(forwarding))
+
+	// Tell the target who the client is
+	if target.ClientAuth {
+		reverseProxy.All("/.*", proxymiddlewares.SetClientIdentityHeaders(newClientAuth(target)))
+	}
+
+	// Connections that switch protocols are tunneled to the target
+	reverseProxy.All("/.*", proxymiddlewares.ProxyUpgrade(pr))
+
 	reverseProxy.All("/.*", proxymiddlewares.SendRequest(pr))
-	reverseProxy.All("/.*", proxymiddlewares.ReadResponseBody())
+
+	// Validate the target response before it is sent to the client. The
+	// responses of endpoints that are not validated are streamed to the
+	// client.
+	err = addResponseValidationMiddlewares(reverseProxy, target, apiValidators)
+	if err != nil {
+		return err
+	}
+
 	reverseProxy.All("/.*", proxymiddlewares.SendResponse())
+
+	return nil
 }
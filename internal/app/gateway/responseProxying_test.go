@@ -0,0 +1,94 @@
+package caf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apidome/gateway/internal/pkg/configs"
+)
+
+func TestResponseValidation(t *testing.T) {
+	bodies := map[string]string{
+		"/items/valid":   `{"id": 1}`,
+		"/items/invalid": `{"name": "a"}`,
+		"/items/empty":   "",
+	}
+
+	testCases := []struct {
+		description string
+		method      string
+		path        string
+		status      int
+	}{
+		{"a valid response", "GET", "/items/valid", http.StatusOK},
+		{"an invalid response", "GET", "/items/invalid", http.StatusBadGateway},
+		{"a response with an empty body", "GET", "/items/empty", http.StatusOK},
+		{"a response without content", "GET", "/items/none", http.StatusNoContent},
+		{"a response to a HEAD request", "HEAD", "/items/invalid", http.StatusOK},
+	}
+
+	t.Log("Given the need to test validation of target responses")
+	{
+		upstream, target := newTestUpstream(t, func(res http.ResponseWriter, req *http.Request) {
+			body, ok := bodies[req.URL.Path]
+			if !ok {
+				res.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			res.Header().Set("Content-Type", "application/json")
+			res.WriteHeader(http.StatusOK)
+			res.Write([]byte(body))
+		})
+		defer upstream.Close()
+
+		responses := []*configs.Response{
+			{Status: "2XX", Schema: `{"type": "object", "required": ["id"]}`},
+		}
+
+		target.Apis = []configs.API{
+			{
+				Type:    configs.TypeRest,
+				Version: "draft-07",
+				Endpoints: []*configs.Endpoint{
+					{Path: "/items/:name", Method: "GET", Responses: responses},
+					{Path: "/items/:name", Method: "HEAD", Responses: responses},
+				},
+			},
+		}
+
+		route, err := newTargetRoute(target)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to set up the target: %v", failed, err)
+		}
+		t.Logf("\t%s\tShould be able to set up the target", succeed)
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When the target sends %s", index, testCase.description)
+			{
+				res := httptest.NewRecorder()
+				route.mm.ServeHTTP(res, httptest.NewRequest(testCase.method, testCase.path, nil))
+
+				if res.Code != testCase.status {
+					t.Errorf("\t%s\tShould answer %d: got %d", failed, testCase.status, res.Code)
+				} else {
+					t.Logf("\t%s\tShould answer %d", succeed, testCase.status)
+				}
+			}
+		}
+
+		t.Logf("\tTest %d: When an api that is not described by json schemas has response schemas",
+			len(testCases))
+		{
+			target.Apis[0].Type = configs.TypeXML
+
+			_, err = newTargetRoute(target)
+			if err == nil {
+				t.Errorf("\t%s\tShould not be able to set up the target", failed)
+			} else {
+				t.Logf("\t%s\tShould not be able to set up the target: %v", succeed, err)
+			}
+		}
+	}
+}
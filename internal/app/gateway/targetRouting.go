@@ -0,0 +1,250 @@
+package caf
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apidome/gateway/internal/pkg/configs"
+	"github.com/apidome/gateway/internal/pkg/middleman"
+	"github.com/apidome/gateway/internal/pkg/proxy"
+	"github.com/pkg/errors"
+)
+
+// targetRoute binds a target to the middleman that handles its traffic
+type targetRoute struct {
+	host       string
+	pathPrefix string
+	target     configs.Target
+	mm         *middleman.Middleman
+}
+
+// newTargetRoute creates a route for a target according to its match block,
+// and assembles the target's own proxy and middlewares
+func newTargetRoute(target configs.Target) (*targetRoute, error) {
+	route := &targetRoute{
+		host:       normalizeHost(target.Match.Host),
+		pathPrefix: normalizePathPrefix(target.Match.PathPrefix),
+		target:     target,
+		mm:         middleman.NewMiddleman("", middlewareErrorHandler),
+	}
+
+	prx, err := newTargetProxy(target)
+	if err != nil {
+		return nil, err
+	}
+
+	err = loadOpenAPIEndpoints(target)
+	if err != nil {
+		return nil, err
+	}
+
+	// The validators of the apis validate both the requests and the
+	// responses
+	apiValidators, err := requestProxying(route.mm, target)
+	if err != nil {
+		return nil, err
+	}
+
+	err = responseProxying(route.mm, prx, target, apiValidators)
+	if err != nil {
+		return nil, err
+	}
+
+	return route, nil
+}
+
+// newTargetProxy creates the proxy that forwards requests to a target,
+// according to the target's transport settings
+func newTargetProxy(target configs.Target) (*proxy.Proxy, error) {
+	prx := proxy.NewProxy(target.GetURL())
+
+	transport := target.Transport
+
+	err := prx.SetTransport(proxy.TransportOptions{
+		DialTimeout:           time.Duration(transport.DialTimeout),
+		TLSHandshakeTimeout:   time.Duration(transport.TLSHandshakeTimeout),
+		ResponseHeaderTimeout: time.Duration(transport.ResponseHeaderTimeout),
+		IdleConnTimeout:       time.Duration(transport.IdleConnTimeout),
+		Timeout:               time.Duration(transport.Timeout),
+		MaxIdleConns:          transport.MaxIdleConns,
+		MaxConns:              transport.MaxConns,
+		CAFile:                transport.CAPath,
+		CertificateFile:       transport.CertificatePath,
+		KeyFile:               transport.KeyPath,
+		ServerName:            transport.ServerName,
+		InsecureSkipVerify:    transport.InsecureSkipVerify,
+		HTTP2:                 transport.HTTP2,
+		H2C:                   transport.H2C,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid transport settings")
+	}
+
+	if transport.InsecureSkipVerify {
+		log.Print("[Proxy WARNING]: The certificate of target " + target.GetURL() + " is not verified")
+	}
+
+	// Spread the requests between the instances of the target
+	if len(target.Upstreams) > 0 {
+		loadBalancing := target.LoadBalancing
+
+		balancer, err := proxy.NewBalancer(target.GetUpstreamURLs(), proxy.BalancerOptions{
+			Strategy:            loadBalancing.Strategy,
+			HashHeader:          loadBalancing.HashHeader,
+			HealthCheckPath:     loadBalancing.HealthCheck.Path,
+			HealthCheckInterval: time.Duration(loadBalancing.HealthCheck.Interval),
+			HealthCheckTimeout:  time.Duration(loadBalancing.HealthCheck.Timeout),
+			HealthyThreshold:    loadBalancing.HealthCheck.HealthyThreshold,
+			UnhealthyThreshold:  loadBalancing.HealthCheck.UnhealthyThreshold,
+			MaxFailures:         loadBalancing.MaxFailures,
+			EjectionTime:        time.Duration(loadBalancing.EjectionTime),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid load balancing settings")
+		}
+
+		prx.SetBalancer(balancer)
+	}
+
+	if breaker := target.CircuitBreaker; breaker.FailureThreshold > 0 {
+		prx.SetCircuitBreaker(proxy.CircuitBreakerOptions{
+			FailureThreshold: breaker.FailureThreshold,
+			OpenTime:         time.Duration(breaker.OpenTime),
+			HalfOpenRequests: breaker.HalfOpenRequests,
+		})
+	}
+
+	if retries := target.Retries; retries.Attempts > 0 {
+		prx.SetRetries(proxy.RetryOptions{
+			Attempts:         retries.Attempts,
+			Backoff:          time.Duration(retries.Backoff),
+			MaxBackoff:       time.Duration(retries.MaxBackoff),
+			RetryOn:          retries.RetryOn,
+			BudgetRatio:      retries.BudgetRatio,
+			BudgetMinRetries: retries.BudgetMinRetries,
+		})
+	}
+
+	return prx, nil
+}
+
+// claim returns a string that identifies the route that the target claims
+func (tr *targetRoute) claim() string {
+	host := tr.host
+	if host == "" {
+		host = "*"
+	}
+
+	return host + tr.pathPrefix
+}
+
+// matches returns true if a request should be routed to the target
+func (tr *targetRoute) matches(req *http.Request) bool {
+	if tr.host != "" {
+		host := normalizeHost(req.Host)
+
+		// If the match block does not specify a port, compare the
+		// host names only
+		if !strings.Contains(tr.host, ":") {
+			if hostname, _, err := net.SplitHostPort(host); err == nil {
+				host = hostname
+			}
+		}
+
+		if host != tr.host {
+			return false
+		}
+	}
+
+	if tr.pathPrefix == "/" {
+		return true
+	}
+
+	// Compare whole path segments, so "/api" will not match "/apis"
+	return req.URL.Path == tr.pathPrefix ||
+		strings.HasPrefix(req.URL.Path, tr.pathPrefix+"/")
+}
+
+// buildTargetRoutes creates a route for each of the targets and returns the
+// routes ordered from the most specific to the least specific.
+// An error is returned if two targets claim the same route.
+func buildTargetRoutes(targets []configs.Target) ([]*targetRoute, error) {
+	var routes []*targetRoute
+	claims := make(map[string]configs.Target)
+
+	for _, target := range targets {
+		route, err := newTargetRoute(target)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to set up target "+target.GetURL())
+		}
+
+		// Two targets with the same route would make one of them unreachable
+		if other, ok := claims[route.claim()]; ok {
+			return nil, errors.New("targets " + other.GetURL() + " and " +
+				target.GetURL() + " both claim the route " + route.claim())
+		}
+
+		claims[route.claim()] = target
+		routes = append(routes, route)
+	}
+
+	// Routes with a host come before routes without one, and longer path
+	// prefixes come before shorter ones
+	sort.SliceStable(routes, func(i, j int) bool {
+		if (routes[i].host != "") != (routes[j].host != "") {
+			return routes[i].host != ""
+		}
+
+		return len(routes[i].pathPrefix) > len(routes[j].pathPrefix)
+	})
+
+	return routes, nil
+}
+
+// selectTargetRoute returns the most specific route that matches a request,
+// or nil if no route matches it
+func selectTargetRoute(routes []*targetRoute, req *http.Request) *targetRoute {
+	for _, route := range routes {
+		if route.matches(req) {
+			return route
+		}
+	}
+
+	return nil
+}
+
+// targetDispatcher is a middleware that hands a request to the middlewares of
+// the target it is routed to
+func targetDispatcher(routes []*targetRoute) middleman.Middleware {
+	return func(res http.ResponseWriter, req *http.Request,
+		store middleman.Store, end middleman.End) error {
+		route := selectTargetRoute(routes, req)
+
+		// If no target matches the request, let the following
+		// middlewares handle it
+		if route == nil {
+			return nil
+		}
+
+		route.mm.ServeHTTP(res, req)
+
+		end()
+
+		return nil
+	}
+}
+
+// normalizeHost lower-cases a host so it can be compared to the Host header
+func normalizeHost(host string) string {
+	return strings.ToLower(strings.TrimSpace(host))
+}
+
+// normalizePathPrefix makes sure a path prefix starts with a '/' and does
+// not end with one (unless the prefix is the root path)
+func normalizePathPrefix(prefix string) string {
+	return "/" + strings.Trim(strings.TrimSpace(prefix), "/")
+}
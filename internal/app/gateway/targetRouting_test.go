@@ -0,0 +1,97 @@
+package caf
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apidome/gateway/internal/pkg/configs"
+)
+
+func TestTargetRouting(t *testing.T) {
+	targets := []configs.Target{
+		{Host: "localhost", Port: "8001", Match: configs.Match{Host: "API.example.com", PathPrefix: "/api/v1/"}},
+		{Host: "localhost", Port: "8002", Match: configs.Match{PathPrefix: "api"}},
+		{Host: "localhost", Port: "8003"},
+		{Host: "localhost", Port: "8004", Match: configs.Match{Host: "api.example.com:8443"}},
+		{Host: "localhost", Port: "8005", Match: configs.Match{Host: "api.example.com", PathPrefix: "/api"}},
+	}
+
+	testCases := []struct {
+		description string
+		host        string
+		path        string
+		port        string
+	}{
+		{"a request to the path of a host", "api.example.com", "/api/v1/pets", "8001"},
+		{"a request to a host in another case and with a port", "API.Example.com:80", "/api/v1", "8001"},
+		{"a request to a shorter path of a host", "api.example.com", "/api/v2", "8005"},
+		{"a request to a path that only starts like a prefix", "api.example.com", "/apis", "8003"},
+		{"a request to a host and port", "api.example.com:8443", "/other", "8004"},
+		{"a request to a longer path of a host than its port claims", "api.example.com:8443", "/api/x", "8005"},
+		{"a request to a path of any host", "other.example.com", "/api/v1", "8002"},
+		{"a request that only the catch-all target matches", "other.example.com", "/", "8003"},
+	}
+
+	t.Log("Given the need to test routing of requests to targets")
+	{
+		routes, err := buildTargetRoutes(targets)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to build the routes: %v", failed, err)
+		}
+		t.Logf("\t%s\tShould be able to build the routes", succeed)
+
+		var claims []string
+		for _, route := range routes {
+			claims = append(claims, route.claim())
+		}
+
+		expectedClaims := "api.example.com/api/v1, api.example.com/api, api.example.com:8443/, */api, */"
+		if strings.Join(claims, ", ") != expectedClaims {
+			t.Errorf("\t%s\tShould order the routes from the most specific: got %s", failed,
+				strings.Join(claims, ", "))
+		} else {
+			t.Logf("\t%s\tShould order the routes from the most specific", succeed)
+		}
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When routing %s", index, testCase.description)
+			{
+				req := httptest.NewRequest("GET", testCase.path, nil)
+				req.Host = testCase.host
+
+				route := selectTargetRoute(routes, req)
+				if route == nil || route.target.Port != testCase.port {
+					t.Errorf("\t%s\tShould route the request to the target on port %s", failed, testCase.port)
+				} else {
+					t.Logf("\t%s\tShould route the request to the target on port %s", succeed, testCase.port)
+				}
+			}
+		}
+
+		t.Logf("\tTest %d: When no target matches a request", len(testCases))
+		{
+			req := httptest.NewRequest("GET", "/pets", nil)
+			req.Host = "api.example.com"
+
+			if route := selectTargetRoute(routes[:3], req); route != nil {
+				t.Errorf("\t%s\tShould not route the request", failed)
+			} else {
+				t.Logf("\t%s\tShould not route the request", succeed)
+			}
+		}
+
+		t.Logf("\tTest %d: When two targets claim the same route", len(testCases)+1)
+		{
+			_, err = buildTargetRoutes([]configs.Target{
+				{Host: "localhost", Port: "8001", Match: configs.Match{Host: "API.example.com", PathPrefix: "/api/"}},
+				{Host: "localhost", Port: "8002", Match: configs.Match{Host: "api.example.com", PathPrefix: "api"}},
+			})
+			if err == nil {
+				t.Errorf("\t%s\tShould not be able to build the routes", failed)
+			} else {
+				t.Logf("\t%s\tShould not be able to build the routes: %v", succeed, err)
+			}
+		}
+	}
+}
@@ -13,67 +13,374 @@ import (
 	"github.com/pkg/errors"
 )
 
-// AddValidationMiddlewares gets a reference to a Middleman and a slice of targets
-// and creates a new middleware for each endpoint in the targets' apis.
-func addValidationMiddlewares(mm *middleman.Middleman, targets []configs.Target) error {
-	// Loop over the targets slice
-	for _, target := range targets {
-		// For each target loop over its apis
-		for index, api := range target.Apis {
-			var err error
-			var validator validators.Validator
-
-			// Each api has a validator that filter the api's traffic.
-			// Here we decide which validator to create according to the api's type.
-			switch api.Type {
-			case configs.TypeRest:
-				validator, err = jsonvalidator.NewJsonValidator(api.Version)
+// AddValidationMiddlewares gets a reference to a Middleman and a target
+// and creates a new middleware for each endpoint in the target's apis.
+// It returns the validators of the apis by their index (nil for apis of an
+// unknown type), which validate the rest of their traffic as well, so all
+// the schemas of an api share a registry.
+func addValidationMiddlewares(mm *middleman.Middleman, target configs.Target) ([]validators.Validator, error) {
+	apiValidators := make([]validators.Validator, len(target.Apis))
+
+	// Loop over the target's apis
+	for index, api := range target.Apis {
+		validator, err := newValidator(api)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to created validator for number - "+strconv.Itoa(index))
+		}
+
+		if validator == nil {
+			continue
+		}
+
+		apiValidators[index] = validator
+
+		// For each api loop over its endpoints
+		for _, endpoint := range api.Endpoints {
+			// Validate the endpoint's parameters before its body, so a
+			// request to a malformed url is rejected as early as possible.
+			err = addParametersValidation(mm, validator, api, endpoint)
+			if err != nil {
+				return nil, err
+			}
+
+			// An endpoint without a schema has no body to validate, but
+			// the files that are uploaded to it may be constrained.
+			if endpoint.Schema == "" {
+				if endpoint.Form != nil {
+					addEndpointMiddleware(mm, endpoint, middleman.BodyReader())
+
+					err = addFormParsing(mm, api, endpoint)
+					if err != nil {
+						return nil, err
+					}
+				}
+
+				continue
+			}
+
+			//Add the endpoint's schema to the api's validator.
+			err = validator.LoadSchema(endpoint.Path, endpoint.Method, []byte(endpoint.Schema))
+			if err != nil {
+				log.Print("[Proxy ERROR]: Failed to load schema for endpoint - " + endpoint.Path + ", Error: " + err.Error())
+				return nil, err
+			}
+
+			// Read the request body and store it in store["requestBody"]
+			// for the validation middleware to use.
+			addEndpointMiddleware(mm, endpoint, middleman.BodyReader())
+
+			// Form bodies are parsed into their fields, which are
+			// validated against the endpoint's schema like json objects,
+			// only if the endpoint accepts them.
+			if endpoint.Form != nil {
+				err = addFormParsing(mm, api, endpoint)
 				if err != nil {
-					return errors.Wrap(err, "failed to created validator for number - "+strconv.Itoa(index))
+					return nil, err
 				}
-			default:
-				log.Print("[Proxy WARNING]: Invalid API Type - " + api.Type)
 			}
 
-			// For each api loop over its endpoints
-			for _, endpoint := range api.Endpoints {
-				//Add the endpoint's schema to the api's validator.
-				err := validator.LoadSchema(endpoint.Path, endpoint.Method, []byte(endpoint.Schema))
+			// Creating a new ValidateRequest middleware with the appropriate HTTP method.
+			if addEndpointMiddleware(mm, endpoint, proxymiddlewares.ValidateRequest(endpoint.Path,
+				endpoint.Method,
+				validator,
+				api.Validator)) {
+				log.Print("[Proxy DEBUG]: Added middleware for - " + endpoint.Method + " " + endpoint.Path)
+			}
+		}
+
+		// References may point to the schemas of endpoints that were
+		// loaded after the referencing schemas.
+		err = resolveReferences(validator)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return apiValidators, nil
+}
+
+// addResponseValidationMiddlewares gets a reference to a Middleman and a
+// target and creates a ValidateResponse middleware for each endpoint in the
+// target's apis that describes its responses.
+// The apis' validators are the ones that addValidationMiddlewares created.
+// Response schemas describe json bodies, so an error is returned if the
+// api's validator cannot validate its responses.
+func addResponseValidationMiddlewares(mm *middleman.Middleman, target configs.Target,
+	apiValidators []validators.Validator) error {
+	for index, api := range target.Apis {
+		validator := apiValidators[index]
+		if validator == nil {
+			continue
+		}
+
+		responseValidator, ok := validator.(validators.ResponseValidator)
+
+		for _, endpoint := range api.Endpoints {
+			if len(endpoint.Responses) == 0 {
+				continue
+			}
+
+			if !ok {
+				return errors.New("endpoint " + endpoint.Method + " " + endpoint.Path +
+					" of a " + api.Type + " api cannot have response schemas")
+			}
+
+			for _, response := range endpoint.Responses {
+				err := responseValidator.LoadResponseSchema(endpoint.Path,
+					endpoint.Method,
+					response.Status,
+					response.ContentType,
+					[]byte(response.Schema))
 				if err != nil {
-					log.Print("[Proxy ERROR]: Failed to load schema for endpoint - " + endpoint.Path + ", Error: " + err.Error())
+					log.Print("[Proxy ERROR]: Failed to load response schema for endpoint - " +
+						endpoint.Path + ", Error: " + err.Error())
 					return err
 				}
+			}
+
+			// Read the target response body and store it in
+			// store["targetResponseBody"] for the validation middleware to use.
+			addEndpointMiddleware(mm, endpoint, proxymiddlewares.ReadResponseBody())
+
+			if addEndpointMiddleware(mm, endpoint, proxymiddlewares.ValidateResponse(endpoint.Path,
+				endpoint.Method,
+				responseValidator,
+				api.Validator)) {
+				log.Print("[Proxy DEBUG]: Added response middleware for - " + endpoint.Method + " " + endpoint.Path)
+			}
+		}
+
+		if ok {
+			err := resolveReferences(responseValidator)
+			if err != nil {
+				return err
+			}
+		}
+	}
 
-				// Creating a new ValidateRequest middleware with the appropriate HTTP method.
-				switch endpoint.Method {
-				case http.MethodGet:
-					mm.Get(endpoint.Path, proxymiddlewares.ValidateRequest(endpoint.Path,
-						endpoint.Method,
-						validator))
-				case http.MethodPost:
-					mm.Post(endpoint.Path, proxymiddlewares.ValidateRequest(endpoint.Path,
-						endpoint.Method,
-						validator))
-				case http.MethodPut:
-					mm.Put(endpoint.Path, proxymiddlewares.ValidateRequest(endpoint.Path,
-						endpoint.Method,
-						validator))
-				case http.MethodDelete:
-					mm.Delete(endpoint.Path, proxymiddlewares.ValidateRequest(endpoint.Path,
-						endpoint.Method,
-						validator))
-				case "ALL":
-					mm.All(endpoint.Path, proxymiddlewares.ValidateRequest(endpoint.Path,
-						endpoint.Method,
-						validator))
-				default:
-					log.Print("[Proxy WARNING]: Invalid method - " + endpoint.Method + " for endpoint - " + endpoint.Path)
+	return nil
+}
+
+// addMessageValidationMiddlewares gets a reference to a Middleman and a
+// target and creates a ValidateMessages middleware for each endpoint in the
+// target's apis that describes its WebSocket messages.
+// Messages are described by json schemas regardless of the api's type, so
+// like parameters they are validated by a JsonValidator if the api's
+// validator cannot validate them.
+func addMessageValidationMiddlewares(mm *middleman.Middleman, target configs.Target,
+	apiValidators []validators.Validator) error {
+	for index, api := range target.Apis {
+		messageValidator, _ := apiValidators[index].(validators.MessageValidator)
+
+		for _, endpoint := range api.Endpoints {
+			if endpoint.MessageSchema == "" {
+				continue
+			}
+
+			if messageValidator == nil {
+				jsonValidator, err := newFallbackJsonValidator(api)
+				if err != nil {
+					return err
 				}
 
-				log.Print("[Proxy DEBUG]: Added middleware for - " + endpoint.Method + " " + endpoint.Path)
+				messageValidator = jsonValidator
+			}
+
+			err := messageValidator.LoadMessageSchema(endpoint.Path, endpoint.Method, []byte(endpoint.MessageSchema))
+			if err != nil {
+				log.Print("[Proxy ERROR]: Failed to load message schema for endpoint - " +
+					endpoint.Path + ", Error: " + err.Error())
+				return err
 			}
+
+			if addEndpointMiddleware(mm, endpoint, proxymiddlewares.ValidateMessages(endpoint.Path,
+				endpoint.Method,
+				messageValidator,
+				api.Validator)) {
+				log.Print("[Proxy DEBUG]: Added message middleware for - " + endpoint.Method + " " + endpoint.Path)
+			}
+		}
+
+		if messageValidator != nil {
+			err := resolveReferences(messageValidator)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolveReferences makes sure that the references of the schemas that were
+// loaded into a validator point to existing schemas, if the validator's
+// schemas can reference other schemas.
+func resolveReferences(validator interface{}) error {
+	resolver, ok := validator.(validators.ReferenceResolver)
+	if !ok {
+		return nil
+	}
+
+	err := resolver.ResolveReferences()
+	if err != nil {
+		log.Print("[Proxy ERROR]: Failed to resolve the references of the schemas, Error: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// newValidator creates the validator that filters an api's traffic
+// according to the api's type.
+// It returns nil if the api's type is unknown.
+func newValidator(api configs.API) (validators.Validator, error) {
+	switch api.Type {
+	case configs.TypeRest:
+		jsonValidator, err := jsonvalidator.NewJsonValidator(api.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		jsonValidator.SetExhaustive(api.Validator.Exhaustive)
+		jsonValidator.SetSchemaSources(api.Schemas.Directories, api.Schemas.Mappings)
+
+		return jsonValidator, nil
+	case configs.TypeOpenAPI:
+		// The schemas that are derived from OpenAPI 3.0 documents are
+		// translated to draft-07, and OpenAPI 3.1 schemas declare draft
+		// 2020-12 in "$schema".
+		jsonValidator, err := jsonvalidator.NewJsonValidator(jsonvalidator.DRAFT_07)
+		if err != nil {
+			return nil, err
+		}
+
+		jsonValidator.SetExhaustive(api.Validator.Exhaustive)
+		jsonValidator.SetSchemaSources(api.Schemas.Directories, api.Schemas.Mappings)
+
+		return jsonValidator, nil
+	case configs.TypeGRPC:
+		return newProtoValidator(api)
+	case configs.TypeGraphQL:
+		return newGraphQLValidator(api)
+	case configs.TypeXML:
+		return newXMLValidator(api), nil
+	default:
+		log.Print("[Proxy WARNING]: Invalid API Type - " + api.Type)
+		return nil, nil
+	}
+}
+
+// newFallbackJsonValidator creates a JsonValidator for the parts of an api's
+// traffic that are described by json schemas regardless of the api's type.
+func newFallbackJsonValidator(api configs.API) (*jsonvalidator.JsonValidator, error) {
+	jsonValidator, err := jsonvalidator.NewJsonValidator(jsonvalidator.DRAFT_07)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonValidator.SetExhaustive(api.Validator.Exhaustive)
+	jsonValidator.SetSchemaSources(api.Schemas.Directories, api.Schemas.Mappings)
+
+	return &jsonValidator, nil
+}
+
+// addParametersValidation loads the schemas of an endpoint's parameters
+// and creates a ValidateParameters middleware for the endpoint.
+// Parameters are described by json schemas regardless of the api's type,
+// so if the api's validator cannot validate them a JsonValidator is used.
+func addParametersValidation(mm *middleman.Middleman, validator validators.Validator,
+	api configs.API, endpoint *configs.Endpoint) error {
+	schemas := map[string]string{
+		validators.ParametersPath:   endpoint.Parameters.Path,
+		validators.ParametersQuery:  endpoint.Parameters.Query,
+		validators.ParametersHeader: endpoint.Parameters.Header,
+	}
+
+	var locations []string
+	for _, location := range []string{
+		validators.ParametersPath,
+		validators.ParametersQuery,
+		validators.ParametersHeader,
+	} {
+		if schemas[location] != "" {
+			locations = append(locations, location)
+		}
+	}
+
+	if len(locations) == 0 {
+		return nil
+	}
+
+	parametersValidator, ok := validator.(validators.ParametersValidator)
+	if !ok {
+		jsonValidator, err := newFallbackJsonValidator(api)
+		if err != nil {
+			return err
+		}
+
+		parametersValidator = jsonValidator
+	}
+
+	for _, location := range locations {
+		err := parametersValidator.LoadParametersSchema(endpoint.Path,
+			endpoint.Method,
+			location,
+			[]byte(schemas[location]))
+		if err != nil {
+			log.Print("[Proxy ERROR]: Failed to load " + location + " parameters schema for endpoint - " +
+				endpoint.Path + ", Error: " + err.Error())
+			return err
+		}
+	}
+
+	// The api's validator resolves its references once all of its schemas
+	// were loaded, but a fallback validator only holds these schemas.
+	if !ok {
+		err := resolveReferences(parametersValidator)
+		if err != nil {
+			return err
 		}
 	}
 
+	if addEndpointMiddleware(mm, endpoint, proxymiddlewares.ValidateParameters(endpoint.Path,
+		endpoint.Method,
+		locations,
+		parametersValidator,
+		api.Validator)) {
+		log.Print("[Proxy DEBUG]: Added parameters middleware for - " + endpoint.Method + " " + endpoint.Path)
+	}
+
 	return nil
 }
+
+// addEndpointMiddleware registers a middleware for an endpoint with the
+// appropriate HTTP method.
+// It returns false if the endpoint's method is not supported.
+func addEndpointMiddleware(mm *middleman.Middleman, endpoint *configs.Endpoint,
+	middleware middleman.Middleware) bool {
+	switch endpoint.Method {
+	case http.MethodGet:
+		mm.Get(endpoint.Path, middleware)
+	case http.MethodPost:
+		mm.Post(endpoint.Path, middleware)
+	case http.MethodPut:
+		mm.Put(endpoint.Path, middleware)
+	case http.MethodDelete:
+		mm.Delete(endpoint.Path, middleware)
+	case http.MethodPatch:
+		mm.Patch(endpoint.Path, middleware)
+	case http.MethodHead:
+		mm.Head(endpoint.Path, middleware)
+	case http.MethodOptions:
+		mm.Options(endpoint.Path, middleware)
+	case http.MethodTrace:
+		mm.Trace(endpoint.Path, middleware)
+	case "ALL":
+		mm.All(endpoint.Path, middleware)
+	default:
+		log.Print("[Proxy WARNING]: Invalid method - " + endpoint.Method + " for endpoint - " + endpoint.Path)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,21 @@
+package caf
+
+import (
+	"github.com/apidome/gateway/internal/pkg/configs"
+	"github.com/apidome/gateway/internal/pkg/validators/xmlvalidator"
+)
+
+// newXMLValidator creates the validator of an XML api with the api's limits
+// and schema sources
+func newXMLValidator(api configs.API) *xmlvalidator.XMLValidator {
+	xmlValidator := xmlvalidator.NewXMLValidator()
+
+	xmlValidator.SetExhaustive(api.Validator.Exhaustive)
+	xmlValidator.SetSchemaSources(api.Schemas.Directories, api.Schemas.Mappings)
+	xmlValidator.SetLimits(xmlvalidator.Limits{
+		MaxDepth:     api.XML.MaxDepth,
+		MaxExpansion: api.XML.MaxExpansion,
+	})
+
+	return xmlValidator
+}
@@ -3,6 +3,18 @@ package configs
 const (
 	// TypeRest Indicates REST configurations
 	TypeRest = "REST"
+
+	// TypeOpenAPI Indicates an API that is described by an OpenAPI 3 document
+	TypeOpenAPI = "OPENAPI"
+
+	// TypeGRPC Indicates a gRPC API that is described by a descriptor set
+	TypeGRPC = "GRPC"
+
+	// TypeGraphQL Indicates a GraphQL API that is described by an SDL schema
+	TypeGraphQL = "GRAPHQL"
+
+	// TypeXML Indicates an XML (or SOAP) API that is described by XSD schemas
+	TypeXML = "XML"
 )
 
 // API holds information on a specific API
@@ -11,4 +23,22 @@ type API struct {
 	Version   string      `json:"version"`
 	Validator Validator   `json:"validator"`
 	Endpoints []*Endpoint `json:"endpoints"`
+
+	// Spec is the path of the OpenAPI document of an OPENAPI api, from
+	// which the api's endpoints are derived.
+	Spec string `json:"spec"`
+
+	// DescriptorSet is the path of the descriptor set of a GRPC api, which
+	// describes the messages of the api's methods.
+	DescriptorSet string `json:"descriptorSet"`
+
+	// GraphQL holds the limits of the operations of a GRAPHQL api.
+	GraphQL GraphQL `json:"graphql"`
+
+	// XML holds the limits of the documents of an XML api.
+	XML XML `json:"xml"`
+
+	// Schemas tells where to find the schemas that the api's schemas
+	// reference but that are not schemas of its endpoints.
+	Schemas Schemas `json:"schemas"`
 }
@@ -0,0 +1,18 @@
+package configs
+
+// CircuitBreaker determines when the gateway stops sending requests to an
+// upstream (or to the target itself) that keeps failing.
+type CircuitBreaker struct {
+	// FailureThreshold is the number of consecutive failed requests that
+	// open the circuit. If it is 0, there is no circuit breaker.
+	FailureThreshold int `json:"failureThreshold"`
+
+	// OpenTime is the time the circuit stays open, during which the
+	// requests are answered with 503.
+	OpenTime Duration `json:"openTime"`
+
+	// HalfOpenRequests is the number of requests that are let through once
+	// the open time passes, all of which should succeed to close the
+	// circuit.
+	HalfOpenRequests int `json:"halfOpenRequests"`
+}
@@ -0,0 +1,29 @@
+package configs
+
+// ClientCertificates determines which client certificates a target with
+// clientAuth accepts, and how the gateway tells the target who the client
+// is. The certificates are verified against the certificate authorities in
+// the clientCaPath of the out section.
+type ClientCertificates struct {
+	// Subjects is an allowlist of the distinguished names ("CN=a,O=b") or
+	// common names of the certificates' subjects.
+	Subjects []string `json:"subjects"`
+
+	// SANs is an allowlist of the subject alternative names of the
+	// certificates, prefixed by their types ("DNS:", "email:", "URI:" or
+	// "IP:"). If both allowlists are empty, any verified certificate is
+	// accepted.
+	SANs []string `json:"sans"`
+
+	// SubjectHeader is the header in which the subject of the client's
+	// certificate is sent to the target.
+	SubjectHeader string `json:"subjectHeader"`
+
+	// SANsHeader is the header in which the subject alternative names of
+	// the client's certificate are sent to the target.
+	SANsHeader string `json:"sansHeader"`
+
+	// CertificateHeader is the header in which the URL encoded PEM of the
+	// client's certificate is sent to the target.
+	CertificateHeader string `json:"certificateHeader"`
+}
@@ -61,21 +61,73 @@ func readConf(config *Configuration) error {
 	SettingsFolderPath :=
 		path.Dir(strings.ReplaceAll(config.SettingsFilePath, "\\", "/")) + "/"
 
-	// Read the schema of each endpoint from
-	// file and set it in the schema field.
+	// Read the schemas of each endpoint from
+	// files and set them in the schema fields.
 	for _, target := range config.In.Targets {
-		for _, api := range target.Apis {
+		for index, api := range target.Apis {
+			// The OpenAPI document is read when the api's endpoints are
+			// derived from it.
+			if api.Spec != "" {
+				target.Apis[index].Spec = SettingsFolderPath + api.Spec
+			}
+
+			// The descriptor set is read when the api's validator is
+			// created.
+			if api.DescriptorSet != "" {
+				target.Apis[index].DescriptorSet = SettingsFolderPath + api.DescriptorSet
+			}
+
+			// The persisted queries are read when the api's validator is
+			// created.
+			if api.GraphQL.PersistedQueries != "" {
+				target.Apis[index].GraphQL.PersistedQueries = SettingsFolderPath + api.GraphQL.PersistedQueries
+			}
+
+			// The referenced schemas are read when the schemas that
+			// reference them are loaded.
+			for directoryIndex, directory := range api.Schemas.Directories {
+				api.Schemas.Directories[directoryIndex] = SettingsFolderPath + directory
+			}
+
+			for uri, file := range api.Schemas.Mappings {
+				api.Schemas.Mappings[uri] = SettingsFolderPath + file
+			}
+
 			for _, endpoint := range api.Endpoints {
-				// Read the data from file.
-				schema, err :=
-					ioutil.ReadFile(SettingsFolderPath + endpoint.Schema)
+				schemas := []*string{
+					&endpoint.Schema,
+					&endpoint.Parameters.Path,
+					&endpoint.Parameters.Query,
+					&endpoint.Parameters.Header,
+					&endpoint.MessageSchema,
+				}
 
-				if err != nil {
-					return err
+				for _, response := range endpoint.Responses {
+					schemas = append(schemas, &response.Schema)
 				}
 
-				// Set the actual schema in the endpoint.
-				endpoint.Schema = string(schema)
+				for _, schema := range schemas {
+					err = readSchema(SettingsFolderPath, schema)
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	// Read the certificates that the gateway uses to connect to the targets
+	// relative to the settings folder.
+	for index := range config.In.Targets {
+		transport := &config.In.Targets[index].Transport
+
+		for _, filePath := range []*string{
+			&transport.CAPath,
+			&transport.CertificatePath,
+			&transport.KeyPath,
+		} {
+			if *filePath != "" {
+				*filePath = SettingsFolderPath + *filePath
 			}
 		}
 	}
@@ -85,6 +137,30 @@ func readConf(config *Configuration) error {
 
 	config.Out.KeyPath = SettingsFolderPath + config.Out.KeyPath
 
+	if config.Out.ClientCAPath != "" {
+		config.Out.ClientCAPath = SettingsFolderPath + config.Out.ClientCAPath
+	}
+
 	// Return the error
 	return err
 }
+
+// readSchema replaces a schema file path, relative to the settings
+// folder, with the schema that the file contains.
+// An empty path means that there is no schema, so it is left empty.
+func readSchema(settingsFolderPath string, schema *string) error {
+	if *schema == "" {
+		return nil
+	}
+
+	// Read the data from file.
+	bytes, err := ioutil.ReadFile(settingsFolderPath + *schema)
+	if err != nil {
+		return err
+	}
+
+	// Set the actual schema in its field.
+	*schema = string(bytes)
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package configs
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Duration is a time.Duration that is written in the configuration as a
+// string of decimal numbers with unit suffixes, like "1.5s" or "300ms".
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string.
+func (d *Duration) UnmarshalJSON(bytes []byte) error {
+	var value string
+
+	err := json.Unmarshal(bytes, &value)
+	if err != nil {
+		return errors.New("a duration should be a string like \"5s\", got " + string(bytes))
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return errors.Wrap(err, "invalid duration")
+	}
+
+	*d = Duration(duration)
+
+	return nil
+}
+
+// MarshalJSON writes a duration as a duration string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
@@ -2,7 +2,23 @@ package configs
 
 // Endpoint represents an API endpoint
 type Endpoint struct {
-	Path   string `json:"path"`
-	Method string `json:"method"`
-	Schema string `json:"schema"`
+	Path       string      `json:"path"`
+	Method     string      `json:"method"`
+	Schema     string      `json:"schema"`
+	Parameters Parameters  `json:"parameters"`
+	Responses  []*Response `json:"responses"`
+
+	// MaxBodySize overrides the maximum size of the endpoint's request
+	// bodies that the target sets. If it is 0, the target's limit applies,
+	// and if it is negative, the size is not limited.
+	MaxBodySize int64 `json:"maxBodySize"`
+
+	// MessageSchema is the schema of the json WebSocket messages that the
+	// clients send once the endpoint's connections are upgraded.
+	MessageSchema string `json:"messageSchema"`
+
+	// Form lets the endpoint accept form bodies, which are validated
+	// against Schema like json objects, and holds the constraints of their
+	// uploads. If it is nil, form bodies are validated like any other body.
+	Form *Form `json:"form"`
 }
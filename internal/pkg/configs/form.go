@@ -0,0 +1,34 @@
+package configs
+
+// Form holds the constraints of the form bodies of an endpoint, whose
+// fields are validated against the endpoint's schema. Zero values are not
+// enforced.
+type Form struct {
+	// MaxParts is the maximum number of parts of a multipart body, or of
+	// fields of a url-encoded body.
+	MaxParts int `json:"maxParts"`
+
+	// MaxFileSize, ContentTypes and Filename constrain all the uploaded
+	// files.
+	MaxFileSize  int64    `json:"maxFileSize"`
+	ContentTypes []string `json:"contentTypes"`
+	Filename     string   `json:"filename"`
+
+	// Files constrain the files of specific fields, by the fields' names,
+	// instead of the constraints of all the files.
+	Files map[string]File `json:"files"`
+}
+
+// File holds the constraints of the files that are uploaded in a field
+type File struct {
+	// MaxSize is the maximum size of a file in bytes.
+	MaxSize int64 `json:"maxSize"`
+
+	// ContentTypes are the media types that the files may have, like
+	// "image/png" or "image/*".
+	ContentTypes []string `json:"contentTypes"`
+
+	// Filename is a regular expression that the names of the files should
+	// match.
+	Filename string `json:"filename"`
+}
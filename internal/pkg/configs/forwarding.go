@@ -0,0 +1,18 @@
+package configs
+
+// Forwarding determines which headers the gateway adds to the requests that
+// it forwards to a target, to tell the target about the clients.
+type Forwarding struct {
+	// XForwarded determines whether the X-Forwarded-For, X-Forwarded-Proto
+	// and X-Forwarded-Host headers should be set.
+	XForwarded bool `json:"xForwarded"`
+
+	// Forwarded determines whether the RFC 7239 Forwarded header should
+	// be set.
+	Forwarded bool `json:"forwarded"`
+
+	// TrustedProxies holds the IP addresses and CIDR ranges of the proxies
+	// in front of the gateway. The forwarding headers of their requests are
+	// extended, while the forwarding headers of other clients are replaced.
+	TrustedProxies []string `json:"trustedProxies"`
+}
@@ -0,0 +1,22 @@
+package configs
+
+// GraphQL holds the limits of the operations that the clients of a GRAPHQL
+// api may execute. A zero limit is not enforced.
+type GraphQL struct {
+	// MaxDepth is the maximum nesting of the fields of an operation, where
+	// the root fields are at depth 1.
+	MaxDepth int `json:"maxDepth"`
+
+	// MaxAliases is the maximum number of aliased fields in an operation.
+	MaxAliases int `json:"maxAliases"`
+
+	// MaxComplexity is the maximum complexity of an operation, where each
+	// field costs 1 plus the complexity of its subfields, multiplied by
+	// the field's "first", "last" or "limit" argument.
+	MaxComplexity int `json:"maxComplexity"`
+
+	// PersistedQueries is the path of a json file that holds the only
+	// queries that the clients may send: an object of queries by their
+	// ids, or an array of queries.
+	PersistedQueries string `json:"persistedQueries"`
+}
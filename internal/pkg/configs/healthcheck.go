@@ -0,0 +1,21 @@
+package configs
+
+// HealthCheck determines how the gateway checks the health of the upstreams
+// of a target.
+type HealthCheck struct {
+	// Path is requested from each upstream periodically. An upstream is
+	// healthy if it answers with a 2XX or 3XX status. If it is empty, the
+	// upstreams are not checked.
+	Path string `json:"path"`
+
+	// Interval is the time between the checks, and Timeout limits the time
+	// of each check.
+	Interval Duration `json:"interval"`
+	Timeout  Duration `json:"timeout"`
+
+	// HealthyThreshold and UnhealthyThreshold are the numbers of
+	// consecutive checks that should pass or fail to change the health of
+	// an upstream.
+	HealthyThreshold   int `json:"healthyThreshold"`
+	UnhealthyThreshold int `json:"unhealthyThreshold"`
+}
@@ -0,0 +1,23 @@
+package configs
+
+// LoadBalancing determines how the gateway spreads the requests of a target
+// between the target's upstreams.
+type LoadBalancing struct {
+	// Strategy is "round-robin" (the default), "least-connections" or
+	// "consistent-hash".
+	Strategy string `json:"strategy"`
+
+	// HashHeader is the header whose value determines the upstream of a
+	// request in the "consistent-hash" strategy. If it is empty or the
+	// request does not have it, the client's address is used.
+	HashHeader string `json:"hashHeader"`
+
+	// HealthCheck determines how the upstreams are actively checked.
+	HealthCheck HealthCheck `json:"healthCheck"`
+
+	// MaxFailures is the number of consecutive failed requests after which
+	// an upstream is ejected for EjectionTime. If it is 0, upstreams are
+	// not ejected.
+	MaxFailures  int      `json:"maxFailures"`
+	EjectionTime Duration `json:"ejectionTime"`
+}
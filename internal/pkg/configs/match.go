@@ -0,0 +1,13 @@
+package configs
+
+// Match is a struct that determines which client requests
+// are routed to a target.
+type Match struct {
+	// Host is compared against the request's Host header, an empty
+	// host matches any host.
+	Host string `json:"host"`
+
+	// PathPrefix is compared against the beginning of the request's
+	// path, an empty prefix matches any path.
+	PathPrefix string `json:"pathPrefix"`
+}
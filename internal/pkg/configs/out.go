@@ -6,4 +6,14 @@ type Out struct {
 	SSL             bool   `json:"ssl"`
 	CertificatePath string `json:"certPath"`
 	KeyPath         string `json:"keyPath"`
+
+	// ClientCAPath is the path of a bundle of the certificate authorities
+	// that client certificates are verified against. It is required if a
+	// target sets ClientAuth.
+	ClientCAPath string `json:"clientCaPath"`
+
+	// H2C determines whether clients may speak HTTP/2 over cleartext
+	// connections, when SSL is not set. TLS connections negotiate HTTP/2
+	// regardless.
+	H2C bool `json:"h2c"`
 }
@@ -0,0 +1,16 @@
+package configs
+
+// Parameters holds the paths of the schemas that describe the parameters
+// of an endpoint. Each schema describes the parameters in its location as
+// a json object that maps the parameter names to their values.
+type Parameters struct {
+	// Path is the schema of the named variables in the endpoint's path.
+	Path string `json:"path"`
+
+	// Query is the schema of the query string parameters.
+	Query string `json:"query"`
+
+	// Header is the schema of the headers. Only the headers that the
+	// schema's properties name are validated.
+	Header string `json:"header"`
+}
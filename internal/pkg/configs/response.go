@@ -0,0 +1,15 @@
+package configs
+
+// Response describes a response of an endpoint that the gateway validates
+type Response struct {
+	// Status is the status code of the response ("200"), a range of
+	// status codes ("2XX") or "default" for any other status code.
+	Status string `json:"status"`
+
+	// ContentType is the media type of the response. If it is empty, the
+	// schema applies to the response regardless of its content type.
+	ContentType string `json:"contentType"`
+
+	// Schema is the schema of the response body.
+	Schema string `json:"schema"`
+}
@@ -0,0 +1,25 @@
+package configs
+
+// Retries determines how the gateway retries the requests that the target
+// failed to handle. Only requests with idempotent methods, whose body was
+// read by the gateway (or that have no body), are retried.
+type Retries struct {
+	// Attempts is the number of times a failed request is retried. If it
+	// is 0, requests are not retried.
+	Attempts int `json:"attempts"`
+
+	// Backoff is the time before the first retry, which doubles with every
+	// retry up to MaxBackoff.
+	Backoff    Duration `json:"backoff"`
+	MaxBackoff Duration `json:"maxBackoff"`
+
+	// RetryOn holds the statuses of the target responses that are retried,
+	// in addition to the requests that could not be sent.
+	RetryOn []int `json:"retryOn"`
+
+	// BudgetRatio is the maximum ratio of retries to requests over the
+	// last 10 seconds. BudgetMinRetries retries are allowed regardless of
+	// the ratio.
+	BudgetRatio      float64 `json:"budgetRatio"`
+	BudgetMinRetries int     `json:"budgetMinRetries"`
+}
@@ -0,0 +1,14 @@
+package configs
+
+// Schemas tells the gateway where to find the schemas that "$ref"s point to
+// when they are not loaded as the schemas of endpoints. The schemas are
+// loaded when the schemas that reference them are loaded.
+type Schemas struct {
+	// Directories are searched, in order, for the files of relative
+	// references, like "common.json#/definitions/address".
+	Directories []string `json:"directories"`
+
+	// Mappings maps absolute URIs to schema files, and URI prefixes that
+	// end with "/" to directories.
+	Mappings map[string]string `json:"mappings"`
+}
@@ -6,11 +6,66 @@ type Target struct {
 	Port       string `json:"port"`
 	SSL        bool   `json:"ssl"`
 	ClientAuth bool   `json:"clientAuth"`
+	Match      Match  `json:"match"`
 	Apis       []API  `json:"apis"`
+
+	// MaxBodySize is the maximum size of a request body in bytes. Requests
+	// with larger bodies are answered with 413. If it is 0, the size of the
+	// bodies is not limited.
+	MaxBodySize int64 `json:"maxBodySize"`
+
+	// Forwarding determines which headers tell the target about the
+	// clients of the requests.
+	Forwarding Forwarding `json:"forwarding"`
+
+	// Transport determines how the gateway connects to the target.
+	Transport Transport `json:"transport"`
+
+	// ClientCertificates determines which client certificates are accepted
+	// if ClientAuth is set.
+	ClientCertificates ClientCertificates `json:"clientCertificates"`
+
+	// Upstreams are the instances of the target. If there are upstreams,
+	// the requests are spread between them instead of being sent to the
+	// target's host and port.
+	Upstreams []Upstream `json:"upstreams"`
+
+	// LoadBalancing determines how the requests are spread between the
+	// upstreams.
+	LoadBalancing LoadBalancing `json:"loadBalancing"`
+
+	// Retries determines which failed requests are retried.
+	Retries Retries `json:"retries"`
+
+	// CircuitBreaker determines when the requests to a failing upstream
+	// are stopped.
+	CircuitBreaker CircuitBreaker `json:"circuitBreaker"`
 }
 
 //GetURL is a function that returns a string of the URL of the target.
+//A target that is defined by its upstreams alone is named after the first
+//upstream.
 func (t Target) GetURL() string {
+	if t.Host == "" && len(t.Upstreams) > 0 {
+		return t.urlOf(t.Upstreams[0].Host, t.Upstreams[0].Port)
+	}
+
+	return t.urlOf(t.Host, t.Port)
+}
+
+// GetUpstreamURLs returns the URLs of the target's upstreams.
+func (t Target) GetUpstreamURLs() []string {
+	var urls []string
+
+	for _, upstream := range t.Upstreams {
+		urls = append(urls, t.urlOf(upstream.Host, upstream.Port))
+	}
+
+	return urls
+}
+
+// urlOf returns the URL of an instance of the target.
+func (t Target) urlOf(host, port string) string {
 	var scheme string
 
 	// Check if the target is listening on http or https.
@@ -21,5 +76,5 @@ func (t Target) GetURL() string {
 	}
 
 	// Return the full URL.
-	return scheme + t.Host + ":" + t.Port
+	return scheme + host + ":" + port
 }
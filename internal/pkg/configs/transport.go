@@ -0,0 +1,57 @@
+package configs
+
+// Transport determines how the gateway connects to a target. Settings that
+// are not set keep their defaults.
+type Transport struct {
+	// DialTimeout limits the time it takes to establish a connection.
+	DialTimeout Duration `json:"dialTimeout"`
+
+	// TLSHandshakeTimeout limits the time it takes to complete the TLS
+	// handshake of a connection.
+	TLSHandshakeTimeout Duration `json:"tlsHandshakeTimeout"`
+
+	// ResponseHeaderTimeout limits the time it takes the target to send the
+	// headers of its response after the request was sent.
+	ResponseHeaderTimeout Duration `json:"responseHeaderTimeout"`
+
+	// Timeout limits the time of a whole exchange with the target,
+	// including the reading of the response body.
+	Timeout Duration `json:"timeout"`
+
+	// IdleConnTimeout is the time an idle connection is kept open.
+	IdleConnTimeout Duration `json:"idleConnTimeout"`
+
+	// MaxIdleConns is the maximum number of idle connections to the target.
+	MaxIdleConns int `json:"maxIdleConns"`
+
+	// MaxConns is the maximum number of connections to the target,
+	// including the connections that are in use.
+	MaxConns int `json:"maxConns"`
+
+	// CAPath is the path of a bundle of the certificate authorities that
+	// the target's certificate is verified against, instead of the
+	// system's certificate authorities.
+	CAPath string `json:"caPath"`
+
+	// CertificatePath and KeyPath are the paths of the client certificate
+	// that the gateway presents to a target that requires one.
+	CertificatePath string `json:"certPath"`
+	KeyPath         string `json:"keyPath"`
+
+	// ServerName is the name that the gateway sends in the TLS handshake
+	// (SNI) and expects in the target's certificate, instead of the
+	// target's host.
+	ServerName string `json:"serverName"`
+
+	// InsecureSkipVerify determines whether the target's certificate should
+	// not be verified. It should only be used in development.
+	InsecureSkipVerify bool `json:"insecureSkipVerify"`
+
+	// HTTP2 determines whether the gateway speaks HTTP/2 with a TLS target
+	// that supports it.
+	HTTP2 bool `json:"http2"`
+
+	// H2C determines whether the gateway speaks HTTP/2 with a cleartext
+	// target, as gRPC targets require.
+	H2C bool `json:"h2c"`
+}
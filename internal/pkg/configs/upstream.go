@@ -0,0 +1,8 @@
+package configs
+
+// Upstream is an instance of a target that the gateway can send the
+// target's requests to.
+type Upstream struct {
+	Host string `json:"host"`
+	Port string `json:"port"`
+}
@@ -1,8 +1,54 @@
 package configs
 
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
 // Validator is a struct that represents what CAF should
 // check for in a reqeust
 type Validator struct {
 	Schema  string `json:"schema"`
 	Monitor bool   `json:"monitor"`
+
+	// Status is the status code of the response to a blocked request,
+	// which should be a 4xx status code.
+	// If it is 0, invalid payloads are answered with 422 and payloads that
+	// could not be parsed are answered with 400.
+	Status int `json:"status"`
+
+	// HideDetails determines whether the reasons of a validation failure
+	// should be omitted from the response to a blocked request.
+	HideDetails bool `json:"hideDetails"`
+
+	// Exhaustive determines whether the validator should collect all the
+	// failures in a request instead of stopping at the first one.
+	Exhaustive bool `json:"exhaustive"`
+
+	// OutputFormat is the standard format ("flag", "basic" or "detailed")
+	// in which the failures are described in the response to a blocked
+	// request. If it is empty, only the list of failures is sent.
+	OutputFormat string `json:"outputFormat"`
+}
+
+// UnmarshalJSON reads the settings of a validator and makes sure that its
+// blocked requests are answered with client errors.
+func (v *Validator) UnmarshalJSON(bytes []byte) error {
+	// settings has the fields of Validator but not its methods, so it is
+	// unmarshaled without calling UnmarshalJSON again
+	type settings Validator
+
+	err := json.Unmarshal(bytes, (*settings)(v))
+	if err != nil {
+		return err
+	}
+
+	if v.Status != 0 && (v.Status < 400 || v.Status > 499) {
+		return errors.New("the status of a validator should be a 4xx status code, got " +
+			strconv.Itoa(v.Status))
+	}
+
+	return nil
 }
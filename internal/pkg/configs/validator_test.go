@@ -0,0 +1,63 @@
+package configs_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/apidome/gateway/internal/pkg/configs"
+)
+
+const succeed = "V"
+const failed = "X"
+
+func TestValidatorStatus(t *testing.T) {
+	testCases := []struct {
+		settings string
+		valid    bool
+	}{
+		{`{"monitor": true}`, true},
+		{`{"status": 403, "hideDetails": true}`, true},
+		{`{"status": 499}`, true},
+		{`{"status": 200}`, false},
+		{`{"status": 399}`, false},
+		{`{"status": 500}`, false},
+		{`{"status": "403"}`, false},
+	}
+
+	t.Log("Given the need to test the status of the responses to blocked requests")
+	{
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When reading the validator settings %s", index, testCase.settings)
+			{
+				var validator configs.Validator
+
+				err := json.Unmarshal([]byte(testCase.settings), &validator)
+				if testCase.valid {
+					if err != nil {
+						t.Errorf("\t%s\tShould be able to read the settings: %v", failed, err)
+					} else {
+						t.Logf("\t%s\tShould be able to read the settings", succeed)
+					}
+				} else {
+					if err != nil {
+						t.Logf("\t%s\tShould not be able to read the settings: %v", succeed, err)
+					} else {
+						t.Errorf("\t%s\tShould not be able to read the settings", failed)
+					}
+				}
+			}
+		}
+
+		t.Logf("\tTest %d: When reading the settings of an api", len(testCases))
+		{
+			var api configs.API
+
+			err := json.Unmarshal([]byte(`{"type": "REST", "validator": {"status": 302}}`), &api)
+			if err == nil {
+				t.Errorf("\t%s\tShould not be able to read a validator that redirects blocked requests", failed)
+			} else {
+				t.Logf("\t%s\tShould not be able to read a validator that redirects blocked requests", succeed)
+			}
+		}
+	}
+}
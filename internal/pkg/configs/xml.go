@@ -0,0 +1,12 @@
+package configs
+
+// XML holds the limits of the documents that the clients of an XML api may
+// send. A zero limit is replaced with the validator's default.
+type XML struct {
+	// MaxDepth is the maximum nesting of the elements of a document.
+	MaxDepth int `json:"maxDepth"`
+
+	// MaxExpansion is the maximum number of bytes that the references to
+	// the entities that a document declares may expand to.
+	MaxExpansion int `json:"maxExpansion"`
+}
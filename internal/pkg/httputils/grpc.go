@@ -0,0 +1,112 @@
+package httputils
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// gRPC status codes
+const (
+	grpcUnknown           = 2
+	grpcInvalidArgument   = 3
+	grpcDeadlineExceeded  = 4
+	grpcPermissionDenied  = 7
+	grpcResourceExhausted = 8
+	grpcUnimplemented     = 12
+	grpcInternal          = 13
+	grpcUnavailable       = 14
+	grpcUnauthenticated   = 16
+)
+
+// grpcContentType is the content type of gRPC requests, which may be
+// followed by the format of the messages ("+proto") or by "-web"
+const grpcContentType = "application/grpc"
+
+// IsGRPC returns true if a request is a gRPC or a gRPC-Web request
+func IsGRPC(req *http.Request) bool {
+	contentType := req.Header.Get("Content-Type")
+
+	if !strings.HasPrefix(contentType, grpcContentType) {
+		return false
+	}
+
+	rest := contentType[len(grpcContentType):]
+
+	return rest == "" || rest[0] == '+' || rest[0] == '-' || rest[0] == ';'
+}
+
+// GRPCStatus returns the gRPC status code that corresponds to an HTTP
+// status code
+func GRPCStatus(status int) int {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return grpcInvalidArgument
+	case http.StatusUnauthorized:
+		return grpcUnauthenticated
+	case http.StatusForbidden:
+		return grpcPermissionDenied
+	case http.StatusNotFound, http.StatusNotImplemented:
+		return grpcUnimplemented
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return grpcResourceExhausted
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return grpcUnavailable
+	case http.StatusGatewayTimeout:
+		return grpcDeadlineExceeded
+	case http.StatusInternalServerError:
+		return grpcInternal
+	default:
+		return grpcUnknown
+	}
+}
+
+// WriteGRPCStatus sends a gRPC response without messages (a "trailers-only"
+// response), whose headers hold the status of the call
+func WriteGRPCStatus(res http.ResponseWriter, req *http.Request, code int, message string) error {
+	res.Header().Set("Content-Type", req.Header.Get("Content-Type"))
+	res.Header().Set("Grpc-Status", strconv.Itoa(code))
+
+	if message != "" {
+		res.Header().Set("Grpc-Message", encodeGRPCMessage(message))
+	}
+
+	res.WriteHeader(http.StatusOK)
+
+	return nil
+}
+
+// RespondProblem answers a request with a problem: gRPC requests get a gRPC
+// status that describes the problem, and other requests get a problem
+// details response.
+func RespondProblem(res http.ResponseWriter, req *http.Request, problem *Problem) error {
+	if IsGRPC(req) {
+		message := problem.Detail
+		if message == "" {
+			message = problem.Title
+		}
+
+		return WriteGRPCStatus(res, req, GRPCStatus(problem.Status), message)
+	}
+
+	return WriteProblem(res, problem)
+}
+
+// encodeGRPCMessage percent-encodes the bytes of a status message that are
+// not printable ASCII characters, as the gRPC protocol requires
+func encodeGRPCMessage(message string) string {
+	var builder strings.Builder
+
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+
+		if c >= ' ' && c <= '~' && c != '%' {
+			builder.WriteByte(c)
+		} else {
+			fmt.Fprintf(&builder, "%%%02X", c)
+		}
+	}
+
+	return builder.String()
+}
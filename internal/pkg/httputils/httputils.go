@@ -4,8 +4,23 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
+// hopByHopHeaders are the headers that describe a single connection rather
+// than the message, so proxies must not forward them (RFC 7230 section 6.1)
+var hopByHopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
 // CopyHeaders copies headers from a Header object to another Header object
 func CopyHeaders(src, dst http.Header) {
 	for key, values := range src {
@@ -15,6 +30,44 @@ func CopyHeaders(src, dst http.Header) {
 	}
 }
 
+// CopyEndToEndHeaders copies headers from a Header object to another Header
+// object, except for the hop-by-hop headers and the headers that the
+// Connection header lists
+func CopyEndToEndHeaders(src, dst http.Header) {
+	hopByHop := HopByHopHeaders(src)
+
+	for key, values := range src {
+		if hopByHop[http.CanonicalHeaderKey(key)] {
+			continue
+		}
+
+		for _, value := range values {
+			dst.Add(key, value)
+		}
+	}
+}
+
+// HopByHopHeaders returns the canonical names of the hop-by-hop headers of a
+// Header object, including the headers that its Connection header lists
+func HopByHopHeaders(header http.Header) map[string]bool {
+	hopByHop := make(map[string]bool, len(hopByHopHeaders))
+
+	for _, name := range hopByHopHeaders {
+		hopByHop[name] = true
+	}
+
+	for _, value := range header["Connection"] {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name != "" {
+				hopByHop[http.CanonicalHeaderKey(name)] = true
+			}
+		}
+	}
+
+	return hopByHop
+}
+
 // GetContentLength returns the content length of a response by its header
 func GetContentLength(header http.Header) int {
 	contentLength := header.Get("Content-Length")
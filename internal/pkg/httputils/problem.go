@@ -0,0 +1,59 @@
+package httputils
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// ProblemContentType is the content type of problem details responses
+const ProblemContentType = "application/problem+json"
+
+// Problem is a struct that represents an RFC 7807 problem details object
+type Problem struct {
+	Type     string         `json:"type"`
+	Title    string         `json:"title"`
+	Status   int            `json:"status"`
+	Detail   string         `json:"detail,omitempty"`
+	Instance string         `json:"instance,omitempty"`
+	Errors   []InvalidValue `json:"errors,omitempty"`
+	Output   interface{}    `json:"output,omitempty"`
+}
+
+// InvalidValue is a struct that describes a single value of a request
+// that caused a problem
+type InvalidValue struct {
+	Path       string `json:"path"`
+	SchemaPath string `json:"schemaPath,omitempty"`
+	Keyword    string `json:"keyword,omitempty"`
+	Reason     string `json:"reason"`
+}
+
+// NewProblem creates a new problem with the status text as its title
+func NewProblem(status int, detail, instance string) *Problem {
+	return &Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: instance,
+	}
+}
+
+// WriteProblem sends a problem details response to the client
+func WriteProblem(res http.ResponseWriter, problem *Problem) error {
+	body, err := json.Marshal(problem)
+
+	if err != nil {
+		return err
+	}
+
+	res.Header().Set("Content-Type", ProblemContentType)
+	res.Header().Set("Content-Length", strconv.Itoa(len(body)))
+
+	res.WriteHeader(problem.Status)
+
+	_, err = res.Write(body)
+
+	return err
+}
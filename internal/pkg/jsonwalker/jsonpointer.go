@@ -32,12 +32,24 @@ func NewJsonPointer(path string) (JsonPointer, error) {
 	// Split path by '/' in order to get a []string of json tokens
 	tokens := strings.Split(path, "/")
 
+	// Decode the escaped '~' and '/' characters of each token ("~0" and
+	// "~1").
+	for index, token := range tokens {
+		tokens[index] = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+	}
+
 	// Convert the []string to JonPointer and omit the first string
 	// in the slice because when the delimiter is the first character
 	// in a string, Split return "" in the slice's first cell.
 	return JsonPointer(tokens[1:]), nil
 }
 
+// EscapeToken escapes the '~' and '/' characters of a json token, so it can
+// be appended to a json pointer.
+func EscapeToken(token string) string {
+	return strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+}
+
 // Evaluate is a receiver function that searches for the JsonPointer's data
 // in a given json value.
 func (jp JsonPointer) Evaluate(jsonData json.RawMessage) (interface{}, error) {
@@ -0,0 +1,45 @@
+package middleman
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrBodyTooLarge is returned when reading a request body that exceeds
+// the body size limit of the request
+var ErrBodyTooLarge = errors.New("Request body too large")
+
+// limitedBody is a request body that fails with ErrBodyTooLarge once more
+// than its limit was read from it, so a body is never read beyond its limit
+// whether it is buffered or streamed to the target
+type limitedBody struct {
+	body      io.ReadCloser
+	remaining int64
+}
+
+// Read reads from the body until the limit is exceeded
+func (lb *limitedBody) Read(p []byte) (int, error) {
+	// Read one byte more than the limit allows to find out whether the
+	// body exceeds it
+	if int64(len(p)) > lb.remaining+1 {
+		p = p[:lb.remaining+1]
+	}
+
+	n, err := lb.body.Read(p)
+
+	if int64(n) > lb.remaining {
+		n = int(lb.remaining)
+		lb.remaining = 0
+
+		return n, ErrBodyTooLarge
+	}
+
+	lb.remaining -= int64(n)
+
+	return n, err
+}
+
+// Close closes the underlying body
+func (lb *limitedBody) Close() error {
+	return lb.body.Close()
+}
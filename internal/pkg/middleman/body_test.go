@@ -0,0 +1,157 @@
+package middleman_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apidome/gateway/internal/pkg/middleman"
+)
+
+const succeed = "V"
+const failed = "X"
+
+// countingBody is a request body that counts the bytes that were read from it
+type countingBody struct {
+	reader io.Reader
+	read   int
+}
+
+func (cb *countingBody) Read(p []byte) (int, error) {
+	n, err := cb.reader.Read(p)
+	cb.read += n
+
+	return n, err
+}
+
+func (cb *countingBody) Close() error {
+	return nil
+}
+
+// streamBody is a middleware that reads the body of a request in small
+// chunks, like a body that is streamed to a target
+func streamBody(res http.ResponseWriter, req *http.Request,
+	store middleman.Store, end middleman.End) error {
+	buffer := make([]byte, 3)
+
+	for {
+		_, err := req.Body.Read(buffer)
+		if err == io.EOF {
+			break
+		}
+
+		if errors.Is(err, middleman.ErrBodyTooLarge) {
+			return middleman.BodyTooLarge(res, req, store, end)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	end()
+
+	res.WriteHeader(http.StatusOK)
+
+	return nil
+}
+
+func TestBodyLimiter(t *testing.T) {
+	testCases := []struct {
+		description string
+		path        string
+		body        string
+		chunked     bool
+		status      int
+		read        int
+	}{
+		{"a body within the limit of the target", "/items", "12345678", false, http.StatusOK, 8},
+		{"a body that declares a size over the limit", "/items", "123456789", false, http.StatusRequestEntityTooLarge, 0},
+		{"a chunked body that exceeds the limit", "/items", "123456789", true, http.StatusRequestEntityTooLarge, 9},
+		{"a body over the limit of the endpoint", "/endpoint", "12345", false, http.StatusRequestEntityTooLarge, 0},
+		{"a chunked body over the limit of the endpoint", "/endpoint", "12345", true, http.StatusRequestEntityTooLarge, 5},
+		{"a body within the limit of the endpoint", "/endpoint", "1234", true, http.StatusOK, 4},
+		{"a streamed body within the limit", "/stream", "12345678", true, http.StatusOK, 8},
+		{"a streamed body that exceeds the limit", "/stream", strings.Repeat("1", 20), true, http.StatusRequestEntityTooLarge, 9},
+	}
+
+	t.Log("Given the need to test the limits of the request bodies")
+	{
+		mm := middleman.NewMiddleman("", func(path, method string, err error) bool {
+			return false
+		})
+
+		// The limit of an endpoint comes before the limit of the target
+		mm.Post("/endpoint", middleman.BodyLimiter(4))
+		mm.All("/.*", middleman.BodyLimiter(8))
+		mm.Post("/stream", streamBody)
+		mm.All("/.*", middleman.BodyReader())
+		mm.All("/.*", func(res http.ResponseWriter, req *http.Request,
+			store middleman.Store, end middleman.End) error {
+			res.Write(store["requestBody"].([]byte))
+			return nil
+		})
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When sending %s", index, testCase.description)
+			{
+				body := &countingBody{reader: strings.NewReader(testCase.body)}
+
+				req := httptest.NewRequest("POST", testCase.path, body)
+				req.ContentLength = int64(len(testCase.body))
+				if testCase.chunked {
+					req.ContentLength = -1
+				}
+
+				res := httptest.NewRecorder()
+				mm.ServeHTTP(res, req)
+
+				if res.Code != testCase.status {
+					t.Errorf("\t%s\tShould answer %d: got %d", failed, testCase.status, res.Code)
+				} else {
+					t.Logf("\t%s\tShould answer %d", succeed, testCase.status)
+				}
+
+				if res.Code == http.StatusRequestEntityTooLarge &&
+					res.Header().Get("Content-Type") != "application/problem+json" {
+					t.Errorf("\t%s\tShould answer with problem details: got %s", failed,
+						res.Header().Get("Content-Type"))
+				}
+
+				// A body is never read more than one byte beyond its limit
+				if body.read != testCase.read {
+					t.Errorf("\t%s\tShould read %d bytes of the body: got %d", failed, testCase.read, body.read)
+				} else {
+					t.Logf("\t%s\tShould read %d bytes of the body", succeed, testCase.read)
+				}
+
+				if res.Code == http.StatusOK && res.Body.String() != testCase.body &&
+					testCase.path != "/stream" {
+					t.Errorf("\t%s\tShould read the whole body: got %s", failed, res.Body.String())
+				}
+			}
+		}
+
+		t.Logf("\tTest %d: When the body is not limited", len(testCases))
+		{
+			store := middleman.Store{}
+			req := httptest.NewRequest("POST", "/", strings.NewReader(strings.Repeat("1", 100)))
+
+			err := middleman.BodyLimiter(0)(httptest.NewRecorder(), req, store, func() {})
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to run the limiter: %v", failed, err)
+			}
+
+			body, err := ioutil.ReadAll(req.Body)
+			if err != nil || len(body) != 100 {
+				t.Errorf("\t%s\tShould not limit the body: %v", failed, err)
+			} else {
+				t.Logf("\t%s\tShould not limit the body", succeed)
+			}
+		}
+	}
+}
@@ -0,0 +1,19 @@
+//go:build go1.24
+// +build go1.24
+
+package middleman
+
+import "net/http"
+
+// EnableH2C lets clients speak HTTP/2 over cleartext connections (h2c with
+// prior knowledge) to the http server, in addition to HTTP/1.1
+func (mm *Middleman) EnableH2C() error {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+
+	mm.httpServer.Protocols = protocols
+
+	return nil
+}
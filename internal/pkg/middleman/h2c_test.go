@@ -0,0 +1,56 @@
+//go:build go1.24
+// +build go1.24
+
+package middleman
+
+import (
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestEnableH2C(t *testing.T) {
+	t.Log("Given the need to test HTTP/2 over cleartext connections")
+	{
+		mm := newProtocolMiddleman()
+
+		err := mm.EnableH2C()
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to enable h2c: %v", failed, err)
+		}
+		t.Logf("\t%s\tShould be able to enable h2c", succeed)
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to listen: %v", failed, err)
+		}
+
+		go mm.httpServer.Serve(listener)
+		defer mm.httpServer.Close()
+
+		url := "http://" + listener.Addr().String() + "/"
+
+		t.Log("\tTest 0: When a client speaks HTTP/2 with prior knowledge")
+		{
+			protocols := new(http.Protocols)
+			protocols.SetUnencryptedHTTP2(true)
+
+			client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+
+			if proto := requestProtocol(t, client, url); proto != "HTTP/2.0" {
+				t.Errorf("\t%s\tShould speak HTTP/2: got %s", failed, proto)
+			} else {
+				t.Logf("\t%s\tShould speak HTTP/2", succeed)
+			}
+		}
+
+		t.Log("\tTest 1: When a client speaks HTTP/1.1")
+		{
+			if proto := requestProtocol(t, &http.Client{Transport: &http.Transport{}}, url); proto != "HTTP/1.1" {
+				t.Errorf("\t%s\tShould speak HTTP/1.1: got %s", failed, proto)
+			} else {
+				t.Logf("\t%s\tShould speak HTTP/1.1", succeed)
+			}
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !go1.24
+// +build !go1.24
+
+package middleman
+
+import "errors"
+
+// EnableH2C lets clients speak HTTP/2 over cleartext connections to the
+// http server. The http package supports cleartext HTTP/2 since Go 1.24, so
+// gateways that are built with older versions return an error.
+func (mm *Middleman) EnableH2C() error {
+	return errors.New("h2c requires a gateway that is built with Go 1.24 or later")
+}
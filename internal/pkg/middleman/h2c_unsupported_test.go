@@ -0,0 +1,23 @@
+//go:build !go1.24
+// +build !go1.24
+
+package middleman
+
+import (
+	"testing"
+)
+
+func TestEnableH2C(t *testing.T) {
+	t.Log("Given the need to test HTTP/2 over cleartext connections")
+	{
+		t.Log("\tTest 0: When the gateway is built with a Go version that does not support h2c")
+		{
+			err := newProtocolMiddleman().EnableH2C()
+			if err == nil {
+				t.Errorf("\t%s\tShould not be able to enable h2c", failed)
+			} else {
+				t.Logf("\t%s\tShould not be able to enable h2c: %v", succeed, err)
+			}
+		}
+	}
+}
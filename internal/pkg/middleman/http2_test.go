@@ -0,0 +1,153 @@
+package middleman
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self-signed certificate for 127.0.0.1 and its
+// key to files in the directory, and returns the files and a pool that
+// trusts the certificate
+func writeCertificate(t *testing.T, directory string) (string, string, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create a key: %v", failed, err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "gateway"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create a certificate: %v", failed, err)
+	}
+
+	rawKey, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to encode a key: %v", failed, err)
+	}
+
+	certificateFile := filepath.Join(directory, "certificate.pem")
+	keyFile := filepath.Join(directory, "key.pem")
+
+	err = ioutil.WriteFile(certificateFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw}), 0600)
+	if err == nil {
+		err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: rawKey}), 0600)
+	}
+
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to write the certificate: %v", failed, err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw}))
+
+	return certificateFile, keyFile, pool
+}
+
+// newProtocolMiddleman creates a middleman that answers every request with
+// the protocol of the request
+func newProtocolMiddleman() *Middleman {
+	mm := NewMiddleman("", func(path, method string, err error) bool {
+		return false
+	})
+
+	mm.All("/.*", func(res http.ResponseWriter, req *http.Request,
+		store Store, end End) error {
+		res.Write([]byte(req.Proto))
+		return nil
+	})
+
+	return mm
+}
+
+// requestProtocol sends a request with the client and returns the protocol
+// of the response
+func requestProtocol(t *testing.T, client *http.Client, url string) string {
+	res, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to send a request: %v", failed, err)
+	}
+	defer res.Body.Close()
+
+	body, _ := ioutil.ReadAll(res.Body)
+	if string(body) != res.Proto {
+		t.Errorf("\t%s\tShould get the request in the protocol of the response: got %s and %s",
+			failed, body, res.Proto)
+	}
+
+	return res.Proto
+}
+
+func TestHTTP2OverTLS(t *testing.T) {
+	t.Log("Given the need to test the protocols of the https server")
+	{
+		directory, err := ioutil.TempDir("", "middleman")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a directory: %v", failed, err)
+		}
+		defer os.RemoveAll(directory)
+
+		certificateFile, keyFile, pool := writeCertificate(t, directory)
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to listen: %v", failed, err)
+		}
+
+		mm := newProtocolMiddleman()
+		go mm.httpServer.ServeTLS(listener, certificateFile, keyFile)
+		defer mm.httpServer.Close()
+
+		url := "https://" + listener.Addr().String() + "/"
+
+		t.Log("\tTest 0: When a client negotiates HTTP/2")
+		{
+			client := &http.Client{Transport: &http.Transport{
+				TLSClientConfig:   &tls.Config{RootCAs: pool},
+				ForceAttemptHTTP2: true,
+			}}
+
+			if proto := requestProtocol(t, client, url); proto != "HTTP/2.0" {
+				t.Errorf("\t%s\tShould speak HTTP/2: got %s", failed, proto)
+			} else {
+				t.Logf("\t%s\tShould speak HTTP/2", succeed)
+			}
+		}
+
+		t.Log("\tTest 1: When a client speaks HTTP/1.1 only")
+		{
+			client := &http.Client{Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: pool},
+			}}
+
+			if proto := requestProtocol(t, client, url); proto != "HTTP/1.1" {
+				t.Errorf("\t%s\tShould speak HTTP/1.1: got %s", failed, proto)
+			} else {
+				t.Logf("\t%s\tShould speak HTTP/1.1", succeed)
+			}
+		}
+	}
+}
@@ -9,6 +9,19 @@ import (
 // Store is a struct that holds data between middlewares
 type Store map[string]interface{}
 
+// Variables returns the values of the named parameters that were extracted
+// from the request path, as stored in store["variables"]
+func (s Store) Variables() map[string]string {
+	variables, ok := s["variables"].(map[string]string)
+
+	if !ok {
+		variables = map[string]string{}
+		s["variables"] = variables
+	}
+
+	return variables
+}
+
 // Middleware is the function needed to implement as a middleware
 type Middleware func(res http.ResponseWriter, req *http.Request,
 	store Store, end End) error
@@ -18,7 +31,7 @@ type errorHandler func(path, method string, err error) bool
 // handler is a struct that hold middleware information
 type middlewareHandler struct {
 	middleware Middleware
-	path       string
+	path       *regexp.Regexp
 	method     string
 }
 
@@ -48,17 +61,21 @@ var (
 )
 
 // InitMiddleman initializes a middleman instance
+// The https server speaks HTTP/2 with the clients that negotiate it (with
+// ALPN), and HTTP/1.1 with the others.
 func InitMiddleman(mm *Middleman, addr string, errHandler errorHandler) {
-	// Disable HTTP/2
-	tlsNextProto := make(map[string]func(*http.Server, *tls.Conn, http.Handler))
-
 	mm.errorHandler = errHandler
 
 	mm.httpServer.Addr = addr
-	mm.httpServer.TLSNextProto = tlsNextProto
 	mm.httpServer.Handler = http.HandlerFunc(mm.mainHandler)
 }
 
+// SetTLSConfig sets the TLS configuration of the https server, for example
+// to request client certificates
+func (mm *Middleman) SetTLSConfig(tlsConfig *tls.Config) {
+	mm.httpServer.TLSConfig = tlsConfig
+}
+
 // NewMiddleman returns a new instance of a middleman
 func NewMiddleman(addr string, errHandler errorHandler) *Middleman {
 	mm := &Middleman{}
@@ -82,6 +99,12 @@ func (mm *Middleman) ListenAndServe() error {
 	return err
 }
 
+// ServeHTTP runs the middlewares on a request, which allows a Middleman
+// to be used as an http.Handler (for example by another Middleman)
+func (mm *Middleman) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	mm.mainHandler(res, req)
+}
+
 // emitError calls the error handler callback to inform the user of an error
 // and returns if execution should continue
 func (mm *Middleman) emitError(path, method string, err error) bool {
@@ -97,7 +120,9 @@ func (mm *Middleman) emitError(path, method string, err error) bool {
 // requests and calls the correct middlewares
 func (mm *Middleman) mainHandler(res http.ResponseWriter, req *http.Request) {
 	// Store holds data between middlewares
-	store := Store{}
+	store := Store{
+		"variables": map[string]string{},
+	}
 
 	_, err := mm.runMiddlewares(res, req, store)
 
@@ -109,13 +134,9 @@ func (mm *Middleman) mainHandler(res http.ResponseWriter, req *http.Request) {
 // addMiddleware adds a middleware to the middleware store
 func (mm *Middleman) addMiddleware(path string, method string,
 	middleware Middleware) error {
-	// We are using the path argument as a regular expression, so in order
-	// to fit our needs we surround it with ^ and $ to avoid regex matching
-	// anything that contains this path, rather than beginning with it or
-	// being equal to it
-	regexPath := "^" + path + "$"
-
-	_, err := regexp.Compile(regexPath)
+	// The path is compiled once into a regular expression that matches
+	// whole request paths and extracts the path parameters
+	pathRegex, err := compilePath(path)
 
 	if err != nil {
 		return err
@@ -123,7 +144,7 @@ func (mm *Middleman) addMiddleware(path string, method string,
 
 	mm.handlers = append(mm.handlers, middlewareHandler{
 		middleware,
-		regexPath,
+		pathRegex,
 		method,
 	})
 
@@ -150,15 +171,18 @@ func (mm *Middleman) runMiddlewares(res http.ResponseWriter, req *http.Request,
 			break
 		}
 
+		if handler.method != req.Method {
+			continue
+		}
+
 		// Match the regex of the handler to the request's uri path
-		regexMatch, err := regexp.MatchString(handler.path,
-			req.URL.Path)
+		matches := handler.path.FindStringSubmatch(req.URL.Path)
 
-		if err != nil {
-			return false, err
-		}
+		if matches != nil {
+			// Expose the path parameters of the handler's path to
+			// the middleware
+			extractVariables(handler.path, matches, store)
 
-		if regexMatch && handler.method == req.Method {
 			err := handler.middleware(res, req, store, end)
 
 			// If an error occured in the middleware, emit the error
@@ -5,7 +5,9 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
-	"strings"
+	"strconv"
+
+	"github.com/apidome/gateway/internal/pkg/httputils"
 )
 
 // RouteLogger is a middleware that prints the path of any route hit
@@ -18,39 +20,81 @@ func RouteLogger() Middleware {
 	}
 }
 
-// BodyReader reads the body of a request as a []byte and stores it
-// in store["body"]
-func BodyReader() Middleware {
+// BodyLimiter limits the size of the request body to maxSize bytes and
+// stores the limit in store["maxBodySize"].
+// Requests that declare a larger body are answered with 413 right away, and
+// reading more than maxSize bytes of the body fails with ErrBodyTooLarge.
+// Only the first BodyLimiter that runs on a request applies, so the limits
+// of specific routes should be added before the general ones.
+// A maxSize of 0 or less does not limit the body.
+func BodyLimiter(maxSize int64) Middleware {
 	return func(res http.ResponseWriter, req *http.Request,
 		store Store, end End) error {
+		if _, ok := store["maxBodySize"]; ok {
+			return nil
+		}
 
-		body, err := ioutil.ReadAll(req.Body)
+		store["maxBodySize"] = maxSize
 
-		if err != nil {
-			return errors.New("Request body read error: " + err.Error())
+		if maxSize <= 0 {
+			return nil
 		}
 
-		req.Body.Close()
+		if req.ContentLength > maxSize {
+			return BodyTooLarge(res, req, store, end)
+		}
 
-		store["requestBody"] = body
+		req.Body = &limitedBody{req.Body, maxSize}
 
 		return nil
 	}
 }
 
-// VariablesReader reads the variables from the request path
-// and stores them in store["variables"]
-func VariablesReader() Middleware {
+// BodyTooLarge ends the handling of a request whose body exceeds the limit
+// in store["maxBodySize"] and answers it with a 413 problem details response
+func BodyTooLarge(res http.ResponseWriter, req *http.Request,
+	store Store, end End) error {
+	end()
+
+	maxSize, _ := store["maxBodySize"].(int64)
+
+	err := httputils.RespondProblem(res, req,
+		httputils.NewProblem(http.StatusRequestEntityTooLarge,
+			"The request body exceeds "+strconv.FormatInt(maxSize, 10)+" bytes",
+			req.URL.Path))
+	if err != nil {
+		return errors.New(ErrBodyTooLarge.Error() + ": " + err.Error())
+	}
+
+	return ErrBodyTooLarge
+}
+
+// BodyReader reads the body of a request as a []byte and stores it
+// in store["requestBody"].
+// Requests whose body is not read are streamed to the target.
+func BodyReader() Middleware {
 	return func(res http.ResponseWriter, req *http.Request,
 		store Store, end End) error {
-		variables := strings.Split(req.URL.Path, "/")[1:]
+		// The body may have already been read for another route of the
+		// request
+		if _, ok := store["requestBody"]; ok {
+			return nil
+		}
+
+		body, err := ioutil.ReadAll(req.Body)
+
+		if err == ErrBodyTooLarge {
+			return BodyTooLarge(res, req, store, end)
+		}
 
-		if variables[0] != "" {
-			store["variables"] = variables
-		} else {
-			store["variables"] = []string{}
+		if err != nil {
+			return errors.New("Request body read error: " + err.Error())
 		}
 
+		req.Body.Close()
+
+		store["requestBody"] = body
+
 		return nil
 	}
 }
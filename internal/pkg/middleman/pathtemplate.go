@@ -0,0 +1,85 @@
+package middleman
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var (
+	// colonParameter matches a path segment that is a ":name" parameter
+	colonParameter = regexp.MustCompile(`^:([A-Za-z_][A-Za-z0-9_]*)$`)
+
+	// braceParameter matches "{name}" parameters inside a path segment
+	braceParameter = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+)
+
+// compilePath compiles a path template into a regular expression that
+// matches request paths.
+// The path may contain regular expressions, as well as:
+// - ":name" segments and "{name}" parameters, which match a single segment
+// and are extracted by name
+// - "*" segments, which match a single segment
+// - "**" segments, which match any number of segments, including none (so
+// "/a/**/b" matches "/a/b" and "/a/**" matches "/a")
+func compilePath(path string) (*regexp.Regexp, error) {
+	segments := strings.Split(path, "/")
+	names := map[string]bool{}
+
+	for index, segment := range segments {
+		var segmentNames []string
+
+		// Each segment but the first is preceded by a "/"
+		separator := "/"
+		if index == 0 {
+			separator = ""
+		}
+
+		switch {
+		case segment == "*":
+			segments[index] = separator + "[^/]+"
+		case segment == "**":
+			// The "/" before the segment is optional as well, so the
+			// segment may match no segments at all
+			segments[index] = "(?:" + separator + ".*)?"
+		case colonParameter.MatchString(segment):
+			name := colonParameter.FindStringSubmatch(segment)[1]
+			segmentNames = append(segmentNames, name)
+			segments[index] = separator + "(?P<" + name + ">[^/]+)"
+		default:
+			for _, match := range braceParameter.FindAllStringSubmatch(segment, -1) {
+				segmentNames = append(segmentNames, match[1])
+			}
+
+			segments[index] = separator +
+				braceParameter.ReplaceAllString(segment, "(?P<$1>[^/]+)")
+		}
+
+		for _, name := range segmentNames {
+			if names[name] {
+				return nil, errors.New("path " + path +
+					" contains the parameter \"" + name + "\" more than once")
+			}
+
+			names[name] = true
+		}
+	}
+
+	// We surround the path with ^ and $ to avoid regex matching anything
+	// that contains this path, rather than beginning with it or being
+	// equal to it
+	return regexp.Compile("^" + strings.Join(segments, "") + "$")
+}
+
+// extractVariables stores the values of the named parameters of a path
+// template, that matched a request path, in the store
+func extractVariables(pathRegex *regexp.Regexp, matches []string,
+	store Store) {
+	variables := store.Variables()
+
+	for index, name := range pathRegex.SubexpNames() {
+		if name != "" {
+			variables[name] = matches[index]
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package middleman
+
+import (
+	"reflect"
+	"testing"
+)
+
+const succeed = "V"
+const failed = "X"
+
+func TestCompilePath(t *testing.T) {
+	testCases := []struct {
+		path      string
+		request   string
+		matches   bool
+		variables map[string]string
+	}{
+		{"/pets/:id", "/pets/5", true, map[string]string{"id": "5"}},
+		{"/pets/:id", "/pets/5/toys", false, nil},
+		{"/pets/{id}.json", "/pets/5.json", true, map[string]string{"id": "5"}},
+		{"/pets/{id}/toys/:toy", "/pets/5/toys/ball", true, map[string]string{"id": "5", "toy": "ball"}},
+		{"/pets/*", "/pets/5", true, map[string]string{}},
+		{"/pets/*", "/pets", false, nil},
+		{"/pets/*", "/pets/5/toys", false, nil},
+		{"/a/**/b", "/a/b", true, map[string]string{}},
+		{"/a/**/b", "/a/x/b", true, map[string]string{}},
+		{"/a/**/b", "/a/x/y/b", true, map[string]string{}},
+		{"/a/**/b", "/a/x/c", false, nil},
+		{"/a/**/b", "/ab", false, nil},
+		{"/a/**", "/a", true, map[string]string{}},
+		{"/a/**", "/a/x/y", true, map[string]string{}},
+		{"/a/**", "/ab", false, nil},
+		{"/**", "/", true, map[string]string{}},
+		{"/**/:id", "/5", true, map[string]string{"id": "5"}},
+		{"/**/:id", "/a/b/5", true, map[string]string{"id": "5"}},
+		{"/pets/[0-9]+", "/pets/55", true, map[string]string{}},
+		{"/pets/[0-9]+", "/pets/rex", false, nil},
+		{"/.*", "/any/path", true, map[string]string{}},
+	}
+
+	t.Log("Given the need to test matching of request paths by path templates")
+	{
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When matching \"%s\" by \"%s\"", index, testCase.request, testCase.path)
+			{
+				pathRegex, err := compilePath(testCase.path)
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to compile the path: %v", failed, err)
+				}
+
+				matches := pathRegex.FindStringSubmatch(testCase.request)
+				if (matches != nil) != testCase.matches {
+					t.Errorf("\t%s\tShould match the path: %v", failed, testCase.matches)
+					continue
+				}
+				t.Logf("\t%s\tShould match the path: %v", succeed, testCase.matches)
+
+				if matches == nil {
+					continue
+				}
+
+				store := Store{}
+				extractVariables(pathRegex, matches, store)
+
+				variables := store.Variables()
+				if !reflect.DeepEqual(variables, testCase.variables) {
+					t.Errorf("\t%s\tShould extract the variables %v: got %v", failed,
+						testCase.variables, variables)
+				} else {
+					t.Logf("\t%s\tShould extract the variables %v", succeed, testCase.variables)
+				}
+			}
+		}
+
+		t.Logf("\tTest %d: When compiling a path that repeats a parameter", len(testCases))
+		{
+			_, err := compilePath("/pets/:id/toys/{id}")
+			if err == nil {
+				t.Errorf("\t%s\tShould not be able to compile the path", failed)
+			} else {
+				t.Logf("\t%s\tShould not be able to compile the path", succeed)
+			}
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package openapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Document represents an OpenAPI 3 document. Only the parts of the document
+// that describe how requests and responses should look are modeled.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Servers    []Server             `json:"servers"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// Server describes a server that serves the api
+type Server struct {
+	URL string `json:"url"`
+}
+
+// PathItem describes the operations of a single path
+type PathItem struct {
+	Ref        string       `json:"$ref"`
+	Parameters []*Parameter `json:"parameters"`
+	Get        *Operation   `json:"get"`
+	Put        *Operation   `json:"put"`
+	Post       *Operation   `json:"post"`
+	Delete     *Operation   `json:"delete"`
+	Options    *Operation   `json:"options"`
+	Head       *Operation   `json:"head"`
+	Patch      *Operation   `json:"patch"`
+	Trace      *Operation   `json:"trace"`
+}
+
+// Operation describes a single method of a path
+type Operation struct {
+	Parameters  []*Parameter         `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter describes a single parameter of an operation
+type Parameter struct {
+	Ref      string          `json:"$ref"`
+	Name     string          `json:"name"`
+	In       string          `json:"in"`
+	Required bool            `json:"required"`
+	Schema   json.RawMessage `json:"schema"`
+}
+
+// RequestBody describes the body of the requests of an operation
+type RequestBody struct {
+	Ref      string                `json:"$ref"`
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response describes a single response of an operation
+type Response struct {
+	Ref     string                `json:"$ref"`
+	Content map[string]*MediaType `json:"content"`
+}
+
+// MediaType describes the body of a request or a response in a specific
+// media type
+type MediaType struct {
+	Schema json.RawMessage `json:"schema"`
+
+	// Encoding describes the fields of form bodies by their names.
+	Encoding map[string]*Encoding `json:"encoding"`
+}
+
+// Encoding describes how a field of a form body is encoded
+type Encoding struct {
+	// ContentType is a comma-separated list of the content types of the
+	// field's parts, which may be "type/*".
+	ContentType string `json:"contentType"`
+}
+
+// Components holds the reusable objects of the document
+type Components struct {
+	Schemas       map[string]json.RawMessage `json:"schemas"`
+	Parameters    map[string]*Parameter      `json:"parameters"`
+	RequestBodies map[string]*RequestBody    `json:"requestBodies"`
+	Responses     map[string]*Response       `json:"responses"`
+	PathItems     map[string]*PathItem       `json:"pathItems"`
+}
+
+// The prefixes of references to the document's components
+const (
+	schemasPrefix       = "#/components/schemas/"
+	parametersPrefix    = "#/components/parameters/"
+	requestBodiesPrefix = "#/components/requestBodies/"
+	responsesPrefix     = "#/components/responses/"
+	pathItemsPrefix     = "#/components/pathItems/"
+)
+
+// maxRefDepth is the maximal length of a chain of references between
+// components, which protects against references that point to each other.
+const maxRefDepth = 32
+
+// LoadDocument reads an OpenAPI 3 document in json format from a file.
+func LoadDocument(path string) (*Document, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read OpenAPI document")
+	}
+
+	return NewDocument(bytes)
+}
+
+// NewDocument parses an OpenAPI 3 document in json format.
+func NewDocument(bytes []byte) (*Document, error) {
+	var document Document
+
+	err := json.Unmarshal(bytes, &document)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse OpenAPI document")
+	}
+
+	if !strings.HasPrefix(document.OpenAPI, "3.") {
+		return nil, errors.New("OpenAPI version \"" +
+			document.OpenAPI +
+			"\" is not supported")
+	}
+
+	return &document, nil
+}
+
+// isVersion31 returns true if the document follows OpenAPI 3.1, whose
+// schemas are standard json schemas.
+func (d *Document) isVersion31() bool {
+	return strings.HasPrefix(d.OpenAPI, "3.1")
+}
+
+// resolvePathItem follows the reference of a path item, if it has one.
+func (d *Document) resolvePathItem(item *PathItem) (*PathItem, error) {
+	for depth := 0; item != nil && item.Ref != ""; depth++ {
+		if depth == maxRefDepth || !strings.HasPrefix(item.Ref, pathItemsPrefix) {
+			return nil, unresolvedRefError(item.Ref)
+		}
+
+		item = d.Components.PathItems[componentName(item.Ref, pathItemsPrefix)]
+	}
+
+	if item == nil {
+		return nil, errors.New("a reference to a path item points to nothing")
+	}
+
+	return item, nil
+}
+
+// resolveParameter follows the reference of a parameter, if it has one.
+func (d *Document) resolveParameter(parameter *Parameter) (*Parameter, error) {
+	for depth := 0; parameter != nil && parameter.Ref != ""; depth++ {
+		if depth == maxRefDepth || !strings.HasPrefix(parameter.Ref, parametersPrefix) {
+			return nil, unresolvedRefError(parameter.Ref)
+		}
+
+		parameter = d.Components.Parameters[componentName(parameter.Ref, parametersPrefix)]
+	}
+
+	if parameter == nil {
+		return nil, errors.New("a reference to a parameter points to nothing")
+	}
+
+	return parameter, nil
+}
+
+// resolveRequestBody follows the reference of a request body, if it has one.
+func (d *Document) resolveRequestBody(body *RequestBody) (*RequestBody, error) {
+	for depth := 0; body != nil && body.Ref != ""; depth++ {
+		if depth == maxRefDepth || !strings.HasPrefix(body.Ref, requestBodiesPrefix) {
+			return nil, unresolvedRefError(body.Ref)
+		}
+
+		body = d.Components.RequestBodies[componentName(body.Ref, requestBodiesPrefix)]
+	}
+
+	if body == nil {
+		return nil, errors.New("a reference to a request body points to nothing")
+	}
+
+	return body, nil
+}
+
+// resolveResponse follows the reference of a response, if it has one.
+func (d *Document) resolveResponse(response *Response) (*Response, error) {
+	for depth := 0; response != nil && response.Ref != ""; depth++ {
+		if depth == maxRefDepth || !strings.HasPrefix(response.Ref, responsesPrefix) {
+			return nil, unresolvedRefError(response.Ref)
+		}
+
+		response = d.Components.Responses[componentName(response.Ref, responsesPrefix)]
+	}
+
+	if response == nil {
+		return nil, errors.New("a reference to a response points to nothing")
+	}
+
+	return response, nil
+}
+
+// componentName returns the name of the component that a reference points
+// to, decoding the escaped characters of the json pointer.
+func componentName(ref, prefix string) string {
+	name := strings.SplitN(strings.TrimPrefix(ref, prefix), "/", 2)[0]
+
+	return strings.Replace(strings.Replace(name, "~1", "/", -1), "~0", "~", -1)
+}
+
+// unresolvedRefError returns the error of a reference that cannot be
+// resolved.
+func unresolvedRefError(ref string) error {
+	return errors.New("could not resolve reference \"" + ref + "\"")
+}
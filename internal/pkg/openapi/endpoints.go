@@ -0,0 +1,357 @@
+package openapi
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/apidome/gateway/internal/pkg/configs"
+	"github.com/pkg/errors"
+)
+
+// Headers that OpenAPI ignores when they are described as parameters,
+// since they are described by other parts of the document
+var ignoredHeaders = []string{
+	"Accept",
+	"Authorization",
+	"Content-Type",
+}
+
+// Endpoints derives the gateway's endpoints from the document's operations:
+// their paths, methods, parameters, request bodies and responses.
+// Only json request and response bodies are described, and cookie
+// parameters are ignored.
+func (d *Document) Endpoints() ([]*configs.Endpoint, error) {
+	var endpoints []*configs.Endpoint
+
+	basePath := d.basePath()
+
+	paths := make([]string, 0, len(d.Paths))
+	for path := range d.Paths {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		item, err := d.resolvePathItem(d.Paths[path])
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid path "+path)
+		}
+
+		for _, operation := range item.operations() {
+			endpoint, err := d.endpoint(basePath+path, operation.method, item, operation.operation)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid operation "+operation.method+" "+path)
+			}
+
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+
+	return endpoints, nil
+}
+
+// methodOperation is an operation of a path item and its method
+type methodOperation struct {
+	method    string
+	operation *Operation
+}
+
+// operations returns the operations of a path item by their methods
+func (p *PathItem) operations() []methodOperation {
+	var operations []methodOperation
+
+	for _, operation := range []methodOperation{
+		{http.MethodGet, p.Get},
+		{http.MethodPut, p.Put},
+		{http.MethodPost, p.Post},
+		{http.MethodDelete, p.Delete},
+		{http.MethodOptions, p.Options},
+		{http.MethodHead, p.Head},
+		{http.MethodPatch, p.Patch},
+		{http.MethodTrace, p.Trace},
+	} {
+		if operation.operation != nil {
+			operations = append(operations, operation)
+		}
+	}
+
+	return operations
+}
+
+// basePath returns the path of the first server of the api, which prefixes
+// all the paths in the document.
+func (d *Document) basePath() string {
+	if len(d.Servers) == 0 {
+		return ""
+	}
+
+	serverURL, err := url.Parse(d.Servers[0].URL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSuffix(serverURL.Path, "/")
+}
+
+// endpoint creates the endpoint of a single operation
+func (d *Document) endpoint(path, method string, item *PathItem,
+	operation *Operation) (*configs.Endpoint, error) {
+	endpoint := &configs.Endpoint{
+		Path:   path,
+		Method: method,
+	}
+
+	err := d.setParameters(endpoint, item.Parameters, operation.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	if operation.RequestBody != nil {
+		body, err := d.resolveRequestBody(operation.RequestBody)
+		if err != nil {
+			return nil, err
+		}
+
+		// The endpoint accepts form bodies only if the document declares
+		// them
+		formType := formMediaType(body.Content)
+		if formType != "" {
+			endpoint.Form = &configs.Form{}
+			setFileContentTypes(endpoint, body.Content[formType])
+		}
+
+		// Form bodies are validated like json objects, so their schema
+		// is used if the body has no json media type
+		mediaType := jsonMediaType(body.Content)
+		if mediaType == "" {
+			mediaType = formType
+		}
+
+		if mediaType != "" && len(body.Content[mediaType].Schema) > 0 {
+			endpoint.Schema, err = d.schema(body.Content[mediaType].Schema)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid request body")
+			}
+		}
+	}
+
+	statuses := make([]string, 0, len(operation.Responses))
+	for status := range operation.Responses {
+		statuses = append(statuses, status)
+	}
+
+	sort.Strings(statuses)
+
+	for _, status := range statuses {
+		response, err := d.resolveResponse(operation.Responses[status])
+		if err != nil {
+			return nil, err
+		}
+
+		mediaTypes := make([]string, 0, len(response.Content))
+		for mediaType := range response.Content {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+
+		sort.Strings(mediaTypes)
+
+		for _, mediaType := range mediaTypes {
+			if !isJSONMediaType(mediaType) || len(response.Content[mediaType].Schema) == 0 {
+				continue
+			}
+
+			schema, err := d.schema(response.Content[mediaType].Schema)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid response "+status)
+			}
+
+			endpoint.Responses = append(endpoint.Responses, &configs.Response{
+				Status:      status,
+				ContentType: mediaType,
+				Schema:      schema,
+			})
+		}
+	}
+
+	return endpoint, nil
+}
+
+// setParameters creates the schemas of the endpoint's parameters out of the
+// parameters of the path item and of the operation, which override the path
+// item's parameters with the same name and location.
+func (d *Document) setParameters(endpoint *configs.Endpoint, itemParameters,
+	operationParameters []*Parameter) error {
+	type parameterKey struct {
+		in   string
+		name string
+	}
+
+	var keys []parameterKey
+	parameters := make(map[parameterKey]*Parameter)
+
+	for _, parameter := range append(append([]*Parameter{}, itemParameters...), operationParameters...) {
+		parameter, err := d.resolveParameter(parameter)
+		if err != nil {
+			return err
+		}
+
+		key := parameterKey{parameter.In, parameter.Name}
+		if parameter.In == "header" {
+			key.name = http.CanonicalHeaderKey(parameter.Name)
+		}
+
+		if _, ok := parameters[key]; !ok {
+			keys = append(keys, key)
+		}
+
+		parameters[key] = parameter
+	}
+
+	// The properties and required properties of each location's schema
+	properties := make(map[string]map[string]json.RawMessage)
+	required := make(map[string][]string)
+
+	for _, key := range keys {
+		parameter := parameters[key]
+
+		if key.in == "header" && isIgnoredHeader(key.name) {
+			continue
+		}
+
+		if key.in != "path" && key.in != "query" && key.in != "header" {
+			continue
+		}
+
+		if properties[key.in] == nil {
+			properties[key.in] = make(map[string]json.RawMessage)
+		}
+
+		// Parameters that are described by "content" rather than by a
+		// schema accept any value.
+		schema := parameter.Schema
+		if len(schema) == 0 {
+			schema = json.RawMessage("{}")
+		}
+
+		properties[key.in][parameter.Name] = schema
+
+		// Path parameters are always required.
+		if parameter.Required || key.in == "path" {
+			required[key.in] = append(required[key.in], parameter.Name)
+		}
+	}
+
+	locations := map[string]*string{
+		"path":   &endpoint.Parameters.Path,
+		"query":  &endpoint.Parameters.Query,
+		"header": &endpoint.Parameters.Header,
+	}
+
+	for location, locationProperties := range properties {
+		object := map[string]interface{}{
+			"type":       "object",
+			"properties": locationProperties,
+		}
+
+		if len(required[location]) > 0 {
+			object["required"] = required[location]
+		}
+
+		raw, err := json.Marshal(object)
+		if err != nil {
+			return err
+		}
+
+		*locations[location], err = d.schema(raw)
+		if err != nil {
+			return errors.Wrap(err, "invalid "+location+" parameters")
+		}
+	}
+
+	return nil
+}
+
+// jsonMediaType returns the json media type among the media types of a
+// body, preferring "application/json", or an empty string if the body has
+// no json media type.
+func jsonMediaType(content map[string]*MediaType) string {
+	if _, ok := content["application/json"]; ok {
+		return "application/json"
+	}
+
+	mediaTypes := make([]string, 0, len(content))
+	for mediaType := range content {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+
+	sort.Strings(mediaTypes)
+
+	for _, mediaType := range mediaTypes {
+		if isJSONMediaType(mediaType) {
+			return mediaType
+		}
+	}
+
+	return ""
+}
+
+// formMediaType returns the form media type among the media types of a
+// body, preferring "multipart/form-data", or an empty string if the body
+// has no form media type.
+func formMediaType(content map[string]*MediaType) string {
+	for _, formType := range []string{"multipart/form-data", "application/x-www-form-urlencoded"} {
+		if _, ok := content[formType]; ok {
+			return formType
+		}
+	}
+
+	return ""
+}
+
+// setFileContentTypes constrains the content types of the files that are
+// uploaded in the fields of a multipart body to the content types of the
+// fields' encodings
+func setFileContentTypes(endpoint *configs.Endpoint, mediaType *MediaType) {
+	for field, encoding := range mediaType.Encoding {
+		if encoding == nil || encoding.ContentType == "" {
+			continue
+		}
+
+		var contentTypes []string
+		for _, contentType := range strings.Split(encoding.ContentType, ",") {
+			contentTypes = append(contentTypes, strings.TrimSpace(contentType))
+		}
+
+		if endpoint.Form.Files == nil {
+			endpoint.Form.Files = make(map[string]configs.File)
+		}
+
+		endpoint.Form.Files[field] = configs.File{ContentTypes: contentTypes}
+	}
+}
+
+// isJSONMediaType returns true if a media type describes json data
+func isJSONMediaType(mediaType string) bool {
+	mediaType, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// isIgnoredHeader returns true if OpenAPI ignores the header as a parameter
+func isIgnoredHeader(header string) bool {
+	for _, ignoredHeader := range ignoredHeaders {
+		if header == ignoredHeader {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,287 @@
+package openapi_test
+
+import (
+	"testing"
+
+	"github.com/apidome/gateway/internal/pkg/configs"
+	"github.com/apidome/gateway/internal/pkg/openapi"
+	"github.com/apidome/gateway/internal/pkg/validators"
+	"github.com/apidome/gateway/internal/pkg/validators/jsonvalidator"
+)
+
+const succeed = "V"
+const failed = "X"
+
+func TestEndpoints(t *testing.T) {
+	testCases := []struct {
+		description string
+		path        string
+		method      string
+		validate    func(jv jsonvalidator.JsonValidator) error
+		valid       bool
+	}{
+		{
+			"a body that matches a referenced component",
+			"/v1/pets",
+			"POST",
+			func(jv jsonvalidator.JsonValidator) error {
+				return jv.Validate("/v1/pets", "POST", []byte(`{"name": "Rex", "tag": null, "parent": {"name": "Max"}}`))
+			},
+			true,
+		},
+		{
+			"a body that fails in a recursive component",
+			"/v1/pets",
+			"POST",
+			func(jv jsonvalidator.JsonValidator) error {
+				return jv.Validate("/v1/pets", "POST", []byte(`{"name": "Rex", "parent": {"name": "Max", "age": 3}}`))
+			},
+			false,
+		},
+		{
+			"a query parameter that fails in an exclusive minimum",
+			"/v1/pets",
+			"GET",
+			func(jv jsonvalidator.JsonValidator) error {
+				return jv.ValidateParameters("/v1/pets", "GET", validators.ParametersQuery,
+					map[string][]string{"limit": {"0"}})
+			},
+			false,
+		},
+		{
+			"a required header from a referenced parameter",
+			"/v1/pets",
+			"GET",
+			func(jv jsonvalidator.JsonValidator) error {
+				return jv.ValidateParameters("/v1/pets", "GET", validators.ParametersHeader,
+					map[string][]string{"X-Request-Id": {"1"}})
+			},
+			true,
+		},
+		{
+			"a path parameter that is declared by the path item",
+			"/v1/pets/{petId}",
+			"GET",
+			func(jv jsonvalidator.JsonValidator) error {
+				return jv.ValidateParameters("/v1/pets/{petId}", "GET", validators.ParametersPath,
+					map[string][]string{"petId": {"abc"}})
+			},
+			false,
+		},
+		{
+			"a response that matches a referenced response",
+			"/v1/pets",
+			"GET",
+			func(jv jsonvalidator.JsonValidator) error {
+				return jv.ValidateResponse("/v1/pets", "GET", 500, "application/problem+json",
+					[]byte(`{"message": "failed"}`))
+			},
+			true,
+		},
+		{
+			"a response with an item that leaks a field",
+			"/v1/pets",
+			"GET",
+			func(jv jsonvalidator.JsonValidator) error {
+				return jv.ValidateResponse("/v1/pets", "GET", 200, "application/json",
+					[]byte(`[{"name": "Rex", "owner": "Bob"}]`))
+			},
+			false,
+		},
+		{
+			"a multipart body with a field that fails in its type",
+			"/v1/pets/{petId}",
+			"PUT",
+			func(jv jsonvalidator.JsonValidator) error {
+				return jv.ValidateForm("/v1/pets/{petId}", "PUT",
+					map[string][]string{"name": {"Rex"}, "age": {"old"}, "photo": {"rex.png"}})
+			},
+			false,
+		},
+		{
+			"a multipart body with a file upload",
+			"/v1/pets/{petId}",
+			"PUT",
+			func(jv jsonvalidator.JsonValidator) error {
+				return jv.ValidateForm("/v1/pets/{petId}", "PUT",
+					map[string][]string{"name": {"Rex"}, "age": {"3"}, "photo": {"rex.png"}})
+			},
+			true,
+		},
+	}
+
+	t.Log("Given the need to test the derivation of endpoints from an OpenAPI document")
+	{
+		document, err := openapi.LoadDocument("testdata/petstore.json")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to load the document: %v", failed, err)
+		}
+		t.Logf("\t%s\tShould be able to load the document", succeed)
+
+		endpoints, err := document.Endpoints()
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to derive endpoints: %v", failed, err)
+		}
+
+		if len(endpoints) != 4 {
+			t.Fatalf("\t%s\tShould derive 4 endpoints: got %d", failed, len(endpoints))
+		}
+		t.Logf("\t%s\tShould derive 4 endpoints", succeed)
+
+		for _, endpoint := range endpoints {
+			if (endpoint.Form != nil) != (endpoint.Method == "PUT") {
+				t.Errorf("\t%s\tShould accept form bodies only in %s %s if it declares them",
+					failed, endpoint.Method, endpoint.Path)
+			}
+
+			if endpoint.Method != "PUT" || endpoint.Form == nil {
+				continue
+			}
+
+			contentTypes := endpoint.Form.Files["photo"].ContentTypes
+			if len(contentTypes) != 2 || contentTypes[0] != "image/png" || contentTypes[1] != "image/jpeg" {
+				t.Errorf("\t%s\tShould take the content types of uploads from the encoding: got %v",
+					failed, contentTypes)
+			} else {
+				t.Logf("\t%s\tShould take the content types of uploads from the encoding", succeed)
+			}
+		}
+
+		jv, err := jsonvalidator.NewJsonValidator("draft-07")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a new JsonValidator: %v", failed, err)
+		}
+
+		for _, endpoint := range endpoints {
+			err = loadEndpoint(jv, endpoint)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to load the schemas of %s %s: %v", failed,
+					endpoint.Method, endpoint.Path, err)
+			}
+		}
+		t.Logf("\t%s\tShould be able to load the schemas of the endpoints", succeed)
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When trying to validate %s", index, testCase.description)
+			{
+				err = testCase.validate(jv)
+				if testCase.valid {
+					if err != nil {
+						t.Errorf("\t%s\tData should be valid: %v", failed, err)
+					} else {
+						t.Logf("\t%s\tData should be valid", succeed)
+					}
+				} else {
+					if err != nil {
+						t.Logf("\t%s\tData should not be valid: %v", succeed, err)
+					} else {
+						t.Errorf("\t%s\tData should not be valid", failed)
+					}
+				}
+			}
+		}
+	}
+}
+
+// loadEndpoint loads all the schemas of an endpoint into the validator
+func loadEndpoint(jv jsonvalidator.JsonValidator, endpoint *configs.Endpoint) error {
+	if endpoint.Schema != "" {
+		err := jv.LoadSchema(endpoint.Path, endpoint.Method, []byte(endpoint.Schema))
+		if err != nil {
+			return err
+		}
+	}
+
+	for location, schema := range map[string]string{
+		validators.ParametersPath:   endpoint.Parameters.Path,
+		validators.ParametersQuery:  endpoint.Parameters.Query,
+		validators.ParametersHeader: endpoint.Parameters.Header,
+	} {
+		if schema == "" {
+			continue
+		}
+
+		err := jv.LoadParametersSchema(endpoint.Path, endpoint.Method, location, []byte(schema))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, response := range endpoint.Responses {
+		err := jv.LoadResponseSchema(endpoint.Path, endpoint.Method, response.Status,
+			response.ContentType, []byte(response.Schema))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestEndpointsVersion31(t *testing.T) {
+	document := `{
+		"openapi": "3.1.0",
+		"paths": {
+			"/points": {
+				"post": {
+					"requestBody": {
+						"content": {
+							"application/json": {
+								"schema": {"$ref": "#/components/schemas/Point"}
+							}
+						}
+					}
+				}
+			}
+		},
+		"components": {
+			"schemas": {
+				"Point": {
+					"type": "array",
+					"prefixItems": [{"type": "number"}, {"type": "number"}],
+					"items": false
+				}
+			}
+		}
+	}`
+
+	t.Log("Given the need to test the derivation of endpoints from an OpenAPI 3.1 document")
+	{
+		parsed, err := openapi.NewDocument([]byte(document))
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to parse the document: %v", failed, err)
+		}
+
+		endpoints, err := parsed.Endpoints()
+		if err != nil || len(endpoints) != 1 {
+			t.Fatalf("\t%s\tShould derive a single endpoint: %v", failed, err)
+		}
+		t.Logf("\t%s\tShould derive a single endpoint", succeed)
+
+		jv, err := jsonvalidator.NewJsonValidator("draft-07")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a new JsonValidator: %v", failed, err)
+		}
+
+		err = loadEndpoint(jv, endpoints[0])
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to load the schemas of the endpoint: %v", failed, err)
+		}
+
+		// The schema is a draft 2020-12 schema regardless of the validator's
+		// draft.
+		err = jv.Validate("/points", "POST", []byte(`[1, 2, 3]`))
+		if err != nil {
+			t.Logf("\t%s\tData should not be valid: %v", succeed, err)
+		} else {
+			t.Errorf("\t%s\tData should not be valid", failed)
+		}
+
+		err = jv.Validate("/points", "POST", []byte(`[1, 2]`))
+		if err != nil {
+			t.Errorf("\t%s\tData should be valid: %v", failed, err)
+		} else {
+			t.Logf("\t%s\tData should be valid", succeed)
+		}
+	}
+}
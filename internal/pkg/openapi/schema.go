@@ -0,0 +1,311 @@
+package openapi
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// definitionsPrefix is the prefix of references to the component schemas
+// that are bundled into each schema that the document produces.
+const definitionsPrefix = "#/definitions/"
+
+// draft202012 is the meta-schema of the json schemas of OpenAPI 3.1 documents.
+const draft202012 = "https://json-schema.org/draft/2020-12/schema"
+
+// idPrefix is the prefix of the "$id" of the schemas that the document
+// produces. The rest of the id is a hash of the schema, so identical
+// schemas share an id and different schemas never do.
+const idPrefix = "urn:apidome:openapi:"
+
+// Keywords whose values are schemas, maps of schemas or arrays of schemas
+var (
+	schemaKeywords = []string{
+		"items",
+		"additionalItems",
+		"additionalProperties",
+		"propertyNames",
+		"contains",
+		"not",
+		"if",
+		"then",
+		"else",
+		"unevaluatedItems",
+		"unevaluatedProperties",
+	}
+	schemaMapKeywords = []string{
+		"properties",
+		"patternProperties",
+		"dependentSchemas",
+		"definitions",
+		"$defs",
+	}
+	schemaArrayKeywords = []string{
+		"items",
+		"allOf",
+		"anyOf",
+		"oneOf",
+		"prefixItems",
+	}
+)
+
+// Keywords of OpenAPI 3.0 schemas that are not json schema keywords and do
+// not affect validation
+var annotationKeywords = []string{
+	"discriminator",
+	"xml",
+	"externalDocs",
+	"example",
+	"deprecated",
+}
+
+// schema creates a self-contained json schema out of a schema in the
+// document: references to component schemas point to a "definitions"
+// keyword that holds the referenced components, and OpenAPI 3.0 keywords
+// are translated to their json schema equivalents.
+func (d *Document) schema(raw json.RawMessage) (string, error) {
+	var value interface{}
+
+	err := json.Unmarshal(raw, &value)
+	if err != nil {
+		return "", errors.Wrap(err, "could not parse schema")
+	}
+
+	converted, refs, err := d.convertSchema(value)
+	if err != nil {
+		return "", err
+	}
+
+	root, refs, err := d.inlineRootRef(converted, refs)
+	if err != nil {
+		return "", err
+	}
+
+	// Bundle every component that the schema references, directly or
+	// through other components.
+	definitions := make(map[string]interface{})
+	for len(refs) > 0 {
+		name := refs[0]
+		refs = refs[1:]
+
+		if _, ok := definitions[name]; ok {
+			continue
+		}
+
+		component, ok := d.Components.Schemas[name]
+		if !ok {
+			return "", unresolvedRefError(schemasPrefix + name)
+		}
+
+		var componentValue interface{}
+		err = json.Unmarshal(component, &componentValue)
+		if err != nil {
+			return "", errors.Wrap(err, "could not parse schema "+name)
+		}
+
+		convertedComponent, componentRefs, err := d.convertSchema(componentValue)
+		if err != nil {
+			return "", errors.Wrap(err, "could not convert schema "+name)
+		}
+
+		definitions[name] = convertedComponent
+		refs = append(refs, componentRefs...)
+	}
+
+	if len(definitions) > 0 {
+		root["definitions"] = definitions
+	}
+
+	// OpenAPI 3.0 schemas are translated to draft-07, which is the default
+	// draft of the validator.
+	if d.isVersion31() {
+		root["$schema"] = draft202012
+	}
+
+	// The id lets the references find the definitions of the schema.
+	bytes, err := json.Marshal(root)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha1.Sum(bytes)
+	root["$id"] = idPrefix + hex.EncodeToString(hash[:])
+
+	bytes, err = json.Marshal(root)
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes), nil
+}
+
+// inlineRootRef replaces a root schema that only references a component
+// with the component itself, since the keywords next to a "$ref" (like
+// "definitions") are ignored. A root schema that is not an object is
+// wrapped in one for the same reason.
+func (d *Document) inlineRootRef(schema interface{}, refs []string) (map[string]interface{}, []string, error) {
+	for depth := 0; ; depth++ {
+		object, ok := schema.(map[string]interface{})
+		if !ok {
+			return map[string]interface{}{"allOf": []interface{}{schema}}, refs, nil
+		}
+
+		ref, ok := object["$ref"].(string)
+		if !ok {
+			return object, refs, nil
+		}
+
+		name := componentName(ref, definitionsPrefix)
+		component, ok := d.Components.Schemas[name]
+
+		// A reference to a part of a component cannot be inlined.
+		if depth == maxRefDepth || !ok ||
+			ref != definitionsPrefix+strings.Replace(strings.Replace(name, "~", "~0", -1), "/", "~1", -1) {
+			return map[string]interface{}{"allOf": []interface{}{object}}, refs, nil
+		}
+
+		var componentValue interface{}
+		err := json.Unmarshal(component, &componentValue)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "could not parse schema "+name)
+		}
+
+		converted, componentRefs, err := d.convertSchema(componentValue)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "could not convert schema "+name)
+		}
+
+		schema = converted
+		refs = append(refs, componentRefs...)
+	}
+}
+
+// convertSchema translates a schema of the document into a json schema and
+// returns the names of the components that it references.
+func (d *Document) convertSchema(value interface{}) (interface{}, []string, error) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		// Boolean schemas have nothing to translate.
+		return value, nil, nil
+	}
+
+	converted := make(map[string]interface{}, len(object))
+	for key, keywordValue := range object {
+		converted[key] = keywordValue
+	}
+
+	var refs []string
+
+	if ref, ok := converted["$ref"].(string); ok {
+		if !strings.HasPrefix(ref, schemasPrefix) {
+			return nil, nil, unresolvedRefError(ref)
+		}
+
+		converted["$ref"] = definitionsPrefix + strings.TrimPrefix(ref, schemasPrefix)
+		refs = append(refs, componentName(ref, schemasPrefix))
+	}
+
+	// OpenAPI 3.1 schemas are json schemas, so only the references are
+	// translated.
+	if !d.isVersion31() {
+		convertNullable(converted)
+		convertExclusiveLimit(converted, "exclusiveMinimum", "minimum")
+		convertExclusiveLimit(converted, "exclusiveMaximum", "maximum")
+
+		for _, keyword := range annotationKeywords {
+			delete(converted, keyword)
+		}
+	}
+
+	for _, keyword := range schemaKeywords {
+		if subSchema, ok := converted[keyword].(map[string]interface{}); ok {
+			convertedSubSchema, subRefs, err := d.convertSchema(subSchema)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			converted[keyword] = convertedSubSchema
+			refs = append(refs, subRefs...)
+		}
+	}
+
+	for _, keyword := range schemaMapKeywords {
+		if subSchemas, ok := converted[keyword].(map[string]interface{}); ok {
+			convertedSubSchemas := make(map[string]interface{}, len(subSchemas))
+
+			for name, subSchema := range subSchemas {
+				convertedSubSchema, subRefs, err := d.convertSchema(subSchema)
+				if err != nil {
+					return nil, nil, err
+				}
+
+				convertedSubSchemas[name] = convertedSubSchema
+				refs = append(refs, subRefs...)
+			}
+
+			converted[keyword] = convertedSubSchemas
+		}
+	}
+
+	for _, keyword := range schemaArrayKeywords {
+		if subSchemas, ok := converted[keyword].([]interface{}); ok {
+			convertedSubSchemas := make([]interface{}, len(subSchemas))
+
+			for index, subSchema := range subSchemas {
+				convertedSubSchema, subRefs, err := d.convertSchema(subSchema)
+				if err != nil {
+					return nil, nil, err
+				}
+
+				convertedSubSchemas[index] = convertedSubSchema
+				refs = append(refs, subRefs...)
+			}
+
+			converted[keyword] = convertedSubSchemas
+		}
+	}
+
+	return converted, refs, nil
+}
+
+// convertNullable translates the "nullable" keyword of OpenAPI 3.0 into a
+// "null" type.
+func convertNullable(schema map[string]interface{}) {
+	nullable, _ := schema["nullable"].(bool)
+	delete(schema, "nullable")
+
+	if !nullable {
+		return
+	}
+
+	switch t := schema["type"].(type) {
+	case string:
+		schema["type"] = []interface{}{t, "null"}
+	case []interface{}:
+		schema["type"] = append(t, "null")
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		schema["enum"] = append(enum, nil)
+	}
+}
+
+// convertExclusiveLimit translates the boolean "exclusiveMinimum" and
+// "exclusiveMaximum" keywords of OpenAPI 3.0 into their numeric json schema
+// form.
+func convertExclusiveLimit(schema map[string]interface{}, exclusiveKeyword, limitKeyword string) {
+	exclusive, ok := schema[exclusiveKeyword].(bool)
+	if !ok {
+		return
+	}
+
+	delete(schema, exclusiveKeyword)
+
+	if limit, ok := schema[limitKeyword]; ok && exclusive {
+		schema[exclusiveKeyword] = limit
+		delete(schema, limitKeyword)
+	}
+}
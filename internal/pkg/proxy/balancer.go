@@ -0,0 +1,423 @@
+package proxy
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Load balancing strategies
+const (
+	// RoundRobin sends the requests to the upstreams in turns
+	RoundRobin = "round-robin"
+
+	// LeastConnections sends each request to the upstream with the fewest
+	// requests in progress
+	LeastConnections = "least-connections"
+
+	// ConsistentHash sends the requests with the same key (the client's
+	// address or a header) to the same upstream, as long as it is available
+	ConsistentHash = "consistent-hash"
+)
+
+// Defaults of the balancer options
+const (
+	defaultHealthCheckInterval = 10 * time.Second
+	defaultHealthCheckTimeout  = 5 * time.Second
+	defaultHealthyThreshold    = 2
+	defaultUnhealthyThreshold  = 2
+	defaultEjectionTime        = 30 * time.Second
+)
+
+// hashReplicas is the number of points of each upstream on the hash ring,
+// which spread the keys evenly between the upstreams
+const hashReplicas = 100
+
+// ErrNoHealthyUpstream is returned when all the upstreams of a target are
+// unhealthy or ejected
+var ErrNoHealthyUpstream = errors.New("No healthy upstream")
+
+// BalancerOptions determines how a Balancer spreads the requests between
+// the upstreams and when it stops sending requests to an upstream.
+// Settings with zero values keep their defaults.
+type BalancerOptions struct {
+	// Strategy is RoundRobin (the default), LeastConnections or
+	// ConsistentHash
+	Strategy string
+
+	// HashHeader is the header whose value is the key of the
+	// ConsistentHash strategy. If it is empty or the request does not have
+	// it, the client's address is the key.
+	HashHeader string
+
+	// HealthCheckPath is the path that is requested from each upstream to
+	// check its health. If it is empty, there are no active health checks.
+	HealthCheckPath     string
+	HealthCheckInterval time.Duration
+	HealthCheckTimeout  time.Duration
+
+	// HealthyThreshold and UnhealthyThreshold are the numbers of
+	// consecutive health checks that should pass or fail to change the
+	// health of an upstream.
+	HealthyThreshold   int
+	UnhealthyThreshold int
+
+	// MaxFailures is the number of consecutive failed requests (requests
+	// that could not be sent, or that were answered with 502, 503 or 504)
+	// after which an upstream is ejected for EjectionTime. If it is 0,
+	// upstreams are not ejected.
+	MaxFailures  int
+	EjectionTime time.Duration
+}
+
+// upstream is an instance of a target that the balancer sends requests to
+type upstream struct {
+	url *url.URL
+
+	// breaker stops the requests to the upstream while it keeps failing
+	breaker *circuitBreaker
+
+	// active is the number of requests in progress
+	active int64
+
+	mutex sync.Mutex
+
+	// healthy is the health of the upstream according to the active health
+	// checks, and checks is the number of consecutive checks that
+	// contradict it
+	healthy bool
+	checks  int
+
+	// failures is the number of consecutive failed requests, and
+	// ejectedUntil is the time until which the upstream is ejected
+	failures     int
+	ejectedUntil time.Time
+}
+
+// available returns true if requests may be sent to the upstream
+func (u *upstream) available(now time.Time) bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	return u.healthy && !now.Before(u.ejectedUntil) && u.breaker.ready(now)
+}
+
+// ringPoint is a point of an upstream on the hash ring
+type ringPoint struct {
+	hash     uint32
+	upstream *upstream
+}
+
+// Balancer spreads the requests to a target between the target's upstreams
+type Balancer struct {
+	upstreams []*upstream
+	options   BalancerOptions
+
+	// next is the turn of the RoundRobin strategy
+	next uint64
+
+	// ring holds the points of the upstreams, ordered by their hashes
+	ring []ringPoint
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewBalancer creates a Balancer that spreads the requests between the
+// upstreams with the given URLs
+func NewBalancer(urls []string, options BalancerOptions) (*Balancer, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("a balancer requires at least one upstream")
+	}
+
+	switch options.Strategy {
+	case "":
+		options.Strategy = RoundRobin
+	case RoundRobin, LeastConnections, ConsistentHash:
+	default:
+		return nil, errors.New("unknown load balancing strategy \"" + options.Strategy + "\"")
+	}
+
+	if options.HealthCheckInterval == 0 {
+		options.HealthCheckInterval = defaultHealthCheckInterval
+	}
+
+	if options.HealthCheckTimeout == 0 {
+		options.HealthCheckTimeout = defaultHealthCheckTimeout
+	}
+
+	if options.HealthyThreshold == 0 {
+		options.HealthyThreshold = defaultHealthyThreshold
+	}
+
+	if options.UnhealthyThreshold == 0 {
+		options.UnhealthyThreshold = defaultUnhealthyThreshold
+	}
+
+	if options.EjectionTime == 0 {
+		options.EjectionTime = defaultEjectionTime
+	}
+
+	b := &Balancer{
+		options: options,
+		stop:    make(chan struct{}),
+	}
+
+	for _, rawURL := range urls {
+		upstreamURL, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, err
+		}
+
+		// Upstreams are healthy until the health checks find otherwise
+		b.upstreams = append(b.upstreams, &upstream{
+			url:     upstreamURL,
+			healthy: true,
+		})
+	}
+
+	if options.Strategy == ConsistentHash {
+		for _, u := range b.upstreams {
+			for replica := 0; replica < hashReplicas; replica++ {
+				b.ring = append(b.ring, ringPoint{
+					hashKey(u.url.Host + "#" + strconv.Itoa(replica)),
+					u,
+				})
+			}
+		}
+
+		sort.Slice(b.ring, func(i, j int) bool {
+			return b.ring[i].hash < b.ring[j].hash
+		})
+	}
+
+	return b, nil
+}
+
+// Close stops the active health checks
+func (b *Balancer) Close() {
+	b.stopOnce.Do(func() {
+		close(b.stop)
+	})
+}
+
+// selectUpstream returns the upstream that a request should be sent to
+func (b *Balancer) selectUpstream(req *http.Request) (*upstream, error) {
+	now := time.Now()
+	count := uint64(len(b.upstreams))
+
+	switch b.options.Strategy {
+	case LeastConnections:
+		// The turn breaks ties between upstreams with the same number of
+		// requests in progress
+		start := atomic.AddUint64(&b.next, 1)
+
+		var selected *upstream
+		for i := uint64(0); i < count; i++ {
+			u := b.upstreams[(start+i)%count]
+
+			if u.available(now) && (selected == nil ||
+				atomic.LoadInt64(&u.active) < atomic.LoadInt64(&selected.active)) {
+				selected = u
+			}
+		}
+
+		if selected != nil {
+			return selected, nil
+		}
+	case ConsistentHash:
+		hash := hashKey(b.hashKeyOf(req))
+
+		// The request belongs to the first available upstream clockwise
+		// from the key's point on the ring
+		index := sort.Search(len(b.ring), func(i int) bool {
+			return b.ring[i].hash >= hash
+		})
+
+		for i := 0; i < len(b.ring); i++ {
+			point := b.ring[(index+i)%len(b.ring)]
+
+			if point.upstream.available(now) {
+				return point.upstream, nil
+			}
+		}
+	default:
+		// The turns are taken by the available upstreams only, so an
+		// unavailable upstream does not double the turns of its neighbor
+		var available []*upstream
+		for _, u := range b.upstreams {
+			if u.available(now) {
+				available = append(available, u)
+			}
+		}
+
+		if len(available) > 0 {
+			turn := atomic.AddUint64(&b.next, 1)
+
+			return available[turn%uint64(len(available))], nil
+		}
+	}
+
+	return nil, ErrNoHealthyUpstream
+}
+
+// hashKeyOf returns the key of a request for the ConsistentHash strategy
+func (b *Balancer) hashKeyOf(req *http.Request) string {
+	if b.options.HashHeader != "" {
+		if key := req.Header.Get(b.options.HashHeader); key != "" {
+			return key
+		}
+	}
+
+	if ip := remoteIP(req.RemoteAddr); ip != nil {
+		return ip.String()
+	}
+
+	return req.RemoteAddr
+}
+
+// report records the outcome of a request to an upstream, and ejects the
+// upstream after too many consecutive failures. The upstream is reinstated
+// once the ejection time passes.
+func (b *Balancer) report(u *upstream, failed bool) {
+	if b.options.MaxFailures <= 0 {
+		return
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if !failed {
+		u.failures = 0
+		return
+	}
+
+	u.failures++
+
+	if u.failures >= b.options.MaxFailures {
+		u.failures = 0
+		u.ejectedUntil = time.Now().Add(b.options.EjectionTime)
+
+		log.Print("[Proxy WARNING]: Ejected upstream " + u.url.Host + " for " +
+			b.options.EjectionTime.String() + " after " +
+			strconv.Itoa(b.options.MaxFailures) + " consecutive failures")
+	}
+}
+
+// startHealthChecks checks the health of the upstreams periodically with the
+// given client, until the balancer is closed
+func (b *Balancer) startHealthChecks(client http.Client) {
+	if b.options.HealthCheckPath == "" {
+		return
+	}
+
+	client.Timeout = b.options.HealthCheckTimeout
+
+	go func() {
+		ticker := time.NewTicker(b.options.HealthCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			var wg sync.WaitGroup
+
+			for _, u := range b.upstreams {
+				wg.Add(1)
+
+				go func(u *upstream) {
+					defer wg.Done()
+
+					b.checkHealth(&client, u)
+				}(u)
+			}
+
+			wg.Wait()
+
+			select {
+			case <-b.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// checkHealth requests the health check path from an upstream, and changes
+// the upstream's health once enough consecutive checks contradict it
+func (b *Balancer) checkHealth(client *http.Client, u *upstream) {
+	healthy := false
+
+	checkURL := *u.url
+	checkURL.Path = b.options.HealthCheckPath
+
+	res, err := client.Get(checkURL.String())
+	if err == nil {
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+
+		healthy = res.StatusCode >= 200 && res.StatusCode < 400
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if healthy == u.healthy {
+		u.checks = 0
+		return
+	}
+
+	u.checks++
+
+	threshold := b.options.UnhealthyThreshold
+	if healthy {
+		threshold = b.options.HealthyThreshold
+	}
+
+	if u.checks < threshold {
+		return
+	}
+
+	u.healthy = healthy
+	u.checks = 0
+
+	if healthy {
+		log.Print("[Proxy DEBUG]: Upstream " + u.url.Host + " is healthy again")
+	} else {
+		log.Print("[Proxy WARNING]: Upstream " + u.url.Host + " is unhealthy")
+	}
+}
+
+// releasingBody is the body of an upstream's response, which releases the
+// upstream's request when it is closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+// Close closes the body and releases the request
+func (rb *releasingBody) Close() error {
+	err := rb.ReadCloser.Close()
+
+	rb.once.Do(rb.release)
+
+	return err
+}
+
+// hashKey hashes a key to a point on the hash ring. Similar keys (like the
+// points of an upstream) should land far apart, so a cryptographic hash is
+// used.
+func hashKey(key string) uint32 {
+	hash := sha1.Sum([]byte(key))
+
+	return binary.BigEndian.Uint32(hash[:4])
+}
@@ -0,0 +1,290 @@
+package proxy_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apidome/gateway/internal/pkg/proxy"
+)
+
+// fakeUpstream is an instance of a target in the tests, whose health and
+// responses can be changed while it runs
+type fakeUpstream struct {
+	server *httptest.Server
+
+	// status is the status of the responses to requests, and healthStatus
+	// is the status of the responses to health checks
+	status       int64
+	healthStatus int64
+}
+
+// newFakeUpstreams starts upstreams that answer with their index in the
+// "X-Upstream" header
+func newFakeUpstreams(count int) []*fakeUpstream {
+	upstreams := make([]*fakeUpstream, count)
+
+	for index := range upstreams {
+		u := &fakeUpstream{status: http.StatusOK, healthStatus: http.StatusOK}
+		name := strconv.Itoa(index)
+
+		u.server = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/health" {
+				res.WriteHeader(int(atomic.LoadInt64(&u.healthStatus)))
+				return
+			}
+
+			res.Header().Set("X-Upstream", name)
+			res.WriteHeader(int(atomic.LoadInt64(&u.status)))
+		}))
+
+		upstreams[index] = u
+	}
+
+	return upstreams
+}
+
+// newBalancedProxy creates a proxy that spreads its requests between the
+// upstreams
+func newBalancedProxy(t *testing.T, upstreams []*fakeUpstream,
+	options proxy.BalancerOptions) (*proxy.Proxy, *proxy.Balancer) {
+	var urls []string
+	for _, u := range upstreams {
+		urls = append(urls, u.server.URL)
+	}
+
+	balancer, err := proxy.NewBalancer(urls, options)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create a balancer: %v", failed, err)
+	}
+
+	pr := proxy.NewProxy(urls[0])
+	pr.SetBalancer(balancer)
+
+	return pr, balancer
+}
+
+// sendTo sends a request through a proxy, and returns the index of the
+// upstream that answered it, or the error of the request. The response
+// body is left open if keepOpen is true.
+func sendTo(t *testing.T, pr *proxy.Proxy, header http.Header, keepOpen bool) (string, error) {
+	if header == nil {
+		header = http.Header{}
+	}
+
+	req, err := pr.CreateRequest(http.MethodGet, "/items", "", header, nil, 0)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create a request: %v", failed, err)
+	}
+
+	res, err := pr.SendRequest(req)
+	if err != nil {
+		return "", err
+	}
+
+	if !keepOpen {
+		res.Body.Close()
+	}
+
+	return res.Header.Get("X-Upstream"), nil
+}
+
+// sequence sends requests through a proxy and returns the indexes of the
+// upstreams that answered them
+func sequence(t *testing.T, pr *proxy.Proxy, count int) string {
+	var selected []string
+
+	for i := 0; i < count; i++ {
+		name, err := sendTo(t, pr, nil, false)
+		if err != nil {
+			name = "-"
+		}
+
+		selected = append(selected, name)
+	}
+
+	return strings.Join(selected, "")
+}
+
+// waitFor checks a condition until it holds or a second passes
+func waitFor(condition func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return condition()
+}
+
+func TestBalancer(t *testing.T) {
+	t.Log("Given the need to test the spreading of requests between upstreams")
+	{
+		upstreams := newFakeUpstreams(3)
+		defer func() {
+			for _, u := range upstreams {
+				u.server.Close()
+			}
+		}()
+
+		t.Logf("\tTest 0: When balancing requests in turns")
+		{
+			pr, balancer := newBalancedProxy(t, upstreams, proxy.BalancerOptions{})
+
+			selected := sequence(t, pr, 6)
+			if strings.Count(selected, "0") != 2 || strings.Count(selected, "1") != 2 ||
+				strings.Count(selected, "2") != 2 || selected[:3] != selected[3:] {
+				t.Errorf("\t%s\tShould send the requests to the upstreams in turns: got %s", failed, selected)
+			} else {
+				t.Logf("\t%s\tShould send the requests to the upstreams in turns", succeed)
+			}
+
+			balancer.Close()
+		}
+
+		t.Logf("\tTest 1: When balancing requests by their connections")
+		{
+			pr, balancer := newBalancedProxy(t, upstreams[:2], proxy.BalancerOptions{
+				Strategy: proxy.LeastConnections,
+			})
+
+			// The first response is kept open, so its upstream has a
+			// request in progress
+			busy, err := sendTo(t, pr, nil, true)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to send a request: %v", failed, err)
+			}
+
+			selected := sequence(t, pr, 4)
+			if strings.Contains(selected, busy) {
+				t.Errorf("\t%s\tShould avoid the upstream with a request in progress: got %s (busy %s)",
+					failed, selected, busy)
+			} else {
+				t.Logf("\t%s\tShould avoid the upstream with a request in progress", succeed)
+			}
+
+			balancer.Close()
+		}
+
+		t.Logf("\tTest 2: When balancing requests by a hash of their keys")
+		{
+			pr, balancer := newBalancedProxy(t, upstreams, proxy.BalancerOptions{
+				Strategy:   proxy.ConsistentHash,
+				HashHeader: "X-User",
+			})
+
+			consistent := true
+			spread := make(map[string]bool)
+
+			for user := 0; user < 30; user++ {
+				header := http.Header{"X-User": {"user-" + strconv.Itoa(user)}}
+
+				first, _ := sendTo(t, pr, header, false)
+				second, _ := sendTo(t, pr, header, false)
+
+				consistent = consistent && first != "" && first == second
+				spread[first] = true
+			}
+
+			if !consistent {
+				t.Errorf("\t%s\tShould send the requests of a key to the same upstream", failed)
+			} else {
+				t.Logf("\t%s\tShould send the requests of a key to the same upstream", succeed)
+			}
+
+			if len(spread) != len(upstreams) {
+				t.Errorf("\t%s\tShould spread the keys between all the upstreams: got %d", failed, len(spread))
+			} else {
+				t.Logf("\t%s\tShould spread the keys between all the upstreams", succeed)
+			}
+
+			balancer.Close()
+		}
+
+		t.Logf("\tTest 3: When an upstream keeps failing")
+		{
+			pr, balancer := newBalancedProxy(t, upstreams[:2], proxy.BalancerOptions{
+				MaxFailures:  2,
+				EjectionTime: 100 * time.Millisecond,
+			})
+
+			atomic.StoreInt64(&upstreams[0].status, http.StatusServiceUnavailable)
+
+			// Each upstream gets every other request, so the failing
+			// upstream fails twice in four requests
+			sequence(t, pr, 4)
+
+			atomic.StoreInt64(&upstreams[0].status, http.StatusOK)
+
+			selected := sequence(t, pr, 4)
+			if selected != "1111" {
+				t.Errorf("\t%s\tShould eject the failing upstream: got %s", failed, selected)
+			} else {
+				t.Logf("\t%s\tShould eject the failing upstream", succeed)
+			}
+
+			time.Sleep(100 * time.Millisecond)
+
+			selected = sequence(t, pr, 4)
+			if !strings.Contains(selected, "0") {
+				t.Errorf("\t%s\tShould reinstate the upstream after the ejection time: got %s", failed, selected)
+			} else {
+				t.Logf("\t%s\tShould reinstate the upstream after the ejection time", succeed)
+			}
+
+			balancer.Close()
+		}
+
+		t.Logf("\tTest 4: When the health checks of upstreams fail")
+		{
+			pr, balancer := newBalancedProxy(t, upstreams[:2], proxy.BalancerOptions{
+				HealthCheckPath:     "/health",
+				HealthCheckInterval: 10 * time.Millisecond,
+				HealthyThreshold:    1,
+				UnhealthyThreshold:  1,
+			})
+
+			atomic.StoreInt64(&upstreams[0].healthStatus, http.StatusInternalServerError)
+
+			if !waitFor(func() bool { return sequence(t, pr, 4) == "1111" }) {
+				t.Errorf("\t%s\tShould stop sending requests to an unhealthy upstream", failed)
+			} else {
+				t.Logf("\t%s\tShould stop sending requests to an unhealthy upstream", succeed)
+			}
+
+			atomic.StoreInt64(&upstreams[1].healthStatus, http.StatusInternalServerError)
+
+			var err error
+			if !waitFor(func() bool {
+				_, err = sendTo(t, pr, nil, false)
+				return err == proxy.ErrNoHealthyUpstream
+			}) || proxy.ErrorStatus(err) != http.StatusServiceUnavailable {
+				t.Errorf("\t%s\tShould answer 503 when all the upstreams are unhealthy: %v", failed, err)
+			} else {
+				t.Logf("\t%s\tShould answer 503 when all the upstreams are unhealthy", succeed)
+			}
+
+			atomic.StoreInt64(&upstreams[0].healthStatus, http.StatusOK)
+			atomic.StoreInt64(&upstreams[1].healthStatus, http.StatusOK)
+
+			if !waitFor(func() bool {
+				selected := sequence(t, pr, 4)
+				return strings.Contains(selected, "0") && strings.Contains(selected, "1")
+			}) {
+				t.Errorf("\t%s\tShould reinstate the upstreams once they are healthy", failed)
+			} else {
+				t.Logf("\t%s\tShould reinstate the upstreams once they are healthy", succeed)
+			}
+
+			balancer.Close()
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package proxy
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Defaults of the circuit breaker options
+const (
+	defaultOpenTime         = 30 * time.Second
+	defaultHalfOpenRequests = 1
+)
+
+// ErrCircuitOpen is returned when the circuit of an upstream is open, so
+// requests are not sent to it
+var ErrCircuitOpen = errors.New("Circuit open")
+
+// The states of a circuit breaker
+const (
+	// circuitClosed lets all the requests through
+	circuitClosed = iota
+
+	// circuitOpen rejects all the requests until its open time passes
+	circuitOpen
+
+	// circuitHalfOpen lets a limited number of requests through to find out
+	// whether the upstream recovered
+	circuitHalfOpen
+)
+
+// CircuitBreakerOptions determines when the circuit of an upstream opens and
+// closes. Settings with zero values keep their defaults.
+type CircuitBreakerOptions struct {
+	// FailureThreshold is the number of consecutive failed requests
+	// (requests that could not be sent, or that were answered with 502,
+	// 503 or 504) that open the circuit.
+	FailureThreshold int
+
+	// OpenTime is the time the circuit stays open before it lets requests
+	// through again.
+	OpenTime time.Duration
+
+	// HalfOpenRequests is the number of requests that are let through a
+	// half-open circuit, all of which should succeed to close the circuit.
+	HalfOpenRequests int
+}
+
+// circuitBreaker stops the requests to an upstream that keeps failing, so
+// the upstream can recover and the clients are answered right away.
+// A nil circuitBreaker lets all the requests through.
+type circuitBreaker struct {
+	name    string
+	options CircuitBreakerOptions
+
+	mutex    sync.Mutex
+	state    int
+	failures int
+	openedAt time.Time
+
+	// probes is the number of requests that were let through the half-open
+	// circuit, and successes is the number of them that succeeded
+	probes    int
+	successes int
+}
+
+// newCircuitBreaker creates a closed circuit breaker for the upstream with
+// the given name
+func newCircuitBreaker(name string, options CircuitBreakerOptions) *circuitBreaker {
+	if options.OpenTime == 0 {
+		options.OpenTime = defaultOpenTime
+	}
+
+	if options.HalfOpenRequests == 0 {
+		options.HalfOpenRequests = defaultHalfOpenRequests
+	}
+
+	return &circuitBreaker{
+		name:    name,
+		options: options,
+	}
+}
+
+// ready returns true if the circuit may let a request through, without
+// letting one through
+func (cb *circuitBreaker) ready(now time.Time) bool {
+	if cb == nil {
+		return true
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	switch cb.state {
+	case circuitOpen:
+		return !now.Before(cb.openedAt.Add(cb.options.OpenTime))
+	case circuitHalfOpen:
+		return cb.probes < cb.options.HalfOpenRequests
+	default:
+		return true
+	}
+}
+
+// allow lets a request through the circuit if it may pass, in which case the
+// outcome of the request should be reported
+func (cb *circuitBreaker) allow(now time.Time) bool {
+	if cb == nil {
+		return true
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	switch cb.state {
+	case circuitOpen:
+		if now.Before(cb.openedAt.Add(cb.options.OpenTime)) {
+			return false
+		}
+
+		cb.state = circuitHalfOpen
+		cb.probes = 1
+		cb.successes = 0
+
+		return true
+	case circuitHalfOpen:
+		if cb.probes >= cb.options.HalfOpenRequests {
+			return false
+		}
+
+		cb.probes++
+
+		return true
+	default:
+		return true
+	}
+}
+
+// report records the outcome of a request that was let through the circuit
+func (cb *circuitBreaker) report(failed bool) {
+	if cb == nil {
+		return
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	switch cb.state {
+	case circuitHalfOpen:
+		if failed {
+			cb.open()
+			return
+		}
+
+		cb.successes++
+
+		if cb.successes >= cb.options.HalfOpenRequests {
+			cb.state = circuitClosed
+			cb.failures = 0
+
+			log.Print("[Proxy DEBUG]: Closed the circuit of upstream " + cb.name)
+		}
+	case circuitClosed:
+		if !failed {
+			cb.failures = 0
+			return
+		}
+
+		cb.failures++
+
+		if cb.failures >= cb.options.FailureThreshold {
+			cb.open()
+		}
+	}
+}
+
+// cancel gives back the place of a request that was let through the circuit
+// and whose outcome is unknown
+func (cb *circuitBreaker) cancel() {
+	if cb == nil {
+		return
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if cb.state == circuitHalfOpen && cb.probes > 0 {
+		cb.probes--
+	}
+}
+
+// open opens the circuit. The mutex should be locked.
+func (cb *circuitBreaker) open() {
+	cb.state = circuitOpen
+	cb.openedAt = time.Now()
+	cb.failures = 0
+
+	log.Print("[Proxy WARNING]: Opened the circuit of upstream " + cb.name + " for " +
+		cb.options.OpenTime.String() + " after " +
+		strconv.Itoa(cb.options.FailureThreshold) + " consecutive failures")
+}
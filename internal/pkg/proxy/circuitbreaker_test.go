@@ -0,0 +1,86 @@
+package proxy_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apidome/gateway/internal/pkg/proxy"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	const openTime = 100 * time.Millisecond
+
+	steps := []struct {
+		description string
+		wait        time.Duration
+		status      int
+		open        bool
+	}{
+		{"a failed request below the threshold", 0, http.StatusServiceUnavailable, false},
+		{"a successful request that resets the failures", 0, http.StatusOK, false},
+		{"a failed request after the reset", 0, http.StatusBadGateway, false},
+		{"a failed request that reaches the threshold", 0, http.StatusGatewayTimeout, false},
+		{"a request to the open circuit", 0, http.StatusOK, true},
+		{"a failing probe of the half-open circuit", openTime, http.StatusServiceUnavailable, false},
+		{"a request after the failed probe", 0, http.StatusOK, true},
+		{"a successful probe of the half-open circuit", openTime, http.StatusOK, false},
+		{"a failed request to the closed circuit", 0, http.StatusServiceUnavailable, false},
+		{"a request after a single failure", 0, http.StatusOK, false},
+	}
+
+	t.Log("Given the need to test the circuit breaker of a target")
+	{
+		var status, hits int64
+
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			atomic.AddInt64(&hits, 1)
+			res.WriteHeader(int(atomic.LoadInt64(&status)))
+		}))
+		defer server.Close()
+
+		pr := proxy.NewProxy(server.URL)
+		pr.SetCircuitBreaker(proxy.CircuitBreakerOptions{
+			FailureThreshold: 2,
+			OpenTime:         openTime,
+		})
+
+		for index, step := range steps {
+			t.Logf("\tTest %d: When sending %s", index, step.description)
+			{
+				time.Sleep(step.wait)
+
+				atomic.StoreInt64(&status, int64(step.status))
+				hitsBefore := atomic.LoadInt64(&hits)
+
+				req, err := pr.CreateRequest(http.MethodGet, "/", "", http.Header{}, nil, 0)
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to create a request: %v", failed, err)
+				}
+
+				res, err := pr.SendRequest(req)
+				if res != nil {
+					res.Body.Close()
+				}
+
+				if step.open {
+					if err != proxy.ErrCircuitOpen || atomic.LoadInt64(&hits) != hitsBefore {
+						t.Errorf("\t%s\tShould be rejected without reaching the target: %v", failed, err)
+					} else {
+						t.Logf("\t%s\tShould be rejected without reaching the target", succeed)
+					}
+
+					continue
+				}
+
+				if err != nil || res.StatusCode != step.status {
+					t.Errorf("\t%s\tShould reach the target and get %d: %v", failed, step.status, err)
+				} else {
+					t.Logf("\t%s\tShould reach the target and get %d", succeed, step.status)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package proxy
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	// ErrNoClientCertificate is returned when a request that requires a
+	// client certificate has no verified client certificate
+	ErrNoClientCertificate = errors.New("A client certificate is required")
+
+	// ErrClientNotAllowed is returned when the client certificate of a
+	// request does not match the allowlists
+	ErrClientNotAllowed = errors.New("The client certificate is not allowed")
+)
+
+// ClientIdentityHeaders holds the names of the headers that tell the target
+// who the client is. Headers with empty names are not set.
+type ClientIdentityHeaders struct {
+	// Subject is the header of the distinguished name of the certificate's
+	// subject
+	Subject string
+
+	// SANs is the header of the subject alternative names of the
+	// certificate, separated by commas
+	SANs string
+
+	// Certificate is the header of the URL encoded PEM of the certificate
+	Certificate string
+}
+
+// ClientAuth authorizes requests by their verified client certificates and
+// tells the target who the client is.
+// The subject alternative names of a certificate are written with the
+// prefix of their type: "DNS:", "email:", "URI:" or "IP:".
+type ClientAuth struct {
+	subjects map[string]bool
+	sans     map[string]bool
+	headers  ClientIdentityHeaders
+}
+
+// NewClientAuth creates a ClientAuth that allows the certificates whose
+// subject (its distinguished name or common name) is in subjects, or one of
+// whose subject alternative names is in sans. If both allowlists are empty,
+// any verified certificate is allowed.
+func NewClientAuth(subjects, sans []string, headers ClientIdentityHeaders) *ClientAuth {
+	ca := &ClientAuth{
+		subjects: make(map[string]bool, len(subjects)),
+		sans:     make(map[string]bool, len(sans)),
+		headers:  headers,
+	}
+
+	for _, subject := range subjects {
+		ca.subjects[subject] = true
+	}
+
+	for _, san := range sans {
+		ca.sans[san] = true
+	}
+
+	return ca
+}
+
+// Authorize returns an error if a request does not have an allowed client
+// certificate
+func (ca *ClientAuth) Authorize(req *http.Request) error {
+	certificate := clientCertificate(req)
+	if certificate == nil {
+		return ErrNoClientCertificate
+	}
+
+	if len(ca.subjects) == 0 && len(ca.sans) == 0 {
+		return nil
+	}
+
+	if ca.subjects[certificate.Subject.String()] || ca.subjects[certificate.Subject.CommonName] {
+		return nil
+	}
+
+	for _, san := range subjectAltNames(certificate) {
+		if ca.sans[san] {
+			return nil
+		}
+	}
+
+	return ErrClientNotAllowed
+}
+
+// SetHeaders sets the identity headers of the target request that was
+// created from a client request. The identity headers that the client sent
+// are removed, so clients cannot spoof their identity.
+func (ca *ClientAuth) SetHeaders(req *http.Request, targetReq *http.Request) {
+	for _, header := range []string{
+		ca.headers.Subject,
+		ca.headers.SANs,
+		ca.headers.Certificate,
+	} {
+		if header != "" {
+			targetReq.Header.Del(header)
+		}
+	}
+
+	certificate := clientCertificate(req)
+	if certificate == nil {
+		return
+	}
+
+	if ca.headers.Subject != "" {
+		targetReq.Header.Set(ca.headers.Subject, certificate.Subject.String())
+	}
+
+	if sans := subjectAltNames(certificate); ca.headers.SANs != "" && len(sans) > 0 {
+		targetReq.Header.Set(ca.headers.SANs, strings.Join(sans, ", "))
+	}
+
+	if ca.headers.Certificate != "" {
+		block := pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: certificate.Raw,
+		})
+
+		targetReq.Header.Set(ca.headers.Certificate, url.QueryEscape(string(block)))
+	}
+}
+
+// clientCertificate returns the verified client certificate of a request, or
+// nil if the client did not present a certificate
+func clientCertificate(req *http.Request) *x509.Certificate {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 ||
+		len(req.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+
+	return req.TLS.VerifiedChains[0][0]
+}
+
+// subjectAltNames returns the subject alternative names of a certificate,
+// prefixed by their types
+func subjectAltNames(certificate *x509.Certificate) []string {
+	var sans []string
+
+	for _, name := range certificate.DNSNames {
+		sans = append(sans, "DNS:"+name)
+	}
+
+	for _, email := range certificate.EmailAddresses {
+		sans = append(sans, "email:"+email)
+	}
+
+	for _, uri := range certificate.URIs {
+		sans = append(sans, "URI:"+uri.String())
+	}
+
+	for _, ip := range certificate.IPAddresses {
+		sans = append(sans, "IP:"+ip.String())
+	}
+
+	return sans
+}
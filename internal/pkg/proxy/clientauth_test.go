@@ -0,0 +1,185 @@
+package proxy_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apidome/gateway/internal/pkg/proxy"
+)
+
+// newCertificate creates a certificate for the subject's common name and
+// DNS name, signed by the parent (or self-signed if parent is nil)
+func newCertificate(t *testing.T, commonName string, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create a key: %v", failed, err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+
+	signer, signerKey := template, interface{}(key)
+
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		template.DNSNames = []string{commonName + ".internal"}
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+
+	raw, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create a certificate: %v", failed, err)
+	}
+
+	leaf, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to parse a certificate: %v", failed, err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{raw}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestClientAuth(t *testing.T) {
+	headers := proxy.ClientIdentityHeaders{
+		Subject:     "X-Client-Subject",
+		SANs:        "X-Client-SANs",
+		Certificate: "X-Client-Cert",
+	}
+
+	testCases := []struct {
+		description string
+		certificate string
+		status      int
+		subject     string
+		sans        string
+	}{
+		{"a request without a certificate", "", http.StatusForbidden, "", ""},
+		{"a request with a certificate that is not allowed", "reports", http.StatusForbidden, "", ""},
+		{"a request with an allowed subject", "billing", http.StatusOK, "CN=billing", "DNS:billing.internal"},
+		{"a request with an allowed alternative name", "audit", http.StatusOK, "CN=audit", "DNS:audit.internal"},
+	}
+
+	t.Log("Given the need to test the authorization of clients by their certificates")
+	{
+		ca := newCertificate(t, "Test CA", nil)
+
+		clientCAs := x509.NewCertPool()
+		clientCAs.AddCert(ca.Leaf)
+
+		clientAuth := proxy.NewClientAuth([]string{"billing"}, []string{"DNS:audit.internal"}, headers)
+
+		// The server authorizes the requests like the gateway, and answers
+		// with the identity headers of the request it would send to the
+		// target
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			err := clientAuth.Authorize(req)
+			if err != nil {
+				http.Error(res, err.Error(), http.StatusForbidden)
+				return
+			}
+
+			targetReq, _ := http.NewRequest(req.Method, "http://target"+req.URL.Path, nil)
+			targetReq.Header = req.Header.Clone()
+
+			clientAuth.SetHeaders(req, targetReq)
+
+			for _, header := range []string{headers.Subject, headers.SANs, headers.Certificate} {
+				for _, value := range targetReq.Header.Values(header) {
+					res.Header().Add("Target-"+header, value)
+				}
+			}
+		}))
+		server.TLS = &tls.Config{
+			ClientAuth: tls.VerifyClientCertIfGiven,
+			ClientCAs:  clientCAs,
+		}
+		server.StartTLS()
+		defer server.Close()
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When sending %s", index, testCase.description)
+			{
+				client := server.Client()
+				transport := client.Transport.(*http.Transport).Clone()
+
+				if testCase.certificate != "" {
+					transport.TLSClientConfig.Certificates = []tls.Certificate{
+						newCertificate(t, testCase.certificate, &ca),
+					}
+				}
+
+				client.Transport = transport
+
+				req, _ := http.NewRequest(http.MethodGet, server.URL+"/invoices", nil)
+
+				// The client pretends to be someone else
+				req.Header.Set(headers.Subject, "CN=admin")
+				req.Header.Set(headers.SANs, "DNS:admin.internal")
+				req.Header.Set(headers.Certificate, "forged")
+
+				res, err := client.Do(req)
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to send the request: %v", failed, err)
+				}
+				res.Body.Close()
+
+				if res.StatusCode != testCase.status {
+					t.Errorf("\t%s\tShould get the status %d: got %d", failed, testCase.status, res.StatusCode)
+				} else {
+					t.Logf("\t%s\tShould get the status %d", succeed, testCase.status)
+				}
+
+				if testCase.status != http.StatusOK {
+					continue
+				}
+
+				subject := res.Header.Values("Target-" + headers.Subject)
+				sans := res.Header.Values("Target-" + headers.SANs)
+				certificate := res.Header.Get("Target-" + headers.Certificate)
+
+				if len(subject) != 1 || subject[0] != testCase.subject ||
+					len(sans) != 1 || sans[0] != testCase.sans ||
+					certificate == "" || certificate == "forged" {
+					t.Errorf("\t%s\tShould replace the identity headers of the client: got %v, %v, %s",
+						failed, subject, sans, certificate)
+				} else {
+					t.Logf("\t%s\tShould replace the identity headers of the client", succeed)
+				}
+			}
+		}
+
+		t.Logf("\tTest %d: When setting the identity headers of a request without a certificate", len(testCases))
+		{
+			req := httptest.NewRequest(http.MethodGet, "/invoices", nil)
+			req.Header.Set(headers.Subject, "CN=admin")
+
+			targetReq := httptest.NewRequest(http.MethodGet, "/invoices", nil)
+			targetReq.Header = req.Header.Clone()
+
+			clientAuth.SetHeaders(req, targetReq)
+
+			if len(targetReq.Header.Values(headers.Subject)) > 0 {
+				t.Errorf("\t%s\tShould remove the identity headers that the client sent", failed)
+			} else {
+				t.Logf("\t%s\tShould remove the identity headers that the client sent", succeed)
+			}
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Forwarding sets the headers that tell the target about the client of a
+// request: the X-Forwarded-For, X-Forwarded-Proto and X-Forwarded-Host
+// headers and the RFC 7239 Forwarded header.
+// The forwarding headers that a trusted proxy sent are extended, and the
+// forwarding headers of other clients are replaced, so clients cannot
+// spoof their address.
+type Forwarding struct {
+	xForwarded     bool
+	forwarded      bool
+	trustedProxies []*net.IPNet
+}
+
+// NewForwarding creates a Forwarding that sets the X-Forwarded-* headers
+// and/or the Forwarded header, and trusts the forwarding headers of the
+// proxies with the given IP addresses or CIDR ranges
+func NewForwarding(xForwarded, forwarded bool,
+	trustedProxies []string) (*Forwarding, error) {
+	f := &Forwarding{
+		xForwarded: xForwarded,
+		forwarded:  forwarded,
+	}
+
+	for _, trustedProxy := range trustedProxies {
+		// A single address is a range of one address
+		if !strings.Contains(trustedProxy, "/") {
+			ip := net.ParseIP(trustedProxy)
+			if ip == nil {
+				return nil, errors.New("invalid trusted proxy address \"" + trustedProxy + "\"")
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+
+			f.trustedProxies = append(f.trustedProxies,
+				&net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(trustedProxy)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid trusted proxy range \""+trustedProxy+"\"")
+		}
+
+		f.trustedProxies = append(f.trustedProxies, ipNet)
+	}
+
+	return f, nil
+}
+
+// SetHeaders sets the forwarding headers of the target request that was
+// created from a client request
+func (f *Forwarding) SetHeaders(req *http.Request, targetReq *http.Request) {
+	clientIP := remoteIP(req.RemoteAddr)
+	trusted := f.isTrusted(clientIP)
+
+	proto := "http"
+	if req.TLS != nil {
+		proto = "https"
+	}
+
+	if f.xForwarded {
+		if !trusted {
+			targetReq.Header.Del("X-Forwarded-For")
+			targetReq.Header.Del("X-Forwarded-Proto")
+			targetReq.Header.Del("X-Forwarded-Host")
+		}
+
+		forwardedFor := clientIP.String()
+		if clientIP == nil {
+			forwardedFor = "unknown"
+		}
+
+		// The client is the last proxy in the chain of the addresses
+		if prior := targetReq.Header["X-Forwarded-For"]; len(prior) > 0 {
+			forwardedFor = strings.Join(prior, ", ") + ", " + forwardedFor
+		}
+
+		targetReq.Header.Set("X-Forwarded-For", forwardedFor)
+
+		// A trusted proxy knows the original protocol and host better
+		if targetReq.Header.Get("X-Forwarded-Proto") == "" {
+			targetReq.Header.Set("X-Forwarded-Proto", proto)
+		}
+
+		if targetReq.Header.Get("X-Forwarded-Host") == "" {
+			targetReq.Header.Set("X-Forwarded-Host", req.Host)
+		}
+	}
+
+	if f.forwarded {
+		if !trusted {
+			targetReq.Header.Del("Forwarded")
+		}
+
+		element := "for=" + forwardedNode(clientIP)
+
+		if req.Host != "" {
+			element += ";host=" + forwardedValue(req.Host)
+		}
+
+		element += ";proto=" + proto
+
+		if prior := targetReq.Header["Forwarded"]; len(prior) > 0 {
+			element = strings.Join(prior, ", ") + ", " + element
+		}
+
+		targetReq.Header.Set("Forwarded", element)
+	}
+}
+
+// isTrusted returns true if an address belongs to a trusted proxy
+func (f *Forwarding) isTrusted(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, ipNet := range f.trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// remoteIP returns the IP address of a request's remote address, or nil if
+// the address is not an IP address
+func remoteIP(remoteAddr string) net.IP {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	return net.ParseIP(host)
+}
+
+// forwardedNode formats an IP address as a node of the Forwarded header, in
+// which IPv6 addresses are enclosed in brackets and quotes
+func forwardedNode(ip net.IP) string {
+	if ip == nil {
+		return "unknown"
+	}
+
+	if ip.To4() == nil {
+		return "\"[" + ip.String() + "]\""
+	}
+
+	return ip.String()
+}
+
+// forwardedValue formats a value of the Forwarded header as a token, or as a
+// quoted string if it contains characters that a token may not contain
+func forwardedValue(value string) string {
+	for _, char := range value {
+		if !isTokenChar(char) {
+			return "\"" + strings.NewReplacer("\\", "\\\\", "\"", "\\\"").Replace(value) + "\""
+		}
+	}
+
+	return value
+}
+
+// isTokenChar returns true if a character may appear in a token (RFC 7230
+// section 3.2.6)
+func isTokenChar(char rune) bool {
+	switch {
+	case char >= 'a' && char <= 'z', char >= 'A' && char <= 'Z', char >= '0' && char <= '9':
+		return true
+	default:
+		return strings.ContainsRune("!#$%&'*+-.^_`|~", char)
+	}
+}
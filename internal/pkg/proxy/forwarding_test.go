@@ -0,0 +1,170 @@
+package proxy_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apidome/gateway/internal/pkg/proxy"
+)
+
+const succeed = "V"
+const failed = "X"
+
+func TestForwarding(t *testing.T) {
+	testCases := []struct {
+		description string
+		remoteAddr  string
+		https       bool
+		headers     http.Header
+		expected    map[string]string
+	}{
+		{
+			"a client that spoofs the forwarding headers",
+			"203.0.113.7:5000",
+			false,
+			http.Header{
+				"X-Forwarded-For":   {"1.2.3.4"},
+				"X-Forwarded-Proto": {"https"},
+				"X-Forwarded-Host":  {"evil.example.com"},
+				"Forwarded":         {"for=1.2.3.4"},
+			},
+			map[string]string{
+				"X-Forwarded-For":   "203.0.113.7",
+				"X-Forwarded-Proto": "http",
+				"X-Forwarded-Host":  "api.example.com",
+				"Forwarded":         "for=203.0.113.7;host=api.example.com;proto=http",
+			},
+		},
+		{
+			"a trusted proxy that forwards a client",
+			"10.1.2.3:5000",
+			false,
+			http.Header{
+				"X-Forwarded-For":   {"198.51.100.1"},
+				"X-Forwarded-Proto": {"https"},
+				"X-Forwarded-Host":  {"shop.example.com"},
+				"Forwarded":         {"for=198.51.100.1"},
+			},
+			map[string]string{
+				"X-Forwarded-For":   "198.51.100.1, 10.1.2.3",
+				"X-Forwarded-Proto": "https",
+				"X-Forwarded-Host":  "shop.example.com",
+				"Forwarded":         "for=198.51.100.1, for=10.1.2.3;host=api.example.com;proto=http",
+			},
+		},
+		{
+			"a trusted proxy with a chain in several header lines",
+			"10.1.2.3:5000",
+			false,
+			http.Header{"X-Forwarded-For": {"198.51.100.1, 198.51.100.2", "172.16.0.1"}},
+			map[string]string{
+				"X-Forwarded-For": "198.51.100.1, 198.51.100.2, 172.16.0.1, 10.1.2.3",
+			},
+		},
+		{
+			"a trusted IPv6 proxy over https",
+			"[2001:db8::1]:443",
+			true,
+			http.Header{},
+			map[string]string{
+				"X-Forwarded-For":   "2001:db8::1",
+				"X-Forwarded-Proto": "https",
+				"Forwarded":         `for="[2001:db8::1]";host=api.example.com;proto=https`,
+			},
+		},
+	}
+
+	t.Log("Given the need to test the forwarding headers of target requests")
+	{
+		_, err := proxy.NewForwarding(true, true, []string{"10.0.0.300"})
+		if err == nil {
+			t.Errorf("\t%s\tShould not accept an invalid trusted proxy", failed)
+		} else {
+			t.Logf("\t%s\tShould not accept an invalid trusted proxy", succeed)
+		}
+
+		forwarding, err := proxy.NewForwarding(true, true, []string{"10.0.0.0/8", "2001:db8::1"})
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a Forwarding: %v", failed, err)
+		}
+
+		pr := proxy.NewProxy("http://target")
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When forwarding %s", index, testCase.description)
+			{
+				req := httptest.NewRequest(http.MethodGet, "http://api.example.com/items", nil)
+				req.RemoteAddr = testCase.remoteAddr
+				req.Header = testCase.headers
+
+				if testCase.https {
+					req.TLS = &tls.ConnectionState{}
+				}
+
+				targetReq, err := pr.CreateRequest(req.Method, req.URL.Path, "", req.Header, nil, 0)
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to create a target request: %v", failed, err)
+				}
+
+				forwarding.SetHeaders(req, targetReq)
+
+				for header, expected := range testCase.expected {
+					actual := strings.Join(targetReq.Header.Values(header), ", ")
+					if actual != expected {
+						t.Errorf("\t%s\tShould set %s to \"%s\": got \"%s\"", failed, header, expected, actual)
+					} else {
+						t.Logf("\t%s\tShould set %s to \"%s\"", succeed, header, expected)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestHopByHopHeaders(t *testing.T) {
+	testCases := []struct {
+		header   string
+		expected string
+	}{
+		{"Connection", ""},
+		{"Foo", ""},
+		{"Keep-Alive", ""},
+		{"Upgrade", ""},
+		{"Proxy-Authorization", ""},
+		{"Te", "trailers"},
+		{"Bar", "kept"},
+	}
+
+	t.Log("Given the need to test the removal of hop-by-hop headers from target requests")
+	{
+		headers := http.Header{
+			"Connection":          {"foo, Keep-Alive"},
+			"Foo":                 {"removed"},
+			"Keep-Alive":          {"timeout=5"},
+			"Upgrade":             {"h2c"},
+			"Proxy-Authorization": {"Basic secret"},
+			"Te":                  {"trailers, deflate"},
+			"Bar":                 {"kept"},
+		}
+
+		targetReq, err := proxy.NewProxy("http://target").CreateRequest(http.MethodGet, "/items", "", headers, nil, 0)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a target request: %v", failed, err)
+		}
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When given the header %s", index, testCase.header)
+			{
+				actual := strings.Join(targetReq.Header.Values(testCase.header), ", ")
+				if actual != testCase.expected {
+					t.Errorf("\t%s\tShould set the header to \"%s\": got \"%s\"", failed, testCase.expected, actual)
+				} else {
+					t.Logf("\t%s\tShould set the header to \"%s\"", succeed, testCase.expected)
+				}
+			}
+		}
+	}
+}
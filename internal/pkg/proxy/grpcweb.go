@@ -0,0 +1,214 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/apidome/gateway/internal/pkg/httputils"
+	"github.com/pkg/errors"
+)
+
+// Content types of gRPC-Web requests. The text variant encodes the body in
+// base64, for clients that cannot send binary bodies.
+const (
+	grpcWebContentType     = "application/grpc-web"
+	grpcWebTextContentType = "application/grpc-web-text"
+)
+
+// grpcWebTrailersFlag marks the frame that holds the trailers at the end of
+// a gRPC-Web response body
+const grpcWebTrailersFlag = 0x80
+
+// IsGRPCWeb returns true if a request is a gRPC-Web request
+func IsGRPCWeb(req *http.Request) bool {
+	return strings.HasPrefix(req.Header.Get("Content-Type"), grpcWebContentType)
+}
+
+// isGRPCWebText returns true if a gRPC-Web content type is of the text
+// variant
+func isGRPCWebText(contentType string) bool {
+	return strings.HasPrefix(contentType, grpcWebTextContentType)
+}
+
+// DecodeGRPCWebText decodes the body of a gRPC-Web request of the text
+// variant as it is read, so that it holds the same messages as the body of
+// a gRPC request
+func DecodeGRPCWebText(req *http.Request) {
+	if !isGRPCWebText(req.Header.Get("Content-Type")) {
+		return
+	}
+
+	req.Body = &base64Body{body: req.Body}
+	req.ContentLength = -1
+}
+
+// TranslateGRPCWebRequest turns a target request that was created from a
+// gRPC-Web request with the given content type into a gRPC request
+func TranslateGRPCWebRequest(targetReq *http.Request, contentType string) {
+	// "application/grpc-web-text+proto" becomes "application/grpc+proto"
+	format := strings.TrimPrefix(contentType, grpcWebTextContentType)
+	if len(format) == len(contentType) {
+		format = strings.TrimPrefix(contentType, grpcWebContentType)
+	}
+
+	targetReq.Header.Set("Content-Type", "application/grpc"+format)
+	targetReq.Header.Set("Te", "trailers")
+}
+
+// StreamGRPCWebResponse sends the gRPC response of the target to a gRPC-Web
+// client whose request had the given content type. The messages are
+// streamed to the client as they arrive, and the trailers of the response
+// are sent in a frame of their own at the end of the body, since browsers
+// cannot read trailers.
+func StreamGRPCWebResponse(res http.ResponseWriter,
+	targetRes *http.Response, contentType string) error {
+	defer targetRes.Body.Close()
+
+	httputils.CopyEndToEndHeaders(targetRes.Header, res.Header())
+
+	res.Header().Set("Content-Type", contentType)
+	res.Header().Del("Content-Length")
+
+	res.WriteHeader(targetRes.StatusCode)
+
+	var writer io.Writer = res
+	if isGRPCWebText(contentType) {
+		writer = base64Writer{res}
+	}
+
+	flusher, _ := res.(http.Flusher)
+	buffer := make([]byte, streamBufferSize)
+
+	for {
+		n, readErr := targetRes.Body.Read(buffer)
+
+		if n > 0 {
+			_, err := writer.Write(buffer[:n])
+			if err != nil {
+				return err
+			}
+
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+
+		if readErr != nil {
+			return readErr
+		}
+	}
+
+	// A response without messages may hold its status in its headers
+	if targetRes.Header.Get("Grpc-Status") != "" {
+		return nil
+	}
+
+	_, err := writer.Write(grpcWebTrailers(targetRes.Trailer))
+
+	return err
+}
+
+// grpcWebTrailers encodes the trailers of a gRPC response in a gRPC-Web
+// trailers frame
+func grpcWebTrailers(trailer http.Header) []byte {
+	keys := make([]string, 0, len(trailer))
+	for key := range trailer {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var trailers bytes.Buffer
+	for _, key := range keys {
+		for _, value := range trailer[key] {
+			trailers.WriteString(strings.ToLower(key) + ": " + value + "\r\n")
+		}
+	}
+
+	frame := make([]byte, 5, 5+trailers.Len())
+	frame[0] = grpcWebTrailersFlag
+	binary.BigEndian.PutUint32(frame[1:], uint32(trailers.Len()))
+
+	return append(frame, trailers.Bytes()...)
+}
+
+// base64Body decodes a body that is encoded in base64 as it is read. The
+// body may be made of several encoded parts, each with its own padding.
+type base64Body struct {
+	body    io.ReadCloser
+	buffer  []byte
+	encoded []byte
+	decoded []byte
+	err     error
+}
+
+func (b *base64Body) Read(p []byte) (int, error) {
+	if b.buffer == nil {
+		b.buffer = make([]byte, streamBufferSize)
+	}
+
+	for len(b.decoded) == 0 {
+		if b.err != nil {
+			return 0, b.err
+		}
+
+		n, err := b.body.Read(b.buffer)
+		b.encoded = append(b.encoded, b.buffer[:n]...)
+
+		// Every group of 4 characters is decoded on its own, since padding
+		// may end any of them
+		groups := len(b.encoded) / 4 * 4
+		for i := 0; i < groups && b.err == nil; i += 4 {
+			var group [3]byte
+
+			size, decodeErr := base64.StdEncoding.Decode(group[:], b.encoded[i:i+4])
+			if decodeErr != nil {
+				b.err = errors.Wrap(decodeErr, "invalid gRPC-Web text body")
+			}
+
+			b.decoded = append(b.decoded, group[:size]...)
+		}
+
+		b.encoded = append(b.encoded[:0], b.encoded[groups:]...)
+
+		if err == io.EOF && len(b.encoded) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+
+		if err != nil && b.err == nil {
+			b.err = err
+		}
+	}
+
+	n := copy(p, b.decoded)
+	b.decoded = b.decoded[n:]
+
+	return n, nil
+}
+
+func (b *base64Body) Close() error {
+	return b.body.Close()
+}
+
+// base64Writer encodes everything that is written to it in base64
+type base64Writer struct {
+	writer io.Writer
+}
+
+func (w base64Writer) Write(p []byte) (int, error) {
+	_, err := w.writer.Write([]byte(base64.StdEncoding.EncodeToString(p)))
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,18 @@
+//go:build go1.24
+// +build go1.24
+
+package proxy
+
+import "net/http"
+
+// enableH2C makes a transport speak HTTP/2 with cleartext targets (h2c with
+// prior knowledge) and with TLS targets, instead of HTTP/1.1
+func enableH2C(transport *http.Transport) error {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+
+	transport.Protocols = protocols
+
+	return nil
+}
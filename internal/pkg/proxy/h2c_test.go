@@ -0,0 +1,76 @@
+//go:build go1.24
+// +build go1.24
+
+package proxy_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apidome/gateway/internal/pkg/proxy"
+)
+
+func TestHTTP2Transport(t *testing.T) {
+	handler := http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+	})
+
+	// The cleartext target speaks HTTP/2 with prior knowledge only
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+
+	cleartextTarget := httptest.NewUnstartedServer(handler)
+	cleartextTarget.Config.Protocols = protocols
+	cleartextTarget.Start()
+	defer cleartextTarget.Close()
+
+	tlsTarget := httptest.NewUnstartedServer(handler)
+	tlsTarget.EnableHTTP2 = true
+	tlsTarget.StartTLS()
+	defer tlsTarget.Close()
+
+	testCases := []struct {
+		description string
+		url         string
+		options     proxy.TransportOptions
+		proto       string
+	}{
+		{"a TLS target without HTTP/2", tlsTarget.URL,
+			proxy.TransportOptions{InsecureSkipVerify: true}, "HTTP/1.1"},
+		{"a TLS target with HTTP/2", tlsTarget.URL,
+			proxy.TransportOptions{InsecureSkipVerify: true, HTTP2: true}, "HTTP/2.0"},
+		{"a TLS target with h2c", tlsTarget.URL,
+			proxy.TransportOptions{InsecureSkipVerify: true, H2C: true}, "HTTP/2.0"},
+		{"a cleartext target with h2c", cleartextTarget.URL,
+			proxy.TransportOptions{H2C: true}, "HTTP/2.0"},
+	}
+
+	t.Log("Given the need to test the protocols that a proxy speaks with its target")
+	{
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When sending a request to %s", index, testCase.description)
+			{
+				pr := proxy.NewProxy(testCase.url)
+
+				err := pr.SetTransport(testCase.options)
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to set the transport: %v", failed, err)
+				}
+
+				res, err := pr.Client.Get(testCase.url)
+				if err != nil {
+					t.Errorf("\t%s\tShould be able to send a request: %v", failed, err)
+					continue
+				}
+				res.Body.Close()
+
+				if res.Proto != testCase.proto {
+					t.Errorf("\t%s\tShould speak %s: got %s", failed, testCase.proto, res.Proto)
+				} else {
+					t.Logf("\t%s\tShould speak %s", succeed, testCase.proto)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !go1.24
+// +build !go1.24
+
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// enableH2C makes a transport speak HTTP/2 with cleartext targets. The http
+// package supports cleartext HTTP/2 since Go 1.24, so gateways that are
+// built with older versions return an error.
+func enableH2C(transport *http.Transport) error {
+	return errors.New("h2c requires a gateway that is built with Go 1.24 or later")
+}
@@ -0,0 +1,25 @@
+//go:build !go1.24
+// +build !go1.24
+
+package proxy_test
+
+import (
+	"testing"
+
+	"github.com/apidome/gateway/internal/pkg/proxy"
+)
+
+func TestHTTP2Transport(t *testing.T) {
+	t.Log("Given the need to test the protocols that a proxy speaks with its target")
+	{
+		t.Log("\tTest 0: When the gateway is built with a Go version that does not support h2c")
+		{
+			err := proxy.NewProxy("http://localhost:8080").SetTransport(proxy.TransportOptions{H2C: true})
+			if err == nil {
+				t.Errorf("\t%s\tShould not be able to speak h2c with the target", failed)
+			} else {
+				t.Logf("\t%s\tShould not be able to speak h2c with the target: %v", succeed, err)
+			}
+		}
+	}
+}
@@ -1,16 +1,22 @@
 package proxy
 
 import (
-	"bytes"
+	"context"
 	"errors"
 	"io"
 	"net"
 	"net/http"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/apidome/gateway/internal/pkg/httputils"
 )
 
+// streamBufferSize is the size of the chunks in which response bodies are
+// streamed to the client
+const streamBufferSize = 32 * 1024
+
 var (
 	// ErrHijackingNotOk is returned when hijacking a request is not supported
 	ErrHijackingNotOk = errors.New("Hijacking not supported")
@@ -20,12 +26,31 @@ var (
 type Proxy struct {
 	target string
 	Client http.Client
+
+	// upstream represents the target itself, if the proxy has no balancer
+	upstream *upstream
+
+	// balancer spreads the requests between the instances of the target,
+	// if the target has several instances
+	balancer *Balancer
+
+	// breakerOptions are the options of the circuit breakers of the
+	// upstreams, if they have circuit breakers
+	breakerOptions *CircuitBreakerOptions
+
+	// retries retries the failed requests, if they should be retried
+	retries *retryPolicy
+
+	// upgradeClient sends the requests whose connections are upgraded, if
+	// the proxy has a transport of its own
+	upgradeClient *http.Client
 }
 
 // InitProxy initializes a Proxy instance
 func InitProxy(pr *Proxy, target string) {
 	pr.Client = http.Client{}
 	pr.target = target
+	pr.upstream = &upstream{healthy: true}
 }
 
 // NewProxy creates a new proxy
@@ -37,50 +62,220 @@ func NewProxy(target string) *Proxy {
 	return pr
 }
 
-// CreateRequest creates a new request
+// SetBalancer spreads the requests of the proxy between the upstreams of the
+// balancer instead of sending them to the proxy's target, and starts the
+// balancer's health checks. It should be called after SetTransport, since
+// the health checks connect to the upstreams like the requests do.
+func (pr *Proxy) SetBalancer(balancer *Balancer) {
+	pr.balancer = balancer
+
+	if pr.breakerOptions != nil {
+		for _, u := range balancer.upstreams {
+			u.breaker = newCircuitBreaker(u.url.Host, *pr.breakerOptions)
+		}
+	}
+
+	balancer.startHealthChecks(pr.Client)
+}
+
+// SetCircuitBreaker gives each upstream of the proxy (or the target itself)
+// a circuit breaker, which stops the requests to the upstream while it keeps
+// failing
+func (pr *Proxy) SetCircuitBreaker(options CircuitBreakerOptions) {
+	pr.breakerOptions = &options
+
+	pr.upstream.breaker = newCircuitBreaker(pr.target, options)
+
+	if pr.balancer != nil {
+		for _, u := range pr.balancer.upstreams {
+			u.breaker = newCircuitBreaker(u.url.Host, options)
+		}
+	}
+}
+
+// SetRetries retries the failed requests of the proxy according to the
+// options
+func (pr *Proxy) SetRetries(options RetryOptions) {
+	pr.retries = newRetryPolicy(options)
+}
+
+// CreateRequest creates a new request.
+// The body is streamed to the target as it is read, and contentLength is its
+// length in bytes, or -1 if the length is unknown.
 func (pr *Proxy) CreateRequest(method,
 	path,
 	rawQuery string,
 	headers http.Header,
-	body []byte) (*http.Request, error) {
-
-	bodyReader := bytes.NewReader(body)
+	body io.Reader,
+	contentLength int64) (*http.Request, error) {
 
 	req, err := http.NewRequest(method,
 		pr.target,
-		bodyReader)
+		body)
 
 	if err != nil {
 		return nil, err
 	}
 
+	req.ContentLength = contentLength
+
+	// An empty body is not sent at all
+	if contentLength == 0 {
+		req.Body = http.NoBody
+		req.GetBody = nil
+	}
+
 	req.URL.Path = path
 	req.URL.RawQuery = rawQuery
 
-	httputils.CopyHeaders(headers, req.Header)
+	httputils.CopyEndToEndHeaders(headers, req.Header)
+
+	// "TE: trailers" is the only value of the hop-by-hop TE header that
+	// does not depend on the connection, and some protocols require it
+	if hasToken(headers, "Te", "trailers") {
+		req.Header.Set("Te", "trailers")
+	}
 
 	return req, nil
 }
 
 // SendRequest forwards the target request to the target
-// and returnes the response
+// and returnes the response.
+// If the proxy has a balancer, the request is sent to the upstream that the
+// balancer selects, and if the request fails and should be retried, it is
+// sent again after a backoff.
 func (pr *Proxy) SendRequest(req *http.Request) (*http.Response, error) {
+	if pr.retries == nil {
+		return pr.sendAttempt(&pr.Client, req)
+	}
+
+	pr.retries.budget.recordRequest()
+
+	retryable := pr.retries.retryable(req)
+
+	for retry := 1; ; retry++ {
+		res, err := pr.sendAttempt(&pr.Client, req)
+
+		if !retryable || retry > pr.retries.options.Attempts ||
+			!pr.retries.shouldRetry(res, err) || !pr.retries.budget.allowRetry() {
+			return res, err
+		}
+
+		// The response of a failed attempt is discarded
+		if res != nil {
+			res.Body.Close()
+		}
+
+		if req.GetBody != nil {
+			req.Body, err = req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(pr.retries.backoff(retry)):
+		}
+	}
+}
+
+// sendAttempt sends a request once with the given client, to the proxy's
+// target or to the upstream that the balancer selects
+func (pr *Proxy) sendAttempt(client *http.Client, req *http.Request) (*http.Response, error) {
+	selected := pr.upstream
+
+	if pr.balancer != nil {
+		var err error
+
+		selected, err = pr.balancer.selectUpstream(req)
+		if err != nil {
+			return nil, err
+		}
+
+		req.URL.Scheme = selected.url.Scheme
+		req.URL.Host = selected.url.Host
+		req.Host = selected.url.Host
+	}
+
+	if !selected.breaker.allow(time.Now()) {
+		return nil, ErrCircuitOpen
+	}
+
+	// The request is in progress until its response body is closed
+	atomic.AddInt64(&selected.active, 1)
+	release := func() {
+		atomic.AddInt64(&selected.active, -1)
+	}
+
 	// Send the target request
-	res, err := pr.Client.Do(req)
+	res, err := client.Do(req)
+
+	// A request that the client canceled says nothing about the upstream
+	if isCanceled(err) {
+		release()
+		selected.breaker.cancel()
+
+		return nil, err
+	}
+
+	failed := err != nil || res.StatusCode == http.StatusBadGateway ||
+		res.StatusCode == http.StatusServiceUnavailable ||
+		res.StatusCode == http.StatusGatewayTimeout
+
+	selected.breaker.report(failed)
+
+	if pr.balancer != nil {
+		pr.balancer.report(selected, failed)
+	}
 
 	if err != nil {
+		release()
+
 		return nil, err
 	}
 
+	// The body of an upgraded connection is the connection itself, which
+	// is written to as well
+	if conn, ok := res.Body.(io.ReadWriteCloser); ok &&
+		res.StatusCode == http.StatusSwitchingProtocols {
+		res.Body = &releasingConn{ReadWriteCloser: conn, release: release}
+	} else {
+		res.Body = &releasingBody{ReadCloser: res.Body, release: release}
+	}
+
 	return res, nil
 }
 
+// ErrorStatus returns the status of the response to a client whose request
+// could not be sent to the target: 503 if no upstream is available, 504 if
+// the target did not answer in time, and 502 otherwise
+func ErrorStatus(err error) int {
+	if errors.Is(err, ErrNoHealthyUpstream) || errors.Is(err, ErrCircuitOpen) {
+		return http.StatusServiceUnavailable
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) ||
+		(errors.As(err, &netErr) && netErr.Timeout()) {
+		return http.StatusGatewayTimeout
+	}
+
+	return http.StatusBadGateway
+}
+
+// isCanceled returns true if a request failed because it was canceled
+func isCanceled(err error) bool {
+	return err != nil && errors.Is(err, context.Canceled)
+}
+
 // CopyResponseToClient sends the target response to the client
 func CopyResponseToClient(res http.ResponseWriter,
 	targetRes *http.Response,
 	body []byte) error {
 
-	httputils.CopyHeaders(targetRes.Header, res.Header())
+	httputils.CopyEndToEndHeaders(targetRes.Header, res.Header())
 
 	res.WriteHeader(targetRes.StatusCode)
 
@@ -94,43 +289,84 @@ func CopyResponseToClient(res http.ResponseWriter,
 		}
 	}
 
+	// The body was read before the response was validated, so its
+	// trailers (like "grpc-status") are already known
+	copyTrailers(targetRes, res)
+
 	return nil
 }
 
-// TunnelConnection tunnels a connection to the target server
-func TunnelConnection(res http.ResponseWriter,
-	req *http.Request,
-	target string) error {
-	destConn, err := net.DialTimeout("tcp", target, 10*time.Second)
+// StreamResponseToClient sends the target response to the client as its body
+// arrives, and flushes every chunk of the body to the client right away so
+// server-sent events and long downloads are not held back
+func StreamResponseToClient(res http.ResponseWriter,
+	targetRes *http.Response) error {
+	defer targetRes.Body.Close()
 
-	if err != nil {
-		return err
-	}
+	httputils.CopyEndToEndHeaders(targetRes.Header, res.Header())
 
-	res.WriteHeader(http.StatusOK)
+	res.WriteHeader(targetRes.StatusCode)
 
-	hijacker, ok := res.(http.Hijacker)
+	flusher, _ := res.(http.Flusher)
+	buffer := make([]byte, streamBufferSize)
 
-	if !ok {
-		return ErrHijackingNotOk
-	}
+	for {
+		n, readErr := targetRes.Body.Read(buffer)
 
-	clientCon, _, err := hijacker.Hijack()
+		if n > 0 {
+			_, err := res.Write(buffer[:n])
 
-	if err != nil {
-		return err
+			// If the response status code does not support body it will
+			// not be written and can be ignored
+			if err == http.ErrBodyNotAllowed {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+
+		if readErr != nil {
+			return readErr
+		}
 	}
 
-	go connectPipes(destConn, clientCon)
-	go connectPipes(clientCon, destConn)
+	// The trailers of the target response are known only after its body
+	// was read
+	copyTrailers(targetRes, res)
 
 	return nil
 }
 
-// connectPipes copies data from a socket to another
-func connectPipes(dst io.WriteCloser, src io.ReadCloser) {
-	defer dst.Close()
-	defer src.Close()
+// copyTrailers sends the trailers of the target response to the client. It
+// should be called after the body of the target response was read.
+func copyTrailers(targetRes *http.Response, res http.ResponseWriter) {
+	for key, values := range targetRes.Trailer {
+		for _, value := range values {
+			res.Header().Add(http.TrailerPrefix+key, value)
+		}
+	}
+}
+
+// hasToken returns true if one of the comma separated values of a header is
+// the given token
+func hasToken(header http.Header, name, token string) bool {
+	for _, value := range header[name] {
+		for _, headerToken := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(headerToken), token) {
+				return true
+			}
+		}
+	}
 
-	io.Copy(dst, src)
+	return false
 }
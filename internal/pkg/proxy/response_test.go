@@ -0,0 +1,124 @@
+package proxy_test
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apidome/gateway/internal/pkg/proxy"
+)
+
+func TestCopyResponseToClient(t *testing.T) {
+	t.Log("Given the need to test sending a buffered target response to the client")
+	{
+		targetRes := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/grpc"}},
+			Trailer:    http.Header{"Grpc-Status": {"0"}},
+		}
+
+		res := httptest.NewRecorder()
+
+		err := proxy.CopyResponseToClient(res, targetRes, []byte("message"))
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to send the response: %v", failed, err)
+		}
+		t.Logf("\t%s\tShould be able to send the response", succeed)
+
+		result := res.Result()
+
+		if result.StatusCode != http.StatusOK || res.Body.String() != "message" ||
+			result.Header.Get("Content-Type") != "application/grpc" {
+			t.Errorf("\t%s\tShould send the status, headers and body of the response", failed)
+		} else {
+			t.Logf("\t%s\tShould send the status, headers and body of the response", succeed)
+		}
+
+		if result.Trailer.Get("Grpc-Status") != "0" {
+			t.Errorf("\t%s\tShould send the trailers of the response: got %v", failed, result.Trailer)
+		} else {
+			t.Logf("\t%s\tShould send the trailers of the response", succeed)
+		}
+	}
+}
+
+func TestStreamResponseToClient(t *testing.T) {
+	t.Log("Given the need to test streaming a target response to the client")
+	{
+		release := make(chan struct{})
+
+		target := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			res.WriteHeader(http.StatusOK)
+			res.Write([]byte("first"))
+			res.(http.Flusher).Flush()
+
+			// The rest of the body is sent only after the client got
+			// the first chunk
+			<-release
+
+			res.Write([]byte("second"))
+			res.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+		}))
+		defer target.Close()
+
+		gateway := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			targetRes, err := http.Get(target.URL)
+			if err != nil {
+				res.WriteHeader(http.StatusBadGateway)
+				return
+			}
+
+			proxy.StreamResponseToClient(res, targetRes)
+		}))
+		defer gateway.Close()
+
+		res, err := http.Get(gateway.URL)
+		if err != nil {
+			close(release)
+			t.Fatalf("\t%s\tShould be able to get the response: %v", failed, err)
+		}
+		defer res.Body.Close()
+
+		t.Log("\tTest 0: When the target sends a part of the body")
+		{
+			chunk := make(chan string)
+			go func() {
+				buffer := make([]byte, len("first"))
+				n, _ := io.ReadFull(res.Body, buffer)
+				chunk <- string(buffer[:n])
+			}()
+
+			select {
+			case first := <-chunk:
+				if first != "first" {
+					t.Errorf("\t%s\tShould send the part to the client right away: got %s", failed, first)
+				} else {
+					t.Logf("\t%s\tShould send the part to the client right away", succeed)
+				}
+			case <-time.After(5 * time.Second):
+				t.Errorf("\t%s\tShould send the part to the client right away", failed)
+			}
+
+			close(release)
+		}
+
+		t.Log("\tTest 1: When the target sends the rest of the body and its trailers")
+		{
+			rest, err := ioutil.ReadAll(res.Body)
+			if err != nil || string(rest) != "second" {
+				t.Errorf("\t%s\tShould send the rest of the body: got %s, %v", failed, rest, err)
+			} else {
+				t.Logf("\t%s\tShould send the rest of the body", succeed)
+			}
+
+			if res.Trailer.Get("Grpc-Status") != "0" {
+				t.Errorf("\t%s\tShould send the trailers of the response: got %v", failed, res.Trailer)
+			} else {
+				t.Logf("\t%s\tShould send the trailers of the response", succeed)
+			}
+		}
+	}
+}
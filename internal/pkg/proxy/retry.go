@@ -0,0 +1,184 @@
+package proxy
+
+import (
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Defaults of the retry options
+const (
+	defaultBackoff          = 100 * time.Millisecond
+	defaultMaxBackoff       = 2 * time.Second
+	defaultBudgetRatio      = 0.2
+	defaultBudgetMinRetries = 10
+)
+
+// budgetWindow is the period over which the retry budget compares the
+// retries to the requests, in seconds
+const budgetWindow = 10
+
+// RetryOptions determines which requests are retried and when.
+// Only requests with idempotent methods, whose body can be sent again, are
+// retried. Settings with zero values keep their defaults.
+type RetryOptions struct {
+	// Attempts is the number of times a failed request is retried.
+	Attempts int
+
+	// Backoff is the time before the first retry, which doubles with every
+	// retry up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// RetryOn holds the statuses of the responses that are retried, in
+	// addition to the requests that could not be sent.
+	RetryOn []int
+
+	// BudgetRatio is the maximum ratio of retries to requests, which keeps
+	// the retries from overloading a failing target. BudgetMinRetries
+	// retries are allowed regardless of the ratio.
+	BudgetRatio      float64
+	BudgetMinRetries int
+}
+
+// retryPolicy retries the requests to a target
+type retryPolicy struct {
+	options RetryOptions
+	retryOn map[int]bool
+	budget  *retryBudget
+}
+
+// newRetryPolicy creates a retry policy according to the options
+func newRetryPolicy(options RetryOptions) *retryPolicy {
+	if options.Backoff == 0 {
+		options.Backoff = defaultBackoff
+	}
+
+	if options.MaxBackoff == 0 {
+		options.MaxBackoff = defaultMaxBackoff
+	}
+
+	if options.BudgetRatio == 0 {
+		options.BudgetRatio = defaultBudgetRatio
+	}
+
+	if options.BudgetMinRetries == 0 {
+		options.BudgetMinRetries = defaultBudgetMinRetries
+	}
+
+	retryOn := make(map[int]bool, len(options.RetryOn))
+	for _, status := range options.RetryOn {
+		retryOn[status] = true
+	}
+
+	return &retryPolicy{
+		options: options,
+		retryOn: retryOn,
+		budget: &retryBudget{
+			ratio:      options.BudgetRatio,
+			minRetries: options.BudgetMinRetries,
+		},
+	}
+}
+
+// retryable returns true if a request may be sent more than once
+func (rp *retryPolicy) retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+
+	// A streamed body cannot be sent again
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// shouldRetry returns true if the outcome of an attempt should be retried
+func (rp *retryPolicy) shouldRetry(res *http.Response, err error) bool {
+	if err != nil {
+		// The client is gone, or there is nothing to retry on
+		return err != ErrNoHealthyUpstream && err != ErrCircuitOpen &&
+			!isCanceled(err)
+	}
+
+	return rp.retryOn[res.StatusCode]
+}
+
+// backoff returns the time to wait before a retry. Half of it is random, so
+// the retries of concurrent requests are spread.
+func (rp *retryPolicy) backoff(retry int) time.Duration {
+	backoff := rp.options.Backoff
+	for i := 1; i < retry && backoff < rp.options.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > rp.options.MaxBackoff {
+		backoff = rp.options.MaxBackoff
+	}
+
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// retryBudget limits the number of retries relative to the number of
+// requests over the last budgetWindow seconds
+type retryBudget struct {
+	ratio      float64
+	minRetries int
+
+	mutex   sync.Mutex
+	buckets [budgetWindow]budgetBucket
+}
+
+// budgetBucket counts the requests and the retries of a single second
+type budgetBucket struct {
+	second   int64
+	requests int
+	retries  int
+}
+
+// bucket returns the bucket of the current second. The mutex should be
+// locked.
+func (rb *retryBudget) bucket(now time.Time) *budgetBucket {
+	second := now.Unix()
+	bucket := &rb.buckets[second%budgetWindow]
+
+	if bucket.second != second {
+		*bucket = budgetBucket{second: second}
+	}
+
+	return bucket
+}
+
+// recordRequest counts a request
+func (rb *retryBudget) recordRequest() {
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+
+	rb.bucket(time.Now()).requests++
+}
+
+// allowRetry counts a retry and returns true if the budget allows it
+func (rb *retryBudget) allowRetry() bool {
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+
+	now := time.Now()
+
+	requests, retries := 0, 0
+	for _, bucket := range rb.buckets {
+		if now.Unix()-bucket.second < budgetWindow {
+			requests += bucket.requests
+			retries += bucket.retries
+		}
+	}
+
+	if retries >= rb.minRetries && float64(retries) >= rb.ratio*float64(requests) {
+		return false
+	}
+
+	rb.bucket(now).retries++
+
+	return true
+}
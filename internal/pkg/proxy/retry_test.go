@@ -0,0 +1,215 @@
+package proxy_test
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apidome/gateway/internal/pkg/proxy"
+)
+
+func TestRetries(t *testing.T) {
+	testCases := []struct {
+		description string
+		method      string
+		body        func() (io.Reader, int64)
+		options     proxy.RetryOptions
+		statuses    []int
+		requests    int
+		attempts    int
+		status      int
+		minDuration time.Duration
+	}{
+		{
+			"a GET that succeeds after failures",
+			http.MethodGet,
+			nil,
+			proxy.RetryOptions{Attempts: 3, RetryOn: []int{503}},
+			[]int{503, 503, 200},
+			1,
+			3,
+			200,
+			0,
+		},
+		{
+			"a GET that keeps failing",
+			http.MethodGet,
+			nil,
+			proxy.RetryOptions{Attempts: 2, RetryOn: []int{503}},
+			[]int{503},
+			1,
+			3,
+			503,
+			0,
+		},
+		{
+			"a GET whose status is not retried",
+			http.MethodGet,
+			nil,
+			proxy.RetryOptions{Attempts: 2, RetryOn: []int{503}},
+			[]int{500, 200},
+			1,
+			1,
+			500,
+			0,
+		},
+		{
+			"a POST, which is not idempotent",
+			http.MethodPost,
+			func() (io.Reader, int64) { return strings.NewReader("payload"), 7 },
+			proxy.RetryOptions{Attempts: 2, RetryOn: []int{503}},
+			[]int{503, 200},
+			1,
+			1,
+			503,
+			0,
+		},
+		{
+			"a PUT with a body that can be sent again",
+			http.MethodPut,
+			func() (io.Reader, int64) { return strings.NewReader("payload"), 7 },
+			proxy.RetryOptions{Attempts: 2, RetryOn: []int{503}},
+			[]int{503, 200},
+			1,
+			2,
+			200,
+			0,
+		},
+		{
+			"a PUT with a streamed body",
+			http.MethodPut,
+			func() (io.Reader, int64) { return io.MultiReader(strings.NewReader("payload")), -1 },
+			proxy.RetryOptions{Attempts: 2, RetryOn: []int{503}},
+			[]int{503, 200},
+			1,
+			1,
+			503,
+			0,
+		},
+		{
+			"GETs that exhaust the retry budget",
+			http.MethodGet,
+			nil,
+			proxy.RetryOptions{Attempts: 5, RetryOn: []int{503}, BudgetRatio: 0.01, BudgetMinRetries: 2},
+			[]int{503},
+			2,
+			4,
+			503,
+			0,
+		},
+		{
+			"a GET whose retries back off",
+			http.MethodGet,
+			nil,
+			proxy.RetryOptions{Attempts: 2, RetryOn: []int{503}, Backoff: 20 * time.Millisecond,
+				MaxBackoff: 30 * time.Millisecond},
+			[]int{503},
+			1,
+			3,
+			503,
+			25 * time.Millisecond,
+		},
+	}
+
+	t.Log("Given the need to test the retries of failed requests")
+	{
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When sending %s", index, testCase.description)
+			{
+				var mutex sync.Mutex
+				var bodies []string
+
+				server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+					body, _ := ioutil.ReadAll(req.Body)
+
+					mutex.Lock()
+					bodies = append(bodies, string(body))
+					attempt := len(bodies)
+					mutex.Unlock()
+
+					if attempt > len(testCase.statuses) {
+						attempt = len(testCase.statuses)
+					}
+
+					res.WriteHeader(testCase.statuses[attempt-1])
+				}))
+
+				options := testCase.options
+				if options.Backoff == 0 {
+					options.Backoff = time.Millisecond
+					options.MaxBackoff = time.Millisecond
+				}
+
+				pr := proxy.NewProxy(server.URL)
+				pr.SetRetries(options)
+
+				start := time.Now()
+
+				var res *http.Response
+				var err error
+
+				for request := 0; request < testCase.requests; request++ {
+					var body io.Reader
+					var length int64
+
+					if testCase.body != nil {
+						body, length = testCase.body()
+					}
+
+					var req *http.Request
+
+					req, err = pr.CreateRequest(testCase.method, "/items", "", http.Header{}, body, length)
+					if err != nil {
+						t.Fatalf("\t%s\tShould be able to create a request: %v", failed, err)
+					}
+
+					res, err = pr.SendRequest(req)
+					if err == nil {
+						res.Body.Close()
+					}
+				}
+
+				elapsed := time.Since(start)
+				server.Close()
+
+				if err != nil || res.StatusCode != testCase.status {
+					t.Errorf("\t%s\tShould get the status %d: %v", failed, testCase.status, err)
+				} else {
+					t.Logf("\t%s\tShould get the status %d", succeed, testCase.status)
+				}
+
+				if len(bodies) != testCase.attempts {
+					t.Errorf("\t%s\tShould send %d attempts: got %d", failed, testCase.attempts, len(bodies))
+				} else {
+					t.Logf("\t%s\tShould send %d attempts", succeed, testCase.attempts)
+				}
+
+				if testCase.body != nil {
+					expected, _ := ioutil.ReadAll(mustReader(testCase.body))
+
+					for _, body := range bodies {
+						if body != string(expected) {
+							t.Errorf("\t%s\tShould send the body with every attempt: got \"%s\"", failed, body)
+						}
+					}
+				}
+
+				if elapsed < testCase.minDuration {
+					t.Errorf("\t%s\tShould back off for at least %v: took %v", failed, testCase.minDuration, elapsed)
+				}
+			}
+		}
+	}
+}
+
+// mustReader returns the reader of a test case's body
+func mustReader(body func() (io.Reader, int64)) io.Reader {
+	reader, _ := body()
+
+	return reader
+}
@@ -0,0 +1,166 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Defaults of the transport settings, which are the defaults of the http
+// package's default transport
+const (
+	defaultDialTimeout         = 30 * time.Second
+	defaultKeepAlive           = 30 * time.Second
+	defaultTLSHandshakeTimeout = 10 * time.Second
+	defaultIdleConnTimeout     = 90 * time.Second
+	defaultMaxIdleConns        = 100
+)
+
+// TransportOptions determines how a Proxy connects to its target.
+// Settings with zero values keep their defaults.
+type TransportOptions struct {
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	IdleConnTimeout       time.Duration
+
+	// Timeout limits the time of a whole exchange with the target,
+	// including the reading of the response body.
+	Timeout time.Duration
+
+	MaxIdleConns int
+	MaxConns     int
+
+	// CAFile is a bundle of the certificate authorities that the target's
+	// certificate is verified against, instead of the system's ones.
+	CAFile string
+
+	// CertificateFile and KeyFile are the client certificate that is
+	// presented to the target.
+	CertificateFile string
+	KeyFile         string
+
+	// ServerName overrides the name that is sent in the TLS handshake and
+	// expected in the target's certificate.
+	ServerName string
+
+	InsecureSkipVerify bool
+
+	// HTTP2 lets the proxy speak HTTP/2 with targets that negotiate it in
+	// the TLS handshake. Cleartext targets are spoken to with HTTP/1.1.
+	HTTP2 bool
+
+	// H2C makes the proxy speak HTTP/2 with cleartext targets too (h2c with
+	// prior knowledge), which gRPC targets require. Such targets cannot be
+	// spoken to with HTTP/1.1.
+	H2C bool
+}
+
+// SetTransport replaces the client of the proxy with a client that
+// connects to the target according to the options
+func (pr *Proxy) SetTransport(options TransportOptions) error {
+	tlsConfig, err := options.tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	transport := options.transport(tlsConfig)
+	transport.ForceAttemptHTTP2 = options.HTTP2
+
+	if options.H2C {
+		err = enableH2C(transport)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Connections cannot be upgraded over HTTP/2, so upgrades have a
+	// transport of their own that speaks HTTP/1.1 only
+	pr.upgradeClient = &http.Client{
+		Transport: options.transport(tlsConfig.Clone()),
+	}
+
+	pr.Client = http.Client{
+		Transport: transport,
+		Timeout:   options.Timeout,
+	}
+
+	return nil
+}
+
+// transport creates a transport that connects to the target according to
+// the options
+func (options TransportOptions) transport(tlsConfig *tls.Config) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   withDefault(options.DialTimeout, defaultDialTimeout),
+		KeepAlive: defaultKeepAlive,
+	}
+
+	maxIdleConns := options.MaxIdleConns
+	if maxIdleConns == 0 {
+		maxIdleConns = defaultMaxIdleConns
+	}
+
+	// All the connections of the proxy are connections to the same target
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   withDefault(options.TLSHandshakeTimeout, defaultTLSHandshakeTimeout),
+		ResponseHeaderTimeout: options.ResponseHeaderTimeout,
+		IdleConnTimeout:       withDefault(options.IdleConnTimeout, defaultIdleConnTimeout),
+		ExpectContinueTimeout: 1 * time.Second,
+		MaxIdleConns:          maxIdleConns,
+		MaxIdleConnsPerHost:   maxIdleConns,
+		MaxConnsPerHost:       options.MaxConns,
+	}
+}
+
+// tlsConfig creates the TLS configuration of the connections to the target
+func (options TransportOptions) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         options.ServerName,
+		InsecureSkipVerify: options.InsecureSkipVerify,
+	}
+
+	if options.CAFile != "" {
+		bundle, err := ioutil.ReadFile(options.CAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read CA bundle")
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(bundle) {
+			return nil, errors.New("no certificates were found in CA bundle " + options.CAFile)
+		}
+	}
+
+	if (options.CertificateFile == "") != (options.KeyFile == "") {
+		return nil, errors.New("a client certificate requires both a certificate and a key")
+	}
+
+	if options.CertificateFile != "" {
+		certificate, err := tls.LoadX509KeyPair(options.CertificateFile, options.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not load client certificate")
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// withDefault returns a duration, or a default duration if it is zero
+func withDefault(duration, defaultDuration time.Duration) time.Duration {
+	if duration == 0 {
+		return defaultDuration
+	}
+
+	return duration
+}
@@ -0,0 +1,165 @@
+package proxy_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apidome/gateway/internal/pkg/proxy"
+)
+
+// writePEM writes a PEM block to a file in the directory and returns the
+// file's path
+func writePEM(t *testing.T, directory, name, blockType string, bytes []byte) string {
+	path := filepath.Join(directory, name)
+
+	err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: bytes}), 0600)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to write %s: %v", failed, name, err)
+	}
+
+	return path
+}
+
+func TestSetTransport(t *testing.T) {
+	t.Log("Given the need to test the connections of a proxy to its target")
+	{
+		directory, err := ioutil.TempDir("", "transport")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a directory: %v", failed, err)
+		}
+		defer os.RemoveAll(directory)
+
+		ca := newCertificate(t, "Test CA", nil)
+		client := newCertificate(t, "gateway", &ca)
+
+		clientKey, err := x509.MarshalECPrivateKey(client.PrivateKey.(*ecdsa.PrivateKey))
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to encode a key: %v", failed, err)
+		}
+
+		clientCAs := x509.NewCertPool()
+		clientCAs.AddCert(ca.Leaf)
+
+		// The target requires client certificates and answers with the
+		// name of the client
+		target := httptest.NewUnstartedServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/slow" {
+				time.Sleep(500 * time.Millisecond)
+			}
+
+			res.Write([]byte(req.TLS.PeerCertificates[0].Subject.CommonName))
+		}))
+		target.TLS = &tls.Config{
+			ClientAuth: tls.RequireAndVerifyClientCert,
+			ClientCAs:  clientCAs,
+		}
+		target.StartTLS()
+		defer target.Close()
+
+		caFile := writePEM(t, directory, "ca.pem", "CERTIFICATE", target.Certificate().Raw)
+		certificateFile := writePEM(t, directory, "client.pem", "CERTIFICATE", client.Certificate[0])
+		keyFile := writePEM(t, directory, "client-key.pem", "EC PRIVATE KEY", clientKey)
+		emptyFile := writePEM(t, directory, "empty.pem", "NOTHING", []byte("nothing"))
+
+		t.Log("\tTest 0: When setting invalid transport options")
+		{
+			for description, options := range map[string]proxy.TransportOptions{
+				"a CA bundle that does not exist":    {CAFile: filepath.Join(directory, "missing.pem")},
+				"a CA bundle without certificates":   {CAFile: emptyFile},
+				"a client certificate without a key": {CertificateFile: certificateFile},
+				"a key without a client certificate": {KeyFile: keyFile},
+				"an invalid client certificate":      {CertificateFile: emptyFile, KeyFile: keyFile},
+			} {
+				err = proxy.NewProxy(target.URL).SetTransport(options)
+				if err == nil {
+					t.Errorf("\t%s\tShould not be able to set %s", failed, description)
+				} else {
+					t.Logf("\t%s\tShould not be able to set %s: %v", succeed, description, err)
+				}
+			}
+		}
+
+		testCases := []struct {
+			description string
+			options     proxy.TransportOptions
+			path        string
+			succeeds    bool
+		}{
+			{"without the CA of the target", proxy.TransportOptions{
+				CertificateFile: certificateFile, KeyFile: keyFile,
+			}, "/", false},
+			{"without a client certificate", proxy.TransportOptions{
+				CAFile: caFile,
+			}, "/", false},
+			{"with the CA of the target and a client certificate", proxy.TransportOptions{
+				CAFile: caFile, CertificateFile: certificateFile, KeyFile: keyFile,
+			}, "/", true},
+			{"with a server name that the certificate of the target has", proxy.TransportOptions{
+				CAFile: caFile, CertificateFile: certificateFile, KeyFile: keyFile,
+				ServerName: "example.com",
+			}, "/", true},
+			{"with a server name that the certificate of the target does not have", proxy.TransportOptions{
+				CAFile: caFile, CertificateFile: certificateFile, KeyFile: keyFile,
+				ServerName: "other.internal",
+			}, "/", false},
+			{"without verifying the target", proxy.TransportOptions{
+				InsecureSkipVerify: true, CertificateFile: certificateFile, KeyFile: keyFile,
+			}, "/", true},
+			{"with a timeout that the target exceeds", proxy.TransportOptions{
+				CAFile: caFile, CertificateFile: certificateFile, KeyFile: keyFile,
+				Timeout: 50 * time.Millisecond,
+			}, "/slow", false},
+			{"with a response header timeout that the target exceeds", proxy.TransportOptions{
+				CAFile: caFile, CertificateFile: certificateFile, KeyFile: keyFile,
+				ResponseHeaderTimeout: 50 * time.Millisecond,
+			}, "/slow", false},
+		}
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When connecting to the target %s", index+1, testCase.description)
+			{
+				pr := proxy.NewProxy(target.URL)
+
+				err = pr.SetTransport(testCase.options)
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to set the transport: %v", failed, err)
+				}
+
+				res, err := pr.Client.Get(target.URL + testCase.path)
+				if !testCase.succeeds {
+					if err == nil {
+						res.Body.Close()
+						t.Errorf("\t%s\tShould not be able to send a request", failed)
+					} else {
+						t.Logf("\t%s\tShould not be able to send a request: %v", succeed, err)
+					}
+
+					continue
+				}
+
+				if err != nil {
+					t.Errorf("\t%s\tShould be able to send a request: %v", failed, err)
+					continue
+				}
+
+				body, _ := ioutil.ReadAll(res.Body)
+				res.Body.Close()
+
+				if string(body) != "gateway" {
+					t.Errorf("\t%s\tShould present the client certificate: got %s", failed, body)
+				} else {
+					t.Logf("\t%s\tShould present the client certificate", succeed)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/apidome/gateway/internal/pkg/httputils"
+)
+
+// ErrNotUpgraded is returned when a connection is tunneled for a response
+// that did not switch protocols
+var ErrNotUpgraded = errors.New("The target did not switch protocols")
+
+// IsUpgrade returns true if a client asks to switch the protocol of its
+// connection (for example to WebSocket)
+func IsUpgrade(req *http.Request) bool {
+	return req.Header.Get("Upgrade") != "" && hasToken(req.Header, "Connection", "upgrade")
+}
+
+// SendUpgrade forwards a target request that was created from an Upgrade
+// request to the target and returns the response. If the target switches
+// protocols, the body of the response is the upgraded connection.
+// Upgraded connections are not limited by the timeout of the proxy's
+// transport, and their requests are not retried.
+// If the messages of the connection are filtered, no WebSocket extension is
+// negotiated, so the messages are not compressed.
+func (pr *Proxy) SendUpgrade(req *http.Request, upgrade string, filter *MessageFilter) (*http.Response, error) {
+	// The upgrade headers are hop-by-hop headers, which were not copied
+	// from the client's request
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", upgrade)
+
+	if filter != nil {
+		req.Header.Del("Sec-Websocket-Extensions")
+	}
+
+	if pr.upgradeClient != nil {
+		return pr.sendAttempt(pr.upgradeClient, req)
+	}
+
+	client := pr.Client
+	client.Timeout = 0
+
+	return pr.sendAttempt(&client, req)
+}
+
+// TunnelConnection takes over the connection of a client whose request was
+// upgraded by the target, sends it the target's response and relays the
+// data between the client and the target until one of them closes its
+// connection.
+// If filter is not nil, the WebSocket messages of the client are filtered
+// before they reach the target, and the first message that fails closes the
+// connection. The error of that message is returned.
+func TunnelConnection(res http.ResponseWriter, targetRes *http.Response,
+	filter *MessageFilter) error {
+	targetConn, ok := targetRes.Body.(io.ReadWriteCloser)
+	if !ok || targetRes.StatusCode != http.StatusSwitchingProtocols {
+		targetRes.Body.Close()
+		return ErrNotUpgraded
+	}
+
+	hijacker, ok := res.(http.Hijacker)
+	if !ok {
+		targetConn.Close()
+		return ErrHijackingNotOk
+	}
+
+	clientConn, clientBuf, err := hijacker.Hijack()
+	if err != nil {
+		targetConn.Close()
+		return err
+	}
+
+	// The deadlines of the server apply to requests, not to tunnels
+	clientConn.SetDeadline(time.Time{})
+
+	header := make(http.Header)
+	httputils.CopyEndToEndHeaders(targetRes.Header, header)
+	header.Set("Connection", "Upgrade")
+	header.Set("Upgrade", targetRes.Header.Get("Upgrade"))
+
+	clientBuf.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	header.Write(clientBuf)
+	clientBuf.WriteString("\r\n")
+
+	err = clientBuf.Flush()
+	if err != nil {
+		clientConn.Close()
+		targetConn.Close()
+		return err
+	}
+
+	// Relay the data of the target to the client. Once the target closes
+	// its connection, the client's side of the tunnel stops reading.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		io.Copy(clientConn, targetConn)
+
+		clientConn.SetReadDeadline(time.Now())
+	}()
+
+	// The client may have sent data right after its request, which was
+	// read into the buffer of its connection
+	if filter == nil {
+		io.Copy(targetConn, clientBuf.Reader)
+	} else {
+		err = filter.copyMessages(targetConn, clientBuf.Reader)
+	}
+
+	targetConn.Close()
+	<-done
+
+	// The client is told why its messages were not delivered
+	var closeErr *wsCloseError
+	if errors.As(err, &closeErr) {
+		clientConn.SetWriteDeadline(time.Now().Add(time.Second))
+		writeCloseFrame(clientConn, closeErr.code, closeErr.reason)
+	}
+
+	clientConn.Close()
+
+	if closeErr != nil {
+		return closeErr.err
+	}
+
+	return nil
+}
+
+// releasingConn is an upgraded connection to an upstream, which releases the
+// upstream's request when it is closed
+type releasingConn struct {
+	io.ReadWriteCloser
+	release func()
+	once    sync.Once
+}
+
+// Close closes the connection and releases the request
+func (rc *releasingConn) Close() error {
+	err := rc.ReadWriteCloser.Close()
+
+	rc.once.Do(rc.release)
+
+	return err
+}
@@ -0,0 +1,246 @@
+package proxy
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// defaultMaxMessageSize is the maximum size of a filtered message, if the
+// filter does not set one
+const defaultMaxMessageSize = 1 << 20
+
+// WebSocket opcodes
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsClose        = 0x8
+)
+
+// WebSocket close codes
+const (
+	wsProtocolError   = 1002
+	wsPolicyViolation = 1008
+	wsMessageTooBig   = 1009
+)
+
+var (
+	// ErrInvalidFrame is returned when a client sends WebSocket frames that
+	// break the protocol
+	ErrInvalidFrame = errors.New("Invalid WebSocket frame")
+
+	// ErrMessageTooLarge is returned when a client sends a WebSocket message
+	// that is larger than the filter allows
+	ErrMessageTooLarge = errors.New("WebSocket message too large")
+)
+
+// MessageFilter validates the WebSocket messages that a client sends
+// through a tunnel. Only text messages are validated, and binary messages
+// pass as they are.
+type MessageFilter struct {
+	// Validate returns an error if a text message should not reach the
+	// target.
+	Validate func(message []byte) error
+
+	// MaxSize is the maximum size of a text message in bytes. If it is 0,
+	// messages are limited to 1MB.
+	MaxSize int64
+}
+
+// wsCloseError is an error that closes a WebSocket connection with a close
+// code
+type wsCloseError struct {
+	code   int
+	reason string
+	err    error
+}
+
+func (e *wsCloseError) Error() string {
+	return e.err.Error()
+}
+
+// wsFrameHeader is the header of a WebSocket frame
+type wsFrameHeader struct {
+	// raw holds the bytes of the header as they were read
+	raw []byte
+
+	fin    bool
+	opcode byte
+	length int64
+	mask   []byte
+}
+
+// copyMessages copies the WebSocket frames of a client to the target. The
+// frames of a text message are held until the message is complete and
+// valid. Other frames are copied as they arrive.
+func (mf *MessageFilter) copyMessages(target io.Writer, client io.Reader) error {
+	maxSize := mf.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxMessageSize
+	}
+
+	// frames holds the frames of the current text message, and message
+	// holds their unmasked payloads
+	var frames, message []byte
+	inMessage, text := false, false
+
+	for {
+		header, err := readFrameHeader(client)
+		if err != nil {
+			return err
+		}
+
+		control := header.opcode&0x8 != 0
+
+		if !control {
+			if header.opcode == wsContinuation {
+				if !inMessage {
+					return &wsCloseError{wsProtocolError, "", ErrInvalidFrame}
+				}
+			} else {
+				if inMessage {
+					return &wsCloseError{wsProtocolError, "", ErrInvalidFrame}
+				}
+
+				inMessage, text = true, header.opcode == wsText
+			}
+		}
+
+		// Control frames may come between the frames of a message, and
+		// binary messages are not validated
+		if control || !text {
+			_, err = target.Write(header.raw)
+			if err != nil {
+				return err
+			}
+
+			_, err = io.CopyN(target, client, header.length)
+			if err != nil {
+				return err
+			}
+
+			if !control && header.fin {
+				inMessage = false
+			}
+
+			continue
+		}
+
+		if int64(len(message))+header.length > maxSize {
+			return &wsCloseError{wsMessageTooBig, "Message too large", ErrMessageTooLarge}
+		}
+
+		payload := make([]byte, header.length)
+
+		_, err = io.ReadFull(client, payload)
+		if err != nil {
+			return err
+		}
+
+		frames = append(append(frames, header.raw...), payload...)
+
+		for i := range payload {
+			if header.mask != nil {
+				payload[i] ^= header.mask[i%4]
+			}
+		}
+
+		message = append(message, payload...)
+
+		if !header.fin {
+			continue
+		}
+
+		err = mf.Validate(message)
+		if err != nil {
+			return &wsCloseError{wsPolicyViolation, "Invalid message", err}
+		}
+
+		_, err = target.Write(frames)
+		if err != nil {
+			return err
+		}
+
+		frames, message, inMessage = nil, nil, false
+	}
+}
+
+// readFrameHeader reads the header of a WebSocket frame
+func readFrameHeader(r io.Reader) (*wsFrameHeader, error) {
+	raw := make([]byte, 2, 14)
+
+	_, err := io.ReadFull(r, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &wsFrameHeader{
+		fin:    raw[0]&0x80 != 0,
+		opcode: raw[0] & 0x0f,
+		length: int64(raw[1] & 0x7f),
+	}
+
+	masked := raw[1]&0x80 != 0
+
+	// The length of the payload may follow in 2 or 8 bytes, and the mask
+	// follows the length
+	extra := 0
+	switch header.length {
+	case 126:
+		extra = 2
+	case 127:
+		extra = 8
+	}
+
+	if masked {
+		extra += 4
+	}
+
+	raw = raw[:2+extra]
+
+	_, err = io.ReadFull(r, raw[2:])
+	if err != nil {
+		return nil, err
+	}
+
+	switch header.length {
+	case 126:
+		header.length = int64(binary.BigEndian.Uint16(raw[2:4]))
+	case 127:
+		length := binary.BigEndian.Uint64(raw[2:10])
+		if length > math.MaxInt64 {
+			return nil, &wsCloseError{wsProtocolError, "", ErrInvalidFrame}
+		}
+
+		header.length = int64(length)
+	}
+
+	// Control frames are never fragmented and carry at most 125 bytes
+	if header.opcode&0x8 != 0 && (!header.fin || header.length > 125) {
+		return nil, &wsCloseError{wsProtocolError, "", ErrInvalidFrame}
+	}
+
+	if masked {
+		header.mask = raw[len(raw)-4:]
+	}
+
+	header.raw = raw
+
+	return header, nil
+}
+
+// writeCloseFrame sends a close frame with a close code and a reason to a
+// client. Frames that are sent to clients are not masked.
+func writeCloseFrame(w io.Writer, code int, reason string) error {
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+
+	frame := []byte{0x80 | wsClose, byte(2 + len(reason)), 0, 0}
+	binary.BigEndian.PutUint16(frame[2:], uint16(code))
+
+	_, err := w.Write(append(frame, reason...))
+
+	return err
+}
@@ -0,0 +1,278 @@
+package proxy_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apidome/gateway/internal/pkg/proxy"
+)
+
+// The opcodes of the frames in the tests
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opPing         = 0x9
+)
+
+// frame builds a masked WebSocket frame, whose payload length is written in
+// the shortest form unless lengthBytes asks for 2 or 8 bytes
+func frame(fin bool, opcode byte, payload []byte, lengthBytes int) []byte {
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+
+	result := []byte{first}
+
+	switch {
+	case lengthBytes == 8 || (lengthBytes == 0 && len(payload) > 0xffff):
+		result = append(result, 0x80|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(result[2:], uint64(len(payload)))
+	case lengthBytes == 2 || (lengthBytes == 0 && len(payload) > 125):
+		result = append(result, 0x80|126, 0, 0)
+		binary.BigEndian.PutUint16(result[2:], uint16(len(payload)))
+	default:
+		result = append(result, 0x80|byte(len(payload)))
+	}
+
+	mask := []byte{0x12, 0x34, 0x56, 0x78}
+	result = append(result, mask...)
+
+	for index, char := range payload {
+		result = append(result, char^mask[index%4])
+	}
+
+	return result
+}
+
+// join concatenates frames
+func join(frames ...[]byte) []byte {
+	return bytes.Join(frames, nil)
+}
+
+func TestMessageFilter(t *testing.T) {
+	long := bytes.Repeat([]byte("a"), 300)
+	medium := bytes.Repeat([]byte("a"), 200)
+	text := frame(true, opText, []byte("hello"), 0)
+
+	// The length of this frame does not fit in a signed 64-bit integer
+	hugeLength := []byte{0x80 | opText, 0x80 | 127, 0x80, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 4}
+
+	testCases := []struct {
+		description string
+		sent        []byte
+		delivered   []byte
+		closeCode   int
+	}{
+		{
+			"a masked text message",
+			text,
+			text,
+			0,
+		},
+		{
+			"a text message with a 16-bit length",
+			frame(true, opText, medium, 0),
+			frame(true, opText, medium, 0),
+			0,
+		},
+		{
+			"a text message with a 64-bit length",
+			frame(true, opText, long, 8),
+			frame(true, opText, long, 8),
+			0,
+		},
+		{
+			"a fragmented message with a ping between its fragments",
+			join(frame(false, opText, []byte("hel"), 0), frame(true, opPing, []byte("p"), 0),
+				frame(true, opContinuation, []byte("lo"), 0)),
+			join(frame(true, opPing, []byte("p"), 0), frame(false, opText, []byte("hel"), 0),
+				frame(true, opContinuation, []byte("lo"), 0)),
+			0,
+		},
+		{
+			"a binary message, which is not validated",
+			frame(true, opBinary, []byte("bad"), 0),
+			frame(true, opBinary, []byte("bad"), 0),
+			0,
+		},
+		{
+			"an invalid text message after a valid one",
+			join(text, frame(true, opText, []byte("bad"), 0)),
+			text,
+			1008,
+		},
+		{
+			"an invalid message that is split between fragments",
+			join(frame(false, opText, []byte("ba"), 0), frame(true, opContinuation, []byte("d"), 0)),
+			nil,
+			1008,
+		},
+		{
+			"a message that is larger than the maximum",
+			frame(true, opText, bytes.Repeat([]byte("a"), 401), 0),
+			nil,
+			1009,
+		},
+		{
+			"fragments that are larger than the maximum together",
+			join(frame(false, opText, medium, 0), frame(false, opContinuation, medium, 0),
+				frame(true, opContinuation, []byte("a"), 0)),
+			nil,
+			1009,
+		},
+		{
+			"a continuation frame without a message",
+			frame(true, opContinuation, []byte("lo"), 0),
+			nil,
+			1002,
+		},
+		{
+			"a new message inside a fragmented message",
+			join(frame(false, opText, []byte("hel"), 0), frame(true, opText, []byte("lo"), 0)),
+			nil,
+			1002,
+		},
+		{
+			"a fragmented control frame",
+			frame(false, opPing, []byte("p"), 0),
+			nil,
+			1002,
+		},
+		{
+			"a control frame with more than 125 bytes",
+			frame(true, opPing, bytes.Repeat([]byte("p"), 126), 0),
+			nil,
+			1002,
+		},
+		{
+			"a frame whose length overflows",
+			hugeLength,
+			nil,
+			1002,
+		},
+		{
+			"a valid message followed by a truncated frame",
+			join(text, text[:len(text)-2]),
+			text,
+			0,
+		},
+		{
+			"a truncated frame header",
+			[]byte{0x80 | opText, 0x80 | 126, 0},
+			nil,
+			0,
+		},
+	}
+
+	t.Log("Given the need to test the filtering of the WebSocket messages of clients")
+	{
+		filter := &proxy.MessageFilter{
+			Validate: func(message []byte) error {
+				if bytes.Contains(message, []byte("bad")) {
+					return errors.New("bad message")
+				}
+
+				return nil
+			},
+			MaxSize: 400,
+		}
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When sending %s", index, testCase.description)
+			{
+				delivered, closeCode := tunnel(t, filter, testCase.sent)
+
+				if !bytes.Equal(delivered, testCase.delivered) {
+					t.Errorf("\t%s\tShould deliver %d bytes to the target: got %d", failed,
+						len(testCase.delivered), len(delivered))
+				} else {
+					t.Logf("\t%s\tShould deliver %d bytes to the target", succeed, len(delivered))
+				}
+
+				if closeCode != testCase.closeCode {
+					t.Errorf("\t%s\tShould close the connection with code %d: got %d", failed,
+						testCase.closeCode, closeCode)
+				} else {
+					t.Logf("\t%s\tShould close the connection with code %d", succeed, closeCode)
+				}
+			}
+		}
+	}
+}
+
+// tunnel sends frames through a filtered tunnel, and returns the bytes that
+// reached the target and the code of the close frame that the client got
+// (0 if it got none)
+func tunnel(t *testing.T, filter *proxy.MessageFilter, frames []byte) ([]byte, int) {
+	gatewayConn, targetConn := net.Pipe()
+
+	received := make(chan []byte, 1)
+	go func() {
+		data, _ := ioutil.ReadAll(targetConn)
+		received <- data
+	}()
+
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		targetRes := &http.Response{
+			StatusCode: http.StatusSwitchingProtocols,
+			Header:     http.Header{"Upgrade": {"websocket"}},
+			Body:       gatewayConn,
+		}
+
+		proxy.TunnelConnection(res, targetRes, filter)
+	}))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to connect to the gateway: %v", failed, err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+
+	err = req.Write(conn)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to send the request: %v", failed, err)
+	}
+
+	reader := bufio.NewReader(conn)
+
+	res, err := http.ReadResponse(reader, req)
+	if err != nil || res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("\t%s\tShould switch protocols: %v", failed, err)
+	}
+
+	_, err = conn.Write(frames)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to send the frames: %v", failed, err)
+	}
+
+	conn.(*net.TCPConn).CloseWrite()
+
+	// The gateway closes the connection once the client stops sending or
+	// the filter fails
+	answer, _ := ioutil.ReadAll(reader)
+	delivered := <-received
+
+	closeCode := 0
+	if len(answer) >= 4 && answer[0] == 0x88 {
+		closeCode = int(binary.BigEndian.Uint16(answer[2:4]))
+	}
+
+	return delivered, closeCode
+}
@@ -1,26 +1,51 @@
 package proxymiddlewares
 
 import (
+	"bytes"
+	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
 
+	"github.com/apidome/gateway/internal/pkg/configs"
 	"github.com/apidome/gateway/internal/pkg/httputils"
 	"github.com/apidome/gateway/internal/pkg/middleman"
 	"github.com/apidome/gateway/internal/pkg/proxy"
 	"github.com/apidome/gateway/internal/pkg/validators"
+	"github.com/apidome/gateway/internal/pkg/validators/formparser"
+	"github.com/pkg/errors"
 )
 
 // CreateRequest creates a new request as a copy
-// of the request from the client
+// of the request from the client.
+// If the request body was not read into store["requestBody"], it is
+// streamed to the target without being buffered.
 func CreateRequest(pr *proxy.Proxy) middleman.Middleware {
 	return func(res http.ResponseWriter, req *http.Request,
 		store middleman.Store, end middleman.End) error {
+		var body io.Reader = req.Body
+		contentLength := req.ContentLength
+
+		if requestBody, ok := store["requestBody"].([]byte); ok {
+			body = bytes.NewReader(requestBody)
+			contentLength = int64(len(requestBody))
+		}
 
 		tReq, err := pr.CreateRequest(req.Method,
 			req.URL.Path,
 			req.URL.RawQuery,
 			req.Header,
-			store["requestBody"].([]byte))
+			body,
+			contentLength)
+
+		// The address of the client lets a balancer send the requests of a
+		// client to the same upstream, and the context of the client's
+		// request stops the target request (and its retries) once the
+		// client is gone
+		if tReq != nil {
+			tReq.RemoteAddr = req.RemoteAddr
+			tReq = tReq.WithContext(req.Context())
+		}
 
 		store["targetRequest"] = tReq
 
@@ -28,8 +53,54 @@ func CreateRequest(pr *proxy.Proxy) middleman.Middleware {
 	}
 }
 
+// SetForwardedHeaders sets the headers that tell the target about the
+// client on the target request in store["targetRequest"]
+func SetForwardedHeaders(forwarding *proxy.Forwarding) middleman.Middleware {
+	return func(res http.ResponseWriter, req *http.Request,
+		store middleman.Store, end middleman.End) error {
+		forwarding.SetHeaders(req, store["targetRequest"].(*http.Request))
+
+		return nil
+	}
+}
+
+// AuthorizeClient is a middleware that blocks requests without an allowed
+// client certificate with a 403 problem details response
+func AuthorizeClient(clientAuth *proxy.ClientAuth) middleman.Middleware {
+	return func(res http.ResponseWriter, req *http.Request,
+		store middleman.Store, end middleman.End) error {
+		err := clientAuth.Authorize(req)
+		if err != nil {
+			end()
+
+			writeErr := httputils.RespondProblem(res, req,
+				httputils.NewProblem(http.StatusForbidden, err.Error(), req.URL.Path))
+			if writeErr != nil {
+				return errors.Wrap(writeErr, err.Error())
+			}
+
+			return err
+		}
+
+		return nil
+	}
+}
+
+// SetClientIdentityHeaders sets the headers that tell the target who the
+// client is on the target request in store["targetRequest"]
+func SetClientIdentityHeaders(clientAuth *proxy.ClientAuth) middleman.Middleware {
+	return func(res http.ResponseWriter, req *http.Request,
+		store middleman.Store, end middleman.End) error {
+		clientAuth.SetHeaders(req, store["targetRequest"].(*http.Request))
+
+		return nil
+	}
+}
+
 // SendRequest forwards the target request to the target
-// and stores the target response in store.TargetResponse
+// and stores the target response in store.TargetResponse.
+// If the target could not handle the request, the client gets a 502, 503 or
+// 504 problem details response, according to the failure.
 func SendRequest(pr *proxy.Proxy) middleman.Middleware {
 	return func(res http.ResponseWriter, req *http.Request,
 		store middleman.Store, end middleman.End) error {
@@ -39,15 +110,128 @@ func SendRequest(pr *proxy.Proxy) middleman.Middleware {
 
 		store["targetResponse"] = tRes
 
-		return err
+		// A streamed body may turn out to be too large only while it is
+		// sent to the target
+		if errors.Is(err, middleman.ErrBodyTooLarge) {
+			return middleman.BodyTooLarge(res, req, store, end)
+		}
+
+		if err != nil {
+			return targetFailure(res, req, end, err)
+		}
+
+		return nil
+	}
+}
+
+// ProxyUpgrade is a middleware that tunnels the connections of clients that
+// ask to switch protocols (like WebSocket) to the target, and lets the
+// following middlewares handle the other requests.
+// If store["messageFilter"] holds a filter, the client's messages are
+// filtered before they reach the target.
+func ProxyUpgrade(pr *proxy.Proxy) middleman.Middleware {
+	return func(res http.ResponseWriter, req *http.Request,
+		store middleman.Store, end middleman.End) error {
+		if !proxy.IsUpgrade(req) {
+			return nil
+		}
+
+		// A connection that cannot be taken over cannot be tunneled
+		if _, ok := res.(http.Hijacker); !ok {
+			end()
+
+			writeErr := httputils.RespondProblem(res, req,
+				httputils.NewProblem(http.StatusNotImplemented,
+					"The connection cannot switch protocols", req.URL.Path))
+			if writeErr != nil {
+				return errors.Wrap(writeErr, proxy.ErrHijackingNotOk.Error())
+			}
+
+			return proxy.ErrHijackingNotOk
+		}
+
+		filter, _ := store["messageFilter"].(*proxy.MessageFilter)
+
+		tRes, err := pr.SendUpgrade(store["targetRequest"].(*http.Request),
+			req.Header.Get("Upgrade"),
+			filter)
+		if err != nil {
+			return targetFailure(res, req, end, err)
+		}
+
+		end()
+
+		// The target may refuse to switch protocols
+		if tRes.StatusCode != http.StatusSwitchingProtocols {
+			return proxy.StreamResponseToClient(res, tRes)
+		}
+
+		return proxy.TunnelConnection(res, tRes, filter)
+	}
+}
+
+// targetFailure ends the handling of a request that the target could not
+// handle and answers it with a 502, 503 or 504 problem details response,
+// according to the failure
+func targetFailure(res http.ResponseWriter, req *http.Request,
+	end middleman.End, err error) error {
+	end()
+
+	writeErr := httputils.RespondProblem(res, req,
+		httputils.NewProblem(proxy.ErrorStatus(err),
+			"The target could not handle the request", req.URL.Path))
+	if writeErr != nil {
+		return errors.Wrap(writeErr, err.Error())
+	}
+
+	return err
+}
+
+// DecodeGRPCWeb is a middleware that prepares gRPC-Web requests to be
+// translated to gRPC requests: it stores their content type in
+// store["grpcWeb"] and decodes the bodies of the text variant as they are
+// read, so that their messages can be validated.
+func DecodeGRPCWeb() middleman.Middleware {
+	return func(res http.ResponseWriter, req *http.Request,
+		store middleman.Store, end middleman.End) error {
+		if !proxy.IsGRPCWeb(req) {
+			return nil
+		}
+
+		store["grpcWeb"] = req.Header.Get("Content-Type")
+
+		proxy.DecodeGRPCWebText(req)
+
+		return nil
+	}
+}
+
+// TranslateGRPCWeb is a middleware that turns the target request in
+// store["targetRequest"] into a gRPC request if the client's request is a
+// gRPC-Web request
+func TranslateGRPCWeb() middleman.Middleware {
+	return func(res http.ResponseWriter, req *http.Request,
+		store middleman.Store, end middleman.End) error {
+		if contentType, ok := store["grpcWeb"].(string); ok {
+			proxy.TranslateGRPCWebRequest(store["targetRequest"].(*http.Request), contentType)
+		}
+
+		return nil
 	}
 }
 
 // ReadResponseBody will read the target response body and store it in
-// store.TargetResponseBody
+// store.TargetResponseBody.
+// Responses whose body is not read are streamed to the client.
 func ReadResponseBody() middleman.Middleware {
 	return func(res http.ResponseWriter, req *http.Request,
 		store middleman.Store, end middleman.End) error {
+		// The body may have already been read for another route of the
+		// request
+		if _, ok := store["targetResponseBody"]; ok {
+			return nil
+		}
+
 		body, err :=
 			httputils.ReadResponseBody(store["targetResponse"].(*http.Response))
 
@@ -57,13 +241,27 @@ func ReadResponseBody() middleman.Middleware {
 	}
 }
 
-// SendResponse sends the target response to the client
+// SendResponse sends the target response to the client. If the target
+// response body was not read into store.TargetResponseBody, it is streamed
+// to the client as it arrives.
+// The responses to gRPC-Web requests are translated back to gRPC-Web.
 func SendResponse() middleman.Middleware {
 	return func(res http.ResponseWriter, req *http.Request,
 		store middleman.Store, end middleman.End) error {
-		err := proxy.CopyResponseToClient(res,
-			store["targetResponse"].(*http.Response),
-			store["targetResponseBody"].([]byte))
+		var err error
+		tRes := store["targetResponse"].(*http.Response)
+
+		if contentType, ok := store["grpcWeb"].(string); ok {
+			if body, ok := store["targetResponseBody"].([]byte); ok {
+				tRes.Body = ioutil.NopCloser(bytes.NewReader(body))
+			}
+
+			err = proxy.StreamGRPCWebResponse(res, tRes, contentType)
+		} else if body, ok := store["targetResponseBody"].([]byte); ok {
+			err = proxy.CopyResponseToClient(res, tRes, body)
+		} else {
+			err = proxy.StreamResponseToClient(res, tRes)
+		}
 
 		end()
 
@@ -75,7 +273,10 @@ func SendResponse() middleman.Middleware {
 func PrintRequestBody() middleman.Middleware {
 	return func(res http.ResponseWriter, req *http.Request,
 		store middleman.Store, end middleman.End) error {
-		log.Println(store["requestBody"].([]byte))
+		// Streamed request bodies are not printed
+		body, _ := store["requestBody"].([]byte)
+
+		log.Println(body)
 
 		return nil
 	}
@@ -85,22 +286,303 @@ func PrintRequestBody() middleman.Middleware {
 func PrintTargetResponseBody() middleman.Middleware {
 	return func(res http.ResponseWriter, req *http.Request,
 		store middleman.Store, end middleman.End) error {
-		log.Println(store["targetResponseBody"].([]byte))
+		// Streamed response bodies are not printed
+		body, _ := store["targetResponseBody"].([]byte)
+
+		log.Println(body)
 
 		return nil
 	}
 }
 
 // ValidateRequest is a middleware that handles validation of an HTTP request.
-func ValidateRequest(path, method string, validator validators.Validator) middleman.Middleware {
+// If the validator is in monitor mode, invalid requests are logged and
+// passed on to the target instead of being blocked, otherwise they are
+// answered with a problem details response.
+func ValidateRequest(path, method string, validator validators.Validator,
+	settings configs.Validator) middleman.Middleware {
 	return func(res http.ResponseWriter, req *http.Request,
 		store middleman.Store, end middleman.End) error {
-		err := validator.Validate(path, method, store["requestBody"].([]byte))
+		var err error
+
+		// Form bodies were parsed into their fields by ParseForm
+		formValidator, isFormValidator := validator.(validators.FormValidator)
+		if fields, ok := store["requestForm"].(map[string][]string); ok && isFormValidator {
+			err = formValidator.ValidateForm(path, method, fields)
+		} else {
+			err = validator.Validate(path, method, store["requestBody"].([]byte))
+		}
+
 		if err != nil {
+			if settings.Monitor {
+				logValidationFailure("[Validator MONITOR]: Validation failed -", path, method, err)
+				return nil
+			}
+
+			return blockRequest(res, req, end, validator, err, settings)
+		}
+
+		return nil
+	}
+}
+
+// ParseForm is a middleware that parses form bodies (url-encoded and
+// multipart) into their fields and stores them in store["requestForm"], so
+// ValidateRequest validates the fields instead of the body.
+// Bodies that cannot be parsed and uploads that fail their constraints are
+// blocked like invalid requests, unless the validator is in monitor mode.
+func ParseForm(path, method string, parser *formparser.Parser,
+	settings configs.Validator) middleman.Middleware {
+	return func(res http.ResponseWriter, req *http.Request,
+		store middleman.Store, end middleman.End) error {
+		contentType := req.Header.Get("Content-Type")
+		if !formparser.IsForm(contentType) {
+			return nil
+		}
+
+		fields, err := parser.Parse(contentType, store["requestBody"].([]byte))
+		if fields == nil {
+			fields = make(map[string][]string)
+		}
+
+		store["requestForm"] = fields
+
+		if err != nil {
+			if settings.Monitor {
+				logValidationFailure("[Validator MONITOR]: Validation failed -", path, method, err)
+				return nil
+			}
+
+			// The constraints of uploads are not described by a schema,
+			// so there is no validator to format their failures
+			return blockRequest(res, req, end, nil, err, settings)
+		}
+
+		return nil
+	}
+}
+
+// ValidateMessages is a middleware that stores a filter in
+// store["messageFilter"], which validates the WebSocket messages that the
+// client sends once its connection is tunneled to the target.
+// An invalid message closes the connection, unless the validator is in
+// monitor mode, in which case it is only logged. Messages are limited to
+// the size of the request bodies (1MB if the bodies are not limited).
+func ValidateMessages(path, method string, validator validators.MessageValidator,
+	settings configs.Validator) middleman.Middleware {
+	return func(res http.ResponseWriter, req *http.Request,
+		store middleman.Store, end middleman.End) error {
+		maxSize, _ := store["maxBodySize"].(int64)
+
+		store["messageFilter"] = &proxy.MessageFilter{
+			Validate: func(message []byte) error {
+				err := validator.ValidateMessage(path, method, message)
+				if err != nil && settings.Monitor {
+					logValidationFailure("[Validator MONITOR]: Message validation failed -",
+						path, method, err)
+					return nil
+				}
+
+				return err
+			},
+			MaxSize: maxSize,
+		}
+
+		return nil
+	}
+}
+
+// ValidateParameters is a middleware that handles validation of the
+// parameters of an HTTP request in the given locations (path variables,
+// query parameters and headers).
+// Like ValidateRequest, invalid requests are only logged if the validator
+// is in monitor mode.
+func ValidateParameters(path, method string, locations []string,
+	validator validators.ParametersValidator,
+	settings configs.Validator) middleman.Middleware {
+	return func(res http.ResponseWriter, req *http.Request,
+		store middleman.Store, end middleman.End) error {
+		for _, location := range locations {
+			err := validator.ValidateParameters(path, method, location,
+				requestParameters(req, store, location))
+			if err != nil {
+				if settings.Monitor {
+					logValidationFailure("[Validator MONITOR]: Validation failed -", path, method, err)
+					continue
+				}
+
+				return blockRequest(res, req, end, validator, err, settings)
+			}
+		}
+
+		return nil
+	}
+}
+
+// requestParameters returns the parameters of a request in a specific
+// location by their names
+func requestParameters(req *http.Request, store middleman.Store,
+	location string) map[string][]string {
+	switch location {
+	case validators.ParametersPath:
+		parameters := make(map[string][]string)
+		for name, value := range store.Variables() {
+			parameters[name] = []string{value}
+		}
+
+		return parameters
+	case validators.ParametersQuery:
+		return req.URL.Query()
+	case validators.ParametersHeader:
+		return req.Header
+	default:
+		return nil
+	}
+}
+
+// blockRequest ends the handling of a request that failed in validation
+// and answers it with a problem details response
+func blockRequest(res http.ResponseWriter, req *http.Request,
+	end middleman.End, validator validators.Validator, err error,
+	settings configs.Validator) error {
+	end()
+
+	writeErr := httputils.RespondProblem(res, req,
+		validationProblem(req, validator, err, settings))
+	if writeErr != nil {
+		return errors.Wrap(writeErr, err.Error())
+	}
+
+	return err
+}
+
+// ValidateResponse is a middleware that handles validation of the target
+// response. Invalid responses are always logged; unless the validator is in
+// monitor mode they are also replaced with a 502 problem details response,
+// which does not describe the failures since they concern the target and
+// not the client.
+func ValidateResponse(path, method string, validator validators.ResponseValidator,
+	settings configs.Validator) middleman.Middleware {
+	return func(res http.ResponseWriter, req *http.Request,
+		store middleman.Store, end middleman.End) error {
+		tRes := store["targetResponse"].(*http.Response)
+
+		err := validator.ValidateResponse(path,
+			method,
+			tRes.StatusCode,
+			tRes.Header.Get("Content-Type"),
+			store["targetResponseBody"].([]byte))
+		if err != nil {
+			if settings.Monitor {
+				logValidationFailure("[Validator MONITOR]: Response validation failed -",
+					path, method, err)
+				return nil
+			}
+
+			logValidationFailure("[Validator ERROR]: Response validation failed -",
+				path, method, err)
+
 			end()
+
+			writeErr := httputils.RespondProblem(res, req,
+				httputils.NewProblem(http.StatusBadGateway,
+					"The target's response failed in validation",
+					req.URL.Path))
+			if writeErr != nil {
+				return errors.Wrap(writeErr, err.Error())
+			}
+
 			return err
 		}
 
 		return nil
 	}
 }
+
+// validationProblem creates the problem details that describe why a
+// request was blocked by a validator
+func validationProblem(req *http.Request, validator validators.Validator,
+	err error, settings configs.Validator) *httputils.Problem {
+	validationErrs := validationFailures(err)
+
+	// A request that could not be validated at all is most likely
+	// malformed, while a request that failed on a schema rule is not
+	// processable.
+	status := http.StatusBadRequest
+	if len(validationErrs) > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+
+	if settings.Status != 0 {
+		status = settings.Status
+	}
+
+	if settings.HideDetails {
+		return httputils.NewProblem(status,
+			"The request failed in validation",
+			req.URL.Path)
+	}
+
+	problem := httputils.NewProblem(status, err.Error(), req.URL.Path)
+
+	for _, validationErr := range validationErrs {
+		problem.Errors = append(problem.Errors, httputils.InvalidValue{
+			Path:       validationErr.Path(),
+			SchemaPath: validationErr.SchemaPath(),
+			Keyword:    validationErr.Keyword(),
+			Reason:     validationErr.Reason(),
+		})
+	}
+
+	// Describe the failures in a standard format if the validator
+	// supports it
+	if formatter, ok := validator.(validators.OutputFormatter); ok &&
+		settings.OutputFormat != "" && len(validationErrs) > 0 {
+		output, formatErr :=
+			formatter.FormatOutput(errors.Cause(err), settings.OutputFormat)
+		if formatErr != nil {
+			log.Println("[Validator WARNING]: Could not format validation output:",
+				formatErr)
+		} else {
+			problem.Output = output
+		}
+	}
+
+	return problem
+}
+
+// logValidationFailure logs the details of a validation failure after the
+// given prefix
+func logValidationFailure(prefix, path, method string, err error) {
+	validationErrs := validationFailures(err)
+
+	for _, validationErr := range validationErrs {
+		log.Println(prefix,
+			"[Endpoint]:", path,
+			"[Method]:", method,
+			"[Path]:", validationErr.Path(),
+			"[Schema Path]:", validationErr.SchemaPath(),
+			"[Keyword]:", validationErr.Keyword(),
+			"[Reason]:", validationErr.Reason())
+	}
+
+	if len(validationErrs) == 0 {
+		log.Println(prefix,
+			"[Endpoint]:", path,
+			"[Method]:", method,
+			"[Error]:", err.Error())
+	}
+}
+
+// validationFailures returns the validation failures that an error holds,
+// or nil if the error is not a validation failure
+func validationFailures(err error) []validators.ValidationError {
+	switch v := errors.Cause(err).(type) {
+	case validators.ValidationErrors:
+		return v.Errors()
+	case validators.ValidationError:
+		return []validators.ValidationError{v}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,105 @@
+package proxymiddlewares_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apidome/gateway/internal/pkg/configs"
+	"github.com/apidome/gateway/internal/pkg/httputils"
+	"github.com/apidome/gateway/internal/pkg/middleman"
+	"github.com/apidome/gateway/internal/pkg/proxymiddlewares"
+	"github.com/apidome/gateway/internal/pkg/validators/jsonvalidator"
+)
+
+const succeed = "V"
+const failed = "X"
+
+func TestValidateRequest(t *testing.T) {
+	testCases := []struct {
+		description string
+		settings    configs.Validator
+		body        string
+		blocked     bool
+		status      int
+		details     bool
+	}{
+		{"a valid request", configs.Validator{}, `{"id": 1}`, false, http.StatusOK, false},
+		{"an invalid request", configs.Validator{}, `{"id": "a"}`, true, http.StatusUnprocessableEntity, true},
+		{"a malformed request", configs.Validator{}, `{"id": `, true, http.StatusBadRequest, false},
+		{"an invalid request to a validator with a status", configs.Validator{Status: 403}, `{"id": "a"}`, true, http.StatusForbidden, true},
+		{"an invalid request to a validator that hides details", configs.Validator{HideDetails: true}, `{"id": "a"}`, true, http.StatusUnprocessableEntity, false},
+		{"an invalid request to a validator in monitor mode", configs.Validator{Monitor: true}, `{"id": "a"}`, false, http.StatusOK, false},
+	}
+
+	t.Log("Given the need to test the responses to blocked requests")
+	{
+		jv, err := jsonvalidator.NewJsonValidator("draft-07")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a new JsonValidator: %v", failed, err)
+		}
+
+		err = jv.LoadSchema("/items", "POST",
+			[]byte(`{"type": "object", "properties": {"id": {"type": "integer"}}, "required": ["id"]}`))
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to load a schema: %v", failed, err)
+		}
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When validating %s", index, testCase.description)
+			{
+				validate := proxymiddlewares.ValidateRequest("/items", "POST", jv, testCase.settings)
+
+				res := httptest.NewRecorder()
+				req := httptest.NewRequest("POST", "/items", strings.NewReader(testCase.body))
+				store := middleman.Store{"requestBody": []byte(testCase.body)}
+
+				ended := false
+				err = validate(res, req, store, func() { ended = true })
+
+				if (err != nil) != testCase.blocked || ended != testCase.blocked {
+					t.Errorf("\t%s\tShould block the request: %v", failed, testCase.blocked)
+				} else {
+					t.Logf("\t%s\tShould block the request: %v", succeed, testCase.blocked)
+				}
+
+				if res.Code != testCase.status {
+					t.Errorf("\t%s\tShould answer %d: got %d", failed, testCase.status, res.Code)
+				} else {
+					t.Logf("\t%s\tShould answer %d", succeed, testCase.status)
+				}
+
+				if !testCase.blocked {
+					continue
+				}
+
+				if res.Header().Get("Content-Type") != httputils.ProblemContentType {
+					t.Errorf("\t%s\tShould answer with problem details: got %s", failed,
+						res.Header().Get("Content-Type"))
+				}
+
+				var problem httputils.Problem
+
+				err = json.Unmarshal(res.Body.Bytes(), &problem)
+				if err != nil {
+					t.Fatalf("\t%s\tShould answer with problem details: %v", failed, err)
+				}
+
+				if problem.Status != testCase.status || problem.Title != http.StatusText(testCase.status) ||
+					problem.Instance != "/items" || problem.Detail == "" {
+					t.Errorf("\t%s\tShould describe the problem: got %+v", failed, problem)
+				} else {
+					t.Logf("\t%s\tShould describe the problem", succeed)
+				}
+
+				if (len(problem.Errors) > 0) != testCase.details {
+					t.Errorf("\t%s\tShould describe the failures: %v", failed, testCase.details)
+				} else {
+					t.Logf("\t%s\tShould describe the failures: %v", succeed, testCase.details)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package formparser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apidome/gateway/internal/pkg/validators"
+)
+
+// PartValidationError describes why a part of a form body failed in the
+// constraints of its uploads
+type PartValidationError struct {
+	path    string
+	keyword string
+	reason  string
+}
+
+func (e PartValidationError) Error() string {
+	return fmt.Sprintf("validation failed in path %s: \"%s\" validation failed, reason: %s",
+		e.Path(), e.keyword, e.reason)
+}
+
+// Path returns the location of the part in the form ("/avatar", or
+// "/photos/1" for the second file of a field).
+func (e PartValidationError) Path() string {
+	if e.path == "" {
+		return "/"
+	}
+
+	return e.path
+}
+
+// SchemaPath returns an empty string, since the constraints of uploads are
+// not part of a schema.
+func (e PartValidationError) SchemaPath() string {
+	return ""
+}
+
+// Keyword returns the constraint that the part failed in ("maxParts",
+// "maxSize", "contentTypes" or "filename").
+func (e PartValidationError) Keyword() string {
+	return e.keyword
+}
+
+// Reason returns the reason of the validation failure.
+func (e PartValidationError) Reason() string {
+	return e.reason
+}
+
+// ValidationErrors is a list of the failures that were found in a form.
+type ValidationErrors []PartValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for index, err := range e {
+		messages[index] = err.Error()
+	}
+
+	return fmt.Sprintf("%d validation failures: %s", len(e), strings.Join(messages, "; "))
+}
+
+// Errors returns the failures in the list.
+func (e ValidationErrors) Errors() []validators.ValidationError {
+	errs := make([]validators.ValidationError, len(e))
+	for index, err := range e {
+		errs[index] = err
+	}
+
+	return errs
+}
+
+// result returns the list as an error: nil if it is empty, the first
+// failure if the parsing is not exhaustive or if the list holds a single
+// failure, and the whole list otherwise.
+func (e ValidationErrors) result(exhaustive bool) error {
+	switch {
+	case len(e) == 0:
+		return nil
+	case len(e) == 1 || !exhaustive:
+		return e[0]
+	default:
+		return e
+	}
+}
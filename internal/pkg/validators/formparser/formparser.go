@@ -0,0 +1,339 @@
+package formparser
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The media types of form bodies
+const (
+	URLEncoded = "application/x-www-form-urlencoded"
+	Multipart  = "multipart/form-data"
+)
+
+// defaultFileContentType is the content type of the files whose parts do
+// not declare one (RFC 7578)
+const defaultFileContentType = "application/octet-stream"
+
+// FileConstraints restrict the files that are uploaded in multipart forms.
+// Zero values are not enforced.
+type FileConstraints struct {
+	// MaxSize is the maximum size of a file in bytes.
+	MaxSize int64
+
+	// ContentTypes are the media types that files may have. A type may
+	// end with "/*" to allow all its subtypes ("image/*").
+	ContentTypes []string
+
+	// Filename is a regular expression that the names of the files should
+	// match (unanchored, like the "pattern" keyword of json schemas).
+	Filename string
+}
+
+// fileRule holds the constraints of files with their compiled pattern
+type fileRule struct {
+	maxSize      int64
+	contentTypes []string
+	filename     *regexp.Regexp
+}
+
+// Parser parses form bodies (application/x-www-form-urlencoded and
+// multipart/form-data) into their fields, so they can be validated like json
+// objects, and enforces the constraints of the files that they upload.
+type Parser struct {
+	maxParts   int
+	exhaustive bool
+
+	// defaults constrain all the files, and fields constrain the files of
+	// specific fields on top of them.
+	defaults fileRule
+	fields   map[string]fileRule
+}
+
+// failure is a failed constraint of a part, whose path depends on the
+// number of values of its field
+type failure struct {
+	field   string
+	index   int
+	keyword string
+	reason  string
+}
+
+// NewParser returns a new instance of Parser without constraints.
+func NewParser() *Parser {
+	return &Parser{fields: make(map[string]fileRule)}
+}
+
+// SetExhaustive determines whether Parse should report the first failed
+// constraint or all of them.
+func (p *Parser) SetExhaustive(exhaustive bool) {
+	p.exhaustive = exhaustive
+}
+
+// SetMaxParts limits the number of parts of multipart forms and the number
+// of fields of url-encoded forms. A limit of 0 is not enforced.
+func (p *Parser) SetMaxParts(maxParts int) {
+	p.maxParts = maxParts
+}
+
+// SetFileConstraints constrains the files that are uploaded in a field, or
+// all the files if the field is empty. The constraints of a field replace
+// the constraints of all the files only where they are not zero.
+func (p *Parser) SetFileConstraints(field string, constraints FileConstraints) error {
+	rule := fileRule{
+		maxSize:      constraints.MaxSize,
+		contentTypes: constraints.ContentTypes,
+	}
+
+	if constraints.Filename != "" {
+		var err error
+
+		rule.filename, err = regexp.Compile(constraints.Filename)
+		if err != nil {
+			return errors.Wrap(err, "invalid filename pattern of field \""+field+"\"")
+		}
+	}
+
+	if field == "" {
+		p.defaults = rule
+	} else {
+		p.fields[field] = rule
+	}
+
+	return nil
+}
+
+// IsForm returns true if a content type is the content type of a form body
+func IsForm(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	return err == nil && (mediaType == URLEncoded || mediaType == Multipart)
+}
+
+// Parse parses a form body into the values of its fields by their names.
+// The fields of uploaded files hold the names of the files.
+// A body that cannot be parsed returns a plain error, while failed
+// constraints return PartValidationErrors together with the fields.
+func (p *Parser) Parse(contentType string, body []byte) (map[string][]string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse form: invalid content type")
+	}
+
+	switch mediaType {
+	case URLEncoded:
+		return p.parseURLEncoded(body)
+	case Multipart:
+		boundary := params["boundary"]
+		if boundary == "" {
+			return nil, errors.New("could not parse form: the multipart boundary is missing")
+		}
+
+		return p.parseMultipart(boundary, body)
+	default:
+		return nil, errors.New("could not parse form: unsupported content type \"" + mediaType + "\"")
+	}
+}
+
+// parseURLEncoded parses the fields of a url-encoded form
+func (p *Parser) parseURLEncoded(body []byte) (map[string][]string, error) {
+	fields, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse url-encoded form")
+	}
+
+	count := 0
+	for _, values := range fields {
+		count += len(values)
+	}
+
+	if p.maxParts > 0 && count > p.maxParts {
+		return fields, PartValidationError{
+			keyword: "maxParts",
+			reason:  "the form has " + strconv.Itoa(count) + " fields, more than " + strconv.Itoa(p.maxParts),
+		}
+	}
+
+	return fields, nil
+}
+
+// parseMultipart parses the parts of a multipart form. Parts without a name
+// are ignored, and the parts beyond the maximum are not read.
+func (p *Parser) parseMultipart(boundary string, body []byte) (map[string][]string, error) {
+	fields := make(map[string][]string)
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+
+	var failures []failure
+
+	for count := 1; ; count++ {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, "could not parse multipart form")
+		}
+
+		if p.maxParts > 0 && count > p.maxParts {
+			failures = append(failures, failure{
+				index:   -1,
+				keyword: "maxParts",
+				reason:  "the form has more than " + strconv.Itoa(p.maxParts) + " parts",
+			})
+
+			break
+		}
+
+		name := part.FormName()
+		if name == "" {
+			continue
+		}
+
+		filename := part.FileName()
+		if filename == "" {
+			value, err := ioutil.ReadAll(part)
+			if err != nil {
+				return nil, errors.Wrap(err, "could not parse multipart form")
+			}
+
+			fields[name] = append(fields[name], string(value))
+
+			continue
+		}
+
+		size, err := io.Copy(ioutil.Discard, part)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not parse multipart form")
+		}
+
+		partContentType := part.Header.Get("Content-Type")
+		if partContentType == "" {
+			partContentType = defaultFileContentType
+		}
+
+		for _, f := range p.checkFile(name, filename, partContentType, size) {
+			f.index = len(fields[name])
+			failures = append(failures, f)
+		}
+
+		fields[name] = append(fields[name], filename)
+	}
+
+	var errs ValidationErrors
+
+	for _, f := range failures {
+		errs = append(errs, PartValidationError{
+			path:    failurePath(f, fields),
+			keyword: f.keyword,
+			reason:  f.reason,
+		})
+	}
+
+	return fields, errs.result(p.exhaustive)
+}
+
+// checkFile returns the constraints that a file failed in
+func (p *Parser) checkFile(field, filename, contentType string, size int64) []failure {
+	rule := p.rule(field)
+
+	var failures []failure
+
+	if rule.maxSize > 0 && size > rule.maxSize {
+		failures = append(failures, failure{
+			field:   field,
+			keyword: "maxSize",
+			reason: "the file \"" + filename + "\" has " + strconv.FormatInt(size, 10) +
+				" bytes, more than " + strconv.FormatInt(rule.maxSize, 10),
+		})
+	}
+
+	if len(rule.contentTypes) > 0 && !allowedContentType(contentType, rule.contentTypes) {
+		failures = append(failures, failure{
+			field:   field,
+			keyword: "contentTypes",
+			reason: "the content type \"" + contentType + "\" of the file \"" + filename +
+				"\" is not one of \"" + strings.Join(rule.contentTypes, "\", \"") + "\"",
+		})
+	}
+
+	if rule.filename != nil && !rule.filename.MatchString(filename) {
+		failures = append(failures, failure{
+			field:   field,
+			keyword: "filename",
+			reason:  "the file name \"" + filename + "\" does not match the pattern \"" + rule.filename.String() + "\"",
+		})
+	}
+
+	return failures
+}
+
+// rule returns the constraints of the files of a field
+func (p *Parser) rule(field string) fileRule {
+	rule, ok := p.fields[field]
+	if !ok {
+		return p.defaults
+	}
+
+	if rule.maxSize == 0 {
+		rule.maxSize = p.defaults.maxSize
+	}
+
+	if len(rule.contentTypes) == 0 {
+		rule.contentTypes = p.defaults.contentTypes
+	}
+
+	if rule.filename == nil {
+		rule.filename = p.defaults.filename
+	}
+
+	return rule
+}
+
+// allowedContentType returns true if a content type matches one of the
+// allowed media types, ignoring its parameters
+func allowedContentType(contentType string, allowed []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, allowedType := range allowed {
+		allowedType = strings.ToLower(strings.TrimSpace(allowedType))
+
+		switch {
+		case allowedType == mediaType, allowedType == "*/*":
+			return true
+		case strings.HasSuffix(allowedType, "/*") &&
+			strings.HasPrefix(mediaType, strings.TrimSuffix(allowedType, "*")):
+			return true
+		}
+	}
+
+	return false
+}
+
+// failurePath returns the location of a failed part: the name of its field,
+// followed by the index of the part if the field has several values, like
+// the path of an array item
+func failurePath(f failure, fields map[string][]string) string {
+	if f.index < 0 {
+		return ""
+	}
+
+	path := "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(f.field)
+	if len(fields[f.field]) > 1 {
+		path += "/" + strconv.Itoa(f.index)
+	}
+
+	return path
+}
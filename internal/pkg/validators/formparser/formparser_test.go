@@ -0,0 +1,321 @@
+package formparser_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/textproto"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/apidome/gateway/internal/pkg/validators"
+	"github.com/apidome/gateway/internal/pkg/validators/formparser"
+)
+
+const succeed = "V"
+const failed = "X"
+
+// part is a part of a multipart body in the tests
+type part struct {
+	name        string
+	filename    string
+	contentType string
+	content     string
+}
+
+// multipartBody encodes parts into a multipart body, and returns its
+// content type
+func multipartBody(t *testing.T, parts []part) (string, []byte) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	for _, p := range parts {
+		header := make(textproto.MIMEHeader)
+
+		disposition := `form-data; name="` + p.name + `"`
+		if p.filename != "" {
+			disposition += `; filename="` + p.filename + `"`
+		}
+
+		header.Set("Content-Disposition", disposition)
+
+		if p.contentType != "" {
+			header.Set("Content-Type", p.contentType)
+		}
+
+		partWriter, err := writer.CreatePart(header)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a multipart body: %v", failed, err)
+		}
+
+		_, err = partWriter.Write([]byte(p.content))
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a multipart body: %v", failed, err)
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create a multipart body: %v", failed, err)
+	}
+
+	return writer.FormDataContentType(), body.Bytes()
+}
+
+// failures describes the validation failures of an error by their paths
+// and keywords
+func failures(t *testing.T, err error) []string {
+	var result []string
+
+	switch v := err.(type) {
+	case nil:
+	case validators.ValidationErrors:
+		for _, validationErr := range v.Errors() {
+			result = append(result, validationErr.Path()+" "+validationErr.Keyword())
+		}
+	case validators.ValidationError:
+		result = append(result, v.Path()+" "+v.Keyword())
+	default:
+		t.Fatalf("\t%s\tShould get validation failures: %v", failed, err)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+func TestParseURLEncoded(t *testing.T) {
+	testCases := []struct {
+		description string
+		body        string
+		fields      string
+		failures    []string
+	}{
+		{
+			"a form with repeated fields",
+			"name=Rex&tags=a&tags=b+c",
+			"name=Rex&tags=a&tags=b c",
+			nil,
+		},
+		{
+			"a form with more fields than allowed",
+			"a=1&b=2&b=3&c=4",
+			"a=1&b=2&b=3&c=4",
+			[]string{"/ maxParts"},
+		},
+	}
+
+	t.Log("Given the need to test parsing of url-encoded forms")
+	{
+		parser := formparser.NewParser()
+		parser.SetMaxParts(3)
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When trying to parse %s", index, testCase.description)
+			{
+				fields, err := parser.Parse("application/x-www-form-urlencoded; charset=utf-8",
+					[]byte(testCase.body))
+
+				if describe(fields) != testCase.fields {
+					t.Errorf("\t%s\tShould get the fields %s: got %s", failed, testCase.fields, describe(fields))
+				} else {
+					t.Logf("\t%s\tShould get the fields %s", succeed, testCase.fields)
+				}
+
+				result := failures(t, err)
+				if strings.Join(result, ", ") != strings.Join(testCase.failures, ", ") {
+					t.Errorf("\t%s\tShould get the failures [%s]: got [%s]", failed,
+						strings.Join(testCase.failures, ", "), strings.Join(result, ", "))
+				} else {
+					t.Logf("\t%s\tShould get the failures [%s]", succeed, strings.Join(result, ", "))
+				}
+			}
+		}
+
+		_, err := parser.Parse("application/x-www-form-urlencoded", []byte("a=%zz"))
+		if _, ok := err.(validators.ValidationError); err == nil || ok {
+			t.Errorf("\t%s\tShould not be able to parse a malformed form", failed)
+		} else {
+			t.Logf("\t%s\tShould not be able to parse a malformed form", succeed)
+		}
+	}
+}
+
+func TestParseMultipart(t *testing.T) {
+	testCases := []struct {
+		description string
+		exhaustive  bool
+		parts       []part
+		fields      string
+		failures    []string
+	}{
+		{
+			"a form with values and files that meet the constraints",
+			true,
+			[]part{
+				{name: "title", content: "Holiday"},
+				{name: "photos", filename: "beach.png", contentType: "image/png", content: "1234"},
+				{name: "photos", filename: "sea.jpg", contentType: "image/jpeg", content: "12"},
+				{name: "report", filename: "report.pdf", contentType: "application/pdf", content: "123456"},
+				{filename: "unnamed.txt", content: "ignored"},
+			},
+			"photos=beach.png&photos=sea.jpg&report=report.pdf&title=Holiday",
+			nil,
+		},
+		{
+			"a file that fails in all the default constraints",
+			true,
+			[]part{
+				{name: "avatar", filename: "avatar.exe", content: "12345"},
+			},
+			"avatar=avatar.exe",
+			[]string{"/avatar contentTypes", "/avatar filename", "/avatar maxSize"},
+		},
+		{
+			"files that fail in the constraints of their fields",
+			true,
+			[]part{
+				{name: "photos", filename: "beach.png", contentType: "image/png", content: "1234"},
+				{name: "photos", filename: "notes.txt", contentType: "text/plain", content: "1"},
+				{name: "report", filename: "report.pdf", contentType: "application/pdf", content: "12345678"},
+			},
+			"photos=beach.png&photos=notes.txt&report=report.pdf",
+			[]string{"/photos/1 contentTypes", "/photos/1 filename", "/report maxSize"},
+		},
+		{
+			"a form with more parts than allowed",
+			true,
+			[]part{
+				{name: "a", content: "1"},
+				{name: "b", content: "2"},
+				{name: "c", content: "3"},
+				{name: "d", content: "4"},
+				{name: "e", content: "5"},
+				{name: "f", content: "6"},
+			},
+			"a=1&b=2&c=3&d=4&e=5",
+			[]string{"/ maxParts"},
+		},
+		{
+			"a file that fails in several constraints without exhaustive parsing",
+			false,
+			[]part{
+				{name: "avatar", filename: "avatar.exe", content: "12345"},
+			},
+			"avatar=avatar.exe",
+			[]string{"/avatar maxSize"},
+		},
+	}
+
+	t.Log("Given the need to test parsing of multipart forms")
+	{
+		parser := formparser.NewParser()
+		parser.SetMaxParts(5)
+
+		err := parser.SetFileConstraints("", formparser.FileConstraints{
+			MaxSize:      4,
+			ContentTypes: []string{"image/*", "application/pdf"},
+			Filename:     `\.(png|jpg|pdf)$`,
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to set the constraints of all files: %v", failed, err)
+		}
+
+		err = parser.SetFileConstraints("report", formparser.FileConstraints{MaxSize: 6})
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to set the constraints of a field: %v", failed, err)
+		}
+		t.Logf("\t%s\tShould be able to set the constraints of files", succeed)
+
+		err = parser.SetFileConstraints("photos", formparser.FileConstraints{Filename: "("})
+		if err == nil {
+			t.Errorf("\t%s\tShould not be able to set an invalid filename pattern", failed)
+		} else {
+			t.Logf("\t%s\tShould not be able to set an invalid filename pattern", succeed)
+		}
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When trying to parse %s", index, testCase.description)
+			{
+				parser.SetExhaustive(testCase.exhaustive)
+
+				contentType, body := multipartBody(t, testCase.parts)
+				fields, err := parser.Parse(contentType, body)
+
+				if describe(fields) != testCase.fields {
+					t.Errorf("\t%s\tShould get the fields %s: got %s", failed, testCase.fields, describe(fields))
+				} else {
+					t.Logf("\t%s\tShould get the fields %s", succeed, testCase.fields)
+				}
+
+				result := failures(t, err)
+				if strings.Join(result, ", ") != strings.Join(testCase.failures, ", ") {
+					t.Errorf("\t%s\tShould get the failures [%s]: got [%s]", failed,
+						strings.Join(testCase.failures, ", "), strings.Join(result, ", "))
+				} else {
+					t.Logf("\t%s\tShould get the failures [%s]", succeed, strings.Join(result, ", "))
+				}
+			}
+		}
+
+		t.Logf("\tTest %d: When trying to parse malformed forms", len(testCases))
+		{
+			for _, contentType := range []string{
+				"multipart/form-data",
+				"multipart/form-data; boundary=missing",
+				"text/plain",
+				"multipart/form-data; boundary=",
+			} {
+				_, err = parser.Parse(contentType, []byte("--other\r\n\r\nvalue\r\n--other--\r\n"))
+				if _, ok := err.(validators.ValidationError); err == nil || ok {
+					t.Errorf("\t%s\tShould not be able to parse a form of type \"%s\"", failed, contentType)
+				} else {
+					t.Logf("\t%s\tShould not be able to parse a form of type \"%s\"", succeed, contentType)
+				}
+			}
+		}
+	}
+}
+
+func TestIsForm(t *testing.T) {
+	testCases := []struct {
+		contentType string
+		isForm      bool
+	}{
+		{"application/x-www-form-urlencoded", true},
+		{"multipart/form-data; boundary=abc", true},
+		{"Multipart/Form-Data; boundary=abc", true},
+		{"application/json", false},
+		{"multipart/mixed; boundary=abc", false},
+		{"", false},
+	}
+
+	t.Log("Given the need to test the detection of form bodies")
+	{
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When given the content type \"%s\"", index, testCase.contentType)
+			{
+				if formparser.IsForm(testCase.contentType) != testCase.isForm {
+					t.Errorf("\t%s\tShould detect whether the body is a form: expected %v",
+						failed, testCase.isForm)
+				} else {
+					t.Logf("\t%s\tShould detect whether the body is a form", succeed)
+				}
+			}
+		}
+	}
+}
+
+// describe encodes fields in a stable order for comparisons
+func describe(fields map[string][]string) string {
+	var pairs []string
+	for name, values := range fields {
+		for _, value := range values {
+			pairs = append(pairs, name+"="+value)
+		}
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, "&")
+}
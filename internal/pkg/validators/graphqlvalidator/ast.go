@@ -0,0 +1,223 @@
+package graphqlvalidator
+
+// location is the location of a node in a GraphQL document
+type location struct {
+	line   int
+	column int
+}
+
+// document is a parsed executable document (a request's query)
+type document struct {
+	operations []*operation
+	fragments  []*fragment
+}
+
+// operation is an operation definition
+type operation struct {
+	location
+	kind         string
+	name         string
+	variables    []*variableDefinition
+	directives   []*directive
+	selectionSet []selection
+}
+
+// variableDefinition is the definition of a variable of an operation
+type variableDefinition struct {
+	location
+	name         string
+	typeRef      *typeRef
+	defaultValue *value
+	directives   []*directive
+}
+
+// fragment is a fragment definition
+type fragment struct {
+	location
+	name          string
+	typeCondition string
+	directives    []*directive
+	selectionSet  []selection
+}
+
+// selection is a field, a fragment spread or an inline fragment
+type selection interface {
+	position() location
+}
+
+// field is a field selection
+type field struct {
+	location
+	alias        string
+	name         string
+	arguments    []*argument
+	directives   []*directive
+	selectionSet []selection
+}
+
+// responseKey returns the name of the field in the response
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+
+	return f.name
+}
+
+// fragmentSpread is a spread of a named fragment
+type fragmentSpread struct {
+	location
+	name       string
+	directives []*directive
+}
+
+// inlineFragment is a fragment that is defined in a selection set. Its type
+// condition is empty if it has none.
+type inlineFragment struct {
+	location
+	typeCondition string
+	directives    []*directive
+	selectionSet  []selection
+}
+
+func (l location) position() location {
+	return l
+}
+
+// directive is a directive that annotates a definition or a selection
+type directive struct {
+	location
+	name      string
+	arguments []*argument
+}
+
+// argument is an argument of a field or of a directive
+type argument struct {
+	location
+	name  string
+	value *value
+}
+
+// Kinds of values
+const (
+	valueVariable = iota
+	valueInt
+	valueFloat
+	valueString
+	valueBoolean
+	valueNull
+	valueEnum
+	valueList
+	valueObject
+)
+
+// value is an input value. The raw value of scalars is held in raw, the
+// name of variables and enum values in raw too, the items of lists in list
+// and the fields of objects in fields.
+type value struct {
+	location
+	kind   int
+	raw    string
+	list   []*value
+	fields []*objectField
+}
+
+// objectField is a field of an input object value
+type objectField struct {
+	location
+	name  string
+	value *value
+}
+
+// typeRef is a reference to a type: a named type, a list type or a
+// non-null type
+type typeRef struct {
+	name    string
+	ofType  *typeRef
+	list    bool
+	nonNull bool
+}
+
+// namedType returns the name of the type that the reference wraps
+func (t *typeRef) namedType() string {
+	for t.ofType != nil {
+		t = t.ofType
+	}
+
+	return t.name
+}
+
+// String formats a type reference like it is written in documents
+func (t *typeRef) String() string {
+	switch {
+	case t.nonNull:
+		return t.ofType.String() + "!"
+	case t.list:
+		return "[" + t.ofType.String() + "]"
+	default:
+		return t.name
+	}
+}
+
+// schemaDocument is a parsed type system document (a schema in SDL)
+type schemaDocument struct {
+	schemas    []*schemaDefinition
+	types      []*typeDefinition
+	directives []*directiveDefinition
+}
+
+// schemaDefinition is a schema definition or extension, which names the
+// root operation types
+type schemaDefinition struct {
+	location
+	operationTypes map[string]string
+}
+
+// Kinds of types
+const (
+	kindScalar      = "SCALAR"
+	kindObject      = "OBJECT"
+	kindInterface   = "INTERFACE"
+	kindUnion       = "UNION"
+	kindEnum        = "ENUM"
+	kindInputObject = "INPUT_OBJECT"
+)
+
+// typeDefinition is a type definition or a type extension
+type typeDefinition struct {
+	location
+	kind        string
+	name        string
+	extension   bool
+	interfaces  []string
+	fields      []*fieldDefinition
+	inputFields []*inputValueDefinition
+	enumValues  []string
+	members     []string
+}
+
+// fieldDefinition is the definition of a field of an object or an interface
+type fieldDefinition struct {
+	location
+	name      string
+	arguments []*inputValueDefinition
+	typeRef   *typeRef
+}
+
+// inputValueDefinition is the definition of an argument or of a field of an
+// input object
+type inputValueDefinition struct {
+	location
+	name         string
+	typeRef      *typeRef
+	defaultValue *value
+}
+
+// directiveDefinition is the definition of a directive
+type directiveDefinition struct {
+	location
+	name       string
+	arguments  []*inputValueDefinition
+	repeatable bool
+	locations  []string
+}
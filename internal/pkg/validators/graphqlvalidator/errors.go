@@ -0,0 +1,109 @@
+package graphqlvalidator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apidome/gateway/internal/pkg/validators"
+)
+
+// SyntaxError describes a GraphQL document that could not be parsed
+type SyntaxError struct {
+	line   int
+	column int
+	reason string
+}
+
+func (e SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at %d:%d: %s", e.line, e.column, e.reason)
+}
+
+// SchemaError describes an SDL schema that is not valid
+type SchemaError struct {
+	location location
+	reason   string
+}
+
+func (e SchemaError) Error() string {
+	if e.location.line == 0 {
+		return "invalid schema: " + e.reason
+	}
+
+	return fmt.Sprintf("invalid schema at %d:%d: %s", e.location.line, e.location.column, e.reason)
+}
+
+// RequestValidationError describes why a GraphQL request failed in
+// validation
+type RequestValidationError struct {
+	path       string
+	schemaPath string
+	keyword    string
+	reason     string
+}
+
+func (e RequestValidationError) Error() string {
+	return fmt.Sprintf("validation failed in path %s: \"%s\" validation failed, reason: %s",
+		e.Path(), e.keyword, e.reason)
+}
+
+// Path returns the location of the failure: the line and column in the
+// query ("3:5"), or the json path of a variable ("/variables/input/name").
+func (e RequestValidationError) Path() string {
+	if e.path == "" {
+		return "/"
+	}
+
+	return e.path
+}
+
+// SchemaPath returns the coordinate of the schema member that the request
+// failed on ("User.friends", "User.friends(first:)"), if there is one.
+func (e RequestValidationError) SchemaPath() string {
+	return e.schemaPath
+}
+
+// Keyword returns the name of the rule that the request failed in.
+func (e RequestValidationError) Keyword() string {
+	return e.keyword
+}
+
+// Reason returns the reason of the validation failure.
+func (e RequestValidationError) Reason() string {
+	return e.reason
+}
+
+// ValidationErrors is a list of the failures that were found in a request.
+type ValidationErrors []RequestValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for index, err := range e {
+		messages[index] = err.Error()
+	}
+
+	return fmt.Sprintf("%d validation failures: %s", len(e), strings.Join(messages, "; "))
+}
+
+// Errors returns the failures in the list.
+func (e ValidationErrors) Errors() []validators.ValidationError {
+	errs := make([]validators.ValidationError, len(e))
+	for index, err := range e {
+		errs[index] = err
+	}
+
+	return errs
+}
+
+// result returns the list as an error: nil if it is empty, the first
+// failure if the validation is not exhaustive or if the list holds a single
+// failure, and the whole list otherwise.
+func (e ValidationErrors) result(exhaustive bool) error {
+	switch {
+	case len(e) == 0:
+		return nil
+	case len(e) == 1 || !exhaustive:
+		return e[0]
+	default:
+		return e
+	}
+}
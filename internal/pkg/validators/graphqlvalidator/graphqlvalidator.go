@@ -0,0 +1,286 @@
+package graphqlvalidator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// GraphQLValidator is a struct that implements the Validator interface and
+// validates the requests of GraphQL apis against SDL schemas.
+// The request bodies are either json objects ({"query": ..., "variables":
+// ..., "operationName": ...}), json arrays of such objects (batches), or
+// bare queries (application/graphql).
+type GraphQLValidator struct {
+	schemaDict map[string]map[string]*schema
+	exhaustive bool
+	limits     Limits
+
+	// persistedQueries holds the queries that requests may execute by
+	// their ids, and allowed holds the same queries by their text. Any
+	// query is allowed if they are nil.
+	persistedQueries map[string]string
+	allowed          map[string]bool
+
+	// mutex guards the dictionary, so schemas can be loaded while
+	// requests are validated.
+	mutex *sync.RWMutex
+}
+
+// request is a GraphQL request in the body of an http request
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+	DocumentID    string                 `json:"documentId"`
+	Extensions    struct {
+		PersistedQuery struct {
+			Sha256Hash string `json:"sha256Hash"`
+		} `json:"persistedQuery"`
+	} `json:"extensions"`
+}
+
+// NewGraphQLValidator returns a new instance of GraphQLValidator.
+func NewGraphQLValidator() *GraphQLValidator {
+	return &GraphQLValidator{
+		schemaDict: make(map[string]map[string]*schema),
+		mutex:      &sync.RWMutex{},
+	}
+}
+
+// SetExhaustive determines whether Validate should stop at the first failure
+// or collect all the failures in a request.
+func (gv *GraphQLValidator) SetExhaustive(exhaustive bool) {
+	gv.exhaustive = exhaustive
+}
+
+// SetLimits bounds the depth, the aliases and the complexity of the
+// operations that requests may execute.
+func (gv *GraphQLValidator) SetLimits(limits Limits) {
+	gv.limits = limits
+}
+
+// SetPersistedQueries restricts the requests to an allowlist of queries,
+// given by their ids. Requests may send a query of the allowlist, or only
+// its id in "documentId" or in the "sha256Hash" of the "persistedQuery"
+// extension. The sha256 hash of every query is an id of the query too.
+func (gv *GraphQLValidator) SetPersistedQueries(queries map[string]string) {
+	gv.persistedQueries = make(map[string]string, len(queries)*2)
+	gv.allowed = make(map[string]bool, len(queries))
+
+	for id, query := range queries {
+		gv.persistedQueries[id] = query
+		gv.persistedQueries[hashQuery(query)] = query
+		gv.allowed[query] = true
+	}
+}
+
+// LoadSchema gets the SDL schema of a GraphQL endpoint and verifies that it
+// is correct.
+func (gv *GraphQLValidator) LoadSchema(path string, method string, rawSchema []byte) error {
+	s, err := buildSchema(string(rawSchema))
+	if err != nil {
+		return errors.Wrap(err, "could not load schema to path "+path)
+	}
+
+	gv.mutex.Lock()
+	defer gv.mutex.Unlock()
+
+	if gv.schemaDict[path] == nil {
+		gv.schemaDict[path] = make(map[string]*schema)
+	}
+
+	gv.schemaDict[path][method] = s
+
+	return nil
+}
+
+// Validate parses the GraphQL requests in a body and validates their
+// operations and variables against the schema of the endpoint.
+func (gv *GraphQLValidator) Validate(path string, method string, body []byte) error {
+	gv.mutex.RLock()
+	schemas, isPathExist := gv.schemaDict[path]
+	s, isMethodExist := schemas[method]
+	gv.mutex.RUnlock()
+
+	if !isPathExist {
+		return errors.New("could not validate request: unknown path \"" + path + "\"")
+	}
+
+	if !isMethodExist {
+		return errors.New("could not validate to path " + path +
+			": no schema exist for method \"" + method + "\"")
+	}
+
+	requests, err := parseRequests(body)
+	if err != nil {
+		return err
+	}
+
+	for _, req := range requests {
+		err = gv.validateRequest(s, req)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseRequests reads the GraphQL requests of a body
+func parseRequests(body []byte) ([]request, error) {
+	trimmed := bytes.TrimSpace(body)
+
+	if len(trimmed) == 0 {
+		return nil, errors.New("could not read GraphQL request: the body is empty")
+	}
+
+	// A body that is not json is a bare query, which may start with "{"
+	// too (a query shorthand)
+	if !json.Valid(trimmed) {
+		return []request{{Query: string(body)}}, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	decoder.UseNumber()
+
+	switch trimmed[0] {
+	case '{':
+		var req request
+
+		err := decoder.Decode(&req)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read GraphQL request")
+		}
+
+		return []request{req}, nil
+	case '[':
+		var batch []request
+
+		err := decoder.Decode(&batch)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read GraphQL requests")
+		}
+
+		if len(batch) == 0 {
+			return nil, errors.New("could not read GraphQL requests: the batch is empty")
+		}
+
+		return batch, nil
+	default:
+		return nil, errors.New("could not read GraphQL request: expected an object or an array")
+	}
+}
+
+// validateRequest validates a single GraphQL request
+func (gv *GraphQLValidator) validateRequest(s *schema, req request) error {
+	query, err := gv.resolveQuery(req)
+	if err != nil {
+		return err
+	}
+
+	doc, err := parseDocument(query)
+	if err != nil {
+		return err
+	}
+
+	errs := validateDocument(s, doc)
+	if len(errs) > 0 {
+		return errs.result(gv.exhaustive)
+	}
+
+	op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		return err
+	}
+
+	errs = validateVariables(s, op, req.Variables)
+	errs = append(errs, checkLimits(s, doc, op, req.Variables, gv.limits)...)
+
+	return errs.result(gv.exhaustive)
+}
+
+// resolveQuery returns the query of a request, which is looked up in the
+// persisted queries when the request sends only its id, and makes sure
+// that it is allowed
+func (gv *GraphQLValidator) resolveQuery(req request) (string, error) {
+	id := req.DocumentID
+	if id == "" {
+		id = req.Extensions.PersistedQuery.Sha256Hash
+	}
+
+	switch {
+	case req.Query == "" && id == "":
+		return "", RequestValidationError{keyword: "query", reason: "the request holds no query"}
+	case req.Query == "" && gv.persistedQueries == nil:
+		return "", RequestValidationError{
+			path:    "/extensions/persistedQuery",
+			keyword: "persistedQuery",
+			reason:  "queries that are sent by their id cannot be validated, send the query",
+		}
+	case req.Query == "":
+		query, ok := gv.persistedQueries[id]
+		if !ok {
+			return "", RequestValidationError{
+				keyword: "persistedQuery",
+				reason:  "the query \"" + id + "\" is not a persisted query",
+			}
+		}
+
+		return query, nil
+	case gv.allowed != nil && !gv.allowed[req.Query]:
+		return "", RequestValidationError{keyword: "persistedQuery", reason: "the query is not a persisted query"}
+	case id != "" && gv.persistedQueries != nil && gv.persistedQueries[id] != req.Query:
+		return "", RequestValidationError{
+			keyword: "persistedQuery",
+			reason:  "the query does not match the persisted query \"" + id + "\"",
+		}
+	case req.Extensions.PersistedQuery.Sha256Hash != "" &&
+		req.Extensions.PersistedQuery.Sha256Hash != hashQuery(req.Query):
+		return "", RequestValidationError{
+			path:    "/extensions/persistedQuery/sha256Hash",
+			keyword: "persistedQuery",
+			reason:  "the hash does not match the query",
+		}
+	}
+
+	return req.Query, nil
+}
+
+// selectOperation returns the operation that a request executes
+func selectOperation(doc *document, operationName string) (*operation, error) {
+	if operationName == "" {
+		if len(doc.operations) > 1 {
+			return nil, RequestValidationError{
+				path:    "/operationName",
+				keyword: "operationName",
+				reason:  "the document holds several operations, an operation name is required",
+			}
+		}
+
+		return doc.operations[0], nil
+	}
+
+	for _, op := range doc.operations {
+		if op.name == operationName {
+			return op, nil
+		}
+	}
+
+	return nil, RequestValidationError{
+		path:    "/operationName",
+		keyword: "operationName",
+		reason:  "unknown operation \"" + operationName + "\"",
+	}
+}
+
+// hashQuery returns the hex encoded sha256 hash of a query, which is the
+// id of automatic persisted queries
+func hashQuery(query string) string {
+	hash := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(hash[:])
+}
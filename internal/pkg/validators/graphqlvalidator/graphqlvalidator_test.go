@@ -0,0 +1,526 @@
+package graphqlvalidator_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/apidome/gateway/internal/pkg/validators"
+	"github.com/apidome/gateway/internal/pkg/validators/graphqlvalidator"
+)
+
+const succeed = "V"
+const failed = "X"
+
+const shopSchema = `
+"""
+The orders of a shop
+"""
+type Query {
+  order(id: ID!): Order
+  orders(first: Int = 10, status: Status): [Order!]!
+  search(text: String!): [SearchResult]
+  node(id: ID!): Node
+}
+
+type Mutation {
+  createOrder(input: OrderInput!): Order
+}
+
+type Subscription {
+  orderUpdated(id: ID!): Order
+  orderCreated: Order
+}
+
+interface Node {
+  id: ID!
+}
+
+type Order implements Node {
+  id: ID!
+  status: Status!
+  total: Float
+  note: String
+  items(first: Int, last: Int): [Item!]!
+  customer: Customer
+}
+
+type Item implements Node {
+  id: ID!
+  name: String!
+  quantity: Int!
+}
+
+type Customer implements Node {
+  id: ID!
+  name: String
+  orders(first: Int): [Order]
+}
+
+union SearchResult = Order | Customer
+
+enum Status {
+  OPEN
+  PAID
+  SHIPPED
+}
+
+scalar DateTime
+
+input OrderInput {
+  customerId: ID!
+  items: [ItemInput!]!
+  note: String
+  deliverAt: DateTime
+}
+
+input ItemInput {
+  name: String!
+  quantity: Int = 1
+}
+`
+
+// loadValidator returns a validator of the shop schema
+func loadValidator(t *testing.T) *graphqlvalidator.GraphQLValidator {
+	validator := graphqlvalidator.NewGraphQLValidator()
+
+	err := validator.LoadSchema("/graphql", "POST", []byte(shopSchema))
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to load a schema: %v", failed, err)
+	}
+
+	return validator
+}
+
+// keywordOf returns the keyword of the validation failure of an error, or
+// an empty string if it is not a validation failure
+func keywordOf(err error) string {
+	validationErr, ok := err.(validators.ValidationError)
+	if !ok {
+		return ""
+	}
+
+	return validationErr.Keyword()
+}
+
+func TestLoadSchema(t *testing.T) {
+	testCases := []struct {
+		description string
+		schema      string
+		valid       bool
+	}{
+		{"a schema", shopSchema, true},
+		{
+			"a schema with an explicit schema definition",
+			"schema { query: Root } type Root { hello: String }",
+			true,
+		},
+		{
+			"a schema with type extensions",
+			"type Query { a: Int } extend type Query { b: Int }",
+			true,
+		},
+		{"a schema without a query type", "type Mutation { a: Int }", false},
+		{"a schema with a syntax error", "type Query { a: Int", false},
+		{"a schema with an unknown type", "type Query { a: Missing }", false},
+		{"a schema with an input type as a field type", "input In { a: Int } type Query { a: In }", false},
+		{"a schema with an output type as an argument type", "type Query { a(b: Query): Int }", false},
+		{"a schema with a duplicate type", "type Query { a: Int } type Query { b: Int }", false},
+		{
+			"a schema whose object misses the fields of its interface",
+			"interface Node { id: ID! } type Query implements Node { a: Int }",
+			false,
+		},
+		{"a schema with a union of scalars", "union U = Int type Query { a: U }", false},
+	}
+
+	t.Log("Given the need to test the loading of GraphQL schemas")
+	{
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When given %s", index, testCase.description)
+			{
+				validator := graphqlvalidator.NewGraphQLValidator()
+
+				err := validator.LoadSchema("/graphql", "POST", []byte(testCase.schema))
+				if testCase.valid != (err == nil) {
+					t.Errorf("\t%s\tShould return an error only for invalid schemas: %v", failed, err)
+				} else {
+					t.Logf("\t%s\tShould return an error only for invalid schemas", succeed)
+				}
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		description string
+		body        string
+		keyword     string
+		valid       bool
+	}{
+		{
+			"a query",
+			`{"query": "{ order(id: 1) { id status total } }"}`,
+			"",
+			true,
+		},
+		{
+			"a bare query",
+			`query { orders { id items { name } } }`,
+			"",
+			true,
+		},
+		{
+			"a query with fragments",
+			`{"query": "query Q { search(text: \"a\") { ...on Order { ...OrderFields } ... on Customer { name } } } fragment OrderFields on Order { id total }"}`,
+			"",
+			true,
+		},
+		{
+			"a query with variables",
+			`{"query": "query Q($id: ID!, $status: Status) { order(id: $id) { id } orders(status: $status) { id } }", "variables": {"id": "o-1", "status": "PAID"}}`,
+			"",
+			true,
+		},
+		{
+			"a mutation with an input object variable",
+			`{"query": "mutation M($input: OrderInput!) { createOrder(input: $input) { id } }", "variables": {"input": {"customerId": 7, "items": {"name": "pen"}, "deliverAt": {"any": "value"}}}}`,
+			"",
+			true,
+		},
+		{
+			"a query with directives and introspection fields",
+			`{"query": "query Q($full: Boolean!) { __typename __type(name: \"Order\") { name } order(id: 1) { id total @include(if: $full) } }", "variables": {"full": true}}`,
+			"",
+			true,
+		},
+		{
+			"a batch of queries",
+			`[{"query": "{ order(id: 1) { id } }"}, {"query": "{ orders { id } }"}]`,
+			"",
+			true,
+		},
+		{
+			"a document with several operations and an operation name",
+			`{"query": "query A { orders { id } } query B { order(id: 1) { id } }", "operationName": "B"}`,
+			"",
+			true,
+		},
+		{
+			"a batch with an invalid query",
+			`[{"query": "{ order(id: 1) { id } }"}, {"query": "{ orders { missing } }"}]`,
+			"fieldsOnCorrectType",
+			false,
+		},
+		{"a query with a syntax error", `{"query": "{ order(id: 1) { id }"}`, "", false},
+		{"a request without a query", `{"variables": {}}`, "query", false},
+		{"an unknown field", `{"query": "{ order(id: 1) { name } }"}`, "fieldsOnCorrectType", false},
+		{"a field of a union", `{"query": "{ search(text: \"a\") { id } }"}`, "fieldsOnCorrectType", false},
+		{"an object without subfields", `{"query": "{ order(id: 1) }"}`, "scalarLeafs", false},
+		{"a scalar with subfields", `{"query": "{ order(id: 1) { id { a } } }"}`, "scalarLeafs", false},
+		{"an unknown argument", `{"query": "{ order(id: 1, x: 2) { id } }"}`, "knownArgumentNames", false},
+		{"a missing required argument", `{"query": "{ order { id } }"}`, "providedRequiredArguments", false},
+		{"a duplicate argument", `{"query": "{ order(id: 1, id: 2) { id } }"}`, "uniqueArgumentNames", false},
+		{"a string for an Int argument", `{"query": "{ orders(first: \"a\") { id } }"}`, "valuesOfCorrectType", false},
+		{"an Int that overflows 32 bits", `{"query": "{ orders(first: 3000000000) { id } }"}`, "valuesOfCorrectType", false},
+		{"an unknown enum value", `{"query": "{ orders(status: LOST) { id } }"}`, "valuesOfCorrectType", false},
+		{"null for a non-null argument", `{"query": "{ order(id: null) { id } }"}`, "valuesOfCorrectType", false},
+		{
+			"an input object without a required field",
+			`{"query": "mutation { createOrder(input: {items: []}) { id } }"}`,
+			"valuesOfCorrectType",
+			false,
+		},
+		{"an unknown fragment", `{"query": "{ orders { ...Missing } }"}`, "knownFragmentNames", false},
+		{
+			"an unused fragment",
+			`{"query": "{ orders { id } } fragment F on Order { id }"}`,
+			"noUnusedFragments",
+			false,
+		},
+		{
+			"a fragment cycle",
+			`{"query": "{ orders { ...A } } fragment A on Order { customer { orders { ...B } } } fragment B on Order { ...A }"}`,
+			"noFragmentCycles",
+			false,
+		},
+		{
+			"a fragment on a scalar",
+			`{"query": "{ orders { ... on Int { id } } }"}`,
+			"fragmentsOnCompositeTypes",
+			false,
+		},
+		{
+			"an impossible fragment spread",
+			`{"query": "{ orders { ... on Item { name } } }"}`,
+			"possibleFragmentSpreads",
+			false,
+		},
+		{
+			"an undefined variable",
+			`{"query": "query Q { order(id: $id) { id } }"}`,
+			"noUndefinedVariables",
+			false,
+		},
+		{
+			"an unused variable",
+			`{"query": "query Q($id: ID) { orders { id } }"}`,
+			"noUnusedVariables",
+			false,
+		},
+		{
+			"a nullable variable in a non-null position",
+			`{"query": "query Q($id: ID) { order(id: $id) { id } }", "variables": {"id": "1"}}`,
+			"variablesInAllowedPosition",
+			false,
+		},
+		{
+			"a variable of an output type",
+			`{"query": "query Q($o: Order) { orders { id } }"}`,
+			"variablesAreInputTypes",
+			false,
+		},
+		{"an unknown directive", `{"query": "{ orders @cached { id } }"}`, "knownDirectives", false},
+		{"a directive in a wrong location", `query Q @include(if: true) { orders { id } }`, "knownDirectives", false},
+		{
+			"fields that cannot be merged",
+			`{"query": "{ orders { id: total id } }"}`,
+			"overlappingFieldsCanBeMerged",
+			false,
+		},
+		{
+			"fields whose subfields cannot be merged",
+			`{"query": "{ orders { id: total } orders { id } }"}`,
+			"overlappingFieldsCanBeMerged",
+			false,
+		},
+		{
+			"fields that are merged on different object types",
+			`{"query": "{ search(text: \"a\") { ... on Order { text: note } ... on Customer { text: name } } }"}`,
+			"",
+			true,
+		},
+		{
+			"an anonymous operation with another operation",
+			`{"query": "{ orders { id } } query A { orders { id } }", "operationName": "A"}`,
+			"loneAnonymousOperation",
+			false,
+		},
+		{
+			"two operations with the same name",
+			`{"query": "query A { orders { id } } query A { order(id: 1) { id } }", "operationName": "A"}`,
+			"uniqueOperationNames",
+			false,
+		},
+		{
+			"a subscription of two fields",
+			`{"query": "subscription { orderCreated { id } orderUpdated(id: 1) { id } }"}`,
+			"singleFieldSubscriptions",
+			false,
+		},
+		{
+			"several operations without an operation name",
+			`{"query": "query A { orders { id } } query B { orders { id } }"}`,
+			"operationName",
+			false,
+		},
+		{
+			"an unknown operation name",
+			`{"query": "query A { orders { id } }", "operationName": "B"}`,
+			"operationName",
+			false,
+		},
+		{
+			"a missing required variable",
+			`{"query": "query Q($id: ID!) { order(id: $id) { id } }"}`,
+			"variables",
+			false,
+		},
+		{
+			"a variable of a wrong type",
+			`{"query": "query Q($first: Int) { orders(first: $first) { id } }", "variables": {"first": 1.5}}`,
+			"variables",
+			false,
+		},
+		{
+			"a variable with an unknown enum value",
+			`{"query": "query Q($status: Status) { orders(status: $status) { id } }", "variables": {"status": "LOST"}}`,
+			"variables",
+			false,
+		},
+		{
+			"an input object variable with an unknown field",
+			`{"query": "mutation M($input: OrderInput!) { createOrder(input: $input) { id } }", "variables": {"input": {"customerId": "1", "items": [], "color": "red"}}}`,
+			"variables",
+			false,
+		},
+		{
+			"an input object variable with a null item",
+			`{"query": "mutation M($input: OrderInput!) { createOrder(input: $input) { id } }", "variables": {"input": {"customerId": "1", "items": [null]}}}`,
+			"variables",
+			false,
+		},
+	}
+
+	t.Log("Given the need to test the validation of GraphQL requests")
+	{
+		validator := loadValidator(t)
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When given %s", index, testCase.description)
+			{
+				err := validator.Validate("/graphql", "POST", []byte(testCase.body))
+				if testCase.valid {
+					if err != nil {
+						t.Errorf("\t%s\tShould be valid: %v", failed, err)
+					} else {
+						t.Logf("\t%s\tShould be valid", succeed)
+					}
+				} else {
+					if err == nil {
+						t.Errorf("\t%s\tShould not be valid", failed)
+					} else if keywordOf(err) != testCase.keyword {
+						t.Errorf("\t%s\tShould fail in \"%s\": %v", failed, testCase.keyword, err)
+					} else {
+						t.Logf("\t%s\tShould not be valid: %v", succeed, err)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestValidateExhaustive(t *testing.T) {
+	t.Log("Given the need to test the collection of all the failures of a request")
+	{
+		validator := loadValidator(t)
+		validator.SetExhaustive(true)
+
+		err := validator.Validate("/graphql", "POST", []byte(`{ order { name } orders(first: "a") { id } }`))
+
+		validationErrs, ok := err.(validators.ValidationErrors)
+		if !ok || len(validationErrs.Errors()) != 3 {
+			t.Errorf("\t%s\tShould return 3 failures: %v", failed, err)
+		} else {
+			t.Logf("\t%s\tShould return 3 failures", succeed)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	testCases := []struct {
+		description string
+		limits      graphqlvalidator.Limits
+		body        string
+		keyword     string
+	}{
+		{
+			"a query within the limits",
+			graphqlvalidator.Limits{MaxDepth: 3, MaxAliases: 1, MaxComplexity: 30},
+			`{ orders(first: 5) { id a: total } }`,
+			"",
+		},
+		{
+			"a query that is too deep",
+			graphqlvalidator.Limits{MaxDepth: 3},
+			`{ orders { customer { orders { id } } } }`,
+			"maxDepth",
+		},
+		{
+			"a query that is too deep through fragments",
+			graphqlvalidator.Limits{MaxDepth: 3},
+			`{ orders { ...F } } fragment F on Order { customer { orders { id } } }`,
+			"maxDepth",
+		},
+		{
+			"a query with too many aliases",
+			graphqlvalidator.Limits{MaxAliases: 2},
+			`{ a: orders { id } b: orders { id } c: orders { id } }`,
+			"maxAliases",
+		},
+		{
+			"a query that is too complex",
+			graphqlvalidator.Limits{MaxComplexity: 100},
+			`{ orders(first: 10) { items(first: 10) { name } } }`,
+			"maxComplexity",
+		},
+		{
+			"a query whose complexity depends on a variable",
+			graphqlvalidator.Limits{MaxComplexity: 100},
+			`{"query": "query Q($n: Int) { orders(first: $n) { id } }", "variables": {"n": 1000}}`,
+			"maxComplexity",
+		},
+		{
+			"a query whose complexity overflows",
+			graphqlvalidator.Limits{MaxComplexity: 100},
+			`{ orders(first: 2000000000) { customer { orders(first: 2000000000) { customer { orders(first: 2000000000) { id } } } } } }`,
+			"maxComplexity",
+		},
+	}
+
+	t.Log("Given the need to test the limits of GraphQL operations")
+	{
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When given %s", index, testCase.description)
+			{
+				validator := loadValidator(t)
+				validator.SetLimits(testCase.limits)
+
+				err := validator.Validate("/graphql", "POST", []byte(testCase.body))
+				if keywordOf(err) != testCase.keyword || (testCase.keyword == "" && err != nil) {
+					t.Errorf("\t%s\tShould fail only in \"%s\": %v", failed, testCase.keyword, err)
+				} else {
+					t.Logf("\t%s\tShould fail only in \"%s\"", succeed, testCase.keyword)
+				}
+			}
+		}
+	}
+}
+
+func TestPersistedQueries(t *testing.T) {
+	const query = "{ orders { id } }"
+
+	hash := sha256.Sum256([]byte(query))
+	otherHash := sha256.Sum256([]byte("{ order(id: 1) { id } }"))
+
+	testCases := []struct {
+		description string
+		body        string
+		valid       bool
+	}{
+		{"an allowed query", `{"query": "{ orders { id } }"}`, true},
+		{"the id of an allowed query", `{"documentId": "orders"}`, true},
+		{"a query that is not allowed", `{"query": "{ order(id: 1) { id } }"}`, false},
+		{"an unknown id", `{"documentId": "missing"}`, false},
+		{
+			"the hash of an allowed query",
+			`{"extensions": {"persistedQuery": {"sha256Hash": "` + hex.EncodeToString(hash[:]) + `"}}}`,
+			true,
+		},
+		{
+			"a hash that does not match the query",
+			`{"query": "{ orders { id } }", "extensions": {"persistedQuery": {"sha256Hash": "` +
+				hex.EncodeToString(otherHash[:]) + `"}}}`,
+			false,
+		},
+	}
+
+	t.Log("Given the need to test the allowlist of persisted queries")
+	{
+		validator := loadValidator(t)
+		validator.SetPersistedQueries(map[string]string{"orders": query})
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When given %s", index, testCase.description)
+			{
+				err := validator.Validate("/graphql", "POST", []byte(testCase.body))
+				if testCase.valid != (err == nil) {
+					t.Errorf("\t%s\tShould allow only persisted queries: %v", failed, err)
+				} else {
+					t.Logf("\t%s\tShould allow only persisted queries", succeed)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package graphqlvalidator
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// Kinds of tokens
+const (
+	tokenEOF = iota
+	tokenPunctuator
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+	tokenBlockString
+)
+
+// token is a lexical token of a GraphQL document. The value of string
+// tokens is the string that they represent, without quotes and escapes.
+type token struct {
+	kind   int
+	value  string
+	line   int
+	column int
+}
+
+// lexer splits a GraphQL document into tokens, skipping the ignored tokens
+// (white space, line terminators, commas and comments)
+type lexer struct {
+	source    string
+	position  int
+	line      int
+	lineStart int
+}
+
+func newLexer(source string) *lexer {
+	// A byte order mark is ignored
+	source = strings.TrimPrefix(source, "\ufeff")
+
+	return &lexer{source: source, line: 1}
+}
+
+// next reads the next token
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+
+	tok := token{line: l.line, column: l.position - l.lineStart + 1}
+
+	if l.position >= len(l.source) {
+		tok.kind = tokenEOF
+		return tok, nil
+	}
+
+	c := l.source[l.position]
+
+	switch {
+	case strings.IndexByte("!$&():=@[]{}|", c) >= 0:
+		l.position++
+		tok.kind = tokenPunctuator
+		tok.value = string(c)
+	case c == '.':
+		if !strings.HasPrefix(l.source[l.position:], "...") {
+			return tok, l.errorAt(tok, "unexpected \".\", did you mean \"...\"?")
+		}
+
+		l.position += 3
+		tok.kind = tokenPunctuator
+		tok.value = "..."
+	case c == '_' || isLetter(c):
+		start := l.position
+		for l.position < len(l.source) && isNameContinue(l.source[l.position]) {
+			l.position++
+		}
+
+		tok.kind = tokenName
+		tok.value = l.source[start:l.position]
+	case c == '-' || isDigit(c):
+		return l.readNumber(tok)
+	case c == '"':
+		if strings.HasPrefix(l.source[l.position:], `"""`) {
+			return l.readBlockString(tok)
+		}
+
+		return l.readString(tok)
+	default:
+		r, _ := utf8.DecodeRuneInString(l.source[l.position:])
+		return tok, l.errorAt(tok, "unexpected character "+strconv.QuoteRune(r))
+	}
+
+	return tok, nil
+}
+
+// skipIgnored skips white space, line terminators, commas and comments
+func (l *lexer) skipIgnored() {
+	for l.position < len(l.source) {
+		switch l.source[l.position] {
+		case ' ', '\t', ',':
+			l.position++
+		case '\n':
+			l.newLine(1)
+		case '\r':
+			if strings.HasPrefix(l.source[l.position:], "\r\n") {
+				l.newLine(2)
+			} else {
+				l.newLine(1)
+			}
+		case '#':
+			for l.position < len(l.source) &&
+				l.source[l.position] != '\n' && l.source[l.position] != '\r' {
+				l.position++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// newLine skips a line terminator of the given size
+func (l *lexer) newLine(size int) {
+	l.position += size
+	l.line++
+	l.lineStart = l.position
+}
+
+// readNumber reads an IntValue or a FloatValue
+func (l *lexer) readNumber(tok token) (token, error) {
+	start := l.position
+	tok.kind = tokenInt
+
+	if l.source[l.position] == '-' {
+		l.position++
+	}
+
+	digits := l.readDigits()
+	if digits == 0 {
+		return tok, l.errorAt(tok, "invalid number, expected a digit")
+	}
+
+	if digits > 1 && l.source[l.position-digits] == '0' {
+		return tok, l.errorAt(tok, "invalid number, unexpected digit after 0")
+	}
+
+	if l.position < len(l.source) && l.source[l.position] == '.' {
+		l.position++
+		tok.kind = tokenFloat
+
+		if l.readDigits() == 0 {
+			return tok, l.errorAt(tok, "invalid number, expected a digit after \".\"")
+		}
+	}
+
+	if l.position < len(l.source) && (l.source[l.position] == 'e' || l.source[l.position] == 'E') {
+		l.position++
+		tok.kind = tokenFloat
+
+		if l.position < len(l.source) && (l.source[l.position] == '+' || l.source[l.position] == '-') {
+			l.position++
+		}
+
+		if l.readDigits() == 0 {
+			return tok, l.errorAt(tok, "invalid number, expected a digit in the exponent")
+		}
+	}
+
+	// A number may not be followed by a name or a dot
+	if l.position < len(l.source) &&
+		(l.source[l.position] == '.' || l.source[l.position] == '_' || isLetter(l.source[l.position])) {
+		return tok, l.errorAt(tok, "invalid number, unexpected "+strconv.Quote(l.source[l.position:l.position+1]))
+	}
+
+	tok.value = l.source[start:l.position]
+
+	return tok, nil
+}
+
+// readDigits skips digits and returns their number
+func (l *lexer) readDigits() int {
+	start := l.position
+	for l.position < len(l.source) && isDigit(l.source[l.position]) {
+		l.position++
+	}
+
+	return l.position - start
+}
+
+// readString reads a quoted StringValue
+func (l *lexer) readString(tok token) (token, error) {
+	var builder strings.Builder
+
+	l.position++
+
+	for {
+		if l.position >= len(l.source) || l.source[l.position] == '\n' || l.source[l.position] == '\r' {
+			return tok, l.errorAt(tok, "unterminated string")
+		}
+
+		c := l.source[l.position]
+
+		switch {
+		case c == '"':
+			l.position++
+			tok.kind = tokenString
+			tok.value = builder.String()
+
+			return tok, nil
+		case c == '\\':
+			if l.position+1 >= len(l.source) {
+				return tok, l.errorAt(tok, "unterminated string")
+			}
+
+			escaped := l.source[l.position+1]
+			l.position += 2
+
+			switch escaped {
+			case '"', '\\', '/':
+				builder.WriteByte(escaped)
+			case 'b':
+				builder.WriteByte('\b')
+			case 'f':
+				builder.WriteByte('\f')
+			case 'n':
+				builder.WriteByte('\n')
+			case 'r':
+				builder.WriteByte('\r')
+			case 't':
+				builder.WriteByte('\t')
+			case 'u':
+				r, err := l.readUnicodeEscape()
+				if err != nil {
+					return tok, l.errorAt(tok, err.Error())
+				}
+
+				builder.WriteRune(r)
+			default:
+				return tok, l.errorAt(tok, "invalid escape sequence \\"+string(escaped))
+			}
+		case c < ' ' && c != '\t':
+			return tok, l.errorAt(tok, "invalid character in string")
+		default:
+			r, size := utf8.DecodeRuneInString(l.source[l.position:])
+			if r == utf8.RuneError && size == 1 {
+				return tok, l.errorAt(tok, "invalid UTF-8 in string")
+			}
+
+			builder.WriteString(l.source[l.position : l.position+size])
+			l.position += size
+		}
+	}
+}
+
+// readUnicodeEscape reads the digits of a \u escape sequence, which may be
+// a surrogate pair ("\uD83D\uDE00") or a variable width escape ("\u{1F600}")
+func (l *lexer) readUnicodeEscape() (rune, error) {
+	if l.position < len(l.source) && l.source[l.position] == '{' {
+		end := strings.IndexByte(l.source[l.position:], '}')
+		if end < 0 {
+			return 0, errors.New("invalid unicode escape sequence")
+		}
+
+		value, err := strconv.ParseUint(l.source[l.position+1:l.position+end], 16, 32)
+		if err != nil || value > utf8.MaxRune || (value >= 0xD800 && value <= 0xDFFF) {
+			return 0, errors.New("invalid unicode escape sequence")
+		}
+
+		l.position += end + 1
+
+		return rune(value), nil
+	}
+
+	value, ok := l.readHex4()
+	if !ok {
+		return 0, errors.New("invalid unicode escape sequence")
+	}
+
+	// A leading surrogate must be followed by a trailing one
+	if value >= 0xD800 && value <= 0xDBFF {
+		if !strings.HasPrefix(l.source[l.position:], `\u`) {
+			return 0, errors.New("invalid unicode escape sequence, unpaired surrogate")
+		}
+
+		l.position += 2
+
+		trailing, ok := l.readHex4()
+		if !ok || trailing < 0xDC00 || trailing > 0xDFFF {
+			return 0, errors.New("invalid unicode escape sequence, unpaired surrogate")
+		}
+
+		return (value-0xD800)<<10 + (trailing - 0xDC00) + 0x10000, nil
+	}
+
+	if value >= 0xDC00 && value <= 0xDFFF {
+		return 0, errors.New("invalid unicode escape sequence, unpaired surrogate")
+	}
+
+	return value, nil
+}
+
+// readHex4 reads 4 hexadecimal digits
+func (l *lexer) readHex4() (rune, bool) {
+	if l.position+4 > len(l.source) {
+		return 0, false
+	}
+
+	value, err := strconv.ParseUint(l.source[l.position:l.position+4], 16, 32)
+	if err != nil {
+		return 0, false
+	}
+
+	l.position += 4
+
+	return rune(value), true
+}
+
+// readBlockString reads a triple quoted StringValue, whose value is its
+// lines without their common indentation and without leading and trailing
+// blank lines
+func (l *lexer) readBlockString(tok token) (token, error) {
+	var builder strings.Builder
+
+	l.position += 3
+
+	for {
+		if l.position >= len(l.source) {
+			return tok, l.errorAt(tok, "unterminated block string")
+		}
+
+		switch {
+		case strings.HasPrefix(l.source[l.position:], `"""`):
+			l.position += 3
+			tok.kind = tokenBlockString
+			tok.value = blockStringValue(builder.String())
+
+			return tok, nil
+		case strings.HasPrefix(l.source[l.position:], `\"""`):
+			builder.WriteString(`"""`)
+			l.position += 4
+		case l.source[l.position] == '\n':
+			builder.WriteByte('\n')
+			l.newLine(1)
+		case l.source[l.position] == '\r':
+			builder.WriteByte('\n')
+
+			if strings.HasPrefix(l.source[l.position:], "\r\n") {
+				l.newLine(2)
+			} else {
+				l.newLine(1)
+			}
+		default:
+			builder.WriteByte(l.source[l.position])
+			l.position++
+		}
+	}
+}
+
+// blockStringValue removes the common indentation and the leading and
+// trailing blank lines of the lines of a block string
+func blockStringValue(raw string) string {
+	lines := strings.Split(raw, "\n")
+
+	// The first line is not indented
+	commonIndent := -1
+	for _, line := range lines[1:] {
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < len(line) && (commonIndent < 0 || indent < commonIndent) {
+			commonIndent = indent
+		}
+	}
+
+	if commonIndent > 0 {
+		for index := 1; index < len(lines); index++ {
+			if len(lines[index]) >= commonIndent {
+				lines[index] = lines[index][commonIndent:]
+			} else {
+				lines[index] = ""
+			}
+		}
+	}
+
+	for len(lines) > 0 && strings.TrimLeft(lines[0], " \t") == "" {
+		lines = lines[1:]
+	}
+
+	for len(lines) > 0 && strings.TrimLeft(lines[len(lines)-1], " \t") == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// errorAt creates a syntax error at the location of a token
+func (l *lexer) errorAt(tok token, reason string) error {
+	return SyntaxError{line: tok.line, column: tok.column, reason: reason}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameContinue(c byte) bool {
+	return c == '_' || isLetter(c) || isDigit(c)
+}
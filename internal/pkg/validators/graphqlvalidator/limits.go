@@ -0,0 +1,222 @@
+package graphqlvalidator
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// maxCost bounds the computed costs of operations, so a crafted operation
+// cannot overflow them
+const maxCost = 1 << 40
+
+// paginationArguments are the arguments that bound the number of items
+// that a list field returns
+var paginationArguments = []string{"first", "last", "limit"}
+
+// Limits bound the operations that requests may execute. A zero limit is
+// not enforced.
+type Limits struct {
+	// MaxDepth is the maximum nesting of fields, where the root fields are
+	// at depth 1.
+	MaxDepth int
+
+	// MaxAliases is the maximum number of aliased fields.
+	MaxAliases int
+
+	// MaxComplexity is the maximum complexity of an operation. Each field
+	// costs 1 plus the complexity of its subfields, which is multiplied by
+	// the value of the field's "first", "last" or "limit" argument when it
+	// returns a list.
+	MaxComplexity int
+}
+
+// cost is the measure of a selection set
+type cost struct {
+	depth      int64
+	aliases    int64
+	complexity int64
+}
+
+// add adds the cost of another selection of the same selection set
+func (c *cost) add(other cost) {
+	if other.depth > c.depth {
+		c.depth = other.depth
+	}
+
+	c.aliases = saturate(c.aliases + other.aliases)
+	c.complexity = saturate(c.complexity + other.complexity)
+}
+
+// measurement measures an operation, with the values of its variables
+type measurement struct {
+	schema    *schema
+	fragments map[string]*fragment
+	variables map[string]interface{}
+
+	// measured holds the costs of the fragments that were already
+	// measured
+	measured map[string]cost
+}
+
+// checkLimits returns the limits that an operation exceeds
+func checkLimits(s *schema, doc *document, op *operation,
+	variables map[string]interface{}, limits Limits) ValidationErrors {
+	if limits.MaxDepth <= 0 && limits.MaxAliases <= 0 && limits.MaxComplexity <= 0 {
+		return nil
+	}
+
+	m := &measurement{
+		schema:    s,
+		fragments: make(map[string]*fragment),
+		variables: variables,
+		measured:  make(map[string]cost),
+	}
+
+	for _, frag := range doc.fragments {
+		m.fragments[frag.name] = frag
+	}
+
+	total := m.selectionSet(s.types[s.rootTypes[op.kind]], op.selectionSet)
+
+	var errs ValidationErrors
+
+	check := func(keyword string, measured int64, limit int, description string) {
+		if limit > 0 && measured > int64(limit) {
+			errs = append(errs, RequestValidationError{
+				path:    formatLocation(op.location),
+				keyword: keyword,
+				reason: "the operation " + description + " " + strconv.FormatInt(measured, 10) +
+					", more than the maximum of " + strconv.Itoa(limit),
+			})
+		}
+	}
+
+	check("maxDepth", total.depth, limits.MaxDepth, "has a depth of")
+	check("maxAliases", total.aliases, limits.MaxAliases, "has a number of aliases of")
+	check("maxComplexity", total.complexity, limits.MaxComplexity, "has a complexity of")
+
+	return errs
+}
+
+// selectionSet measures a selection set on a composite type
+func (m *measurement) selectionSet(parent *namedType, selectionSet []selection) cost {
+	var total cost
+
+	for _, sel := range selectionSet {
+		switch s := sel.(type) {
+		case *field:
+			total.add(m.field(parent, s))
+		case *inlineFragment:
+			t := parent
+			if s.typeCondition != "" {
+				t = m.schema.types[s.typeCondition]
+			}
+
+			total.add(m.selectionSet(t, s.selectionSet))
+		case *fragmentSpread:
+			total.add(m.fragment(s.name))
+		}
+	}
+
+	return total
+}
+
+// fragment measures a named fragment once
+func (m *measurement) fragment(name string) cost {
+	if measured, ok := m.measured[name]; ok {
+		return measured
+	}
+
+	frag := m.fragments[name]
+	measured := m.selectionSet(m.schema.types[frag.typeCondition], frag.selectionSet)
+	m.measured[name] = measured
+
+	return measured
+}
+
+// field measures a field selection
+func (m *measurement) field(parent *namedType, f *field) cost {
+	var children cost
+
+	definition := m.schema.field(parent, f.name)
+
+	if len(f.selectionSet) > 0 {
+		children = m.selectionSet(m.schema.types[definition.typeRef.namedType()], f.selectionSet)
+
+		if returnsList(definition.typeRef) {
+			children.complexity = multiply(children.complexity, m.pageSize(f))
+		}
+	}
+
+	measured := cost{
+		depth:      children.depth + 1,
+		aliases:    children.aliases,
+		complexity: saturate(children.complexity + 1),
+	}
+
+	if f.alias != "" {
+		measured.aliases++
+	}
+
+	return measured
+}
+
+// pageSize returns the value of the pagination argument of a list field,
+// or 1 if it has none
+func (m *measurement) pageSize(f *field) int64 {
+	for _, arg := range f.arguments {
+		if !containsString(paginationArguments, arg.name) {
+			continue
+		}
+
+		var raw string
+
+		switch arg.value.kind {
+		case valueInt:
+			raw = arg.value.raw
+		case valueVariable:
+			number, ok := m.variables[arg.value.raw].(json.Number)
+			if !ok {
+				continue
+			}
+
+			raw = number.String()
+		default:
+			continue
+		}
+
+		size, err := strconv.ParseInt(raw, 10, 64)
+		if err == nil && size > 1 {
+			return saturate(size)
+		}
+	}
+
+	return 1
+}
+
+// returnsList returns true if a type is a list type
+func returnsList(t *typeRef) bool {
+	if t.nonNull {
+		t = t.ofType
+	}
+
+	return t.list
+}
+
+// multiply multiplies two costs without overflowing
+func multiply(a, b int64) int64 {
+	if b > 0 && a > maxCost/b {
+		return maxCost
+	}
+
+	return a * b
+}
+
+// saturate bounds a cost
+func saturate(value int64) int64 {
+	if value > maxCost || value < 0 {
+		return maxCost
+	}
+
+	return value
+}
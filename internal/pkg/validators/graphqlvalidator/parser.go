@@ -0,0 +1,1207 @@
+package graphqlvalidator
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// maxNesting limits the nesting of the selection sets, the values and the
+// types of a document, so a malicious document cannot exhaust the stack of
+// the parser
+const maxNesting = 256
+
+// parser parses GraphQL documents with a single token of lookahead
+type parser struct {
+	lexer   *lexer
+	token   token
+	nesting int
+}
+
+// parseDocument parses an executable document, which may only hold
+// operations and fragments
+func parseDocument(source string) (*document, error) {
+	p, err := newParser(source)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &document{}
+
+	for p.token.kind != tokenEOF {
+		switch {
+		case p.peekPunctuator("{"):
+			selectionSet, err := p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+
+			doc.operations = append(doc.operations, &operation{
+				location:     selectionSetLocation(selectionSet),
+				kind:         "query",
+				selectionSet: selectionSet,
+			})
+		case p.peekName("query"), p.peekName("mutation"), p.peekName("subscription"):
+			op, err := p.parseOperation()
+			if err != nil {
+				return nil, err
+			}
+
+			doc.operations = append(doc.operations, op)
+		case p.peekName("fragment"):
+			frag, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+
+			doc.fragments = append(doc.fragments, frag)
+		case p.token.kind == tokenName, p.token.kind == tokenString, p.token.kind == tokenBlockString:
+			return nil, p.errorf("unexpected %s, a request may only hold operations and fragments",
+				p.describe())
+		default:
+			return nil, p.unexpected()
+		}
+	}
+
+	if len(doc.operations) == 0 {
+		return nil, SyntaxError{line: 1, column: 1, reason: "the document does not hold an operation"}
+	}
+
+	return doc, nil
+}
+
+// selectionSetLocation returns the location of the first selection of a
+// selection set
+func selectionSetLocation(selectionSet []selection) location {
+	if len(selectionSet) == 0 {
+		return location{line: 1, column: 1}
+	}
+
+	return selectionSet[0].position()
+}
+
+// parseSchemaDocument parses a type system document
+func parseSchemaDocument(source string) (*schemaDocument, error) {
+	p, err := newParser(source)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &schemaDocument{}
+
+	for p.token.kind != tokenEOF {
+		// Descriptions do not matter for validation
+		if p.token.kind == tokenString || p.token.kind == tokenBlockString {
+			err = p.advance()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		extension := false
+		if p.peekName("extend") {
+			extension = true
+
+			err = p.advance()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		loc := p.location()
+
+		switch {
+		case p.peekName("schema"):
+			var schema *schemaDefinition
+
+			schema, err = p.parseSchemaDefinition(loc)
+			doc.schemas = append(doc.schemas, schema)
+		case p.peekName("directive") && !extension:
+			var definition *directiveDefinition
+
+			definition, err = p.parseDirectiveDefinition(loc)
+			doc.directives = append(doc.directives, definition)
+		case p.peekName("scalar"), p.peekName("type"), p.peekName("interface"),
+			p.peekName("union"), p.peekName("enum"), p.peekName("input"):
+			var definition *typeDefinition
+
+			definition, err = p.parseTypeDefinition(loc)
+			if definition != nil {
+				definition.extension = extension
+			}
+
+			doc.types = append(doc.types, definition)
+		default:
+			err = p.unexpected()
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return doc, nil
+}
+
+func newParser(source string) (*parser, error) {
+	p := &parser{lexer: newLexer(source)}
+
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// advance reads the next token
+func (p *parser) advance() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+
+	p.token = tok
+
+	return nil
+}
+
+// location returns the location of the current token
+func (p *parser) location() location {
+	return location{line: p.token.line, column: p.token.column}
+}
+
+// peekPunctuator returns true if the current token is the given punctuator
+func (p *parser) peekPunctuator(punctuator string) bool {
+	return p.token.kind == tokenPunctuator && p.token.value == punctuator
+}
+
+// peekName returns true if the current token is the given name
+func (p *parser) peekName(name string) bool {
+	return p.token.kind == tokenName && p.token.value == name
+}
+
+// skipPunctuator skips the current token if it is the given punctuator,
+// and returns true if it was skipped
+func (p *parser) skipPunctuator(punctuator string) (bool, error) {
+	if !p.peekPunctuator(punctuator) {
+		return false, nil
+	}
+
+	return true, p.advance()
+}
+
+// expectPunctuator skips the given punctuator, or fails if the current
+// token is not the punctuator
+func (p *parser) expectPunctuator(punctuator string) error {
+	if !p.peekPunctuator(punctuator) {
+		return p.errorf("expected \"%s\", found %s", punctuator, p.describe())
+	}
+
+	return p.advance()
+}
+
+// expectKeyword skips the given name, or fails if the current token is not
+// the name
+func (p *parser) expectKeyword(keyword string) error {
+	if !p.peekName(keyword) {
+		return p.errorf("expected \"%s\", found %s", keyword, p.describe())
+	}
+
+	return p.advance()
+}
+
+// parseName reads a name
+func (p *parser) parseName() (string, error) {
+	if p.token.kind != tokenName {
+		return "", p.errorf("expected a name, found %s", p.describe())
+	}
+
+	name := p.token.value
+
+	return name, p.advance()
+}
+
+// nest enters a nested construct, and fails if the document is nested too
+// deeply
+func (p *parser) nest() error {
+	p.nesting++
+	if p.nesting > maxNesting {
+		return p.errorf("the document is nested more than %d levels deep", maxNesting)
+	}
+
+	return nil
+}
+
+func (p *parser) unnest() {
+	p.nesting--
+}
+
+// parseOperation parses an operation with a name or variables
+func (p *parser) parseOperation() (*operation, error) {
+	op := &operation{location: p.location(), kind: p.token.value}
+
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.token.kind == tokenName {
+		op.name = p.token.value
+
+		err = p.advance()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.peekPunctuator("(") {
+		op.variables, err = p.parseVariableDefinitions()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	op.directives, err = p.parseDirectives(false)
+	if err != nil {
+		return nil, err
+	}
+
+	op.selectionSet, err = p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+// parseVariableDefinitions parses the variable definitions of an operation
+func (p *parser) parseVariableDefinitions() ([]*variableDefinition, error) {
+	var definitions []*variableDefinition
+
+	err := p.expectPunctuator("(")
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		closed, err := p.skipPunctuator(")")
+		if err != nil || closed {
+			return definitions, err
+		}
+
+		definition := &variableDefinition{location: p.location()}
+
+		err = p.expectPunctuator("$")
+		if err != nil {
+			return nil, err
+		}
+
+		definition.name, err = p.parseName()
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.expectPunctuator(":")
+		if err != nil {
+			return nil, err
+		}
+
+		definition.typeRef, err = p.parseTypeRef()
+		if err != nil {
+			return nil, err
+		}
+
+		hasDefault, err := p.skipPunctuator("=")
+		if err != nil {
+			return nil, err
+		}
+
+		if hasDefault {
+			definition.defaultValue, err = p.parseValue(true)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		definition.directives, err = p.parseDirectives(true)
+		if err != nil {
+			return nil, err
+		}
+
+		definitions = append(definitions, definition)
+	}
+}
+
+// parseFragment parses a fragment definition
+func (p *parser) parseFragment() (*fragment, error) {
+	frag := &fragment{location: p.location()}
+
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peekName("on") {
+		return nil, p.errorf("expected a fragment name, found \"on\"")
+	}
+
+	frag.name, err = p.parseName()
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.expectKeyword("on")
+	if err != nil {
+		return nil, err
+	}
+
+	frag.typeCondition, err = p.parseName()
+	if err != nil {
+		return nil, err
+	}
+
+	frag.directives, err = p.parseDirectives(false)
+	if err != nil {
+		return nil, err
+	}
+
+	frag.selectionSet, err = p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+
+	return frag, nil
+}
+
+// parseSelectionSet parses a selection set, which may not be empty
+func (p *parser) parseSelectionSet() ([]selection, error) {
+	err := p.nest()
+	if err != nil {
+		return nil, err
+	}
+
+	defer p.unnest()
+
+	err = p.expectPunctuator("{")
+	if err != nil {
+		return nil, err
+	}
+
+	var selections []selection
+
+	for {
+		closed, err := p.skipPunctuator("}")
+		if err != nil {
+			return nil, err
+		}
+
+		if closed {
+			if len(selections) == 0 {
+				return nil, p.errorf("a selection set may not be empty")
+			}
+
+			return selections, nil
+		}
+
+		var sel selection
+
+		if p.peekPunctuator("...") {
+			sel, err = p.parseFragmentSelection()
+		} else {
+			sel, err = p.parseField()
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		selections = append(selections, sel)
+	}
+}
+
+// parseField parses a field selection
+func (p *parser) parseField() (*field, error) {
+	f := &field{location: p.location()}
+
+	name, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+
+	aliased, err := p.skipPunctuator(":")
+	if err != nil {
+		return nil, err
+	}
+
+	if aliased {
+		f.alias = name
+
+		name, err = p.parseName()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	f.name = name
+
+	if p.peekPunctuator("(") {
+		f.arguments, err = p.parseArguments(false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	f.directives, err = p.parseDirectives(false)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peekPunctuator("{") {
+		f.selectionSet, err = p.parseSelectionSet()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// parseFragmentSelection parses a fragment spread or an inline fragment
+func (p *parser) parseFragmentSelection() (selection, error) {
+	loc := p.location()
+
+	err := p.expectPunctuator("...")
+	if err != nil {
+		return nil, err
+	}
+
+	if p.token.kind == tokenName && !p.peekName("on") {
+		spread := &fragmentSpread{location: loc, name: p.token.value}
+
+		err = p.advance()
+		if err != nil {
+			return nil, err
+		}
+
+		spread.directives, err = p.parseDirectives(false)
+		if err != nil {
+			return nil, err
+		}
+
+		return spread, nil
+	}
+
+	inline := &inlineFragment{location: loc}
+
+	if p.peekName("on") {
+		err = p.advance()
+		if err != nil {
+			return nil, err
+		}
+
+		inline.typeCondition, err = p.parseName()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	inline.directives, err = p.parseDirectives(false)
+	if err != nil {
+		return nil, err
+	}
+
+	inline.selectionSet, err = p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+
+	return inline, nil
+}
+
+// parseArguments parses the arguments of a field or a directive. Constant
+// arguments may not hold variables.
+func (p *parser) parseArguments(constant bool) ([]*argument, error) {
+	var arguments []*argument
+
+	err := p.expectPunctuator("(")
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		closed, err := p.skipPunctuator(")")
+		if err != nil {
+			return nil, err
+		}
+
+		if closed {
+			if len(arguments) == 0 {
+				return nil, p.errorf("an argument list may not be empty")
+			}
+
+			return arguments, nil
+		}
+
+		arg := &argument{location: p.location()}
+
+		arg.name, err = p.parseName()
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.expectPunctuator(":")
+		if err != nil {
+			return nil, err
+		}
+
+		arg.value, err = p.parseValue(constant)
+		if err != nil {
+			return nil, err
+		}
+
+		arguments = append(arguments, arg)
+	}
+}
+
+// parseDirectives parses the directives that annotate a definition or a
+// selection
+func (p *parser) parseDirectives(constant bool) ([]*directive, error) {
+	var directives []*directive
+
+	for p.peekPunctuator("@") {
+		d := &directive{location: p.location()}
+
+		err := p.advance()
+		if err != nil {
+			return nil, err
+		}
+
+		d.name, err = p.parseName()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.peekPunctuator("(") {
+			d.arguments, err = p.parseArguments(constant)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		directives = append(directives, d)
+	}
+
+	return directives, nil
+}
+
+// parseValue parses an input value. Constant values may not hold
+// variables.
+func (p *parser) parseValue(constant bool) (*value, error) {
+	v := &value{location: p.location(), raw: p.token.value}
+
+	switch p.token.kind {
+	case tokenPunctuator:
+		switch p.token.value {
+		case "$":
+			if constant {
+				return nil, p.errorf("unexpected variable, the value must be constant")
+			}
+
+			err := p.advance()
+			if err != nil {
+				return nil, err
+			}
+
+			v.kind = valueVariable
+			v.raw, err = p.parseName()
+
+			return v, err
+		case "[":
+			return p.parseListValue(v, constant)
+		case "{":
+			return p.parseObjectValue(v, constant)
+		default:
+			return nil, p.unexpected()
+		}
+	case tokenInt:
+		v.kind = valueInt
+	case tokenFloat:
+		v.kind = valueFloat
+	case tokenString, tokenBlockString:
+		v.kind = valueString
+	case tokenName:
+		switch p.token.value {
+		case "true", "false":
+			v.kind = valueBoolean
+		case "null":
+			v.kind = valueNull
+		default:
+			v.kind = valueEnum
+		}
+	default:
+		return nil, p.unexpected()
+	}
+
+	return v, p.advance()
+}
+
+// parseListValue parses the items of a list value
+func (p *parser) parseListValue(v *value, constant bool) (*value, error) {
+	err := p.nest()
+	if err != nil {
+		return nil, err
+	}
+
+	defer p.unnest()
+
+	v.kind = valueList
+	v.list = []*value{}
+
+	err = p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		closed, err := p.skipPunctuator("]")
+		if err != nil || closed {
+			return v, err
+		}
+
+		item, err := p.parseValue(constant)
+		if err != nil {
+			return nil, err
+		}
+
+		v.list = append(v.list, item)
+	}
+}
+
+// parseObjectValue parses the fields of an input object value
+func (p *parser) parseObjectValue(v *value, constant bool) (*value, error) {
+	err := p.nest()
+	if err != nil {
+		return nil, err
+	}
+
+	defer p.unnest()
+
+	v.kind = valueObject
+
+	err = p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		closed, err := p.skipPunctuator("}")
+		if err != nil || closed {
+			return v, err
+		}
+
+		objectField := &objectField{location: p.location()}
+
+		objectField.name, err = p.parseName()
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.expectPunctuator(":")
+		if err != nil {
+			return nil, err
+		}
+
+		objectField.value, err = p.parseValue(constant)
+		if err != nil {
+			return nil, err
+		}
+
+		v.fields = append(v.fields, objectField)
+	}
+}
+
+// parseTypeRef parses a reference to a type
+func (p *parser) parseTypeRef() (*typeRef, error) {
+	err := p.nest()
+	if err != nil {
+		return nil, err
+	}
+
+	defer p.unnest()
+
+	var ref *typeRef
+
+	isList, err := p.skipPunctuator("[")
+	if err != nil {
+		return nil, err
+	}
+
+	if isList {
+		ofType, err := p.parseTypeRef()
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.expectPunctuator("]")
+		if err != nil {
+			return nil, err
+		}
+
+		ref = &typeRef{list: true, ofType: ofType}
+	} else {
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+
+		ref = &typeRef{name: name}
+	}
+
+	nonNull, err := p.skipPunctuator("!")
+	if err != nil {
+		return nil, err
+	}
+
+	if nonNull {
+		ref = &typeRef{nonNull: true, ofType: ref}
+	}
+
+	return ref, nil
+}
+
+// parseSchemaDefinition parses a schema definition or extension
+func (p *parser) parseSchemaDefinition(loc location) (*schemaDefinition, error) {
+	schema := &schemaDefinition{location: loc, operationTypes: make(map[string]string)}
+
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.parseDirectives(true)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.peekPunctuator("{") {
+		return schema, nil
+	}
+
+	err = p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		closed, err := p.skipPunctuator("}")
+		if err != nil || closed {
+			return schema, err
+		}
+
+		operationType, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+
+		if operationType != "query" && operationType != "mutation" && operationType != "subscription" {
+			return nil, p.errorf("unknown operation type \"%s\"", operationType)
+		}
+
+		err = p.expectPunctuator(":")
+		if err != nil {
+			return nil, err
+		}
+
+		schema.operationTypes[operationType], err = p.parseName()
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseDirectiveDefinition parses the definition of a directive
+func (p *parser) parseDirectiveDefinition(loc location) (*directiveDefinition, error) {
+	definition := &directiveDefinition{location: loc}
+
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.expectPunctuator("@")
+	if err != nil {
+		return nil, err
+	}
+
+	definition.name, err = p.parseName()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peekPunctuator("(") {
+		definition.arguments, err = p.parseInputValueDefinitions("(", ")")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.peekName("repeatable") {
+		definition.repeatable = true
+
+		err = p.advance()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = p.expectKeyword("on")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.skipPunctuator("|")
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		directiveLocation, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+
+		definition.locations = append(definition.locations, directiveLocation)
+
+		more, err := p.skipPunctuator("|")
+		if err != nil {
+			return nil, err
+		}
+
+		if !more {
+			return definition, nil
+		}
+	}
+}
+
+// parseTypeDefinition parses a type definition or extension
+func (p *parser) parseTypeDefinition(loc location) (*typeDefinition, error) {
+	definition := &typeDefinition{location: loc}
+
+	keyword := p.token.value
+
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	definition.name, err = p.parseName()
+	if err != nil {
+		return nil, err
+	}
+
+	switch keyword {
+	case "scalar":
+		definition.kind = kindScalar
+	case "type", "interface":
+		definition.kind = kindObject
+		if keyword == "interface" {
+			definition.kind = kindInterface
+		}
+
+		if p.peekName("implements") {
+			definition.interfaces, err = p.parseImplements()
+			if err != nil {
+				return nil, err
+			}
+		}
+	case "union":
+		definition.kind = kindUnion
+	case "enum":
+		definition.kind = kindEnum
+	case "input":
+		definition.kind = kindInputObject
+	}
+
+	_, err = p.parseDirectives(true)
+	if err != nil {
+		return nil, err
+	}
+
+	switch definition.kind {
+	case kindObject, kindInterface:
+		if p.peekPunctuator("{") {
+			definition.fields, err = p.parseFieldDefinitions()
+		}
+	case kindUnion:
+		if p.peekPunctuator("=") {
+			definition.members, err = p.parseUnionMembers()
+		}
+	case kindEnum:
+		if p.peekPunctuator("{") {
+			definition.enumValues, err = p.parseEnumValues()
+		}
+	case kindInputObject:
+		if p.peekPunctuator("{") {
+			definition.inputFields, err = p.parseInputValueDefinitions("{", "}")
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return definition, nil
+}
+
+// parseImplements parses the interfaces that a type implements
+func (p *parser) parseImplements() ([]string, error) {
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.skipPunctuator("&")
+	if err != nil {
+		return nil, err
+	}
+
+	var interfaces []string
+
+	for {
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+
+		interfaces = append(interfaces, name)
+
+		more, err := p.skipPunctuator("&")
+		if err != nil {
+			return nil, err
+		}
+
+		if !more {
+			return interfaces, nil
+		}
+	}
+}
+
+// parseFieldDefinitions parses the fields of an object or an interface
+func (p *parser) parseFieldDefinitions() ([]*fieldDefinition, error) {
+	var fields []*fieldDefinition
+
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		closed, err := p.skipPunctuator("}")
+		if err != nil || closed {
+			return fields, err
+		}
+
+		err = p.skipDescription()
+		if err != nil {
+			return nil, err
+		}
+
+		definition := &fieldDefinition{location: p.location()}
+
+		definition.name, err = p.parseName()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.peekPunctuator("(") {
+			definition.arguments, err = p.parseInputValueDefinitions("(", ")")
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err = p.expectPunctuator(":")
+		if err != nil {
+			return nil, err
+		}
+
+		definition.typeRef, err = p.parseTypeRef()
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = p.parseDirectives(true)
+		if err != nil {
+			return nil, err
+		}
+
+		fields = append(fields, definition)
+	}
+}
+
+// parseInputValueDefinitions parses the arguments of a field or a
+// directive, or the fields of an input object, between the given
+// punctuators
+func (p *parser) parseInputValueDefinitions(open, close string) ([]*inputValueDefinition, error) {
+	var definitions []*inputValueDefinition
+
+	err := p.expectPunctuator(open)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		closed, err := p.skipPunctuator(close)
+		if err != nil || closed {
+			return definitions, err
+		}
+
+		err = p.skipDescription()
+		if err != nil {
+			return nil, err
+		}
+
+		definition := &inputValueDefinition{location: p.location()}
+
+		definition.name, err = p.parseName()
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.expectPunctuator(":")
+		if err != nil {
+			return nil, err
+		}
+
+		definition.typeRef, err = p.parseTypeRef()
+		if err != nil {
+			return nil, err
+		}
+
+		hasDefault, err := p.skipPunctuator("=")
+		if err != nil {
+			return nil, err
+		}
+
+		if hasDefault {
+			definition.defaultValue, err = p.parseValue(true)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		_, err = p.parseDirectives(true)
+		if err != nil {
+			return nil, err
+		}
+
+		definitions = append(definitions, definition)
+	}
+}
+
+// parseUnionMembers parses the member types of a union
+func (p *parser) parseUnionMembers() ([]string, error) {
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.skipPunctuator("|")
+	if err != nil {
+		return nil, err
+	}
+
+	var members []string
+
+	for {
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, name)
+
+		more, err := p.skipPunctuator("|")
+		if err != nil {
+			return nil, err
+		}
+
+		if !more {
+			return members, nil
+		}
+	}
+}
+
+// parseEnumValues parses the values of an enum
+func (p *parser) parseEnumValues() ([]string, error) {
+	var values []string
+
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		closed, err := p.skipPunctuator("}")
+		if err != nil || closed {
+			return values, err
+		}
+
+		err = p.skipDescription()
+		if err != nil {
+			return nil, err
+		}
+
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+
+		if name == "true" || name == "false" || name == "null" {
+			return nil, p.errorf("\"%s\" may not be an enum value", name)
+		}
+
+		_, err = p.parseDirectives(true)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, name)
+	}
+}
+
+// skipDescription skips the description of a definition, if it has one
+func (p *parser) skipDescription() error {
+	if p.token.kind == tokenString || p.token.kind == tokenBlockString {
+		return p.advance()
+	}
+
+	return nil
+}
+
+// describe describes the current token for error messages
+func (p *parser) describe() string {
+	switch p.token.kind {
+	case tokenEOF:
+		return "the end of the document"
+	case tokenString, tokenBlockString:
+		return "a string"
+	default:
+		return strconv.Quote(p.token.value)
+	}
+}
+
+// unexpected returns an error that describes the current token as
+// unexpected
+func (p *parser) unexpected() error {
+	return p.errorf("unexpected %s", p.describe())
+}
+
+// errorf returns a syntax error at the location of the current token
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return SyntaxError{
+		line:   p.token.line,
+		column: p.token.column,
+		reason: fmt.Sprintf(format, args...),
+	}
+}
@@ -0,0 +1,885 @@
+package graphqlvalidator
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// variableUsage is a variable that is used as a value of an argument or of
+// an input field
+type variableUsage struct {
+	location
+	name    string
+	typeRef *typeRef
+
+	// hasDefault is true if the argument or the input field has a default
+	// value, which is used when the variable is null
+	hasDefault bool
+}
+
+// scope holds the variables that an operation or a fragment uses and the
+// fragments that it spreads
+type scope struct {
+	usages  []variableUsage
+	spreads []string
+}
+
+// validation validates an executable document against a schema, by the
+// rules of the "Validation" section of the GraphQL specification
+type validation struct {
+	schema    *schema
+	document  *document
+	fragments map[string]*fragment
+	scopes    map[string]*scope
+	current   *scope
+	errs      ValidationErrors
+
+	// merged holds the pairs of fields that were already checked to be
+	// mergeable, so fragments that are spread many times or in cycles are
+	// not checked again
+	merged map[[2]*field]bool
+}
+
+// validateDocument returns the failures of a document
+func validateDocument(s *schema, doc *document) ValidationErrors {
+	v := &validation{
+		schema:    s,
+		document:  doc,
+		fragments: make(map[string]*fragment),
+		scopes:    make(map[string]*scope),
+		merged:    make(map[[2]*field]bool),
+	}
+
+	v.validateFragments()
+	v.validateOperations()
+	v.checkFragmentCycles()
+	v.checkUnusedFragments()
+
+	return v.errs
+}
+
+// report adds a failure at a location of the document
+func (v *validation) report(loc location, schemaPath, keyword, format string, args ...interface{}) {
+	v.errs = append(v.errs, RequestValidationError{
+		path:       formatLocation(loc),
+		schemaPath: schemaPath,
+		keyword:    keyword,
+		reason:     fmt.Sprintf(format, args...),
+	})
+}
+
+// formatLocation formats a location as "line:column"
+func formatLocation(loc location) string {
+	return strconv.Itoa(loc.line) + ":" + strconv.Itoa(loc.column)
+}
+
+// validateOperations validates the operations of the document
+func (v *validation) validateOperations() {
+	names := make(map[string]bool)
+
+	for _, op := range v.document.operations {
+		if op.name == "" && len(v.document.operations) > 1 {
+			v.report(op.location, "", "loneAnonymousOperation",
+				"an anonymous operation must be the only operation of the document")
+		}
+
+		if op.name != "" {
+			if names[op.name] {
+				v.report(op.location, "", "uniqueOperationNames",
+					"there can be only one operation named \"%s\"", op.name)
+			}
+
+			names[op.name] = true
+		}
+
+		rootType := v.schema.types[v.schema.rootTypes[op.kind]]
+		if rootType == nil {
+			v.report(op.location, "", "knownOperationTypes",
+				"the schema does not support %s operations", op.kind)
+			continue
+		}
+
+		v.current = &scope{}
+
+		v.validateVariableDefinitions(op)
+		v.validateDirectives(op.directives, strings.ToUpper(op.kind))
+		v.validateSelectionSet(rootType, op.selectionSet)
+
+		if op.kind == "subscription" {
+			v.checkSubscriptionRootField(op)
+		}
+
+		v.checkVariableUsages(op, v.current)
+	}
+}
+
+// validateVariableDefinitions validates the variable definitions of an
+// operation
+func (v *validation) validateVariableDefinitions(op *operation) {
+	names := make(map[string]bool)
+
+	for _, definition := range op.variables {
+		if names[definition.name] {
+			v.report(definition.location, "", "uniqueVariableNames",
+				"there can be only one variable named \"$%s\"", definition.name)
+		}
+
+		names[definition.name] = true
+
+		t := v.schema.types[definition.typeRef.namedType()]
+
+		switch {
+		case t == nil:
+			v.report(definition.location, "", "knownTypeNames",
+				"unknown type \"%s\"", definition.typeRef.namedType())
+		case !t.isInput():
+			v.report(definition.location, "", "variablesAreInputTypes",
+				"variable \"$%s\" cannot be of the non-input type \"%s\"", definition.name, definition.typeRef)
+		case definition.defaultValue != nil:
+			v.validateValue(definition.defaultValue, definition.typeRef, false, "")
+		}
+
+		v.validateDirectives(definition.directives, "VARIABLE_DEFINITION")
+	}
+}
+
+// checkSubscriptionRootField makes sure that a subscription selects a
+// single root field, which is not an introspection field
+func (v *validation) checkSubscriptionRootField(op *operation) {
+	fields := v.collectFields(v.schema.types[v.schema.rootTypes["subscription"]],
+		op.selectionSet, nil, make(map[string]bool))
+
+	keys := make(map[string]bool)
+	for _, collected := range fields {
+		keys[collected.field.responseKey()] = true
+
+		if strings.HasPrefix(collected.field.name, "__") {
+			v.report(collected.field.location, "", "singleFieldSubscriptions",
+				"a subscription may not select an introspection field")
+		}
+	}
+
+	if len(keys) > 1 {
+		v.report(op.location, "", "singleFieldSubscriptions",
+			"a subscription must select only one top level field")
+	}
+}
+
+// checkVariableUsages makes sure that the variables that an operation
+// uses, including in the fragments that it spreads, are defined by the
+// operation and can be used where they are used, and that the variables
+// that it defines are used
+func (v *validation) checkVariableUsages(op *operation, opScope *scope) {
+	definitions := make(map[string]*variableDefinition)
+	for _, definition := range op.variables {
+		definitions[definition.name] = definition
+	}
+
+	used := make(map[string]bool)
+
+	for _, usage := range v.collectUsages(opScope, make(map[string]bool)) {
+		definition := definitions[usage.name]
+		if definition == nil {
+			if op.name != "" {
+				v.report(usage.location, "", "noUndefinedVariables",
+					"variable \"$%s\" is not defined by operation \"%s\"", usage.name, op.name)
+			} else {
+				v.report(usage.location, "", "noUndefinedVariables",
+					"variable \"$%s\" is not defined", usage.name)
+			}
+
+			continue
+		}
+
+		used[usage.name] = true
+
+		if !variableAllowed(definition, usage) {
+			v.report(usage.location, "", "variablesInAllowedPosition",
+				"variable \"$%s\" of type \"%s\" used in position expecting type \"%s\"",
+				usage.name, definition.typeRef, usage.typeRef)
+		}
+	}
+
+	for _, definition := range op.variables {
+		if !used[definition.name] {
+			v.report(definition.location, "", "noUnusedVariables",
+				"variable \"$%s\" is never used", definition.name)
+		}
+	}
+}
+
+// collectUsages returns the variable usages of a scope and of the
+// fragments that it spreads
+func (v *validation) collectUsages(s *scope, visited map[string]bool) []variableUsage {
+	usages := s.usages
+
+	for _, name := range s.spreads {
+		if visited[name] || v.scopes[name] == nil {
+			continue
+		}
+
+		visited[name] = true
+		usages = append(usages, v.collectUsages(v.scopes[name], visited)...)
+	}
+
+	return usages
+}
+
+// variableAllowed returns true if a variable can be used where it is used
+func variableAllowed(definition *variableDefinition, usage variableUsage) bool {
+	if usage.typeRef.nonNull && !definition.typeRef.nonNull {
+		hasNonNullDefault := definition.defaultValue != nil && definition.defaultValue.kind != valueNull
+		if !hasNonNullDefault && !usage.hasDefault {
+			return false
+		}
+
+		return typesCompatible(definition.typeRef, usage.typeRef.ofType)
+	}
+
+	return typesCompatible(definition.typeRef, usage.typeRef)
+}
+
+// typesCompatible returns true if a variable of a type can be used where
+// another type is expected
+func typesCompatible(variableType, locationType *typeRef) bool {
+	switch {
+	case locationType.nonNull:
+		return variableType.nonNull && typesCompatible(variableType.ofType, locationType.ofType)
+	case variableType.nonNull:
+		return typesCompatible(variableType.ofType, locationType)
+	case locationType.list:
+		return variableType.list && typesCompatible(variableType.ofType, locationType.ofType)
+	case variableType.list:
+		return false
+	default:
+		return variableType.name == locationType.name
+	}
+}
+
+// validateFragments validates the fragment definitions of the document
+func (v *validation) validateFragments() {
+	for _, frag := range v.document.fragments {
+		if v.fragments[frag.name] != nil {
+			v.report(frag.location, "", "uniqueFragmentNames",
+				"there can be only one fragment named \"%s\"", frag.name)
+			continue
+		}
+
+		v.fragments[frag.name] = frag
+	}
+
+	for _, frag := range v.document.fragments {
+		if v.scopes[frag.name] != nil {
+			continue
+		}
+
+		v.current = &scope{}
+		v.scopes[frag.name] = v.current
+
+		v.validateDirectives(frag.directives, "FRAGMENT_DEFINITION")
+
+		t := v.schema.types[frag.typeCondition]
+
+		switch {
+		case t == nil:
+			v.report(frag.location, "", "knownTypeNames", "unknown type \"%s\"", frag.typeCondition)
+		case !t.isComposite():
+			v.report(frag.location, "", "fragmentsOnCompositeTypes",
+				"fragment \"%s\" cannot condition on the non-composite type \"%s\"", frag.name, frag.typeCondition)
+		default:
+			v.validateSelectionSet(t, frag.selectionSet)
+		}
+	}
+}
+
+// checkFragmentCycles makes sure that fragments do not spread themselves,
+// directly or through other fragments
+func (v *validation) checkFragmentCycles() {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	states := make(map[string]int)
+
+	var visit func(name string)
+	visit = func(name string) {
+		states[name] = visiting
+
+		for _, spread := range v.scopes[name].spreads {
+			if v.scopes[spread] == nil {
+				continue
+			}
+
+			switch states[spread] {
+			case visiting:
+				v.report(v.fragments[spread].location, "", "noFragmentCycles",
+					"cannot spread fragment \"%s\" within itself", spread)
+			case unvisited:
+				visit(spread)
+			}
+		}
+
+		states[name] = visited
+	}
+
+	for _, frag := range v.document.fragments {
+		if states[frag.name] == unvisited {
+			visit(frag.name)
+		}
+	}
+}
+
+// checkUnusedFragments makes sure that every fragment is spread by an
+// operation, directly or through other fragments
+func (v *validation) checkUnusedFragments() {
+	used := make(map[string]bool)
+
+	var use func(spreads []string)
+	use = func(spreads []string) {
+		for _, name := range spreads {
+			if used[name] || v.scopes[name] == nil {
+				continue
+			}
+
+			used[name] = true
+			use(v.scopes[name].spreads)
+		}
+	}
+
+	for _, op := range v.document.operations {
+		use(operationSpreads(op.selectionSet))
+	}
+
+	for _, frag := range v.document.fragments {
+		if !used[frag.name] && v.fragments[frag.name] == frag {
+			v.report(frag.location, "", "noUnusedFragments", "fragment \"%s\" is never used", frag.name)
+		}
+	}
+}
+
+// operationSpreads returns the names of the fragments that a selection set
+// spreads, outside of the fragments themselves
+func operationSpreads(selectionSet []selection) []string {
+	var spreads []string
+
+	for _, sel := range selectionSet {
+		switch s := sel.(type) {
+		case *field:
+			spreads = append(spreads, operationSpreads(s.selectionSet)...)
+		case *inlineFragment:
+			spreads = append(spreads, operationSpreads(s.selectionSet)...)
+		case *fragmentSpread:
+			spreads = append(spreads, s.name)
+		}
+	}
+
+	return spreads
+}
+
+// validateSelectionSet validates the selections of a selection set on a
+// composite type
+func (v *validation) validateSelectionSet(parent *namedType, selectionSet []selection) {
+	for _, sel := range selectionSet {
+		switch s := sel.(type) {
+		case *field:
+			v.validateField(parent, s)
+		case *fragmentSpread:
+			v.validateDirectives(s.directives, "FRAGMENT_SPREAD")
+			v.current.spreads = append(v.current.spreads, s.name)
+
+			frag := v.fragments[s.name]
+			if frag == nil {
+				v.report(s.location, "", "knownFragmentNames", "unknown fragment \"%s\"", s.name)
+				continue
+			}
+
+			t := v.schema.types[frag.typeCondition]
+			if t != nil && t.isComposite() && !v.schema.overlap(parent, t) {
+				v.report(s.location, "", "possibleFragmentSpreads",
+					"fragment \"%s\" cannot be spread here as objects of type \"%s\" can never be of type \"%s\"",
+					s.name, parent.name, t.name)
+			}
+		case *inlineFragment:
+			v.validateDirectives(s.directives, "INLINE_FRAGMENT")
+
+			t := parent
+			if s.typeCondition != "" {
+				t = v.schema.types[s.typeCondition]
+
+				switch {
+				case t == nil:
+					v.report(s.location, "", "knownTypeNames", "unknown type \"%s\"", s.typeCondition)
+					continue
+				case !t.isComposite():
+					v.report(s.location, "", "fragmentsOnCompositeTypes",
+						"fragment cannot condition on the non-composite type \"%s\"", s.typeCondition)
+					continue
+				case !v.schema.overlap(parent, t):
+					v.report(s.location, "", "possibleFragmentSpreads",
+						"fragment cannot be spread here as objects of type \"%s\" can never be of type \"%s\"",
+						parent.name, t.name)
+				}
+			}
+
+			v.validateSelectionSet(t, s.selectionSet)
+		}
+	}
+
+	v.checkFieldMerging(parent, selectionSet)
+}
+
+// validateField validates a field selection on a composite type
+func (v *validation) validateField(parent *namedType, f *field) {
+	definition := v.schema.field(parent, f.name)
+	if definition == nil {
+		v.report(f.location, parent.name, "fieldsOnCorrectType",
+			"cannot query field \"%s\" on type \"%s\"", f.name, parent.name)
+		return
+	}
+
+	coordinate := parent.name + "." + f.name
+
+	v.validateArguments(f.location, f.arguments, definition.arguments, coordinate)
+	v.validateDirectives(f.directives, "FIELD")
+
+	t := v.schema.types[definition.typeRef.namedType()]
+
+	switch {
+	case t.isComposite() && len(f.selectionSet) == 0:
+		v.report(f.location, coordinate, "scalarLeafs",
+			"field \"%s\" of type \"%s\" must have a selection of subfields", f.name, definition.typeRef)
+	case t.isComposite():
+		v.validateSelectionSet(t, f.selectionSet)
+	case len(f.selectionSet) > 0:
+		v.report(f.location, coordinate, "scalarLeafs",
+			"field \"%s\" must not have a selection since type \"%s\" has no subfields", f.name, definition.typeRef)
+	}
+}
+
+// validateArguments validates the arguments of a field or a directive
+// against their definitions
+func (v *validation) validateArguments(loc location, arguments []*argument,
+	definitions []*inputValueDefinition, coordinate string) {
+	provided := make(map[string]bool)
+
+	for _, arg := range arguments {
+		if provided[arg.name] {
+			v.report(arg.location, coordinate, "uniqueArgumentNames",
+				"there can be only one argument named \"%s\"", arg.name)
+			continue
+		}
+
+		provided[arg.name] = true
+
+		definition := findInputValue(definitions, arg.name)
+		if definition == nil {
+			v.report(arg.location, coordinate, "knownArgumentNames",
+				"unknown argument \"%s\" on \"%s\"", arg.name, coordinate)
+			continue
+		}
+
+		v.validateValue(arg.value, definition.typeRef, definition.defaultValue != nil,
+			coordinate+"("+arg.name+":)")
+	}
+
+	for _, definition := range definitions {
+		if definition.typeRef.nonNull && definition.defaultValue == nil && !provided[definition.name] {
+			v.report(loc, coordinate+"("+definition.name+":)", "providedRequiredArguments",
+				"argument \"%s\" of type \"%s\" is required, but it was not provided",
+				definition.name, definition.typeRef)
+		}
+	}
+}
+
+// findInputValue returns an argument or an input field by its name
+func findInputValue(definitions []*inputValueDefinition, name string) *inputValueDefinition {
+	for _, definition := range definitions {
+		if definition.name == name {
+			return definition
+		}
+	}
+
+	return nil
+}
+
+// validateDirectives validates the directives in a location of the
+// document
+func (v *validation) validateDirectives(directives []*directive, directiveLocation string) {
+	used := make(map[string]bool)
+
+	for _, d := range directives {
+		definition := v.schema.directives[d.name]
+		if definition == nil {
+			v.report(d.location, "", "knownDirectives", "unknown directive \"@%s\"", d.name)
+			continue
+		}
+
+		coordinate := "@" + d.name
+
+		if !containsString(definition.locations, directiveLocation) {
+			v.report(d.location, coordinate, "knownDirectives",
+				"directive \"@%s\" may not be used on %s", d.name, directiveLocation)
+		}
+
+		if used[d.name] && !definition.repeatable {
+			v.report(d.location, coordinate, "uniqueDirectivesPerLocation",
+				"the directive \"@%s\" can only be used once at this location", d.name)
+		}
+
+		used[d.name] = true
+
+		v.validateArguments(d.location, d.arguments, definition.arguments, coordinate)
+	}
+}
+
+// validateValue validates a value in the document against the type that
+// is expected where it is used. Variables are recorded to be checked with
+// the operations that use them.
+func (v *validation) validateValue(val *value, t *typeRef, hasDefault bool, coordinate string) {
+	if val.kind == valueVariable {
+		if v.current != nil {
+			v.current.usages = append(v.current.usages, variableUsage{
+				location:   val.location,
+				name:       val.raw,
+				typeRef:    t,
+				hasDefault: hasDefault,
+			})
+		}
+
+		return
+	}
+
+	if t.nonNull {
+		if val.kind == valueNull {
+			v.report(val.location, coordinate, "valuesOfCorrectType",
+				"expected value of type \"%s\", found null", t)
+			return
+		}
+
+		v.validateValue(val, t.ofType, false, coordinate)
+
+		return
+	}
+
+	if val.kind == valueNull {
+		return
+	}
+
+	if t.list {
+		if val.kind != valueList {
+			v.validateValue(val, t.ofType, false, coordinate)
+			return
+		}
+
+		for _, item := range val.list {
+			v.validateValue(item, t.ofType, false, coordinate)
+		}
+
+		return
+	}
+
+	named := v.schema.types[t.name]
+
+	switch named.kind {
+	case kindScalar:
+		if !literalMatchesScalar(named.name, val) {
+			v.report(val.location, coordinate, "valuesOfCorrectType",
+				"%s cannot represent the value %s", named.name, printValue(val))
+		}
+	case kindEnum:
+		if val.kind != valueEnum || !named.enumValues[val.raw] {
+			v.report(val.location, coordinate, "valuesOfCorrectType",
+				"value %s does not exist in \"%s\" enum", printValue(val), named.name)
+		}
+	case kindInputObject:
+		v.validateObjectValue(val, named)
+	}
+}
+
+// validateObjectValue validates an input object value
+func (v *validation) validateObjectValue(val *value, t *namedType) {
+	if val.kind != valueObject {
+		v.report(val.location, t.name, "valuesOfCorrectType",
+			"expected value of type \"%s\", found %s", t.name, printValue(val))
+		return
+	}
+
+	provided := make(map[string]bool)
+
+	for _, objectField := range val.fields {
+		coordinate := t.name + "." + objectField.name
+
+		if provided[objectField.name] {
+			v.report(objectField.location, coordinate, "uniqueInputFieldNames",
+				"there can be only one input field named \"%s\"", objectField.name)
+			continue
+		}
+
+		provided[objectField.name] = true
+
+		definition := t.inputField(objectField.name)
+		if definition == nil {
+			v.report(objectField.location, t.name, "valuesOfCorrectType",
+				"field \"%s\" is not defined by type \"%s\"", objectField.name, t.name)
+			continue
+		}
+
+		v.validateValue(objectField.value, definition.typeRef, definition.defaultValue != nil, coordinate)
+	}
+
+	for _, definition := range t.inputFields {
+		if definition.typeRef.nonNull && definition.defaultValue == nil && !provided[definition.name] {
+			v.report(val.location, t.name+"."+definition.name, "valuesOfCorrectType",
+				"field \"%s.%s\" of required type \"%s\" was not provided", t.name, definition.name, definition.typeRef)
+		}
+	}
+}
+
+// literalMatchesScalar returns true if a literal can represent a value of
+// a scalar. Custom scalars accept any literal.
+func literalMatchesScalar(scalar string, val *value) bool {
+	switch scalar {
+	case "Int":
+		if val.kind != valueInt {
+			return false
+		}
+
+		_, err := strconv.ParseInt(val.raw, 10, 32)
+
+		return err == nil
+	case "Float":
+		if val.kind != valueInt && val.kind != valueFloat {
+			return false
+		}
+
+		number, err := strconv.ParseFloat(val.raw, 64)
+
+		return err == nil && !math.IsInf(number, 0)
+	case "String":
+		return val.kind == valueString
+	case "Boolean":
+		return val.kind == valueBoolean
+	case "ID":
+		return val.kind == valueString || val.kind == valueInt
+	default:
+		return true
+	}
+}
+
+// printValue formats a value like it is written in documents
+func printValue(val *value) string {
+	switch val.kind {
+	case valueVariable:
+		return "$" + val.raw
+	case valueString:
+		return strconv.Quote(val.raw)
+	case valueList:
+		items := make([]string, len(val.list))
+		for index, item := range val.list {
+			items[index] = printValue(item)
+		}
+
+		return "[" + strings.Join(items, ", ") + "]"
+	case valueObject:
+		fields := make([]string, len(val.fields))
+		for index, objectField := range val.fields {
+			fields[index] = objectField.name + ": " + printValue(objectField.value)
+		}
+
+		return "{" + strings.Join(fields, ", ") + "}"
+	case valueNull:
+		return "null"
+	default:
+		return val.raw
+	}
+}
+
+// collectedField is a field that is selected in a selection set, directly
+// or through fragments, with the type that it is selected on
+type collectedField struct {
+	field      *field
+	parent     *namedType
+	definition *fieldDefinition
+}
+
+// collectFields returns the fields that a selection set selects, including
+// the fields of its fragments
+func (v *validation) collectFields(parent *namedType, selectionSet []selection,
+	fields []collectedField, visited map[string]bool) []collectedField {
+	for _, sel := range selectionSet {
+		switch s := sel.(type) {
+		case *field:
+			fields = append(fields, collectedField{s, parent, v.schema.field(parent, s.name)})
+		case *inlineFragment:
+			t := parent
+			if s.typeCondition != "" {
+				t = v.schema.types[s.typeCondition]
+			}
+
+			if t != nil && t.isComposite() {
+				fields = v.collectFields(t, s.selectionSet, fields, visited)
+			}
+		case *fragmentSpread:
+			frag := v.fragments[s.name]
+			if frag == nil || visited[s.name] {
+				continue
+			}
+
+			visited[s.name] = true
+
+			if t := v.schema.types[frag.typeCondition]; t != nil && t.isComposite() {
+				fields = v.collectFields(t, frag.selectionSet, fields, visited)
+			}
+		}
+	}
+
+	return fields
+}
+
+// checkFieldMerging makes sure that the fields of a selection set that
+// have the same response name can be merged: they select the same field
+// with the same arguments, unless they are selected on different object
+// types, their values have the same shape, and their subfields can be
+// merged too
+func (v *validation) checkFieldMerging(parent *namedType, selectionSet []selection) {
+	fields := v.collectFields(parent, selectionSet, nil, make(map[string]bool))
+
+	at, reason := v.findConflict(fields, false)
+	if at != nil {
+		v.report(at.location, "", "overlappingFieldsCanBeMerged", "%s", reason)
+	}
+}
+
+// findConflict returns the first field that cannot be merged with another
+// field of the same response name, and the reason. The fields are selected
+// on mutually exclusive object types if exclusive is true.
+func (v *validation) findConflict(fields []collectedField, exclusive bool) (*field, string) {
+	first := make(map[string]collectedField)
+
+	for _, collected := range fields {
+		key := collected.field.responseKey()
+
+		other, ok := first[key]
+		if !ok {
+			first[key] = collected
+			continue
+		}
+
+		if collected.field == other.field {
+			continue
+		}
+
+		at, reason := v.mergeConflict(other, collected, exclusive)
+		if at != nil {
+			return at, reason
+		}
+	}
+
+	return nil, ""
+}
+
+// mergeConflict returns the field that cannot be merged when two fields
+// with the same response name are merged, and the reason
+func (v *validation) mergeConflict(a, b collectedField, exclusive bool) (*field, string) {
+	pair := [2]*field{a.field, b.field}
+	if v.merged[pair] {
+		return nil, ""
+	}
+
+	v.merged[pair] = true
+
+	key := a.field.responseKey()
+
+	exclusive = exclusive || (a.parent != b.parent && a.parent.kind == kindObject && b.parent.kind == kindObject)
+
+	if !exclusive {
+		if a.field.name != b.field.name {
+			return b.field, fmt.Sprintf("fields \"%s\" conflict because \"%s\" and \"%s\" are different fields",
+				key, a.field.name, b.field.name)
+		}
+
+		if !sameArguments(a.field.arguments, b.field.arguments) {
+			return b.field, fmt.Sprintf("fields \"%s\" conflict because they have differing arguments", key)
+		}
+	}
+
+	if a.definition == nil || b.definition == nil {
+		return nil, ""
+	}
+
+	if !v.sameShape(a.definition.typeRef, b.definition.typeRef) {
+		return b.field, fmt.Sprintf("fields \"%s\" conflict because they return conflicting types \"%s\" and \"%s\"",
+			key, a.definition.typeRef, b.definition.typeRef)
+	}
+
+	typeA := v.schema.types[a.definition.typeRef.namedType()]
+	typeB := v.schema.types[b.definition.typeRef.namedType()]
+
+	if !typeA.isComposite() || !typeB.isComposite() {
+		return nil, ""
+	}
+
+	visited := make(map[string]bool)
+	subfields := v.collectFields(typeA, a.field.selectionSet, nil, visited)
+	subfields = v.collectFields(typeB, b.field.selectionSet, subfields, visited)
+
+	return v.findConflict(subfields, exclusive)
+}
+
+// sameArguments returns true if two lists of arguments hold the same
+// arguments with the same values
+func sameArguments(a, b []*argument) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for _, argA := range a {
+		found := false
+
+		for _, argB := range b {
+			if argA.name == argB.name && printValue(argA.value) == printValue(argB.value) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sameShape returns true if the values of two types have the same shape in
+// the response
+func (v *validation) sameShape(a, b *typeRef) bool {
+	switch {
+	case a.nonNull || b.nonNull:
+		return a.nonNull && b.nonNull && v.sameShape(a.ofType, b.ofType)
+	case a.list || b.list:
+		return a.list && b.list && v.sameShape(a.ofType, b.ofType)
+	}
+
+	typeA, typeB := v.schema.types[a.name], v.schema.types[b.name]
+	if typeA == nil || typeB == nil || (typeA.isComposite() && typeB.isComposite()) {
+		return true
+	}
+
+	return a.name == b.name
+}
+
+// containsString returns true if a list holds a string
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,515 @@
+package graphqlvalidator
+
+import (
+	"sort"
+)
+
+// builtInScalars are the scalars that every schema holds
+var builtInScalars = []string{"Int", "Float", "String", "Boolean", "ID"}
+
+// builtInDirectives defines the directives that every schema holds
+const builtInDirectives = `
+directive @skip(if: Boolean!) on FIELD | FRAGMENT_SPREAD | INLINE_FRAGMENT
+directive @include(if: Boolean!) on FIELD | FRAGMENT_SPREAD | INLINE_FRAGMENT
+directive @deprecated(reason: String = "No longer supported") on FIELD_DEFINITION | ARGUMENT_DEFINITION | INPUT_FIELD_DEFINITION | ENUM_VALUE
+directive @specifiedBy(url: String!) on SCALAR
+`
+
+// introspectionTypes defines the types of the introspection system, which
+// every schema holds
+const introspectionTypes = `
+type __Schema {
+  description: String
+  types: [__Type!]!
+  queryType: __Type!
+  mutationType: __Type
+  subscriptionType: __Type
+  directives: [__Directive!]!
+}
+
+type __Type {
+  kind: __TypeKind!
+  name: String
+  description: String
+  specifiedByURL: String
+  fields(includeDeprecated: Boolean = false): [__Field!]
+  interfaces: [__Type!]
+  possibleTypes: [__Type!]
+  enumValues(includeDeprecated: Boolean = false): [__EnumValue!]
+  inputFields(includeDeprecated: Boolean = false): [__InputValue!]
+  ofType: __Type
+}
+
+enum __TypeKind {
+  SCALAR
+  OBJECT
+  INTERFACE
+  UNION
+  ENUM
+  INPUT_OBJECT
+  LIST
+  NON_NULL
+}
+
+type __Field {
+  name: String!
+  description: String
+  args(includeDeprecated: Boolean = false): [__InputValue!]!
+  type: __Type!
+  isDeprecated: Boolean!
+  deprecationReason: String
+}
+
+type __InputValue {
+  name: String!
+  description: String
+  type: __Type!
+  defaultValue: String
+  isDeprecated: Boolean!
+  deprecationReason: String
+}
+
+type __EnumValue {
+  name: String!
+  description: String
+  isDeprecated: Boolean!
+  deprecationReason: String
+}
+
+type __Directive {
+  name: String!
+  description: String
+  locations: [__DirectiveLocation!]!
+  args(includeDeprecated: Boolean = false): [__InputValue!]!
+  isRepeatable: Boolean!
+}
+
+enum __DirectiveLocation {
+  QUERY
+  MUTATION
+  SUBSCRIPTION
+  FIELD
+  FRAGMENT_DEFINITION
+  FRAGMENT_SPREAD
+  INLINE_FRAGMENT
+  VARIABLE_DEFINITION
+  SCHEMA
+  SCALAR
+  OBJECT
+  FIELD_DEFINITION
+  ARGUMENT_DEFINITION
+  INTERFACE
+  UNION
+  ENUM
+  ENUM_VALUE
+  INPUT_OBJECT
+  INPUT_FIELD_DEFINITION
+}
+`
+
+// The meta fields, which are not defined by the types that they can be
+// selected on
+var (
+	typenameField = &fieldDefinition{
+		name:    "__typename",
+		typeRef: &typeRef{nonNull: true, ofType: &typeRef{name: "String"}},
+	}
+
+	schemaField = &fieldDefinition{
+		name:    "__schema",
+		typeRef: &typeRef{nonNull: true, ofType: &typeRef{name: "__Schema"}},
+	}
+
+	typeField = &fieldDefinition{
+		name: "__type",
+		arguments: []*inputValueDefinition{{
+			name:    "name",
+			typeRef: &typeRef{nonNull: true, ofType: &typeRef{name: "String"}},
+		}},
+		typeRef: &typeRef{name: "__Type"},
+	}
+)
+
+// namedType is a type of a schema
+type namedType struct {
+	kind       string
+	name       string
+	interfaces []string
+	fields     map[string]*fieldDefinition
+
+	// inputFields are kept in their order, to report missing fields in a
+	// stable order
+	inputFields []*inputValueDefinition
+	enumValues  map[string]bool
+
+	// possibleTypes are the object types that a value of the type may be:
+	// the type itself for objects, the members of unions and the
+	// implementations of interfaces
+	possibleTypes map[string]bool
+}
+
+// isComposite returns true for the types whose fields are selected
+func (t *namedType) isComposite() bool {
+	return t.kind == kindObject || t.kind == kindInterface || t.kind == kindUnion
+}
+
+// isInput returns true for the types of arguments and variables
+func (t *namedType) isInput() bool {
+	return t.kind == kindScalar || t.kind == kindEnum || t.kind == kindInputObject
+}
+
+// inputField returns a field of an input object by its name
+func (t *namedType) inputField(name string) *inputValueDefinition {
+	for _, inputField := range t.inputFields {
+		if inputField.name == name {
+			return inputField
+		}
+	}
+
+	return nil
+}
+
+// schema is a GraphQL schema that requests are validated against
+type schema struct {
+	types      map[string]*namedType
+	directives map[string]*directiveDefinition
+
+	// rootTypes are the names of the root operation types by the kind of
+	// the operations
+	rootTypes map[string]string
+}
+
+// buildSchema builds a schema from an SDL document
+func buildSchema(sdl string) (*schema, error) {
+	doc, err := parseSchemaDocument(sdl)
+	if err != nil {
+		return nil, err
+	}
+
+	builtIn, err := parseSchemaDocument(builtInDirectives + introspectionTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &schema{
+		types:      make(map[string]*namedType),
+		directives: make(map[string]*directiveDefinition),
+		rootTypes:  make(map[string]string),
+	}
+
+	for _, name := range builtInScalars {
+		s.types[name] = &namedType{kind: kindScalar, name: name}
+	}
+
+	for _, definitions := range [][]*typeDefinition{builtIn.types, doc.types} {
+		err = s.addTypes(definitions, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.addTypes(doc.types, true)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, definition := range builtIn.directives {
+		s.directives[definition.name] = definition
+	}
+
+	// Printed schemas may repeat the definitions of the built-in
+	// directives
+	defined := make(map[string]bool)
+	for _, definition := range doc.directives {
+		if defined[definition.name] {
+			return nil, SchemaError{definition.location, "directive @" + definition.name + " is defined twice"}
+		}
+
+		defined[definition.name] = true
+		s.directives[definition.name] = definition
+	}
+
+	err = s.setRootTypes(doc.schemas)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.check()
+	if err != nil {
+		return nil, err
+	}
+
+	s.setPossibleTypes()
+
+	return s, nil
+}
+
+// addTypes adds the types of the definitions to the schema, or applies the
+// extensions of types to their types
+func (s *schema) addTypes(definitions []*typeDefinition, extensions bool) error {
+	for _, definition := range definitions {
+		if definition.extension != extensions {
+			continue
+		}
+
+		t, ok := s.types[definition.name]
+
+		if !extensions {
+			if ok {
+				return SchemaError{definition.location, "type " + definition.name + " is defined twice"}
+			}
+
+			t = &namedType{
+				kind:       definition.kind,
+				name:       definition.name,
+				fields:     make(map[string]*fieldDefinition),
+				enumValues: make(map[string]bool),
+			}
+
+			s.types[definition.name] = t
+		} else if !ok {
+			return SchemaError{definition.location, "cannot extend the unknown type " + definition.name}
+		} else if t.kind != definition.kind {
+			return SchemaError{definition.location, "cannot extend type " + definition.name +
+				" as " + definition.kind + ", it is " + t.kind}
+		}
+
+		t.interfaces = append(t.interfaces, definition.interfaces...)
+
+		for _, definedField := range definition.fields {
+			if t.fields[definedField.name] != nil {
+				return SchemaError{definedField.location, "field " + definition.name + "." +
+					definedField.name + " is defined twice"}
+			}
+
+			t.fields[definedField.name] = definedField
+		}
+
+		for _, inputField := range definition.inputFields {
+			if t.inputField(inputField.name) != nil {
+				return SchemaError{inputField.location, "field " + definition.name + "." +
+					inputField.name + " is defined twice"}
+			}
+
+			t.inputFields = append(t.inputFields, inputField)
+		}
+
+		for _, enumValue := range definition.enumValues {
+			if t.enumValues[enumValue] {
+				return SchemaError{definition.location, "value " + definition.name + "." +
+					enumValue + " is defined twice"}
+			}
+
+			t.enumValues[enumValue] = true
+		}
+
+		if definition.kind == kindUnion {
+			if t.possibleTypes == nil {
+				t.possibleTypes = make(map[string]bool)
+			}
+
+			for _, member := range definition.members {
+				t.possibleTypes[member] = true
+			}
+		}
+	}
+
+	return nil
+}
+
+// setRootTypes sets the root operation types by the schema definition, or
+// by their default names if the schema is not defined
+func (s *schema) setRootTypes(schemas []*schemaDefinition) error {
+	if len(schemas) == 0 {
+		for kind, name := range map[string]string{
+			"query":        "Query",
+			"mutation":     "Mutation",
+			"subscription": "Subscription",
+		} {
+			if s.types[name] != nil {
+				s.rootTypes[kind] = name
+			}
+		}
+	}
+
+	for _, definition := range schemas {
+		for kind, name := range definition.operationTypes {
+			if s.rootTypes[kind] != "" {
+				return SchemaError{definition.location, "the " + kind + " type is defined twice"}
+			}
+
+			t := s.types[name]
+			if t == nil || t.kind != kindObject {
+				return SchemaError{definition.location, "the " + kind + " type " + name +
+					" is not an object type"}
+			}
+
+			s.rootTypes[kind] = name
+		}
+	}
+
+	if s.rootTypes["query"] == "" {
+		return SchemaError{reason: "the schema has no query type"}
+	}
+
+	return nil
+}
+
+// check makes sure that the types that the schema refers to exist and can
+// be used where they are referred to
+func (s *schema) check() error {
+	for _, name := range s.typeNames() {
+		t := s.types[name]
+
+		switch t.kind {
+		case kindObject, kindInterface:
+			if len(t.fields) == 0 {
+				return SchemaError{reason: "type " + name + " has no fields"}
+			}
+
+			for _, interfaceName := range t.interfaces {
+				implemented := s.types[interfaceName]
+				if implemented == nil || implemented.kind != kindInterface {
+					return SchemaError{reason: "type " + name + " implements " + interfaceName +
+						", which is not an interface"}
+				}
+
+				for fieldName := range implemented.fields {
+					if t.fields[fieldName] == nil {
+						return SchemaError{reason: "type " + name + " implements " + interfaceName +
+							" but has no field " + fieldName}
+					}
+				}
+			}
+
+			for _, definedField := range t.fields {
+				err := s.checkTypeRef(definedField.location, definedField.typeRef, false)
+				if err != nil {
+					return err
+				}
+
+				err = s.checkInputValues(definedField.arguments)
+				if err != nil {
+					return err
+				}
+			}
+		case kindUnion:
+			for member := range t.possibleTypes {
+				if s.types[member] == nil || s.types[member].kind != kindObject {
+					return SchemaError{reason: "union " + name + " has the member " + member +
+						", which is not an object type"}
+				}
+			}
+		case kindInputObject:
+			err := s.checkInputValues(t.inputFields)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, definition := range s.directives {
+		err := s.checkInputValues(definition.arguments)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkInputValues makes sure that arguments and input fields are of input
+// types
+func (s *schema) checkInputValues(definitions []*inputValueDefinition) error {
+	for _, definition := range definitions {
+		err := s.checkTypeRef(definition.location, definition.typeRef, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkTypeRef makes sure that a type reference refers to an existing type
+// of the right kind
+func (s *schema) checkTypeRef(loc location, ref *typeRef, input bool) error {
+	name := ref.namedType()
+
+	t := s.types[name]
+	if t == nil {
+		return SchemaError{loc, "unknown type " + name}
+	}
+
+	if input && !t.isInput() {
+		return SchemaError{loc, "type " + name + " is not an input type"}
+	}
+
+	if !input && t.kind == kindInputObject {
+		return SchemaError{loc, "type " + name + " is an input type"}
+	}
+
+	return nil
+}
+
+// setPossibleTypes sets the possible types of objects and interfaces
+func (s *schema) setPossibleTypes() {
+	for _, t := range s.types {
+		if t.kind == kindObject || t.kind == kindInterface {
+			if t.possibleTypes == nil {
+				t.possibleTypes = make(map[string]bool)
+			}
+		}
+	}
+
+	for _, t := range s.types {
+		if t.kind != kindObject {
+			continue
+		}
+
+		t.possibleTypes[t.name] = true
+
+		for _, interfaceName := range t.interfaces {
+			s.types[interfaceName].possibleTypes[t.name] = true
+		}
+	}
+}
+
+// typeNames returns the names of the types in order, so the schema is
+// checked in a stable order
+func (s *schema) typeNames() []string {
+	names := make([]string, 0, len(s.types))
+	for name := range s.types {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// field returns the definition of a field of a composite type, including
+// the meta fields, or nil if the type has no such field
+func (s *schema) field(t *namedType, name string) *fieldDefinition {
+	switch {
+	case name == typenameField.name:
+		return typenameField
+	case t.name == s.rootTypes["query"] && name == schemaField.name:
+		return schemaField
+	case t.name == s.rootTypes["query"] && name == typeField.name:
+		return typeField
+	default:
+		return t.fields[name]
+	}
+}
+
+// overlap returns true if a value can be of both types
+func (s *schema) overlap(a, b *namedType) bool {
+	for name := range a.possibleTypes {
+		if b.possibleTypes[name] {
+			return true
+		}
+	}
+
+	return false
+}
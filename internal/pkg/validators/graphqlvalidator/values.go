@@ -0,0 +1,198 @@
+package graphqlvalidator
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// variableValidation validates the values of the variables of a request
+// against the variable definitions of the operation that it executes, by
+// the rules of the "Input Coercion" sections of the GraphQL specification
+type variableValidation struct {
+	schema *schema
+	errs   ValidationErrors
+}
+
+// validateVariables returns the failures of the values of the variables of
+// an operation
+func validateVariables(s *schema, op *operation, values map[string]interface{}) ValidationErrors {
+	v := &variableValidation{schema: s}
+
+	for _, definition := range op.variables {
+		path := "/variables/" + escapePointer(definition.name)
+
+		val, ok := values[definition.name]
+		if !ok {
+			if definition.typeRef.nonNull && definition.defaultValue == nil {
+				v.report(path, "variables", "variable \"$%s\" of required type \"%s\" was not provided",
+					definition.name, definition.typeRef)
+			}
+
+			continue
+		}
+
+		v.validateValue(val, definition.typeRef, path)
+	}
+
+	return v.errs
+}
+
+// report adds a failure at a json path of the request
+func (v *variableValidation) report(path, keyword, format string, args ...interface{}) {
+	v.errs = append(v.errs, RequestValidationError{
+		path:    path,
+		keyword: keyword,
+		reason:  fmt.Sprintf(format, args...),
+	})
+}
+
+// validateValue validates the value of a variable, or a part of it,
+// against the type that is expected at its path
+func (v *variableValidation) validateValue(val interface{}, t *typeRef, path string) {
+	if val == nil {
+		if t.nonNull {
+			v.report(path, "variables", "expected non-nullable type \"%s\" not to be null", t)
+		}
+
+		return
+	}
+
+	if t.nonNull {
+		v.validateValue(val, t.ofType, path)
+		return
+	}
+
+	if t.list {
+		items, ok := val.([]interface{})
+		if !ok {
+			// A single value is coerced to a list of one item
+			v.validateValue(val, t.ofType, path)
+			return
+		}
+
+		for index, item := range items {
+			v.validateValue(item, t.ofType, path+"/"+strconv.Itoa(index))
+		}
+
+		return
+	}
+
+	named := v.schema.types[t.name]
+
+	switch named.kind {
+	case kindScalar:
+		if reason := scalarMismatch(named.name, val); reason != "" {
+			v.report(path, "variables", "%s", reason)
+		}
+	case kindEnum:
+		s, ok := val.(string)
+		if !ok || !named.enumValues[s] {
+			v.report(path, "variables", "value %s does not exist in \"%s\" enum", formatJSON(val), named.name)
+		}
+	case kindInputObject:
+		v.validateObject(val, named, path)
+	}
+}
+
+// validateObject validates the value of an input object
+func (v *variableValidation) validateObject(val interface{}, t *namedType, path string) {
+	object, ok := val.(map[string]interface{})
+	if !ok {
+		v.report(path, "variables", "expected type \"%s\" to be an object", t.name)
+		return
+	}
+
+	for name := range object {
+		if t.inputField(name) == nil {
+			v.report(path+"/"+escapePointer(name), "variables",
+				"field \"%s\" is not defined by type \"%s\"", name, t.name)
+		}
+	}
+
+	for _, definition := range t.inputFields {
+		fieldPath := path + "/" + escapePointer(definition.name)
+
+		fieldValue, ok := object[definition.name]
+		if !ok {
+			if definition.typeRef.nonNull && definition.defaultValue == nil {
+				v.report(fieldPath, "variables", "field \"%s.%s\" of required type \"%s\" was not provided",
+					t.name, definition.name, definition.typeRef)
+			}
+
+			continue
+		}
+
+		v.validateValue(fieldValue, definition.typeRef, fieldPath)
+	}
+}
+
+// scalarMismatch returns the reason why a json value cannot represent a
+// value of a scalar, or an empty string if it can. Custom scalars accept
+// any value.
+func scalarMismatch(scalar string, val interface{}) string {
+	switch scalar {
+	case "Int":
+		number, ok := val.(json.Number)
+		if !ok {
+			break
+		}
+
+		value, err := strconv.ParseFloat(number.String(), 64)
+		if err != nil || value != math.Trunc(value) {
+			return "Int cannot represent the non-integer value " + number.String()
+		}
+
+		if value > math.MaxInt32 || value < math.MinInt32 {
+			return "Int cannot represent the non 32-bit signed integer value " + number.String()
+		}
+
+		return ""
+	case "Float":
+		if number, ok := val.(json.Number); ok {
+			if _, err := strconv.ParseFloat(number.String(), 64); err != nil {
+				return "Float cannot represent the non numeric value " + number.String()
+			}
+
+			return ""
+		}
+	case "String":
+		if _, ok := val.(string); ok {
+			return ""
+		}
+	case "Boolean":
+		if _, ok := val.(bool); ok {
+			return ""
+		}
+	case "ID":
+		switch value := val.(type) {
+		case string:
+			return ""
+		case json.Number:
+			if _, err := strconv.ParseInt(value.String(), 10, 64); err == nil {
+				return ""
+			}
+		}
+	default:
+		return ""
+	}
+
+	return scalar + " cannot represent the value " + formatJSON(val)
+}
+
+// formatJSON formats a json value for error messages
+func formatJSON(val interface{}) string {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprint(val)
+	}
+
+	return string(data)
+}
+
+// escapePointer escapes a name to be a reference token of a json pointer
+func escapePointer(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
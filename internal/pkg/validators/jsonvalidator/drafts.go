@@ -0,0 +1,263 @@
+package jsonvalidator
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Supported json schema drafts
+const (
+	DRAFT_07      = "draft-07"
+	DRAFT_2019_09 = "draft-2019-09"
+	DRAFT_2020_12 = "draft-2020-12"
+)
+
+// draftURIs maps the URIs of the drafts' meta-schemas, as they appear in
+// the "$schema" keyword, to the drafts.
+var draftURIs = map[string]string{
+	"http://json-schema.org/draft-07/schema":       DRAFT_07,
+	"https://json-schema.org/draft/2019-09/schema": DRAFT_2019_09,
+	"https://json-schema.org/draft/2020-12/schema": DRAFT_2020_12,
+}
+
+// Vocabularies that group the keywords of drafts 2019-09 and 2020-12
+const (
+	VOCABULARY_CORE              = "core"
+	VOCABULARY_APPLICATOR        = "applicator"
+	VOCABULARY_UNEVALUATED       = "unevaluated"
+	VOCABULARY_VALIDATION        = "validation"
+	VOCABULARY_META_DATA         = "meta-data"
+	VOCABULARY_FORMAT_ANNOTATION = "format-annotation"
+	VOCABULARY_FORMAT_ASSERTION  = "format-assertion"
+	VOCABULARY_CONTENT           = "content"
+)
+
+// The URI of the draft 2019-09 vocabulary of "format", which asserts the
+// format only if the meta-schema requires the vocabulary.
+const formatVocabulary2019 = "https://json-schema.org/draft/2019-09/vocab/format"
+
+// vocabularyURIs maps the URIs of the known vocabularies, as they appear in
+// the "$vocabulary" keyword of meta-schemas, to their names.
+var vocabularyURIs = map[string]string{
+	"https://json-schema.org/draft/2019-09/vocab/core":       VOCABULARY_CORE,
+	"https://json-schema.org/draft/2019-09/vocab/applicator": VOCABULARY_APPLICATOR,
+	"https://json-schema.org/draft/2019-09/vocab/validation": VOCABULARY_VALIDATION,
+	"https://json-schema.org/draft/2019-09/vocab/meta-data":  VOCABULARY_META_DATA,
+	"https://json-schema.org/draft/2019-09/vocab/content":    VOCABULARY_CONTENT,
+	formatVocabulary2019: VOCABULARY_FORMAT_ANNOTATION,
+	"https://json-schema.org/draft/2020-12/vocab/core":              VOCABULARY_CORE,
+	"https://json-schema.org/draft/2020-12/vocab/applicator":        VOCABULARY_APPLICATOR,
+	"https://json-schema.org/draft/2020-12/vocab/unevaluated":       VOCABULARY_UNEVALUATED,
+	"https://json-schema.org/draft/2020-12/vocab/validation":        VOCABULARY_VALIDATION,
+	"https://json-schema.org/draft/2020-12/vocab/meta-data":         VOCABULARY_META_DATA,
+	"https://json-schema.org/draft/2020-12/vocab/format-annotation": VOCABULARY_FORMAT_ANNOTATION,
+	"https://json-schema.org/draft/2020-12/vocab/format-assertion":  VOCABULARY_FORMAT_ASSERTION,
+	"https://json-schema.org/draft/2020-12/vocab/content":           VOCABULARY_CONTENT,
+}
+
+// keywordVocabularies maps the keywords that a vocabulary can turn off to
+// the vocabulary that defines them. Keywords that are not in the map are
+// always evaluated.
+var keywordVocabularies = map[string]string{
+	"prefixItems":           VOCABULARY_APPLICATOR,
+	"items":                 VOCABULARY_APPLICATOR,
+	"additionalItems":       VOCABULARY_APPLICATOR,
+	"contains":              VOCABULARY_APPLICATOR,
+	"properties":            VOCABULARY_APPLICATOR,
+	"patternProperties":     VOCABULARY_APPLICATOR,
+	"additionalProperties":  VOCABULARY_APPLICATOR,
+	"dependentSchemas":      VOCABULARY_APPLICATOR,
+	"propertyNames":         VOCABULARY_APPLICATOR,
+	"if":                    VOCABULARY_APPLICATOR,
+	"allOf":                 VOCABULARY_APPLICATOR,
+	"anyOf":                 VOCABULARY_APPLICATOR,
+	"oneOf":                 VOCABULARY_APPLICATOR,
+	"not":                   VOCABULARY_APPLICATOR,
+	"unevaluatedItems":      VOCABULARY_UNEVALUATED,
+	"unevaluatedProperties": VOCABULARY_UNEVALUATED,
+	"type":                  VOCABULARY_VALIDATION,
+	"enum":                  VOCABULARY_VALIDATION,
+	"const":                 VOCABULARY_VALIDATION,
+	"multipleOf":            VOCABULARY_VALIDATION,
+	"maximum":               VOCABULARY_VALIDATION,
+	"exclusiveMaximum":      VOCABULARY_VALIDATION,
+	"minimum":               VOCABULARY_VALIDATION,
+	"exclusiveMinimum":      VOCABULARY_VALIDATION,
+	"maxLength":             VOCABULARY_VALIDATION,
+	"minLength":             VOCABULARY_VALIDATION,
+	"pattern":               VOCABULARY_VALIDATION,
+	"maxItems":              VOCABULARY_VALIDATION,
+	"minItems":              VOCABULARY_VALIDATION,
+	"uniqueItems":           VOCABULARY_VALIDATION,
+	"maxContains":           VOCABULARY_VALIDATION,
+	"minContains":           VOCABULARY_VALIDATION,
+	"maxProperties":         VOCABULARY_VALIDATION,
+	"minProperties":         VOCABULARY_VALIDATION,
+	"required":              VOCABULARY_VALIDATION,
+	"dependentRequired":     VOCABULARY_VALIDATION,
+	"format":                VOCABULARY_FORMAT_ASSERTION,
+}
+
+// draftKeywords maps the keywords that only some of the drafts define to
+// those drafts. Keywords that are not in the map are defined by all drafts.
+var draftKeywords = map[string][]string{
+	"$recursiveRef":         {DRAFT_2019_09},
+	"$dynamicRef":           {DRAFT_2020_12},
+	"prefixItems":           {DRAFT_2020_12},
+	"additionalItems":       {DRAFT_07, DRAFT_2019_09},
+	"dependentRequired":     {DRAFT_2019_09, DRAFT_2020_12},
+	"dependentSchemas":      {DRAFT_2019_09, DRAFT_2020_12},
+	"minContains":           {DRAFT_2019_09, DRAFT_2020_12},
+	"maxContains":           {DRAFT_2019_09, DRAFT_2020_12},
+	"unevaluatedItems":      {DRAFT_2019_09, DRAFT_2020_12},
+	"unevaluatedProperties": {DRAFT_2019_09, DRAFT_2020_12},
+}
+
+// isDraftKeyword returns true if a draft defines the keyword.
+func isDraftKeyword(draft, keyword string) bool {
+	drafts, ok := draftKeywords[keyword]
+	if !ok {
+		return true
+	}
+
+	for _, keywordDraft := range drafts {
+		if keywordDraft == draft {
+			return true
+		}
+	}
+
+	return false
+}
+
+// vocabularies is the set of vocabularies that a schema uses.
+// A nil set means that the schema's draft has no vocabularies, so all of its
+// keywords are evaluated.
+type vocabularies map[string]bool
+
+// enabled returns true if the keyword should be evaluated.
+func (v vocabularies) enabled(keyword string) bool {
+	if v == nil {
+		return true
+	}
+
+	vocabulary, ok := keywordVocabularies[keyword]
+	if !ok {
+		return true
+	}
+
+	return v[vocabulary]
+}
+
+// defaultVocabularies returns the vocabularies of the standard meta-schema of
+// a draft. "format" is only an annotation in drafts 2019-09 and 2020-12.
+func defaultVocabularies(draft string) vocabularies {
+	switch draft {
+	case DRAFT_2019_09, DRAFT_2020_12:
+		return vocabularies{
+			VOCABULARY_CORE:              true,
+			VOCABULARY_APPLICATOR:        true,
+			VOCABULARY_UNEVALUATED:       true,
+			VOCABULARY_VALIDATION:        true,
+			VOCABULARY_META_DATA:         true,
+			VOCABULARY_FORMAT_ANNOTATION: true,
+			VOCABULARY_CONTENT:           true,
+		}
+	default:
+		return nil
+	}
+}
+
+// isSupportedDraft returns true if JsonValidator supports the draft.
+func isSupportedDraft(draft string) bool {
+	for _, supportedDraft := range draftURIs {
+		if supportedDraft == draft {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dialect describes the draft and vocabularies that a schema is written in.
+type dialect struct {
+	draft        string
+	vocabularies vocabularies
+
+	// metaSchema is the custom meta-schema that the schema declares, if it
+	// does not declare the meta-schema of a draft.
+	metaSchema *RootJsonSchema
+}
+
+// schemaDialect determines the dialect of a raw schema according to its
+// "$schema" keyword. A schema without "$schema" is written in the default
+// draft. A schema may declare a custom meta-schema that was already loaded
+// into the registry, in which case the dialect is taken from the
+// meta-schema's own "$schema" and "$vocabulary" keywords.
+func schemaDialect(rawSchema []byte, defaultDraft string, registry *schemaRegistry) (dialect, error) {
+	var declaration struct {
+		Schema string `json:"$schema"`
+	}
+
+	// Boolean schemas cannot declare a dialect.
+	_ = json.Unmarshal(rawSchema, &declaration)
+
+	if declaration.Schema == "" {
+		return dialect{defaultDraft, defaultVocabularies(defaultDraft), nil}, nil
+	}
+
+	if draft, ok := draftURIs[strings.TrimSuffix(declaration.Schema, "#")]; ok {
+		return dialect{draft, defaultVocabularies(draft), nil}, nil
+	}
+
+	metaSchemaURI, err := resolveReference(DEFAULT_BASE_URI, declaration.Schema)
+	if err != nil {
+		return dialect{}, errors.Wrap(err, "invalid meta-schema \""+declaration.Schema+"\"")
+	}
+
+	metaSchema := registry.lookup(metaSchemaURI)
+	if metaSchema == nil {
+		return dialect{}, errors.New("unknown meta-schema \"" + declaration.Schema + "\"")
+	}
+
+	// A meta-schema without "$vocabulary" uses the vocabularies of its
+	// own draft.
+	if metaSchema.Vocabulary == nil || metaSchema.draft == DRAFT_07 {
+		return dialect{metaSchema.draft, defaultVocabularies(metaSchema.draft), metaSchema}, nil
+	}
+
+	schemaVocabularies := vocabularies{}
+	for uri, required := range metaSchema.Vocabulary {
+		name, ok := vocabularyURIs[uri]
+		if !ok {
+			// Unknown optional vocabularies are ignored, but a schema that
+			// requires an unknown vocabulary cannot be evaluated correctly.
+			if required {
+				return dialect{}, errors.New("meta-schema \"" +
+					declaration.Schema +
+					"\" requires an unsupported vocabulary \"" +
+					uri +
+					"\"")
+			}
+
+			continue
+		}
+
+		// The draft 2019-09 "format" vocabulary asserts the format only
+		// when it is required.
+		if uri == formatVocabulary2019 && required {
+			name = VOCABULARY_FORMAT_ASSERTION
+		}
+
+		schemaVocabularies[name] = true
+	}
+
+	// Draft 2019-09 defines the "unevaluated" keywords in its applicator
+	// vocabulary.
+	if metaSchema.draft == DRAFT_2019_09 && schemaVocabularies[VOCABULARY_APPLICATOR] {
+		schemaVocabularies[VOCABULARY_UNEVALUATED] = true
+	}
+
+	return dialect{metaSchema.draft, schemaVocabularies, metaSchema}, nil
+}
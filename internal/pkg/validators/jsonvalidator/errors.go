@@ -1,6 +1,11 @@
 package jsonvalidator
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apidome/gateway/internal/pkg/validators"
+)
 
 type KeywordValidationError struct {
 	keyword string
@@ -12,22 +17,109 @@ func (e KeywordValidationError) Error() string {
 }
 
 type SchemaValidationError struct {
-	path string
-	err  string
+	path       string
+	schemaPath string
+	keyword    string
+	reason     string
 }
 
 func (e SchemaValidationError) Error() string {
-	var jsonPath string
-	if e.path == "" {
-		jsonPath = "/"
-	} else {
-		jsonPath = e.path
+	err := e.reason
+	if e.keyword != "" {
+		err = KeywordValidationError{e.keyword, e.reason}.Error()
 	}
 
 	return fmt.Sprintf("validation failed in path " +
-		jsonPath +
+		e.Path() +
 		": " +
-		e.err)
+		err)
+}
+
+// Path returns the json path of the value that failed in validation.
+func (e SchemaValidationError) Path() string {
+	if e.path == "" {
+		return "/"
+	}
+
+	return e.path
+}
+
+// SchemaPath returns the json path of the keyword that the value failed in,
+// relative to the root schema and following the "$ref"s on the way.
+func (e SchemaValidationError) SchemaPath() string {
+	if e.schemaPath == "" {
+		return "/"
+	}
+
+	return e.schemaPath
+}
+
+// Keyword returns the keyword that the value failed in, or an empty string
+// if the failure is not related to a specific keyword.
+func (e SchemaValidationError) Keyword() string {
+	return e.keyword
+}
+
+// Reason returns the reason of the validation failure.
+func (e SchemaValidationError) Reason() string {
+	return e.reason
+}
+
+// ValidationErrors is a list of all the failures that were found during an
+// exhaustive validation.
+type ValidationErrors []SchemaValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for index, err := range e {
+		messages[index] = err.Error()
+	}
+
+	return fmt.Sprintf("%d validation failures: %s", len(e), strings.Join(messages, "; "))
+}
+
+// Errors returns the failures in the list.
+func (e ValidationErrors) Errors() []validators.ValidationError {
+	errs := make([]validators.ValidationError, len(e))
+	for index, err := range e {
+		errs[index] = err
+	}
+
+	return errs
+}
+
+// add appends the validation failures that err holds to the list, after
+// prefixing their schema path with the location of the keyword that
+// produced them.
+// If err is not a validation failure, it is returned as is.
+func (e ValidationErrors) add(err error, schemaPath string) (ValidationErrors, error) {
+	switch v := err.(type) {
+	case SchemaValidationError:
+		v.schemaPath = schemaPath + v.schemaPath
+		return append(e, v), nil
+	case ValidationErrors:
+		for _, schemaValidationError := range v {
+			schemaValidationError.schemaPath = schemaPath + schemaValidationError.schemaPath
+			e = append(e, schemaValidationError)
+		}
+
+		return e, nil
+	default:
+		return e, err
+	}
+}
+
+// result returns the list as an error: nil if it is empty, the failure
+// itself if it holds a single failure, and the whole list otherwise.
+func (e ValidationErrors) result() error {
+	switch len(e) {
+	case 0:
+		return nil
+	case 1:
+		return e[0]
+	default:
+		return e
+	}
 }
 
 type SchemaCompilationError struct {
@@ -45,6 +137,12 @@ func (e InvalidDraftError) Error() string {
 	return fmt.Sprintf("draft " + string(e) + " is not supported by JsonValidator")
 }
 
+type InvalidOutputFormatError string
+
+func (e InvalidOutputFormatError) Error() string {
+	return fmt.Sprintf("output format \"%s\" is not supported by JsonValidator", string(e))
+}
+
 type InvalidReferenceError struct {
 	schemaURI string
 	fragment  string
@@ -59,3 +157,20 @@ func (e InvalidReferenceError) Error() string {
 
 	return fmt.Sprintf(e.err + ": schema id - " + e.schemaURI + ", fragment - " + fragment)
 }
+
+type ConflictingIdError struct {
+	id     string
+	reason string
+}
+
+func (e ConflictingIdError) Error() string {
+	return fmt.Sprintf("conflicting $id \"%s\": %s", e.id, e.reason)
+}
+
+// UnresolvedReferencesError lists the references of the loaded schemas that
+// point to schemas that were not loaded.
+type UnresolvedReferencesError []string
+
+func (e UnresolvedReferencesError) Error() string {
+	return fmt.Sprintf("could not resolve %d references: %s", len(e), strings.Join(e, ", "))
+}
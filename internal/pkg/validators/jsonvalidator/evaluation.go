@@ -0,0 +1,105 @@
+package jsonvalidator
+
+// evaluation collects the annotations that the keywords of a schema produce
+// about the properties and items of a json value that they evaluated.
+// "unevaluatedProperties" and "unevaluatedItems" rely on these annotations
+// in order to find the properties and items that no other keyword evaluated.
+type evaluation struct {
+	// jsonPath is the location of the evaluated value in the data.
+	jsonPath string
+
+	// properties holds the names of the evaluated properties.
+	properties map[string]bool
+
+	// items is the number of items that were evaluated from the beginning
+	// of the array, and allItems is true if all of them were.
+	items    int
+	allItems bool
+
+	// indices holds the indices of items that were evaluated individually,
+	// like the items that matched "contains".
+	indices map[int]bool
+}
+
+// newEvaluation creates an empty evaluation of the value at jsonPath.
+func newEvaluation(jsonPath string) *evaluation {
+	return &evaluation{jsonPath: jsonPath}
+}
+
+// addProperty marks a property as evaluated.
+func (e *evaluation) addProperty(name string) {
+	if e == nil {
+		return
+	}
+
+	if e.properties == nil {
+		e.properties = make(map[string]bool)
+	}
+
+	e.properties[name] = true
+}
+
+// addItems marks the first count items as evaluated.
+func (e *evaluation) addItems(count int) {
+	if e == nil {
+		return
+	}
+
+	if count > e.items {
+		e.items = count
+	}
+}
+
+// addAllItems marks all the items as evaluated.
+func (e *evaluation) addAllItems() {
+	if e == nil {
+		return
+	}
+
+	e.allItems = true
+}
+
+// addIndex marks a single item as evaluated.
+func (e *evaluation) addIndex(index int) {
+	if e == nil {
+		return
+	}
+
+	if e.indices == nil {
+		e.indices = make(map[int]bool)
+	}
+
+	e.indices[index] = true
+}
+
+// isPropertyEvaluated returns true if a property was evaluated.
+func (e *evaluation) isPropertyEvaluated(name string) bool {
+	return e != nil && e.properties[name]
+}
+
+// isItemEvaluated returns true if an item was evaluated.
+func (e *evaluation) isItemEvaluated(index int) bool {
+	return e != nil && (e.allItems || index < e.items || e.indices[index])
+}
+
+// merge adds the annotations of a sub-schema that evaluated the same value to
+// the evaluation.
+func (e *evaluation) merge(other *evaluation) {
+	if e == nil || other == nil || e.jsonPath != other.jsonPath {
+		return
+	}
+
+	for name := range other.properties {
+		e.addProperty(name)
+	}
+
+	e.addItems(other.items)
+
+	if other.allItems {
+		e.addAllItems()
+	}
+
+	for index := range other.indices {
+		e.addIndex(index)
+	}
+}
@@ -0,0 +1,38 @@
+package jsonvalidator
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// ValidateForm validates the fields of a form body (url-encoded or
+// multipart) against the schema of the endpoint's request bodies, as a json
+// object that maps each field name to its value.
+// Like parameters, the values are converted to the types that the schema
+// expects, and a field is an array only if its schema allows arrays.
+func (jv JsonValidator) ValidateForm(path, method string, fields map[string][]string) error {
+	jv.mutex.RLock()
+	schemas, isPathExist := jv.schemaDict[path]
+	schema, isMethodExist := schemas[method]
+	jv.mutex.RUnlock()
+
+	if !isPathExist {
+		return errors.New("could not validate request: unknown path \"" + path + "\"")
+	}
+
+	if !isMethodExist {
+		return errors.New("could not validate to path " +
+			path +
+			": no schema exist for method \"" +
+			method +
+			"\"")
+	}
+
+	bytes, err := json.Marshal(coerceParameters(schema, "", fields))
+	if err != nil {
+		return errors.Wrap(err, "could not encode the form fields")
+	}
+
+	return schema.validateBytes(bytes, jv.exhaustive)
+}
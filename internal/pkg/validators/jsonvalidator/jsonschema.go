@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"sort"
 	"strconv"
 	"strings"
 
@@ -31,19 +32,167 @@ const (
 	ENCODING_BASE64           = "base64"
 )
 
+// jsonData is a json value that is being validated. The data is decoded
+// once, and the keywords pass the parts of the decoded value on to their
+// sub-schemas.
 type jsonData struct {
-	raw   json.RawMessage
 	value interface{}
 }
 
+// newJsonData wraps a decoded json value.
+func newJsonData(value interface{}) jsonData {
+	return jsonData{value}
+}
+
+// validationContext holds the information that is shared between the
+// keywords during the validation of a json value.
+type validationContext struct {
+	// rootSchemaId is the URI of the schema resource that the validated
+	// keywords belong to.
+	rootSchemaId string
+
+	// registry holds the schemas that references point to.
+	registry *schemaRegistry
+
+	// exhaustive determines whether the validation should continue after
+	// the first failure in order to collect all the failures.
+	exhaustive bool
+
+	// draft and vocabularies describe the dialect of the root schema, which
+	// determines how its keywords are evaluated.
+	draft        string
+	vocabularies vocabularies
+
+	// dynamicScope holds the schema resources that the validation passed
+	// through, from the outermost one. "$recursiveRef" and "$dynamicRef"
+	// are resolved against it.
+	dynamicScope []*RootJsonSchema
+
+	// evaluation collects the annotations of the schema that is being
+	// validated.
+	evaluation *evaluation
+
+	// references holds the references that were followed since the
+	// validation reached the value at referencesPath.
+	references     []string
+	referencesPath string
+}
+
+// newValidationContext creates the context for validating a json value
+// against a root schema.
+func newValidationContext(rootSchema *RootJsonSchema, exhaustive bool) *validationContext {
+	ctx := &validationContext{
+		registry:   rootSchema.registry,
+		exhaustive: exhaustive,
+	}
+
+	return ctx.withRootSchema(rootSchema)
+}
+
+// withRootSchema returns a copy of the context for validating against the
+// keywords of another root schema.
+func (ctx *validationContext) withRootSchema(rootSchema *RootJsonSchema) *validationContext {
+	newCtx := *ctx
+	newCtx.rootSchemaId = rootSchema.uri
+	newCtx.draft = rootSchema.draft
+	newCtx.vocabularies = rootSchema.vocabularies
+
+	// The scope is copied since other branches of the validation share
+	// the original.
+	newCtx.dynamicScope = make([]*RootJsonSchema, len(ctx.dynamicScope), len(ctx.dynamicScope)+1)
+	copy(newCtx.dynamicScope, ctx.dynamicScope)
+	newCtx.dynamicScope = append(newCtx.dynamicScope, rootSchema)
+
+	return &newCtx
+}
+
+// withEvaluation returns a copy of the context that collects annotations
+// into another evaluation.
+func (ctx *validationContext) withEvaluation(evaluation *evaluation) *validationContext {
+	newCtx := *ctx
+	newCtx.evaluation = evaluation
+
+	return &newCtx
+}
+
+// isLegacyDraft returns true if the root schema is written in draft-07, in
+// which "$ref" overrides the keywords next to it.
+func (ctx *validationContext) isLegacyDraft() bool {
+	return ctx.draft == DRAFT_07 || ctx.draft == ""
+}
+
+// evaluates returns true if a keyword is part of the root schema's draft and
+// of the vocabularies that it uses.
+func (ctx *validationContext) evaluates(keyword string) bool {
+	draft := ctx.draft
+	if ctx.isLegacyDraft() {
+		draft = DRAFT_07
+	}
+
+	return isDraftKeyword(draft, keyword) && ctx.vocabularies.enabled(keyword)
+}
+
+// withReference returns a copy of the context that remembers that a
+// reference was followed for the value at jsonPath. Following the same
+// reference again for the same value means that the references form a cycle
+// that never reaches deeper into the data, so an error is returned instead.
+func (ctx *validationContext) withReference(reference, jsonPath string) (*validationContext, error) {
+	var references []string
+	if ctx.referencesPath == jsonPath {
+		references = ctx.references
+	}
+
+	for _, followed := range references {
+		if followed == reference {
+			schemaURI, fragment := splitReference(reference)
+
+			return nil, InvalidReferenceError{
+				schemaURI: schemaURI,
+				fragment:  fragment,
+				err:       "circular reference",
+			}
+		}
+	}
+
+	newCtx := *ctx
+
+	// The references are copied since other branches of the validation
+	// share the original.
+	newCtx.references = make([]string, len(references), len(references)+1)
+	copy(newCtx.references, references)
+	newCtx.references = append(newCtx.references, reference)
+	newCtx.referencesPath = jsonPath
+
+	return &newCtx, nil
+}
+
+// firstFailure returns a copy of the context that stops at the first
+// failure. It is used by keywords that only care whether a sub-schema
+// passed or failed.
+func (ctx *validationContext) firstFailure() *validationContext {
+	if !ctx.exhaustive {
+		return ctx
+	}
+
+	newCtx := *ctx
+	newCtx.exhaustive = false
+
+	return &newCtx
+}
+
 type JsonSchema struct {
 	// RejectAll is ***not*** a json schema keyword!
 	// It is an internal flag for internal use that represents a json schema
 	// that suppose to reject all json values.
-	// If it is true, all other field will be ignored and validateJsonData()
+	// If it is true, all other field will be ignored and validateValue()
 	// will always return false.
 	RejectAll bool `json:"rejectAll,omitempty"`
 
+	// keywords holds the keywords of the schema that its dialect evaluates,
+	// in the order of their evaluation. They are collected once when the
+	// schema is scanned, so the validation only walks the data.
+	keywords []keywordValidator
+
 	// The $schema keyword is used to declare that a JSON fragment is
 	// actually a piece of JSON Schema.
 	Schema *schema `json:"$schema,omitempty"`
@@ -52,11 +201,31 @@ type JsonSchema struct {
 	// called JSON Pointer.
 	Ref *ref `json:"$ref,omitempty"`
 
+	// "$recursiveRef" (draft 2019-09) and "$dynamicRef" (draft 2020-12) are
+	// references that are resolved against the dynamic scope of the
+	// validation, which lets schemas that extend a recursive schema extend
+	// its recursion as well.
+	RecursiveRef *recursiveRef `json:"$recursiveRef,omitempty"`
+	DynamicRef   *dynamicRef   `json:"$dynamicRef,omitempty"`
+
 	// The $id property is a URI that serves two purposes:
 	// It declares a unique identifier for the schema
 	// It declares a base URI against which $ref URIs are resolved.
 	Id *id `json:"$id,omitempty"`
 
+	// "$anchor" gives the schema a plain name that references can use as
+	// their fragment. "$dynamicAnchor" does the same and marks the schema as
+	// a target of "$dynamicRef", and "$recursiveAnchor" marks a root schema
+	// as a target of "$recursiveRef".
+	Anchor          *anchor          `json:"$anchor,omitempty"`
+	DynamicAnchor   *dynamicAnchor   `json:"$dynamicAnchor,omitempty"`
+	RecursiveAnchor *recursiveAnchor `json:"$recursiveAnchor,omitempty"`
+
+	// The "$vocabulary" keyword of a meta-schema declares the vocabularies
+	// that the schemas which use the meta-schema are written in, and whether
+	// an implementation must understand them.
+	Vocabulary vocabulary `json:"$vocabulary,omitempty"`
+
 	// The $comment keyword is strictly intended for adding comments
 	// to the JSON schema source. Its value must always be a string.
 	Comment *comment `json:"$comment,omitempty"`
@@ -97,6 +266,9 @@ type JsonSchema struct {
 	// object MUST be a valid JSON Schema.
 	Definitions definitions `json:"definitions,omitempty"`
 
+	// "$defs" replaces "definitions" since draft 2019-09.
+	Defs definitions `json:"$defs,omitempty"`
+
 	// The value of "properties" MUST be an object. Each value of this object
 	// MUST be a valid JSON Schema.
 	// This keyword determines how child instances validate for objects, and
@@ -142,6 +314,12 @@ type JsonSchema struct {
 	// must be a property that exists in the instance.
 	Dependencies dependencies `json:"dependencies,omitempty"`
 
+	// Since draft 2019-09 "dependencies" is split into "dependentRequired",
+	// whose values are arrays of property names, and "dependentSchemas",
+	// whose values are schemas.
+	DependentRequired dependentRequired `json:"dependentRequired,omitempty"`
+	DependentSchemas  dependentSchemas  `json:"dependentSchemas,omitempty"`
+
 	// The value of "patternProperties" MUST be an object. Each property name
 	// of this object SHOULD be a valid regular expression, according to the
 	// ECMA 262 regular expression dialect. Each property value of this object
@@ -164,7 +342,14 @@ type JsonSchema struct {
 	// If "items" is an array of schemas, validation succeeds if each element
 	// of the instance validates against the schema at the same position,
 	// if any.
-	Items items `json:"items,omitempty"`
+	// In draft 2020-12 "items" is always a schema, and it applies to the
+	// elements that "prefixItems" does not.
+	Items *items `json:"items,omitempty"`
+
+	// The value of "prefixItems" (draft 2020-12) MUST be a non-empty array
+	// of valid JSON Schemas. Validation succeeds if each element of the
+	// instance validates against the schema at the same position, if any.
+	PrefixItems prefixItems `json:"prefixItems,omitempty"`
 
 	// The value of this keyword MUST be a valid JSON Schema.
 	// An array instance is valid against "contains" if at least one of its
@@ -174,6 +359,12 @@ type JsonSchema struct {
 	// possible annotations are collected.
 	Contains *contains `json:"contains,omitempty"`
 
+	// "minContains" and "maxContains" (since draft 2019-09) limit the number
+	// of elements that are valid against "contains". They are ignored when
+	// "contains" is absent.
+	MinContains *minContains `json:"minContains,omitempty"`
+	MaxContains *maxContains `json:"maxContains,omitempty"`
+
 	// The value of "additionalItems" MUST be a valid JSON Schema.
 	// This keyword determines how child instances validate for arrays, and
 	// does not directly validate the immediate instance itself.
@@ -233,6 +424,13 @@ type JsonSchema struct {
 	Then *_then `json:"then,omitempty"`
 	Else *_else `json:"else,omitempty"`
 
+	// "unevaluatedProperties" and "unevaluatedItems" (since draft 2019-09)
+	// apply to the properties and items that no other keyword of the schema
+	// or of its in-place sub-schemas ("allOf", "$ref", "if" and so on)
+	// evaluated successfully.
+	UnevaluatedProperties *unevaluatedProperties `json:"unevaluatedProperties,omitempty"`
+	UnevaluatedItems      *unevaluatedItems      `json:"unevaluatedItems,omitempty"`
+
 	// If "readOnly" has a value of boolean true, it indicates that the value
 	// of the instance is managed exclusively by the owning authority, and
 	// attempts by an application to modify the value of this property are
@@ -274,7 +472,7 @@ func NewJsonSchema(bytes []byte) (*JsonSchema, error) {
 		return nil, err
 	}
 
-	err = schema.scanSchema("", "")
+	err = schema.scanSchema(schemaLocation{})
 	if err != nil {
 		fmt.Println("[JsonSchema DEBUG] connectRelatedKeywords() " +
 			"failed: " + err.Error())
@@ -284,17 +482,86 @@ func NewJsonSchema(bytes []byte) (*JsonSchema, error) {
 	return schema, nil
 }
 
+// schemaScope is the location of a sub-schema inside one of the schema
+// resources that contain it.
+type schemaScope struct {
+	resource *RootJsonSchema
+	path     string
+}
+
+// schemaLocation is the location of a sub-schema during the scan of a root
+// schema: its scopes in the root schema and in every embedded schema
+// resource (a sub-schema with an "$id") that contains it, from the outermost
+// one.
+type schemaLocation struct {
+	scopes []schemaScope
+
+	// document is the decoded root schema, from which the content of the
+	// embedded resources is taken.
+	document interface{}
+}
+
+// child returns the location of a sub-schema of the schema at l. The paths
+// are json pointers, so the tokens are escaped.
+func (l schemaLocation) child(tokens ...string) schemaLocation {
+	var suffix string
+	for _, token := range tokens {
+		suffix += "/" + jsonwalker.EscapeToken(token)
+	}
+
+	scopes := make([]schemaScope, len(l.scopes))
+	for index, scope := range l.scopes {
+		scopes[index] = schemaScope{scope.resource, scope.path + suffix}
+	}
+
+	return schemaLocation{scopes, l.document}
+}
+
+// path returns the path of the location in the root schema.
+func (l schemaLocation) path() string {
+	if len(l.scopes) == 0 {
+		return ""
+	}
+
+	return l.scopes[0].path
+}
+
+// resource returns the innermost schema resource that contains the
+// location, or nil if the schema is not scanned as part of a root schema.
+func (l schemaLocation) resource() *RootJsonSchema {
+	if len(l.scopes) == 0 {
+		return nil
+	}
+
+	return l.scopes[len(l.scopes)-1].resource
+}
+
 // scanSchema is a recursive function that connect the related
 // keywords of the schema (as mentioned in the description of NewJsonSchema()).
 // The function scans the schema in and it's sub-schemas and perform the
 // required connections.
-func (js *JsonSchema) scanSchema(schemaPath string, rootSchemaID string) error {
+func (js *JsonSchema) scanSchema(location schemaLocation) error {
 	js.connectRelatedKeywords()
-	js.mapSubSchema(schemaPath, rootSchemaID)
+
+	// The keywords are collected before the schema is mapped, since an
+	// embedded resource is a copy of the schema. A schema is evaluated
+	// according to the dialect of the resource that contains it.
+	dialectCtx := &validationContext{}
+	if resource := location.resource(); resource != nil {
+		dialectCtx.draft = resource.draft
+		dialectCtx.vocabularies = resource.vocabularies
+	}
+
+	js.keywords = getNonNilKeywordsSlice(js, dialectCtx)
+
+	location, err := js.mapSubSchema(location)
+	if err != nil {
+		return err
+	}
 
 	// Connect sub-schemas in "properties" field.
 	for key := range js.Properties {
-		err := js.Properties[key].scanSchema(schemaPath+"/properties/"+key, rootSchemaID)
+		err := js.Properties[key].scanSchema(location.child("properties", key))
 		if err != nil {
 			return err
 		}
@@ -302,7 +569,7 @@ func (js *JsonSchema) scanSchema(schemaPath string, rootSchemaID string) error {
 
 	// Connect sub-schema in "additionalProperties" field.
 	if js.AdditionalProperties != nil {
-		err := js.AdditionalProperties.scanSchema(schemaPath+"/additionalProperties", rootSchemaID)
+		err := js.AdditionalProperties.scanSchema(location.child("additionalProperties"))
 		if err != nil {
 			return err
 		}
@@ -310,7 +577,7 @@ func (js *JsonSchema) scanSchema(schemaPath string, rootSchemaID string) error {
 
 	// Connect sub-schema in "propertyNames" field.
 	if js.PropertyNames != nil {
-		err := js.PropertyNames.scanSchema(schemaPath+"/propertyNames", rootSchemaID)
+		err := js.PropertyNames.scanSchema(location.child("propertyNames"))
 		if err != nil {
 			return err
 		}
@@ -325,7 +592,7 @@ func (js *JsonSchema) scanSchema(schemaPath string, rootSchemaID string) error {
 			rawDependency, err := json.Marshal(v)
 			if err != nil {
 				return SchemaCompilationError{
-					schemaPath + "/dependencies" + key,
+					location.path() + "/dependencies/" + key,
 					err.Error(),
 				}
 			}
@@ -334,12 +601,12 @@ func (js *JsonSchema) scanSchema(schemaPath string, rootSchemaID string) error {
 			err = json.Unmarshal(rawDependency, subSchema)
 			if err != nil {
 				return SchemaCompilationError{
-					schemaPath,
+					location.path() + "/dependencies/" + key,
 					err.Error(),
 				}
 			}
 
-			err = subSchema.scanSchema(schemaPath+"/dependencies"+key, rootSchemaID)
+			err = subSchema.scanSchema(location.child("dependencies", key))
 			if err != nil {
 				return err
 			}
@@ -351,7 +618,7 @@ func (js *JsonSchema) scanSchema(schemaPath string, rootSchemaID string) error {
 
 	// Connect sub-schemas in "patternProperties" field.
 	for key := range js.PatternProperties {
-		err := js.PatternProperties[key].scanSchema(schemaPath+"/patternProperties/"+key, rootSchemaID)
+		err := js.PatternProperties[key].scanSchema(location.child("patternProperties", key))
 		if err != nil {
 			return err
 		}
@@ -359,113 +626,56 @@ func (js *JsonSchema) scanSchema(schemaPath string, rootSchemaID string) error {
 
 	// Connect sub-schemas in "definitions" field.
 	for key := range js.Definitions {
-		err := js.Definitions[key].scanSchema(schemaPath+"/definitions/"+key, rootSchemaID)
+		err := js.Definitions[key].scanSchema(location.child("definitions", key))
 		if err != nil {
 			return err
 		}
 	}
 
-	// Connect sub-schemas in "items" field.
-	if js.Items != nil {
-		var items interface{}
-
-		// Unmarshal the item to an empty interface variable in order
-		// to check if the "items" is a single schema of a list of schemas.
-		err := json.Unmarshal(js.Items, &items)
+	// Connect sub-schemas in "$defs" field.
+	for key := range js.Defs {
+		err := js.Defs[key].scanSchema(location.child("$defs", key))
 		if err != nil {
-			return SchemaCompilationError{
-				schemaPath,
-				err.Error(),
-			}
+			return err
 		}
+	}
 
-		// Check the type of "items"
-		switch v := items.(type) {
-		// In this case, "items" is an object which means its a single schema.
-		case map[string]interface{}, bool:
-			{
-				// Marshal the dependency in order to Unmarshal it into JsonSchema struct.
-				rawSubSchema, err := json.Marshal(v)
-				if err != nil {
-					return SchemaCompilationError{
-						schemaPath + "/items",
-						err.Error(),
-					}
-				}
-
-				subSchema := new(JsonSchema)
-
-				// Create a new JsonSchema instance.
-				err = json.Unmarshal(rawSubSchema, subSchema)
-				if err != nil {
-					return SchemaCompilationError{
-						schemaPath + "/items",
-						err.Error(),
-					}
-				}
-
-				err = subSchema.scanSchema(schemaPath+"/items", rootSchemaID)
-				if err != nil {
-					return err
-				}
+	// Connect sub-schemas in "dependentSchemas" field.
+	for key := range js.DependentSchemas {
+		err := js.DependentSchemas[key].scanSchema(location.child("dependentSchemas", key))
+		if err != nil {
+			return err
+		}
+	}
 
-				js.Items, err = json.Marshal(subSchema)
-				if err != nil {
-					return SchemaCompilationError{
-						schemaPath + "/items",
-						err.Error(),
-					}
-				}
+	// Connect sub-schemas in "items" field.
+	if js.Items != nil {
+		if js.Items.schema != nil {
+			err := js.Items.schema.scanSchema(location.child("items"))
+			if err != nil {
+				return err
 			}
-		// In this case "items" hold an array of schemas.
-		case []interface{}:
-			{
-				// Iterate over each schema in "items".
-				for index, value := range v {
-					// Marshal the dependency in order to Unmarshal it into JsonSchema struct.
-					rawSubSchema, err := json.Marshal(value)
-					if err != nil {
-						return SchemaCompilationError{
-							schemaPath + "/items" + strconv.Itoa(index),
-							err.Error(),
-						}
-					}
-
-					subSchema := new(JsonSchema)
-
-					// Create a new JsonSchema instance.
-					err = json.Unmarshal(rawSubSchema, subSchema)
-					if err != nil {
-						return SchemaCompilationError{
-							path: schemaPath + "/items" + strconv.Itoa(index),
-							err:  "",
-						}
-					}
-
-					err = subSchema.scanSchema(schemaPath+"/items"+strconv.Itoa(index), rootSchemaID)
-					if err != nil {
-						return nil
-					}
-
-					// Save the sub-schema in "items" array.
-					v[index] = subSchema
-				}
+		}
 
-				// Marshal "items" back to a json.RawMessage and store it in the parent schema.
-				js.Items, err = json.Marshal(v)
-				if err != nil {
-					return SchemaCompilationError{
-						schemaPath + "/items",
-						err.Error(),
-					}
-				}
+		for index := range js.Items.schemas {
+			err := js.Items.schemas[index].scanSchema(location.child("items", strconv.Itoa(index)))
+			if err != nil {
+				return err
 			}
 		}
 	}
 
+	// Connect sub-schemas in "prefixItems" field.
+	for index := range js.PrefixItems {
+		err := js.PrefixItems[index].scanSchema(location.child("prefixItems", strconv.Itoa(index)))
+		if err != nil {
+			return err
+		}
+	}
+
 	// Connect sub-schema in "additionalItems" field.
 	if js.AdditionalItems != nil {
-		err := js.AdditionalItems.scanSchema(schemaPath+"/additionalItems", rootSchemaID)
+		err := js.AdditionalItems.scanSchema(location.child("additionalItems"))
 		if err != nil {
 			return err
 		}
@@ -473,7 +683,7 @@ func (js *JsonSchema) scanSchema(schemaPath string, rootSchemaID string) error {
 
 	// Connect sub-schema in "contains" field.
 	if js.Contains != nil {
-		err := js.Contains.scanSchema(schemaPath+"/contains", rootSchemaID)
+		err := js.Contains.scanSchema(location.child("contains"))
 		if err != nil {
 			return err
 		}
@@ -481,7 +691,7 @@ func (js *JsonSchema) scanSchema(schemaPath string, rootSchemaID string) error {
 
 	// Connect sub-schemas in "anyOf" field.
 	for index := range js.AnyOf {
-		err := js.AnyOf[index].scanSchema(schemaPath+"/anyOf/"+strconv.Itoa(index), rootSchemaID)
+		err := js.AnyOf[index].scanSchema(location.child("anyOf", strconv.Itoa(index)))
 		if err != nil {
 			return err
 		}
@@ -489,7 +699,7 @@ func (js *JsonSchema) scanSchema(schemaPath string, rootSchemaID string) error {
 
 	// Connect sub-schemas in "allOf" field.
 	for index := range js.AllOf {
-		err := js.AllOf[index].scanSchema(schemaPath+"/allOf/"+strconv.Itoa(index), rootSchemaID)
+		err := js.AllOf[index].scanSchema(location.child("allOf", strconv.Itoa(index)))
 		if err != nil {
 			return err
 		}
@@ -497,7 +707,7 @@ func (js *JsonSchema) scanSchema(schemaPath string, rootSchemaID string) error {
 
 	// Connect sub-schemas in "oneOf" field.
 	for index := range js.OneOf {
-		err := js.OneOf[index].scanSchema(schemaPath+"/oneOf/"+strconv.Itoa(index), rootSchemaID)
+		err := js.OneOf[index].scanSchema(location.child("oneOf", strconv.Itoa(index)))
 		if err != nil {
 			return err
 		}
@@ -505,7 +715,23 @@ func (js *JsonSchema) scanSchema(schemaPath string, rootSchemaID string) error {
 
 	// Connect sub-schema in "not" field.
 	if js.Not != nil {
-		err := js.Not.scanSchema(schemaPath+"/not", rootSchemaID)
+		err := js.Not.scanSchema(location.child("not"))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Connect sub-schema in "unevaluatedProperties" field.
+	if js.UnevaluatedProperties != nil {
+		err := js.UnevaluatedProperties.scanSchema(location.child("unevaluatedProperties"))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Connect sub-schema in "unevaluatedItems" field.
+	if js.UnevaluatedItems != nil {
+		err := js.UnevaluatedItems.scanSchema(location.child("unevaluatedItems"))
 		if err != nil {
 			return err
 		}
@@ -513,14 +739,14 @@ func (js *JsonSchema) scanSchema(schemaPath string, rootSchemaID string) error {
 
 	// Connect sub-schema in "if" field.
 	if js.If != nil {
-		err := js.If.scanSchema(schemaPath+"/if", rootSchemaID)
+		err := js.If.scanSchema(location.child("if"))
 		if err != nil {
 			return err
 		}
 
 		// Connect sub-schema in "then" field.
 		if js.Then != nil {
-			err := js.Then.scanSchema(schemaPath+"/then", rootSchemaID)
+			err := js.Then.scanSchema(location.child("then"))
 			if err != nil {
 				return err
 			}
@@ -528,7 +754,7 @@ func (js *JsonSchema) scanSchema(schemaPath string, rootSchemaID string) error {
 
 		// Connect sub-schema in "else" field.
 		if js.Else != nil {
-			err := js.Else.scanSchema(schemaPath+"/else", rootSchemaID)
+			err := js.Else.scanSchema(location.child("else"))
 			if err != nil {
 				return err
 			}
@@ -543,6 +769,9 @@ func (js *JsonSchema) scanSchema(schemaPath string, rootSchemaID string) error {
 // Schema.AdditionalProperties 	---> 	Schema.Properties
 // Schema.AdditionalProperties 	---> 	Schema.PatternProperties
 // JsonSchema.AdditionalItems 	---> 	JsonSchema.Items
+// JsonSchema.Items 			---> 	JsonSchema.PrefixItems
+// JsonSchema.Contains 			---> 	JsonSchema.MinContains
+// JsonSchema.Contains 			---> 	JsonSchema.MaxContains
 // JsonSchema.If 				---> 	JsonSchema.Then
 // JsonSchema.IF 				---> 	JsonSchema.Else
 func (js *JsonSchema) connectRelatedKeywords() {
@@ -566,10 +795,27 @@ func (js *JsonSchema) connectRelatedKeywords() {
 		// If "items" field exists in the schema, save the keywordValidator's
 		// address in "AdditionalItems".
 		if js.Items != nil {
-			js.AdditionalItems.siblingItems = &js.Items
+			js.AdditionalItems.siblingItems = js.Items
 		}
 	}
 
+	// Connect sub-schemas in "items" field.
+	if js.Items != nil {
+		// If "prefixItems" field exists in the schema, save the keywordValidator's
+		// address in "Items".
+		if js.PrefixItems != nil {
+			js.Items.siblingPrefixItems = &js.PrefixItems
+		}
+	}
+
+	// Connect sub-schema in "contains" field.
+	if js.Contains != nil {
+		// If "minContains" or "maxContains" fields exist in the schema, save
+		// the keywordValidators' addresses in "Contains".
+		js.Contains.siblingMinContains = js.MinContains
+		js.Contains.siblingMaxContains = js.MaxContains
+	}
+
 	// Connect sub-schema in "if" field.
 	if js.If != nil {
 		// Connect sub-schema in "then" field.
@@ -590,239 +836,371 @@ func (js *JsonSchema) connectRelatedKeywords() {
 	}
 }
 
-func (js *JsonSchema) mapSubSchema(schemaPath, rootSchemaID string) {
-	// If the schema path is not an empty string (means we are not in the root schema),
-	// and the rootSchemaID is not an empty string (means the root schema contains
-	// the "$id" field), map the current sub schema into the subSchemaMap of the rootSchema.
-	if schemaPath != "" && rootSchemaID != "" {
-		// If the rootSchema exists in the pool, add the sub schema to it.
-		// Else, TODO: decide what to do.
-		if rs, ok := rootSchemaPool[rootSchemaID]; ok && rs != nil {
-			// If the root schema does not contain the sub schema already, add it to the
-			// subSchemaMap.
-			// Else, TODO: decide what to do.
-			if _, ok := rs.subSchemaMap[schemaPath]; !ok {
-				rs.subSchemaMap[schemaPath] = js
-			}
+// mapSubSchema maps the sub-schema into the schema resources that contain it,
+// by its path in each of them and by its anchors in the innermost one, and
+// resolves its references against the base URI of the innermost one.
+// A sub-schema with an "$id" is a schema resource of its own, so it is
+// returned with a location that includes it.
+func (js *JsonSchema) mapSubSchema(location schemaLocation) (schemaLocation, error) {
+	// A schema that is not part of a root schema has nothing to map into.
+	resource := location.resource()
+	if resource == nil {
+		return location, nil
+	}
+
+	// In draft-07 an "$id" that only has a fragment is a plain name of the
+	// sub-schema, like "$anchor".
+	if js.Id != nil && strings.HasPrefix(string(*js.Id), "#") {
+		resource.subSchemaMap[strings.TrimPrefix(string(*js.Id), "#")] = js
+	} else if js.Id != nil && location.path() != "" {
+		embedded, err := resource.embedResource(js, location)
+		if err != nil {
+			return location, err
 		}
+
+		location.scopes = append(location.scopes, schemaScope{embedded, ""})
+		resource = embedded
 	}
-}
 
-// validateJsonData is a function that gets a byte array of data and validates
-// it against the schema that encoded in the receiver's field.
-func (js *JsonSchema) validateJsonData(jsonPath string, bytes []byte, rootSchemaId string) error {
-	// If RejectAll field exists and true, reject the value.
-	if js.RejectAll {
-		return SchemaValidationError{
-			jsonPath,
-			"json schema \"false\" drops everything",
+	// If the schema path is not an empty string (means we are not in the root
+	// of the resource), and the resource does not contain the sub schema
+	// already, add it to the subSchemaMap.
+	for _, scope := range location.scopes {
+		if scope.path != "" {
+			if _, ok := scope.resource.subSchemaMap[scope.path]; !ok {
+				scope.resource.subSchemaMap[scope.path] = js
+			}
 		}
 	}
 
-	// If the schema contains the $ref field, validate the data against the
-	// referenced schema (and by the way ignore all the keywords of the current
-	// schema).
+	// Anchors are plain names, so they never collide with the json pointers
+	// in the subSchemaMap.
+	if js.Anchor != nil {
+		resource.subSchemaMap[string(*js.Anchor)] = js
+	}
+
+	if js.DynamicAnchor != nil {
+		resource.subSchemaMap[string(*js.DynamicAnchor)] = js
+		resource.dynamicAnchors[string(*js.DynamicAnchor)] = js
+	}
+
+	// The references are resolved once, so the validation does not depend on
+	// the resource that it started from.
+	root := location.scopes[0].resource
+
 	if js.Ref != nil {
-		return js.Ref.validateByRef(jsonPath, bytes, rootSchemaId)
+		uri, err := resolveReference(resource.uri, string(*js.Ref))
+		if err != nil {
+			return location, SchemaCompilationError{location.path() + "/$ref", err.Error()}
+		}
+
+		*js.Ref = ref(uri)
+		root.references = append(root.references, uri)
 	}
 
-	// Calculate the relative path in order to evaluate the data
-	jsonTokens := strings.Split(jsonPath, "/")
-	relativeJsonPath := "/" + jsonTokens[len(jsonTokens)-1]
+	if js.RecursiveRef != nil {
+		uri, err := resolveReference(resource.uri, string(*js.RecursiveRef))
+		if err != nil {
+			return location, SchemaCompilationError{location.path() + "/$recursiveRef", err.Error()}
+		}
 
-	// Create a new JsonPointer.
-	jsonPointer, err := jsonwalker.NewJsonPointer(relativeJsonPath)
-	if err != nil {
-		fmt.Println("[JsonSchema DEBUG] validateJsonData() " +
-			"failed while trying to create JsonPointer " + jsonPath)
-		return errors.Wrap(err, "JsonPointer creation failed")
+		*js.RecursiveRef = recursiveRef(uri)
+		root.references = append(root.references, uri)
 	}
 
-	// Get the piece of json that the current schema describes.
-	value, err := jsonPointer.Evaluate(bytes)
-	if err != nil {
-		fmt.Println("[JsonSchema DEBUG] validateJsonData() " +
-			"failed while trying to evaluate a JsonPointer " + jsonPath)
-		return errors.Wrap(err, "JsonPointer evaluation failed")
+	if js.DynamicRef != nil {
+		uri, err := resolveReference(resource.uri, string(*js.DynamicRef))
+		if err != nil {
+			return location, SchemaCompilationError{location.path() + "/$dynamicRef", err.Error()}
+		}
+
+		*js.DynamicRef = dynamicRef(uri)
+		root.references = append(root.references, uri)
 	}
 
-	// Marshal the evaluated value to a byte array.
-	newBytes, err := json.Marshal(value)
-	if err != nil {
-		return errors.Wrap(err, "data marshaling after JsonPointer evaluation failed")
+	return location, nil
+}
+
+// validateValue is a function that validates a json value that was already
+// extracted from the data against the schema. jsonPath is the location of
+// the value in the data and is used for reporting failures.
+func (js *JsonSchema) validateValue(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	// Draft-07 has no keywords that rely on annotations.
+	if ctx.isLegacyDraft() {
+		return js.validateKeywords(jsonPath, jsonData, ctx)
 	}
 
-	// Create a new json data container
-	jsonData := jsonData{
-		newBytes,
-		value,
+	// Each schema collects the annotations of its own keywords, and passes
+	// them on to the schema that applied it only if the value is valid.
+	parentEvaluation := ctx.evaluation
+	ctx = ctx.withEvaluation(newEvaluation(jsonPath))
+
+	err := js.validateKeywords(jsonPath, jsonData, ctx)
+	if err == nil {
+		parentEvaluation.merge(ctx.evaluation)
+	}
+
+	return err
+}
+
+// validateKeywords validates a json value against each of the schema's
+// keywords.
+func (js *JsonSchema) validateKeywords(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	// If RejectAll field exists and true, reject the value.
+	if js.RejectAll {
+		return SchemaValidationError{
+			jsonPath,
+			"",
+			"",
+			"json schema \"false\" drops everything",
+		}
+	}
+
+	// In draft-07, if the schema contains the $ref field, validate the data
+	// against the referenced schema (and by the way ignore all the keywords of
+	// the current schema).
+	if js.Ref != nil && ctx.isLegacyDraft() {
+		return js.Ref.validate(jsonPath, jsonData, ctx)
 	}
 
-	// Get a slice of all of JsonSchema's field in order to iterate them
-	// and call each of their validate() functions.
-	keywordValidators := getNonNilKeywordsSlice(js)
+	var errs ValidationErrors
 
 	// Iterate over the keywords.
-	for _, keyword := range keywordValidators {
+	for _, keyword := range js.keywords {
 		// Validate the value that we extracted from the jsonData at each
 		// keyword.
-		err := keyword.validate(jsonPath, jsonData, rootSchemaId)
+		err := keyword.validate(jsonPath, jsonData, ctx)
 		if err != nil {
-			// If the error is a SchemaValidationError, it means it came from
-			// a deeper call to this function, so we do not touch the error.
-			if schemaValidationError, ok := err.(SchemaValidationError); ok {
-				return schemaValidationError
-			}
-
 			// If the error is a KeywordValidationError, create a new
-			// SchemaValidationError and return it.
+			// SchemaValidationError that points to the keyword.
 			if keywordValidationError, ok := err.(KeywordValidationError); ok {
-				return SchemaValidationError{
+				err = SchemaValidationError{
 					jsonPath,
-					keywordValidationError.Error(),
+					"/" + keywordValidationError.keyword,
+					keywordValidationError.keyword,
+					keywordValidationError.reason,
 				}
 			}
 
-			return err
+			// If the error is a SchemaValidationError or ValidationErrors, it
+			// came from a keyword that already set its location, so we only
+			// collect it. Any other error stops the validation.
+			errs, err = errs.add(err, "")
+			if err != nil {
+				return err
+			}
+
+			if !ctx.exhaustive {
+				break
+			}
 		}
 	}
 
-	return nil
+	return errs.result()
 }
 
 // getNonNilKeywordsMap gets a reference to JsonSchema and returns a
-// map of the schema's keywords that are not nil.
-func getNonNilKeywordsSlice(js *JsonSchema) []keywordValidator {
+// map of the schema's keywords that are not nil, leaving out the keywords
+// that the schema's draft and vocabularies do not evaluate.
+func getNonNilKeywordsSlice(js *JsonSchema, ctx *validationContext) []keywordValidator {
 	var slice []keywordValidator
 
+	// add appends a keyword to the slice if the context evaluates it.
+	add := func(keyword string, validator keywordValidator) {
+		if ctx.evaluates(keyword) {
+			slice = append(slice, validator)
+		}
+	}
+
+	// Since draft 2019-09 the references are evaluated along with the
+	// keywords next to them.
+	if js.Ref != nil && !ctx.isLegacyDraft() {
+		add("$ref", js.Ref)
+	}
+
+	if js.RecursiveRef != nil {
+		add("$recursiveRef", js.RecursiveRef)
+	}
+
+	if js.DynamicRef != nil {
+		add("$dynamicRef", js.DynamicRef)
+	}
+
 	if js.Type != nil {
-		slice = append(slice, js.Type)
+		add("type", js.Type)
 	}
 
 	if js.Const != nil {
-		slice = append(slice, js.Const)
+		add("const", js.Const)
 	}
 
 	if js.Enum != nil {
-		slice = append(slice, js.Enum)
+		add("enum", js.Enum)
 	}
 
 	if js.MinLength != nil {
-		slice = append(slice, js.MinLength)
+		add("minLength", js.MinLength)
 	}
 
 	if js.MaxLength != nil {
-		slice = append(slice, js.MaxLength)
+		add("maxLength", js.MaxLength)
 	}
 
 	if js.Pattern != nil {
-		slice = append(slice, js.Pattern)
+		add("pattern", js.Pattern)
 	}
 
 	if js.Format != nil {
-		slice = append(slice, js.Format)
+		add("format", js.Format)
 	}
 
 	if js.MultipleOf != nil {
-		slice = append(slice, js.MultipleOf)
+		add("multipleOf", js.MultipleOf)
 	}
 
 	if js.Minimum != nil {
-		slice = append(slice, js.Minimum)
+		add("minimum", js.Minimum)
 	}
 
 	if js.Maximum != nil {
-		slice = append(slice, js.Maximum)
+		add("maximum", js.Maximum)
 	}
 
 	if js.ExclusiveMinimum != nil {
-		slice = append(slice, js.ExclusiveMinimum)
+		add("exclusiveMinimum", js.ExclusiveMinimum)
 	}
 
 	if js.ExclusiveMaximum != nil {
-		slice = append(slice, js.ExclusiveMaximum)
+		add("exclusiveMaximum", js.ExclusiveMaximum)
 	}
 
 	if js.Required != nil {
-		slice = append(slice, js.Required)
+		add("required", js.Required)
 	}
 
 	if js.PropertyNames != nil {
-		slice = append(slice, js.PropertyNames)
+		add("propertyNames", js.PropertyNames)
 	}
 
 	if js.Properties != nil {
-		slice = append(slice, js.Properties)
+		add("properties", js.Properties)
 	}
 
 	if js.AdditionalProperties != nil {
-		slice = append(slice, js.AdditionalProperties)
+		add("additionalProperties", js.AdditionalProperties)
 	}
 
 	if js.PatternProperties != nil {
-		slice = append(slice, js.PatternProperties)
+		add("patternProperties", js.PatternProperties)
 	}
 
 	if js.Dependencies != nil {
-		slice = append(slice, js.Dependencies)
+		add("dependencies", js.Dependencies)
+	}
+
+	if js.DependentRequired != nil {
+		add("dependentRequired", js.DependentRequired)
+	}
+
+	if js.DependentSchemas != nil {
+		add("dependentSchemas", js.DependentSchemas)
 	}
 
 	if js.MinProperties != nil {
-		slice = append(slice, js.MinProperties)
+		add("minProperties", js.MinProperties)
 	}
 
 	if js.MaxProperties != nil {
-		slice = append(slice, js.MaxProperties)
+		add("maxProperties", js.MaxProperties)
+	}
+
+	if js.PrefixItems != nil {
+		add("prefixItems", js.PrefixItems)
 	}
 
 	if js.Items != nil {
-		slice = append(slice, js.Items)
+		add("items", js.Items)
 	}
 
 	if js.Contains != nil {
-		slice = append(slice, js.Contains)
+		add("contains", js.Contains)
 	}
 
 	if js.AdditionalItems != nil {
-		slice = append(slice, js.AdditionalItems)
+		add("additionalItems", js.AdditionalItems)
 	}
 
 	if js.MinItems != nil {
-		slice = append(slice, js.MinItems)
+		add("minItems", js.MinItems)
 	}
 
 	if js.MaxItems != nil {
-		slice = append(slice, js.MaxItems)
+		add("maxItems", js.MaxItems)
 	}
 
 	if js.UniqueItems != nil {
-		slice = append(slice, js.UniqueItems)
+		add("uniqueItems", js.UniqueItems)
 	}
 
 	if js.AnyOf != nil {
-		slice = append(slice, js.AnyOf)
+		add("anyOf", js.AnyOf)
 	}
 
 	if js.AllOf != nil {
-		slice = append(slice, js.AllOf)
+		add("allOf", js.AllOf)
 	}
 
 	if js.OneOf != nil {
-		slice = append(slice, js.OneOf)
+		add("oneOf", js.OneOf)
 	}
 
 	if js.Not != nil {
-		slice = append(slice, js.Not)
+		add("not", js.Not)
 	}
 
 	if js.If != nil {
-		slice = append(slice, js.If)
+		add("if", js.If)
+	}
+
+	// The "unevaluated" keywords must come last, since they depend on the
+	// annotations of all the other keywords.
+	if js.UnevaluatedProperties != nil {
+		add("unevaluatedProperties", js.UnevaluatedProperties)
+	}
+
+	if js.UnevaluatedItems != nil {
+		add("unevaluatedItems", js.UnevaluatedItems)
 	}
 
 	// Return the map.
 	return slice
 }
 
+// sortedKeys returns the keys of a json object in a sorted order, so
+// validation failures are always reported in the same order.
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// sortedSchemaKeys returns the keys of a map of sub-schemas in a sorted order.
+func sortedSchemaKeys(schemas map[string]*JsonSchema) []string {
+	keys := make([]string, 0, len(schemas))
+	for key := range schemas {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
 func (js *JsonSchema) UnmarshalJSON(bytes []byte) error {
 	// First, unmarshal the raw data into empty interface variable
 	// in order to figure out its type.
@@ -1,12 +1,14 @@
 package jsonvalidator
 
 import (
+	"encoding/json"
 	"github.com/pkg/errors"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"path"
 	"runtime"
+	"sync"
 )
 
 var (
@@ -28,53 +30,111 @@ var (
 type JsonValidator struct {
 	draft      string
 	schemaDict map[string]map[string]*RootJsonSchema
+	exhaustive bool
+
+	// parametersDict holds the schemas of the requests' parameters by
+	// their location (path, query or header), path and method.
+	parametersDict map[string]map[string]map[string]*RootJsonSchema
+
+	// responsesDict holds the schemas of the targets' responses by their
+	// path and method.
+	responsesDict map[string]map[string][]*responseSchema
+
+	// messagesDict holds the schemas of the WebSocket messages of the
+	// endpoints by their path and method.
+	messagesDict map[string]map[string]*RootJsonSchema
+
+	// registry holds the schemas that the references of the loaded schemas
+	// can point to.
+	registry *schemaRegistry
+
+	// mutex guards the dictionaries, so schemas can be loaded while
+	// requests are validated.
+	mutex *sync.RWMutex
+
+	// sources tells where to find the schemas that references point to when
+	// they were not loaded.
+	sources *schemaSources
 }
 
-// NewJsonValidator returns a new instance of JsonValidator
+// NewJsonValidator returns a new instance of JsonValidator.
+// The draft ("draft-07", "draft-2019-09" or "draft-2020-12") applies to the
+// schemas that do not declare their draft in "$schema".
 func NewJsonValidator(draft string) (JsonValidator, error) {
-	supportedDrafts := []string{"draft-07"}
-
-	for _, supportedDraft := range supportedDrafts {
-		if supportedDraft == draft {
-			return JsonValidator{
-				draft,
-				make(map[string]map[string]*RootJsonSchema),
-			}, nil
-		}
+	if !isSupportedDraft(draft) {
+		return JsonValidator{}, InvalidDraftError(draft)
 	}
 
-	return JsonValidator{}, InvalidDraftError(draft)
+	return JsonValidator{
+		draft,
+		make(map[string]map[string]*RootJsonSchema),
+		false,
+		make(map[string]map[string]map[string]*RootJsonSchema),
+		make(map[string]map[string][]*responseSchema),
+		make(map[string]map[string]*RootJsonSchema),
+		newSchemaRegistry(metaSchemaRegistry),
+		&sync.RWMutex{},
+		&schemaSources{},
+	}, nil
+}
+
+// SetExhaustive determines whether Validate should stop at the first failure
+// or collect all the failures in the data and return them as ValidationErrors.
+func (jv *JsonValidator) SetExhaustive(exhaustive bool) {
+	jv.exhaustive = exhaustive
 }
 
 // LoadSchema is a function that handles addition of new schema to the
 // JsonValidator's schemas list
 func (jv JsonValidator) LoadSchema(path, method string, rawSchema []byte) error {
-	// Check if the given method is correct
+	err := checkMethod(path, method)
+	if err != nil {
+		return err
+	}
+
+	schema, err := jv.compileSchema("body "+method+" "+path, rawSchema)
+	if err != nil {
+		return err
+	}
+
+	jv.mutex.Lock()
+	defer jv.mutex.Unlock()
+
+	// If the schema is valid make a new map and insert the new schema to it.
+	if jv.schemaDict[path] == nil {
+		// Create a new empty method-JsonSchema map for the current path.
+		jv.schemaDict[path] = make(map[string]*RootJsonSchema)
+	}
+
+	// Add the schema to the appropriate map according to its path and
+	// method.
+	jv.schemaDict[path][method] = schema
+
+	return nil
+}
+
+// AddSchema registers a schema that the loaded schemas can reference by its
+// "$id", without assigning it to an endpoint. A relative "$id" is resolved
+// against DEFAULT_BASE_URI.
+func (jv JsonValidator) AddSchema(rawSchema []byte) error {
+	var declaration struct {
+		Id string `json:"$id"`
+	}
+
+	_ = json.Unmarshal(rawSchema, &declaration)
+	if declaration.Id == "" {
+		return errors.New("could not add schema: the schema has no \"$id\"")
+	}
+
+	_, err := jv.compileSchema("schema "+declaration.Id, rawSchema)
+
+	return err
+}
+
+// checkMethod returns an error if the method is not an http method.
+func checkMethod(path, method string) error {
 	for _, httpMethod := range methods {
 		if method == httpMethod {
-			// Validate the given schema against draft-07 meta-schema.
-			err := validateJsonSchema(jv.draft, rawSchema)
-			if err != nil {
-				return errors.Wrap(err, "validation against meta-schema failed")
-			}
-
-			// If the schema is valid make a new map and insert the new schema to it.
-			if jv.schemaDict[path] == nil {
-				// Create a new empty method-JsonSchema map for the current path.
-				jv.schemaDict[path] = make(map[string]*RootJsonSchema)
-			}
-
-			// Create a new JsonSchema object.
-			schema, err := NewRootJsonSchema(rawSchema)
-			if err != nil {
-				return errors.Wrap(err, "failed to create a RootJsonSchema "+
-					"instance")
-			}
-
-			// Add the schema to the appropriate map according to its path and
-			// method.
-			jv.schemaDict[path][method] = schema
-
 			return nil
 		}
 	}
@@ -86,12 +146,89 @@ func (jv JsonValidator) LoadSchema(path, method string, rawSchema []byte) error
 		"\"")
 }
 
+// compileSchema validates a schema against the meta-schema of its draft,
+// creates a RootJsonSchema out of it and registers it in the validator's
+// registry on behalf of owner, replacing the schema that owner registered
+// before. The schemas that its references point to are loaded from the
+// validator's schema sources if they were not loaded yet.
+func (jv JsonValidator) compileSchema(owner string, rawSchema []byte) (*RootJsonSchema, error) {
+	return jv.compileResource(owner, rawSchema, "", make(map[string]bool))
+}
+
+// compileResource compiles and registers a schema that was retrieved from
+// baseURI (or from no URI if it is empty). loading holds the URIs of the
+// schemas that are being loaded from the schema sources, so references that
+// form a cycle are loaded only once.
+func (jv JsonValidator) compileResource(owner string, rawSchema []byte, baseURI string,
+	loading map[string]bool) (*RootJsonSchema, error) {
+	// A custom meta-schema may be loaded from the schema sources as well.
+	err := jv.loadMetaSchemaReference(rawSchema, loading)
+	if err != nil {
+		return nil, err
+	}
+
+	declaredDialect, err := schemaDialect(rawSchema, jv.draft, jv.registry)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not determine the schema's draft")
+	}
+
+	// Validate the given schema against the meta-schema of its draft.
+	err = validateJsonSchema(declaredDialect.draft, rawSchema)
+	if err != nil {
+		return nil, errors.Wrap(err, "validation against meta-schema failed")
+	}
+
+	// A schema that declares a custom meta-schema must follow it as well.
+	if declaredDialect.metaSchema != nil {
+		err = declaredDialect.metaSchema.validateBytes(rawSchema, false)
+		if err != nil {
+			return nil, errors.Wrap(err, "validation against custom meta-schema failed")
+		}
+	}
+
+	// Create a new JsonSchema object.
+	schema, err := newRootJsonSchema(rawSchema, jv.draft, jv.registry, baseURI)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create a RootJsonSchema "+
+			"instance")
+	}
+
+	// A schema that was loaded for a reference must be the schema that the
+	// reference points to.
+	if baseURI != "" && schema.uri != baseURI {
+		return nil, errors.New("the schema of \"" +
+			baseURI +
+			"\" declares another id \"" +
+			schema.uri +
+			"\"")
+	}
+
+	err = jv.registry.register(owner, schema)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not register the schema")
+	}
+
+	loading[schema.uri] = true
+
+	err = jv.loadReferences(schema, loading)
+	if err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
 // Validate is the function that actually perform validation of json value
 // according to a specific json schema
 func (jv JsonValidator) Validate(path string, method string, body []byte) error {
-	if _, isPathExist := jv.schemaDict[path]; isPathExist {
-		if _, isMethodExist := jv.schemaDict[path][method]; isMethodExist {
-			return jv.schemaDict[path][method].validateBytes(body)
+	jv.mutex.RLock()
+	schemas, isPathExist := jv.schemaDict[path]
+	schema, isMethodExist := schemas[method]
+	jv.mutex.RUnlock()
+
+	if isPathExist {
+		if isMethodExist {
+			return schema.validateBytes(body, jv.exhaustive)
 		} else {
 			return errors.New("could not validate request: unknown path \"" +
 				path +
@@ -109,6 +246,32 @@ func (jv JsonValidator) Validate(path string, method string, body []byte) error
 // validateJsonSchema is a function that validates the schema's
 // structure according to Json Schema.
 func validateJsonSchema(draft string, rawSchema []byte) error {
+	metaSchema, err := loadMetaSchema(draft)
+	if err != nil {
+		return err
+	}
+
+	return metaSchema.validateBytes(rawSchema, false)
+}
+
+// metaSchemas holds the meta-schemas that were already loaded by their
+// drafts.
+var (
+	metaSchemas      = map[string]*RootJsonSchema{}
+	metaSchemasMutex sync.Mutex
+)
+
+// loadMetaSchema returns the meta-schema of a draft. The meta-schema is read
+// from its file and registered in metaSchemaRegistry the first time it is
+// needed.
+func loadMetaSchema(draft string) (*RootJsonSchema, error) {
+	metaSchemasMutex.Lock()
+	defer metaSchemasMutex.Unlock()
+
+	if metaSchema, ok := metaSchemas[draft]; ok {
+		return metaSchema, nil
+	}
+
 	// Get the path of the current go file (including the path inside
 	// the project).
 	var absolutePath string
@@ -119,7 +282,7 @@ func validateJsonSchema(draft string, rawSchema []byte) error {
 	// Open the meta-schema file.
 	file, err := os.Open(absolutePath + "/meta-schemas/" + draft)
 	if err != nil {
-		return errors.Wrap(err, "json schema version \""+
+		return nil, errors.Wrap(err, "json schema version \""+
 			draft+
 			"\" is not supported")
 	}
@@ -129,16 +292,23 @@ func validateJsonSchema(draft string, rawSchema []byte) error {
 	// Read the data from the file.
 	bytes, err := ioutil.ReadAll(file)
 	if err != nil {
-		return errors.Wrap(err, "could not read meta-schema from file")
+		return nil, errors.Wrap(err, "could not read meta-schema from file")
 	}
 
 	// Create a new RootJsonSchema.
-	metaSchema, err := NewRootJsonSchema(bytes)
+	metaSchema, err := newRootJsonSchema(bytes, draft, metaSchemaRegistry, "")
 	if err != nil {
-		return errors.Wrap(err, "failed to create a RootJsonSchema instance "+
+		return nil, errors.Wrap(err, "failed to create a RootJsonSchema instance "+
 			"for meta-schema - "+
 			draft)
 	}
 
-	return metaSchema.validateBytes(rawSchema)
+	err = metaSchemaRegistry.register(draft, metaSchema)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not register meta-schema - "+draft)
+	}
+
+	metaSchemas[draft] = metaSchema
+
+	return metaSchema, nil
 }
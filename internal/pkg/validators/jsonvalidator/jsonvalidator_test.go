@@ -6,8 +6,13 @@ import (
 	"os"
 	"path"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/apidome/gateway/internal/pkg/validators"
 	"github.com/apidome/gateway/internal/pkg/validators/jsonvalidator"
 )
 
@@ -38,6 +43,14 @@ func TestNewJsonValidator(t *testing.T) {
 			"draft-07",
 			true,
 		},
+		{
+			"draft-2019-09",
+			true,
+		},
+		{
+			"draft-2020-12",
+			true,
+		},
 		{
 			"draft-06",
 			false,
@@ -217,95 +230,1492 @@ func TestValidate(t *testing.T) {
 		"additionalProperties", "required", "propertyNames", "minProperties", "maxProperties", "items", "contains",
 		"additionalItems", "minItems", "maxItems", "uniqueItems", "anyOf", "allOf", "oneOf", "not",
 		"if_then_else", "ref"}
-	testCases := make([]testCase, 0)
+	testCases := readTestCases(t, "", keywords)
 
-	// Read all the test data from the files and append them to the main slice.
-	for _, keyword := range keywords {
-		testData := make([]testCase, 0)
-		rawTestData, err := readTestDataFromFile(keyword + ".json")
+	t.Log("Given the need to test json validation against json schema according to method and endpoint")
+	{
+		jv, err := jsonvalidator.NewJsonValidator("draft-07")
 		if err != nil {
-			t.Fatalf("Could not read test data from file: %v", err)
+			t.Fatalf("\t%s\tShould be able to create a new JsonValidator: %v", failed, err)
 		}
+		t.Logf("\t%s\tShould be able to create a new JsonValidator", succeed)
 
-		err = json.Unmarshal(rawTestData, &testData)
-		if err != nil {
-			t.Fatalf("Could not unmarshal test data to test cases slice, "+
-				"probably one or more cases is not in the correct format in %s.json: %v", keyword, err)
-		}
+		runTestCases(t, jv, testCases)
+	}
+}
 
-		for index := range testData {
-			testData[index].Keyword = keyword
+func TestValidateDrafts(t *testing.T) {
+	drafts := []struct {
+		draft    string
+		keywords []string
+	}{
+		{
+			"draft-2019-09",
+			[]string{"defs", "ref", "recursiveRef", "unevaluatedProperties", "unevaluatedItems",
+				"dependentRequired", "dependentSchemas", "minContains_maxContains", "format"},
+		},
+		{
+			"draft-2020-12",
+			[]string{"prefixItems", "dynamicRef", "unevaluatedItems"},
+		},
+	}
+
+	t.Log("Given the need to test json validation against json schemas of drafts 2019-09 and 2020-12")
+	{
+		for _, draft := range drafts {
+			testCases := readTestCases(t, draft.draft+"/", draft.keywords)
+
+			jv, err := jsonvalidator.NewJsonValidator(draft.draft)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to create a new JsonValidator: %v", failed, err)
+			}
+			t.Logf("\t%s\tShould be able to create a new JsonValidator for %s", succeed, draft.draft)
+
+			runTestCases(t, jv, testCases)
 		}
+	}
+}
 
-		testCases = append(testCases, testData...)
+func TestSchemaDialect(t *testing.T) {
+	testCases := []struct {
+		description string
+		draft       string
+		schema      string
+		data        string
+		loads       bool
+		valid       bool
+	}{
+		{
+			"a draft 2020-12 schema in a draft-07 validator",
+			"draft-07",
+			`{"$schema": "https://json-schema.org/draft/2020-12/schema", "prefixItems": [{"type": "string"}]}`,
+			`[1]`,
+			true,
+			false,
+		},
+		{
+			"a draft-07 schema in a draft 2020-12 validator",
+			"draft-2020-12",
+			`{"$schema": "http://json-schema.org/draft-07/schema#", "format": "email"}`,
+			`"not an email"`,
+			true,
+			false,
+		},
+		{
+			"a schema without \"$schema\" that follows the validator's draft",
+			"draft-2020-12",
+			`{"prefixItems": [{"type": "string"}]}`,
+			`[1]`,
+			true,
+			false,
+		},
+		{
+			"a schema that breaks the meta-schema of its draft",
+			"draft-07",
+			`{"$schema": "https://json-schema.org/draft/2019-09/schema", "dependentRequired": {"a": "b"}}`,
+			``,
+			false,
+			false,
+		},
+		{
+			"a schema whose meta-schema turns off the validation vocabulary",
+			"draft-2020-12",
+			`{"$schema": "https://example.com/meta/applicator-only", "type": "string",
+				"properties": {"a": {"type": "string"}}}`,
+			`{"a": 1}`,
+			true,
+			true,
+		},
+		{
+			"a schema whose meta-schema requires the format assertion vocabulary",
+			"draft-2020-12",
+			`{"$schema": "https://example.com/meta/format-assertion", "format": "email"}`,
+			`"not an email"`,
+			true,
+			false,
+		},
+		{
+			"a schema whose meta-schema requires an unknown vocabulary",
+			"draft-2020-12",
+			`{"$schema": "https://example.com/meta/unknown-vocabulary"}`,
+			``,
+			false,
+			false,
+		},
+		{
+			"a schema with an unknown meta-schema",
+			"draft-2020-12",
+			`{"$schema": "https://example.com/meta/missing"}`,
+			``,
+			false,
+			false,
+		},
 	}
 
-	t.Log("Given the need to test json validation against json schema according to method and endpoint")
+	// Custom meta-schemas that the schemas use
+	metaSchemas := []string{
+		`{
+			"$schema": "https://json-schema.org/draft/2020-12/schema",
+			"$id": "https://example.com/meta/applicator-only",
+			"$vocabulary": {
+				"https://json-schema.org/draft/2020-12/vocab/core": true,
+				"https://json-schema.org/draft/2020-12/vocab/applicator": true
+			}
+		}`,
+		`{
+			"$schema": "https://json-schema.org/draft/2020-12/schema",
+			"$id": "https://example.com/meta/format-assertion",
+			"$vocabulary": {
+				"https://json-schema.org/draft/2020-12/vocab/core": true,
+				"https://json-schema.org/draft/2020-12/vocab/format-assertion": true
+			}
+		}`,
+		`{
+			"$schema": "https://json-schema.org/draft/2020-12/schema",
+			"$id": "https://example.com/meta/unknown-vocabulary",
+			"$vocabulary": {
+				"https://json-schema.org/draft/2020-12/vocab/core": true,
+				"https://example.com/vocab/unknown": true
+			}
+		}`,
+	}
+
+	t.Log("Given the need to test the selection of a schema's draft and vocabularies")
 	{
-		jv, err := jsonvalidator.NewJsonValidator("draft-07")
-		if err != nil {
-			t.Fatalf("\t%s\tShould be able to create a new JsonValidator: %v", failed, err)
-		}
-		t.Logf("\t%s\tShould be able to create a new JsonValidator", succeed)
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When trying to validate %s", index, testCase.description)
+			{
+				jv, err := jsonvalidator.NewJsonValidator(testCase.draft)
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to create a new JsonValidator: %v", failed, err)
+				}
 
-		for i, testCase := range testCases {
-			subTest := func(t *testing.T) {
-				t.Logf("\t[%s] Test Schema %d: %s", testCase.Keyword, i, testCase.Descriptions)
-				{
-					for j, test := range testCase.Tests {
-						t.Logf("\t\tTest %d.%d: When trying to validate %s against the given schema", i, j, test.Description)
-						{
-							err = jv.LoadSchema(testCase.Path, testCase.Method, testCase.Schema)
-							if err != nil {
-								t.Errorf("\t\t%s\tShould be able to Load schema: %v", failed, err)
-							}
+				for _, metaSchema := range metaSchemas {
+					err = jv.AddSchema([]byte(metaSchema))
+					if err != nil {
+						t.Fatalf("\t%s\tShould be able to add the custom meta-schemas: %v", failed, err)
+					}
+				}
 
-							err = jv.Validate(testCase.Path, testCase.Method, test.Data)
-							if test.Valid {
-								if err != nil {
-									t.Errorf("\t\t%s\tData should be valid against the specified json schema: %v", failed, err)
-								} else {
-									t.Logf("\t\t%s\tData should be valid against the specified json schema", succeed)
-								}
-							} else {
-								if err != nil {
-									t.Logf("\t\t%s\tData should not be valid against the specified json schema: %v", succeed, err)
-								} else {
-									t.Errorf("\t\t%s\tData should not be valid against the specified json schema", failed)
-								}
-							}
-						}
+				err = jv.LoadSchema("/v1/dialect", "POST", []byte(testCase.schema))
+				if !testCase.loads {
+					if err != nil {
+						t.Logf("\t%s\tShould not be able to load the schema: %v", succeed, err)
+					} else {
+						t.Errorf("\t%s\tShould not be able to load the schema", failed)
 					}
+
+					continue
+				}
+
+				if err != nil {
+					t.Errorf("\t%s\tShould be able to load the schema: %v", failed, err)
+					continue
 				}
-				t.Log()
-			}
 
-			t.Run(testCase.Keyword, subTest)
+				err = jv.Validate("/v1/dialect", "POST", []byte(testCase.data))
+				if testCase.valid {
+					if err != nil {
+						t.Errorf("\t%s\tData should be valid: %v", failed, err)
+					} else {
+						t.Logf("\t%s\tData should be valid", succeed)
+					}
+				} else {
+					if err != nil {
+						t.Logf("\t%s\tData should not be valid: %v", succeed, err)
+					} else {
+						t.Errorf("\t%s\tData should not be valid", failed)
+					}
+				}
+			}
 		}
 	}
 }
 
-func readTestDataFromFile(fileName string) ([]byte, error) {
-	// Get the path of the current go file (including the path inside
-	// the project).
-	var absolutePath string
-	if _, filename, _, ok := runtime.Caller(0); ok {
-		absolutePath = path.Dir(filename)
+func TestSchemaRegistry(t *testing.T) {
+	testCases := []struct {
+		description string
+		added       []string
+		schema      string
+		data        string
+		loads       bool
+		valid       bool
+	}{
+		{
+			"a reference to an added schema with a relative \"$id\"",
+			[]string{`{"$id": "common/name.json", "type": "string", "maxLength": 3}`},
+			`{"properties": {"name": {"$ref": "common/name.json"}}}`,
+			`{"name": "abcd"}`,
+			true,
+			false,
+		},
+		{
+			"a relative reference that is resolved against the schema's \"$id\"",
+			[]string{`{"$id": "https://example.com/schemas/address.json", "required": ["city"]}`},
+			`{"$id": "https://example.com/schemas/person.json", "properties": {"address": {"$ref": "address.json"}}}`,
+			`{"address": {}}`,
+			true,
+			false,
+		},
+		{
+			"a reference to an embedded schema resource",
+			nil,
+			`{"$id": "https://example.com/list.json", "definitions": {"item": {"$id": "item.json", "type": "integer"}},
+				"items": {"$ref": "item.json"}}`,
+			`[1, "a"]`,
+			true,
+			false,
+		},
+		{
+			"a reference inside an embedded resource that is resolved against the resource's \"$id\"",
+			nil,
+			`{"$id": "https://example.com/outer.json",
+				"definitions": {"inner": {"$id": "nested/inner.json",
+					"definitions": {"leaf": {"type": "string"}},
+					"properties": {"a": {"$ref": "#/definitions/leaf"}}}},
+				"properties": {"inner": {"$ref": "nested/inner.json"}}}`,
+			`{"inner": {"a": 1}}`,
+			true,
+			false,
+		},
+		{
+			"a reference to a plain name \"$id\"",
+			nil,
+			`{"definitions": {"positive": {"$id": "#positive", "minimum": 0}}, "$ref": "#positive"}`,
+			`-1`,
+			true,
+			false,
+		},
+		{
+			"a schema whose \"$id\" conflicts with an added schema",
+			[]string{`{"$id": "https://example.com/conflict.json", "type": "string"}`},
+			`{"$id": "https://example.com/conflict.json", "type": "number"}`,
+			``,
+			false,
+			false,
+		},
+		{
+			"a schema that is identical to an added schema",
+			[]string{`{"$id": "https://example.com/same.json", "type": "string"}`},
+			`{"type": "string", "$id": "https://example.com/same.json"}`,
+			`"a"`,
+			true,
+			true,
+		},
+		{
+			"a schema that declares the same \"$id\" twice",
+			nil,
+			`{"definitions": {"a": {"$id": "https://example.com/twice.json"},
+				"b": {"$id": "https://example.com/twice.json", "type": "string"}}}`,
+			``,
+			false,
+			false,
+		},
+		{
+			"a schema that declares the \"$id\" of a meta-schema",
+			nil,
+			`{"$id": "http://json-schema.org/draft-07/schema#", "type": "string"}`,
+			``,
+			false,
+			false,
+		},
 	}
 
-	// Open the meta-schema file.
-	file, err := os.Open(absolutePath + "/testdata/" + fileName)
-	if err != nil {
-		return nil, err
+	t.Log("Given the need to test the resolution of schemas by their ids")
+	{
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When trying to validate %s", index, testCase.description)
+			{
+				jv, err := jsonvalidator.NewJsonValidator("draft-07")
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to create a new JsonValidator: %v", failed, err)
+				}
+
+				for _, schema := range testCase.added {
+					err = jv.AddSchema([]byte(schema))
+					if err != nil {
+						t.Fatalf("\t%s\tShould be able to add a schema: %v", failed, err)
+					}
+				}
+
+				err = jv.LoadSchema("/v1/registry", "POST", []byte(testCase.schema))
+				if !testCase.loads {
+					if err != nil {
+						t.Logf("\t%s\tShould not be able to load the schema: %v", succeed, err)
+					} else {
+						t.Errorf("\t%s\tShould not be able to load the schema", failed)
+					}
+
+					continue
+				}
+
+				if err != nil {
+					t.Errorf("\t%s\tShould be able to load the schema: %v", failed, err)
+					continue
+				}
+
+				err = jv.Validate("/v1/registry", "POST", []byte(testCase.data))
+				if testCase.valid {
+					if err != nil {
+						t.Errorf("\t%s\tData should be valid: %v", failed, err)
+					} else {
+						t.Logf("\t%s\tData should be valid", succeed)
+					}
+				} else {
+					if err != nil {
+						t.Logf("\t%s\tData should not be valid: %v", succeed, err)
+					} else {
+						t.Errorf("\t%s\tData should not be valid", failed)
+					}
+				}
+			}
+		}
 	}
+}
 
-	defer file.Close()
+func TestSchemaRegistryReload(t *testing.T) {
+	t.Log("Given the need to test the registration of reloaded schemas")
+	{
+		jv, err := jsonvalidator.NewJsonValidator("draft-07")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a new JsonValidator: %v", failed, err)
+		}
 
-	// Read the data from the file.
-	bytes, err := ioutil.ReadAll(file)
-	if err != nil {
-		return nil, err
+		t.Log("\tTest 0: When trying to load different schemas without \"$id\" to different endpoints")
+		{
+			err = jv.LoadSchema("/v1/a", "POST", []byte(`{"type": "string"}`))
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to load the first schema: %v", failed, err)
+			}
+
+			err = jv.LoadSchema("/v1/b", "POST", []byte(`{"type": "number"}`))
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to load the second schema: %v", failed, err)
+			}
+
+			if jv.Validate("/v1/a", "POST", []byte(`"a"`)) != nil || jv.Validate("/v1/b", "POST", []byte(`1`)) != nil {
+				t.Errorf("\t%s\tEach endpoint should use its own schema", failed)
+			} else {
+				t.Logf("\t%s\tEach endpoint should use its own schema", succeed)
+			}
+		}
+
+		t.Log("\tTest 1: When trying to reload an endpoint with a changed schema that keeps its \"$id\"")
+		{
+			err = jv.LoadSchema("/v1/c", "POST", []byte(`{"$id": "https://example.com/c.json", "type": "string"}`))
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to load the schema: %v", failed, err)
+			}
+
+			err = jv.LoadSchema("/v1/c", "POST", []byte(`{"$id": "https://example.com/c.json", "type": "number"}`))
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to reload the schema: %v", failed, err)
+			}
+
+			err = jv.LoadSchema("/v1/d", "POST", []byte(`{"$ref": "https://example.com/c.json"}`))
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to load a schema that references it: %v", failed, err)
+			}
+
+			if jv.Validate("/v1/d", "POST", []byte(`1`)) != nil {
+				t.Errorf("\t%s\tReferences should point to the reloaded schema", failed)
+			} else {
+				t.Logf("\t%s\tReferences should point to the reloaded schema", succeed)
+			}
+		}
+
+		t.Log("\tTest 2: When trying to reference a schema that another validator holds")
+		{
+			other, err := jsonvalidator.NewJsonValidator("draft-07")
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to create a new JsonValidator: %v", failed, err)
+			}
+
+			err = other.LoadSchema("/v1/d", "POST", []byte(`{"$ref": "https://example.com/c.json"}`))
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to load the schema: %v", failed, err)
+			}
+
+			err = other.Validate("/v1/d", "POST", []byte(`1`))
+			if err != nil {
+				t.Logf("\t%s\tThe reference should not be resolved: %v", succeed, err)
+			} else {
+				t.Errorf("\t%s\tThe reference should not be resolved", failed)
+			}
+		}
 	}
+}
 
-	return bytes, nil
+func TestSchemaSources(t *testing.T) {
+	testCases := []struct {
+		description string
+		schema      string
+		data        string
+		loads       bool
+		resolves    bool
+		valid       bool
+	}{
+		{
+			"a relative reference to a file in a schema directory",
+			`{"properties": {"address": {"$ref": "common.json#/definitions/address"}}}`,
+			`{"address": {}}`,
+			true,
+			true,
+			false,
+		},
+		{
+			"a relative reference inside a file that was loaded for a reference",
+			`{"properties": {"address": {"$ref": "common.json#/definitions/address"}}}`,
+			`{"address": {"city": "Paris", "country": "France"}}`,
+			true,
+			true,
+			false,
+		},
+		{
+			"files that reference each other",
+			`{"$ref": "cycle/a.json"}`,
+			`{"next": {"previous": {"next": 1}}}`,
+			true,
+			true,
+			false,
+		},
+		{
+			"an absolute reference that is mapped to a file",
+			`{"$ref": "https://example.com/person.json"}`,
+			`{"name": "Bob"}`,
+			true,
+			true,
+			true,
+		},
+		{
+			"an absolute reference under a prefix that is mapped to a directory",
+			`{"items": {"$ref": "https://example.com/shared/id.json"}}`,
+			`[1, 0]`,
+			true,
+			true,
+			false,
+		},
+		{
+			"a file that declares another id than the reference that it is mapped to",
+			`{"$ref": "https://example.com/other-person.json"}`,
+			``,
+			false,
+			false,
+			false,
+		},
+		{
+			"references to a missing file and to a missing definition",
+			`{"properties": {"a": {"$ref": "missing.json"}, "b": {"$ref": "#/definitions/missing"}}}`,
+			``,
+			true,
+			false,
+			false,
+		},
+		{
+			"references that point to each other without reaching into the data",
+			`{"definitions": {"a": {"$ref": "#/definitions/b"}, "b": {"$ref": "#/definitions/a"}},
+				"$ref": "#/definitions/a"}`,
+			`{}`,
+			true,
+			true,
+			false,
+		},
+	}
+
+	// Get the path of the current go file (including the path inside
+	// the project).
+	var absolutePath string
+	if _, filename, _, ok := runtime.Caller(0); ok {
+		absolutePath = path.Dir(filename)
+	}
+
+	sources := absolutePath + "/testdata/sources"
+
+	t.Log("Given the need to test the loading of referenced schemas from files")
+	{
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When trying to validate %s", index, testCase.description)
+			{
+				jv, err := jsonvalidator.NewJsonValidator("draft-07")
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to create a new JsonValidator: %v", failed, err)
+				}
+
+				jv.SetSchemaSources([]string{sources}, map[string]string{
+					"https://example.com/person.json":       sources + "/mapped/person.json",
+					"https://example.com/other-person.json": sources + "/mapped/person.json",
+					"https://example.com/shared/":           sources + "/shared",
+				})
+
+				err = jv.LoadSchema("/v1/sources", "POST", []byte(testCase.schema))
+				if !testCase.loads {
+					if err != nil {
+						t.Logf("\t%s\tShould not be able to load the schema: %v", succeed, err)
+					} else {
+						t.Errorf("\t%s\tShould not be able to load the schema", failed)
+					}
+
+					continue
+				}
+
+				if err != nil {
+					t.Errorf("\t%s\tShould be able to load the schema: %v", failed, err)
+					continue
+				}
+
+				err = jv.ResolveReferences()
+				if !testCase.resolves {
+					if err != nil {
+						t.Logf("\t%s\tShould not be able to resolve the references: %v", succeed, err)
+					} else {
+						t.Errorf("\t%s\tShould not be able to resolve the references", failed)
+					}
+
+					continue
+				}
+
+				if err != nil {
+					t.Errorf("\t%s\tShould be able to resolve the references: %v", failed, err)
+					continue
+				}
+
+				err = jv.Validate("/v1/sources", "POST", []byte(testCase.data))
+				if testCase.valid {
+					if err != nil {
+						t.Errorf("\t%s\tData should be valid: %v", failed, err)
+					} else {
+						t.Logf("\t%s\tData should be valid", succeed)
+					}
+				} else {
+					if err != nil {
+						t.Logf("\t%s\tData should not be valid: %v", succeed, err)
+					} else {
+						t.Errorf("\t%s\tData should not be valid", failed)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestConcurrentReload(t *testing.T) {
+	schemas := []string{
+		`{"$id": "https://example.com/concurrent.json", "type": "string"}`,
+		`{"$id": "https://example.com/concurrent.json", "type": ["string", "number"]}`,
+	}
+
+	t.Log("Given the need to test validations while schemas are reloaded")
+	{
+		jv, err := jsonvalidator.NewJsonValidator("draft-07")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a new JsonValidator: %v", failed, err)
+		}
+
+		err = jv.LoadSchema("/v1/concurrent", "POST", []byte(schemas[0]))
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to load the schema: %v", failed, err)
+		}
+
+		err = jv.LoadSchema("/v1/reference", "POST", []byte(`{"$ref": "https://example.com/concurrent.json"}`))
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to load the schema: %v", failed, err)
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 8)
+
+		for worker := 0; worker < 4; worker++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				for iteration := 0; iteration < 50; iteration++ {
+					for _, path := range []string{"/v1/concurrent", "/v1/reference"} {
+						if err := jv.Validate(path, "POST", []byte(`"a"`)); err != nil {
+							errs <- err
+							return
+						}
+					}
+				}
+			}()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for iteration := 0; iteration < 50; iteration++ {
+				if err := jv.LoadSchema("/v1/concurrent", "POST", []byte(schemas[iteration%2])); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			t.Fatalf("\t%s\tShould validate and reload concurrently: %v", failed, err)
+		}
+		t.Logf("\t%s\tShould validate and reload concurrently", succeed)
+	}
+}
+
+// runTestCases loads the schema of each test case into the validator and
+// validates the test case's data against it.
+func runTestCases(t *testing.T, jv jsonvalidator.JsonValidator, testCases []testCase) {
+	var err error
+
+	for i, testCase := range testCases {
+		subTest := func(t *testing.T) {
+			t.Logf("\t[%s] Test Schema %d: %s", testCase.Keyword, i, testCase.Descriptions)
+			{
+				for j, test := range testCase.Tests {
+					t.Logf("\t\tTest %d.%d: When trying to validate %s against the given schema", i, j, test.Description)
+					{
+						err = jv.LoadSchema(testCase.Path, testCase.Method, testCase.Schema)
+						if err != nil {
+							t.Errorf("\t\t%s\tShould be able to Load schema: %v", failed, err)
+						}
+
+						err = jv.Validate(testCase.Path, testCase.Method, test.Data)
+						if test.Valid {
+							if err != nil {
+								t.Errorf("\t\t%s\tData should be valid against the specified json schema: %v", failed, err)
+							} else {
+								t.Logf("\t\t%s\tData should be valid against the specified json schema", succeed)
+							}
+						} else {
+							if err != nil {
+								t.Logf("\t\t%s\tData should not be valid against the specified json schema: %v", succeed, err)
+							} else {
+								t.Errorf("\t\t%s\tData should not be valid against the specified json schema", failed)
+							}
+						}
+					}
+				}
+			}
+			t.Log()
+		}
+
+		t.Run(testCase.Keyword, subTest)
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	testCases := []struct {
+		description string
+		schema      string
+		data        string
+		path        string
+		keyword     string
+	}{
+		{
+			"a string instead of a number at the root",
+			`{"type": "number"}`,
+			`"a"`,
+			"/",
+			"type",
+		},
+		{
+			"a missing required property",
+			`{"required": ["a"]}`,
+			`{"b": 1}`,
+			"/",
+			"required",
+		},
+		{
+			"a nested property that breaks its maximum",
+			`{"properties": {"a": {"properties": {"b": {"maximum": 3}}}}}`,
+			`{"a": {"b": 4}}`,
+			"/a/b",
+			"maximum",
+		},
+	}
+
+	t.Log("Given the need to test the details of validation errors")
+	{
+		jv, err := jsonvalidator.NewJsonValidator("draft-07")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a new JsonValidator: %v", failed, err)
+		}
+		t.Logf("\t%s\tShould be able to create a new JsonValidator", succeed)
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When trying to validate %s", index, testCase.description)
+			{
+				err = jv.LoadSchema("/v1/error", "POST", []byte(testCase.schema))
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to Load schema: %v", failed, err)
+				}
+
+				err = jv.Validate("/v1/error", "POST", []byte(testCase.data))
+
+				validationErr, ok := err.(validators.ValidationError)
+				if !ok {
+					t.Errorf("\t%s\tShould get a ValidationError: %v", failed, err)
+					continue
+				}
+				t.Logf("\t%s\tShould get a ValidationError", succeed)
+
+				if validationErr.Path() != testCase.path || validationErr.Keyword() != testCase.keyword {
+					t.Errorf("\t%s\tShould fail in path %s on keyword %s: got path %s, keyword %s",
+						failed, testCase.path, testCase.keyword, validationErr.Path(), validationErr.Keyword())
+				} else {
+					t.Logf("\t%s\tShould fail in path %s on keyword %s", succeed, testCase.path, testCase.keyword)
+				}
+			}
+		}
+	}
+}
+
+func TestExhaustiveValidation(t *testing.T) {
+	schema := `{
+		"type": "object",
+		"required": ["id", "name"],
+		"properties": {
+			"id": {"type": "integer"},
+			"tags": {"items": {"type": "string"}},
+			"size": {"allOf": [{"minimum": 1}, {"multipleOf": 2}]}
+		}
+	}`
+	testCases := []struct {
+		description string
+		data        string
+		failures    []string
+	}{
+		{
+			"a valid object",
+			`{"id": 1, "name": "a", "tags": ["a", "b"], "size": 2}`,
+			nil,
+		},
+		{
+			"an object with a single failure",
+			`{"id": 1.5, "name": "a"}`,
+			[]string{"/id /properties/id/type"},
+		},
+		{
+			"an object with failures in several locations",
+			`{"id": "a", "tags": ["a", 1, 2], "size": -1}`,
+			[]string{
+				"/ /required",
+				"/id /properties/id/type",
+				"/size /properties/size/allOf/0/minimum",
+				"/size /properties/size/allOf/1/multipleOf",
+				"/tags/1 /properties/tags/items/type",
+				"/tags/2 /properties/tags/items/type",
+			},
+		},
+	}
+
+	t.Log("Given the need to test exhaustive validation of json values")
+	{
+		jv, err := jsonvalidator.NewJsonValidator("draft-07")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a new JsonValidator: %v", failed, err)
+		}
+		t.Logf("\t%s\tShould be able to create a new JsonValidator", succeed)
+
+		jv.SetExhaustive(true)
+
+		err = jv.LoadSchema("/v1/exhaustive", "POST", []byte(schema))
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to Load schema: %v", failed, err)
+		}
+		t.Logf("\t%s\tShould be able to Load schema", succeed)
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When trying to validate %s", index, testCase.description)
+			{
+				err = jv.Validate("/v1/exhaustive", "POST", []byte(testCase.data))
+
+				var failures []string
+				switch v := err.(type) {
+				case nil:
+				case validators.ValidationErrors:
+					for _, validationErr := range v.Errors() {
+						failures = append(failures, validationErr.Path()+" "+validationErr.SchemaPath())
+					}
+				case validators.ValidationError:
+					failures = append(failures, v.Path()+" "+v.SchemaPath())
+				default:
+					t.Fatalf("\t%s\tShould get validation failures: %v", failed, err)
+				}
+
+				if strings.Join(failures, ", ") != strings.Join(testCase.failures, ", ") {
+					t.Errorf("\t%s\tShould get the failures [%s]: got [%s]", failed,
+						strings.Join(testCase.failures, ", "), strings.Join(failures, ", "))
+				} else {
+					t.Logf("\t%s\tShould get the failures [%s]", succeed, strings.Join(failures, ", "))
+				}
+			}
+		}
+	}
+}
+
+func TestOutput(t *testing.T) {
+	schema := `{
+		"properties": {
+			"a": {"minLength": 2, "pattern": "^[0-9]+$"},
+			"b": {"type": "number"}
+		}
+	}`
+	testCases := []struct {
+		format string
+		output string
+	}{
+		{
+			jsonvalidator.OUTPUT_FLAG,
+			`{"valid":false,"keywordLocation":"","instanceLocation":""}`,
+		},
+		{
+			jsonvalidator.OUTPUT_BASIC,
+			`{"valid":false,"keywordLocation":"","instanceLocation":"","errors":[` +
+				`{"valid":false,"keywordLocation":"/properties/a/minLength","instanceLocation":"/a","error":"\"minLength\" validation failed, reason: inspected string is less than 2"},` +
+				`{"valid":false,"keywordLocation":"/properties/a/pattern","instanceLocation":"/a","error":"\"pattern\" validation failed, reason: value x does not match to pattern^[0-9]+$"},` +
+				`{"valid":false,"keywordLocation":"/properties/b/type","instanceLocation":"/b","error":"\"type\" validation failed, reason: inspected value expected to be a json number"}]}`,
+		},
+		{
+			jsonvalidator.OUTPUT_DETAILED,
+			`{"valid":false,"keywordLocation":"","instanceLocation":"","errors":[` +
+				`{"valid":false,"keywordLocation":"/properties","instanceLocation":"","errors":[` +
+				`{"valid":false,"keywordLocation":"/properties/a","instanceLocation":"/a","errors":[` +
+				`{"valid":false,"keywordLocation":"/properties/a/minLength","instanceLocation":"/a","error":"\"minLength\" validation failed, reason: inspected string is less than 2"},` +
+				`{"valid":false,"keywordLocation":"/properties/a/pattern","instanceLocation":"/a","error":"\"pattern\" validation failed, reason: value x does not match to pattern^[0-9]+$"}]},` +
+				`{"valid":false,"keywordLocation":"/properties/b/type","instanceLocation":"/b","error":"\"type\" validation failed, reason: inspected value expected to be a json number"}]}]}`,
+		},
+	}
+
+	t.Log("Given the need to test the output formats of validation results")
+	{
+		jv, err := jsonvalidator.NewJsonValidator("draft-07")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a new JsonValidator: %v", failed, err)
+		}
+		t.Logf("\t%s\tShould be able to create a new JsonValidator", succeed)
+
+		jv.SetExhaustive(true)
+
+		err = jv.LoadSchema("/v1/output", "POST", []byte(schema))
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to Load schema: %v", failed, err)
+		}
+
+		validationErr := jv.Validate("/v1/output", "POST", []byte(`{"a": "x", "b": "1"}`))
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When trying to describe the failures in the %s format", index, testCase.format)
+			{
+				output, err := jsonvalidator.Output(validationErr, testCase.format)
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to create the output: %v", failed, err)
+				}
+
+				rawOutput, err := json.Marshal(output)
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to marshal the output: %v", failed, err)
+				}
+
+				if string(rawOutput) != testCase.output {
+					t.Errorf("\t%s\tShould get the expected output: got %s", failed, rawOutput)
+				} else {
+					t.Logf("\t%s\tShould get the expected output", succeed)
+				}
+			}
+		}
+	}
+}
+
+func TestValidateParameters(t *testing.T) {
+	schema := `{
+		"type": "object",
+		"required": ["id"],
+		"properties": {
+			"id": {"type": "integer", "minimum": 1},
+			"verbose": {"type": "boolean"},
+			"ratio": {"type": ["number", "null"]},
+			"tags": {"type": "array", "items": {"type": "integer"}},
+			"name": {"type": "string", "maxLength": 3},
+			"X-Request-Id": {"type": "string", "pattern": "^[0-9a-f]+$"},
+			"petId": {"$ref": "#/definitions/count"},
+			"limit": {"$ref": "#/definitions/limit"},
+			"pages": {"type": "array", "items": {"$ref": "#/definitions/count"}}
+		},
+		"definitions": {
+			"count": {"type": "integer", "minimum": 1},
+			"limit": {"allOf": [{"$ref": "#/definitions/count"}, {"maximum": 100}]}
+		}
+	}`
+	testCases := []struct {
+		description string
+		location    string
+		parameters  map[string][]string
+		failures    []string
+	}{
+		{
+			"query parameters that can be converted to the schema's types",
+			validators.ParametersQuery,
+			map[string][]string{
+				"id":      {"12"},
+				"verbose": {"true"},
+				"ratio":   {""},
+				"tags":    {"1", "2"},
+				"name":    {"123"},
+			},
+			nil,
+		},
+		{
+			"query parameters that cannot be converted to the schema's types",
+			validators.ParametersQuery,
+			map[string][]string{
+				"id":      {"1.5"},
+				"verbose": {"yes"},
+				"tags":    {"1", "a"},
+			},
+			[]string{
+				"/query/id /properties/id/type",
+				"/query/tags/1 /properties/tags/items/type",
+				"/query/verbose /properties/verbose/type",
+			},
+		},
+		{
+			"a path variable that fails in a keyword",
+			validators.ParametersPath,
+			map[string][]string{"id": {"0"}},
+			[]string{"/path/id /properties/id/minimum"},
+		},
+		{
+			"a path variable whose schema is a reference",
+			validators.ParametersPath,
+			map[string][]string{"id": {"1"}, "petId": {"123"}},
+			nil,
+		},
+		{
+			"query parameters whose types come from references and allOf",
+			validators.ParametersQuery,
+			map[string][]string{
+				"id":    {"1"},
+				"limit": {"500"},
+				"pages": {"1", "2"},
+			},
+			[]string{"/query/limit /properties/limit/$ref/allOf/1/maximum"},
+		},
+		{
+			"query parameters that are not finite numbers",
+			validators.ParametersQuery,
+			map[string][]string{
+				"id":    {"NaN"},
+				"ratio": {"Inf"},
+			},
+			[]string{
+				"/query/id /properties/id/type",
+				"/query/ratio /properties/ratio/type",
+			},
+		},
+		{
+			"headers that are matched to the schema regardless of their case",
+			validators.ParametersHeader,
+			map[string][]string{
+				"Id":           {"1"},
+				"X-Request-Id": {"xyz"},
+				"Accept":       {"*/*"},
+			},
+			[]string{"/header/X-Request-Id /properties/X-Request-Id/pattern"},
+		},
+	}
+
+	t.Log("Given the need to test validation of request parameters")
+	{
+		jv, err := jsonvalidator.NewJsonValidator("draft-07")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a new JsonValidator: %v", failed, err)
+		}
+		t.Logf("\t%s\tShould be able to create a new JsonValidator", succeed)
+
+		jv.SetExhaustive(true)
+
+		for _, location := range []string{
+			validators.ParametersPath,
+			validators.ParametersQuery,
+			validators.ParametersHeader,
+		} {
+			err = jv.LoadParametersSchema("/v1/parameters/:id", "GET", location, []byte(schema))
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to Load %s parameters schema: %v", failed, location, err)
+			}
+		}
+		t.Logf("\t%s\tShould be able to Load parameters schemas", succeed)
+
+		err = jv.LoadParametersSchema("/v1/parameters/:id", "GET", "cookie", []byte(schema))
+		if err == nil {
+			t.Errorf("\t%s\tShould not be able to Load parameters schema to an unknown location", failed)
+		} else {
+			t.Logf("\t%s\tShould not be able to Load parameters schema to an unknown location", succeed)
+		}
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When trying to validate %s", index, testCase.description)
+			{
+				err = jv.ValidateParameters("/v1/parameters/:id", "GET", testCase.location, testCase.parameters)
+
+				var failures []string
+				switch v := err.(type) {
+				case nil:
+				case validators.ValidationErrors:
+					for _, validationErr := range v.Errors() {
+						failures = append(failures, validationErr.Path()+" "+validationErr.SchemaPath())
+					}
+				case validators.ValidationError:
+					failures = append(failures, v.Path()+" "+v.SchemaPath())
+				default:
+					t.Fatalf("\t%s\tShould get validation failures: %v", failed, err)
+				}
+
+				if strings.Join(failures, ", ") != strings.Join(testCase.failures, ", ") {
+					t.Errorf("\t%s\tShould get the failures [%s]: got [%s]", failed,
+						strings.Join(testCase.failures, ", "), strings.Join(failures, ", "))
+				} else {
+					t.Logf("\t%s\tShould get the failures [%s]", succeed, strings.Join(failures, ", "))
+				}
+			}
+		}
+	}
+}
+
+func TestValidateForm(t *testing.T) {
+	schema := `{
+		"type": "object",
+		"required": ["title", "attachment"],
+		"additionalProperties": false,
+		"properties": {
+			"title": {"type": "string", "minLength": 1},
+			"priority": {"type": "integer", "maximum": 5},
+			"labels": {"type": "array", "maxItems": 2},
+			"attachment": {"type": "string", "format": "binary"}
+		}
+	}`
+	testCases := []struct {
+		description string
+		fields      map[string][]string
+		failures    []string
+	}{
+		{
+			"fields that can be converted to the schema's types",
+			map[string][]string{
+				"title":      {"Broken link"},
+				"priority":   {"2"},
+				"labels":     {"bug", "docs"},
+				"attachment": {"screenshot.png"},
+			},
+			nil,
+		},
+		{
+			"fields that fail in the schema's keywords",
+			map[string][]string{
+				"title":    {""},
+				"priority": {"9"},
+				"labels":   {"bug", "docs", "ui"},
+				"extra":    {"1"},
+			},
+			[]string{
+				"/ /required",
+				"/extra /additionalProperties",
+				"/labels /properties/labels/maxItems",
+				"/priority /properties/priority/maximum",
+				"/title /properties/title/minLength",
+			},
+		},
+	}
+
+	t.Log("Given the need to test validation of form bodies")
+	{
+		jv, err := jsonvalidator.NewJsonValidator("draft-07")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a new JsonValidator: %v", failed, err)
+		}
+		t.Logf("\t%s\tShould be able to create a new JsonValidator", succeed)
+
+		jv.SetExhaustive(true)
+
+		err = jv.LoadSchema("/v1/issues", "POST", []byte(schema))
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to Load schema: %v", failed, err)
+		}
+		t.Logf("\t%s\tShould be able to Load schema", succeed)
+
+		err = jv.ValidateForm("/v1/issues", "PUT", map[string][]string{})
+		if err == nil {
+			t.Errorf("\t%s\tShould not be able to validate a form without a schema", failed)
+		} else {
+			t.Logf("\t%s\tShould not be able to validate a form without a schema", succeed)
+		}
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When trying to validate %s", index, testCase.description)
+			{
+				err = jv.ValidateForm("/v1/issues", "POST", testCase.fields)
+
+				var failures []string
+				switch v := err.(type) {
+				case nil:
+				case validators.ValidationErrors:
+					for _, validationErr := range v.Errors() {
+						failures = append(failures, validationErr.Path()+" "+validationErr.SchemaPath())
+					}
+				case validators.ValidationError:
+					failures = append(failures, v.Path()+" "+v.SchemaPath())
+				default:
+					t.Fatalf("\t%s\tShould get validation failures: %v", failed, err)
+				}
+
+				sort.Strings(failures)
+
+				if strings.Join(failures, ", ") != strings.Join(testCase.failures, ", ") {
+					t.Errorf("\t%s\tShould get the failures [%s]: got [%s]", failed,
+						strings.Join(testCase.failures, ", "), strings.Join(failures, ", "))
+				} else {
+					t.Logf("\t%s\tShould get the failures [%s]", succeed, strings.Join(failures, ", "))
+				}
+			}
+		}
+	}
+}
+
+func TestValidateMessage(t *testing.T) {
+	bodySchema := `{
+		"$id": "http://example.com/chat.json",
+		"type": "object",
+		"required": ["room"],
+		"definitions": {"text": {"type": "string", "maxLength": 5}}
+	}`
+	messageSchema := `{
+		"type": "object",
+		"required": ["text"],
+		"properties": {"text": {"$ref": "http://example.com/chat.json#/definitions/text"}}
+	}`
+	testCases := []struct {
+		description string
+		message     string
+		valid       bool
+	}{
+		{"a message that matches a referenced body schema", `{"text": "hi"}`, true},
+		{"a message that fails in a referenced body schema", `{"text": "hello world"}`, false},
+		{"a message that matches the body schema instead", `{"room": "lobby"}`, false},
+	}
+
+	t.Log("Given the need to test validation of WebSocket messages")
+	{
+		jv, err := jsonvalidator.NewJsonValidator("draft-07")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a new JsonValidator: %v", failed, err)
+		}
+		t.Logf("\t%s\tShould be able to create a new JsonValidator", succeed)
+
+		err = jv.LoadSchema("/v1/chat", "GET", []byte(bodySchema))
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to Load schema: %v", failed, err)
+		}
+
+		err = jv.LoadMessageSchema("/v1/chat", "GET", []byte(messageSchema))
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to Load message schema: %v", failed, err)
+		}
+
+		err = jv.ResolveReferences()
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to resolve the references of the message schema: %v", failed, err)
+		}
+		t.Logf("\t%s\tShould be able to Load a message schema next to the body schema", succeed)
+
+		err = jv.Validate("/v1/chat", "GET", []byte(`{"room": "lobby"}`))
+		if err != nil {
+			t.Errorf("\t%s\tShould keep the body schema of the endpoint: %v", failed, err)
+		} else {
+			t.Logf("\t%s\tShould keep the body schema of the endpoint", succeed)
+		}
+
+		err = jv.ValidateMessage("/v1/chat", "POST", []byte(`{}`))
+		if err == nil {
+			t.Errorf("\t%s\tShould not be able to validate a message without a schema", failed)
+		} else {
+			t.Logf("\t%s\tShould not be able to validate a message without a schema", succeed)
+		}
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When trying to validate %s", index, testCase.description)
+			{
+				err = jv.ValidateMessage("/v1/chat", "GET", []byte(testCase.message))
+				if testCase.valid {
+					if err != nil {
+						t.Errorf("\t%s\tMessage should be valid: %v", failed, err)
+					} else {
+						t.Logf("\t%s\tMessage should be valid", succeed)
+					}
+				} else {
+					if err != nil {
+						t.Logf("\t%s\tMessage should not be valid: %v", succeed, err)
+					} else {
+						t.Errorf("\t%s\tMessage should not be valid", failed)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	schemas := []struct {
+		status      string
+		contentType string
+		schema      string
+	}{
+		{"200", "application/json", `{"required": ["id"], "additionalProperties": false, "properties": {"id": {}}}`},
+		{"200", "", `{"type": "string"}`},
+		{"2XX", "", `{"type": "object"}`},
+		{"default", "application/*", `{"required": ["error"]}`},
+	}
+	testCases := []struct {
+		description string
+		method      string
+		status      int
+		contentType string
+		body        string
+		valid       bool
+	}{
+		{"a response that matches its status and content type", "GET", 200, "application/json; charset=utf-8", `{"id": 1}`, true},
+		{"a response that leaks an unexpected field", "GET", 200, "application/json", `{"id": 1, "password": "a"}`, false},
+		{"a response with a content type that no schema names", "GET", 200, "text/plain", `"text"`, true},
+		{"a response that matches a range of status codes", "GET", 201, "application/json", `[]`, false},
+		{"a response that matches the default schema", "GET", 500, "application/problem+json", `{"title": "a"}`, false},
+		{"a response that no schema describes", "GET", 500, "text/html", `<html></html>`, true},
+		{"a response with an empty body", "GET", 200, "application/json", ``, true},
+		{"a response without content", "GET", 204, "application/json", `[]`, true},
+		{"a response that was not modified", "GET", 304, "application/json", `[]`, true},
+		{"an informational response", "GET", 103, "application/json", `[]`, true},
+		{"a response to a HEAD request", "HEAD", 200, "application/json", `[]`, true},
+	}
+
+	t.Log("Given the need to test validation of target responses")
+	{
+		jv, err := jsonvalidator.NewJsonValidator("draft-07")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to create a new JsonValidator: %v", failed, err)
+		}
+		t.Logf("\t%s\tShould be able to create a new JsonValidator", succeed)
+
+		for _, method := range []string{"GET", "HEAD"} {
+			for _, schema := range schemas {
+				err = jv.LoadResponseSchema("/v1/responses", method, schema.status, schema.contentType, []byte(schema.schema))
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to Load response schema for %s: %v", failed, schema.status, err)
+				}
+			}
+		}
+		t.Logf("\t%s\tShould be able to Load response schemas", succeed)
+
+		err = jv.LoadResponseSchema("/v1/responses", "GET", "2xx", "", []byte(`{}`))
+		if err == nil {
+			t.Errorf("\t%s\tShould not be able to Load a response schema twice", failed)
+		} else {
+			t.Logf("\t%s\tShould not be able to Load a response schema twice", succeed)
+		}
+
+		err = jv.LoadResponseSchema("/v1/responses", "GET", "20", "", []byte(`{}`))
+		if err == nil {
+			t.Errorf("\t%s\tShould not be able to Load a response schema with an invalid status", failed)
+		} else {
+			t.Logf("\t%s\tShould not be able to Load a response schema with an invalid status", succeed)
+		}
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When trying to validate %s", index, testCase.description)
+			{
+				err = jv.ValidateResponse("/v1/responses", testCase.method, testCase.status,
+					testCase.contentType, []byte(testCase.body))
+				if testCase.valid {
+					if err != nil {
+						t.Errorf("\t%s\tResponse should be valid: %v", failed, err)
+					} else {
+						t.Logf("\t%s\tResponse should be valid", succeed)
+					}
+				} else {
+					if err != nil {
+						t.Logf("\t%s\tResponse should not be valid: %v", succeed, err)
+					} else {
+						t.Errorf("\t%s\tResponse should not be valid", failed)
+					}
+				}
+			}
+		}
+	}
+}
+
+// readTestCases reads the test cases of each keyword from the keyword's file
+// in a directory under testdata.
+func readTestCases(t *testing.T, directory string, keywords []string) []testCase {
+	testCases := make([]testCase, 0)
+
+	// Read all the test data from the files and append them to the main slice.
+	for _, keyword := range keywords {
+		testData := make([]testCase, 0)
+		rawTestData, err := readTestDataFromFile(directory + keyword + ".json")
+		if err != nil {
+			t.Fatalf("Could not read test data from file: %v", err)
+		}
+
+		err = json.Unmarshal(rawTestData, &testData)
+		if err != nil {
+			t.Fatalf("Could not unmarshal test data to test cases slice, "+
+				"probably one or more cases is not in the correct format in %s.json: %v", keyword, err)
+		}
+
+		for index := range testData {
+			testData[index].Keyword = keyword
+		}
+
+		testCases = append(testCases, testData...)
+	}
+
+	return testCases
+}
+
+func readTestDataFromFile(fileName string) ([]byte, error) {
+	// Get the path of the current go file (including the path inside
+	// the project).
+	var absolutePath string
+	if _, filename, _, ok := runtime.Caller(0); ok {
+		absolutePath = path.Dir(filename)
+	}
+
+	// Open the meta-schema file.
+	file, err := os.Open(absolutePath + "/testdata/" + fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	// Read the data from the file.
+	bytes, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes, nil
+}
+
+// benchmarkSchema describes the records of the payloads of the benchmarks.
+const benchmarkSchema = `{
+	"type": "array",
+	"items": {"$ref": "#/definitions/record"},
+	"definitions": {
+		"record": {
+			"type": "object",
+			"required": ["id", "name", "email", "tags", "address"],
+			"properties": {
+				"id": {"type": "integer", "minimum": 0},
+				"name": {"type": "string", "minLength": 1, "maxLength": 64},
+				"email": {"type": "string", "format": "email"},
+				"active": {"type": "boolean"},
+				"score": {"type": "number", "multipleOf": 0.5},
+				"tags": {
+					"type": "array",
+					"items": {"type": "string", "pattern": "^[a-z]+$"},
+					"uniqueItems": true
+				},
+				"address": {
+					"type": "object",
+					"properties": {
+						"street": {"type": "string"},
+						"city": {"type": "string"},
+						"zip": {"type": "string", "pattern": "^[0-9]{5}$"}
+					},
+					"additionalProperties": false
+				},
+				"children": {"type": "array", "items": {"$ref": "#/definitions/record"}}
+			},
+			"patternProperties": {"^x-": {"type": "string"}},
+			"additionalProperties": false
+		}
+	}
+}`
+
+// benchmarkPayload generates an array of records that matches
+// benchmarkSchema. Each record holds nested records, depth levels deep.
+func benchmarkPayload(b *testing.B, records, depth int) []byte {
+	var record func(id, depth int) map[string]interface{}
+	record = func(id, depth int) map[string]interface{} {
+		r := map[string]interface{}{
+			"id":     id,
+			"name":   "record " + strconv.Itoa(id),
+			"email":  "record" + strconv.Itoa(id) + "@example.com",
+			"active": id%2 == 0,
+			"score":  float64(id) / 2,
+			"tags":   []string{"alpha", "beta", "gamma"},
+			"address": map[string]interface{}{
+				"street": "Main Street " + strconv.Itoa(id),
+				"city":   "Springfield",
+				"zip":    "12345",
+			},
+			"x-origin": "benchmark",
+		}
+
+		if depth > 0 {
+			r["children"] = []interface{}{record(id*10+1, depth-1), record(id*10+2, depth-1)}
+		}
+
+		return r
+	}
+
+	payload := make([]interface{}, records)
+	for index := range payload {
+		payload[index] = record(index, depth)
+	}
+
+	bytes, err := json.Marshal(payload)
+	if err != nil {
+		b.Fatalf("could not generate the payload: %v", err)
+	}
+
+	return bytes
+}
+
+// benchmarkValidate measures the throughput of validating a payload against
+// benchmarkSchema.
+func benchmarkValidate(b *testing.B, records, depth int) {
+	jv, err := jsonvalidator.NewJsonValidator("draft-07")
+	if err != nil {
+		b.Fatalf("could not create a new JsonValidator: %v", err)
+	}
+
+	err = jv.LoadSchema("/records", "POST", []byte(benchmarkSchema))
+	if err != nil {
+		b.Fatalf("could not load the schema: %v", err)
+	}
+
+	payload := benchmarkPayload(b, records, depth)
+
+	b.SetBytes(int64(len(payload)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		err = jv.Validate("/records", "POST", payload)
+		if err != nil {
+			b.Fatalf("the payload should be valid: %v", err)
+		}
+	}
+}
+
+func BenchmarkValidateSmall(b *testing.B) {
+	benchmarkValidate(b, 10, 0)
+}
+
+func BenchmarkValidateLarge(b *testing.B) {
+	benchmarkValidate(b, 1000, 0)
+}
+
+func BenchmarkValidateNested(b *testing.B) {
+	benchmarkValidate(b, 100, 4)
 }
@@ -4,10 +4,14 @@ import (
 	"encoding/json"
 
 	"math"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/apidome/gateway/internal/pkg/jsonwalker"
 	"github.com/apidome/gateway/internal/pkg/validators/formatchecker"
 )
 
@@ -33,9 +37,16 @@ Implemented keywordValidators:
 > patternProperties: 		V
 > minProperties: 			V
 > maxProperties: 			V
-> items: 					V ***
+> dependentRequired: 		V
+> dependentSchemas: 		V
+> unevaluatedProperties: 	V
+> items: 					V
+> prefixItems: 				V
 > contains: 				V
+> minContains: 				V
+> maxContains: 				V
 > additionalItems: 			V
+> unevaluatedItems: 		V
 > minItems: 				V
 > maxItems: 				V
 > uniqueItems: 				V
@@ -46,6 +57,9 @@ Implemented keywordValidators:
 > _if: 						V
 > _then: 					V
 > _else: 					V
+> $ref: 					V
+> $recursiveRef: 			V
+> $dynamicRef: 				V
 
 *** These keywords are being un-marshaled in their validate() function.
 	We need to find a way to do that on startup and not on runtime.
@@ -74,7 +88,7 @@ const (
 )
 
 type keywordValidator interface {
-	validate(string, jsonData, string) error
+	validate(string, jsonData, *validationContext) error
 }
 
 /*****************/
@@ -83,29 +97,53 @@ type keywordValidator interface {
 
 type ref string
 
-func (r ref) validateByRef(jsonPath string, jsonData []byte, rootSchemaID string) error {
-	splittedRef := strings.Split(string(r), "#")
-	schemaURI := splittedRef[0]
-	fragment := splittedRef[1]
+func (r ref) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	err := r.validateByRef(jsonPath, jsonData, ctx)
+	if err != nil {
+		errs, err := ValidationErrors{}.add(err, "/$ref")
+		if err != nil {
+			return err
+		}
+
+		return errs.result()
+	}
+
+	return nil
+}
+
+func (r ref) validateByRef(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	// The reference was resolved to an absolute URI when the schema was
+	// compiled, so it is split into the URI of the referenced schema
+	// resource and the fragment that points into it.
+	schemaURI, fragment := splitReference(string(r))
 
 	// If the schemaURI is empty string it means that the reference points to a schema
-	// in the local schema (for example #/definitions/x), so we want to use the rootSchemaID
-	// in order to get the current root-schema from the rootSchemaPool.
+	// in the local schema (for example #/definitions/x) of a schema that
+	// is not part of a root schema, so we want to use the rootSchemaID.
 	if schemaURI == "" {
-		schemaURI = rootSchemaID
+		schemaURI = ctx.rootSchemaId
+	}
+
+	ctx, err := ctx.withReference(schemaURI+"#"+fragment, jsonPath)
+	if err != nil {
+		return err
 	}
 
-	// If the root-schema exists in the rootSchemaPool, validate the data according to the
+	// If the root-schema exists in the registry, validate the data according to the
 	// fragment.
 	// Else, return an error
-	if rootSchema, ok := rootSchemaPool[schemaURI]; ok {
+	if rootSchema := ctx.registry.lookup(schemaURI); rootSchema != nil {
+		// The keywords of the referenced schema resolve their own references
+		// against the referenced root schema.
+		refCtx := ctx.withRootSchema(rootSchema)
+
 		// If the fragment is an empty fragment, validate the data against the root-schema.
 		// Else, validate the data against the sub-schema that the fragment points to.
 		if fragment != "" {
 			// If the referenced sub-schema exists, validate the data against it.
 			// Else, return an error
 			if subSchema, ok := rootSchema.subSchemaMap[fragment]; ok {
-				return subSchema.validateJsonData(jsonPath, jsonData, rootSchemaID)
+				return subSchema.validateValue(jsonPath, jsonData, refCtx)
 			} else {
 				return InvalidReferenceError{
 					schemaURI: schemaURI,
@@ -114,7 +152,7 @@ func (r ref) validateByRef(jsonPath string, jsonData []byte, rootSchemaID string
 				}
 			}
 		} else {
-			return rootSchema.validateJsonData(jsonPath, jsonData, rootSchemaID)
+			return rootSchema.validateValue(jsonPath, jsonData, refCtx)
 		}
 	} else {
 		return InvalidReferenceError{
@@ -125,8 +163,108 @@ func (r ref) validateByRef(jsonPath string, jsonData []byte, rootSchemaID string
 	}
 }
 
+type recursiveRef string
+
+func (rr recursiveRef) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	// "$recursiveRef" only has a dynamic behavior when its value is "#",
+	// which was resolved to the URI of the schema resource that contains it.
+	schemaURI, fragment := splitReference(string(rr))
+	if fragment != "" {
+		return ref(rr).validate(jsonPath, jsonData, ctx)
+	}
+
+	if schemaURI == "" {
+		schemaURI = ctx.rootSchemaId
+	}
+
+	rootSchema := ctx.registry.lookup(schemaURI)
+	if rootSchema == nil {
+		return InvalidReferenceError{
+			schemaURI: schemaURI,
+			err:       "could not find the referenced root schema",
+		}
+	}
+
+	// If the current root schema is marked with "$recursiveAnchor", the
+	// reference points to the outermost root schema in the dynamic scope
+	// that is marked as well.
+	if rootSchema.RecursiveAnchor != nil && bool(*rootSchema.RecursiveAnchor) {
+		for _, scopeSchema := range ctx.dynamicScope {
+			if scopeSchema.RecursiveAnchor != nil && bool(*scopeSchema.RecursiveAnchor) {
+				rootSchema = scopeSchema
+				break
+			}
+		}
+	}
+
+	err := rootSchema.validateValue(jsonPath, jsonData, ctx.withRootSchema(rootSchema))
+	if err != nil {
+		errs, err := ValidationErrors{}.add(err, "/$recursiveRef")
+		if err != nil {
+			return err
+		}
+
+		return errs.result()
+	}
+
+	return nil
+}
+
+type dynamicRef string
+
+func (dr dynamicRef) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	schemaURI, fragment := splitReference(string(dr))
+	if schemaURI == "" {
+		schemaURI = ctx.rootSchemaId
+	}
+
+	rootSchema := ctx.registry.lookup(schemaURI)
+
+	// A reference to a "$dynamicAnchor" points to the outermost schema in
+	// the dynamic scope with the same dynamic anchor. Any other reference
+	// behaves like "$ref".
+	if rootSchema == nil || rootSchema.dynamicAnchors[fragment] == nil {
+		err := ref(dr).validateByRef(jsonPath, jsonData, ctx)
+		if err != nil {
+			errs, err := ValidationErrors{}.add(err, "/$dynamicRef")
+			if err != nil {
+				return err
+			}
+
+			return errs.result()
+		}
+
+		return nil
+	}
+
+	subSchema := rootSchema.dynamicAnchors[fragment]
+
+	for _, scopeSchema := range ctx.dynamicScope {
+		if scopeSchema.dynamicAnchors[fragment] != nil {
+			rootSchema, subSchema = scopeSchema, scopeSchema.dynamicAnchors[fragment]
+			break
+		}
+	}
+
+	err := subSchema.validateValue(jsonPath, jsonData, ctx.withRootSchema(rootSchema))
+	if err != nil {
+		errs, err := ValidationErrors{}.add(err, "/$dynamicRef")
+		if err != nil {
+			return err
+		}
+
+		return errs.result()
+	}
+
+	return nil
+}
+
 type schema string
 type id string
+type anchor string
+type dynamicAnchor string
+type recursiveAnchor bool
+type vocabulary map[string]bool
 type comment string
 type title string
 type description string
@@ -143,16 +281,17 @@ func (d *_default) UnmarshalJSON(data []byte) error {
 /** Generic Keywords **/
 /**********************/
 
-type _type json.RawMessage
+type _type struct {
+	// raw is the keyword's value as it appears in the schema.
+	raw json.RawMessage
 
-func (t *_type) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
-	var data interface{}
+	// decoded is the keyword's value, decoded once when the schema is
+	// compiled.
+	decoded interface{}
+}
 
-	// First we need to unmarshal the json data.
-	err := json.Unmarshal(*t, &data)
-	if err != nil {
-		return err
-	}
+func (t *_type) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	data := t.decoded
 
 	// The "type" field in json schema can be represented by two different values:
 	// - string - the inspected value can be only one json type.
@@ -297,29 +436,22 @@ func assertJsonType(jsonType string, jsonData interface{}) error {
 }
 
 func (t *_type) UnmarshalJSON(data []byte) error {
-	*t = data
-	return nil
+	t.raw = data
+	return json.Unmarshal(data, &t.decoded)
 }
 
 func (t *_type) MarshalJSON() ([]byte, error) {
-	return []byte(*t), nil
+	return []byte(t.raw), nil
 }
 
 type enum []interface{}
 
-func (e enum) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (e enum) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
 	// Iterate over the items in "enum" array.
 	for _, item := range e {
-		// Marshal the item from "enum" array back comparable value that does
-		// not require type assertion.
-		rawEnumItem, err := json.Marshal(item)
-		if err != nil {
-			return nil
-		}
-
-		// Convert both of the byte arrays to string for more convenient
-		// comparison. If they are equal, the data is valid against "enum".
-		if string(rawEnumItem) == string(jsonData.raw) {
+		// If the item is equal to the inspected value, the data is valid
+		// against "enum".
+		if equalJson(item, jsonData.value) {
 			return nil
 		}
 	}
@@ -332,17 +464,22 @@ func (e enum) validate(jsonPath string, jsonData jsonData, rootSchemaId string)
 	}
 }
 
-type _const json.RawMessage
+type _const struct {
+	// raw is the compact form of the keyword's value, which describes the
+	// value in failures.
+	raw json.RawMessage
+
+	// value is the decoded value of the keyword.
+	value interface{}
+}
 
-func (c *_const) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
-	// Convert both of the byte arrays to string for more convenient
-	// comparison. If they are equal, the data is valid against "const".
-	if string(*c) == string(jsonData.raw) {
+func (c *_const) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	if equalJson(c.value, jsonData.value) {
 		return nil
 	} else {
 		return KeywordValidationError{
 			"const",
-			"inspected value not equal to \"" + string(*c) + "\"",
+			"inspected value not equal to \"" + string(c.raw) + "\"",
 		}
 	}
 }
@@ -351,30 +488,51 @@ func (c *_const) UnmarshalJSON(data []byte) error {
 	// In this function we Unmarshal and then Marshal again
 	// the argument data in order to remove special characters
 	// like \n \t \r etc.
-
-	var unmarshaledData interface{}
-
-	err := json.Unmarshal(data, &unmarshaledData)
+	err := json.Unmarshal(data, &c.value)
 	if err != nil {
 		return err
 	}
 
-	rawConst, err := json.Marshal(unmarshaledData)
-	if err != nil {
-		return err
-	}
+	c.raw, err = json.Marshal(c.value)
 
-	*c = rawConst
-	return nil
+	return err
+}
+
+// equalJson returns true if two decoded json values are equal: numbers are
+// compared by their values, objects regardless of the order of their
+// properties and arrays item by item.
+func equalJson(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
 }
 
 /*********************/
 /** String Keywords **/
 /*********************/
 
+// compiledPatterns caches the regular expressions of the schemas, so each
+// expression is compiled once instead of at every validated value.
+var compiledPatterns sync.Map
+
+// matchPattern reports whether a string matches a regular expression of a
+// schema.
+func matchPattern(pattern, s string) (bool, error) {
+	if compiled, ok := compiledPatterns.Load(pattern); ok {
+		return compiled.(*regexp.Regexp).MatchString(s), nil
+	}
+
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+
+	compiledPatterns.Store(pattern, compiled)
+
+	return compiled.MatchString(s), nil
+}
+
 type minLength int
 
-func (ml *minLength) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (ml *minLength) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
 	// If jsonData is a string, validate its length,
 	// else, return a KeywordValidationError
 	if v, ok := jsonData.value.(string); ok {
@@ -393,7 +551,7 @@ func (ml *minLength) validate(jsonPath string, jsonData jsonData, rootSchemaId s
 
 type maxLength int
 
-func (ml *maxLength) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (ml *maxLength) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
 	// If jsonData is a string, validate its length,
 	// else, return a KeywordValidationError
 	if v, ok := jsonData.value.(string); ok {
@@ -412,11 +570,11 @@ func (ml *maxLength) validate(jsonPath string, jsonData jsonData, rootSchemaId s
 
 type pattern string
 
-func (p *pattern) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (p *pattern) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
 	// If jsonData is a string, validate its length,
 	// else, return a KeywordValidationError
 	if v, ok := jsonData.value.(string); ok {
-		match, err := regexp.MatchString(string(*p), v)
+		match, err := matchPattern(string(*p), v)
 
 		// The pattern or the value is not in the right format (string)
 		if err != nil {
@@ -441,7 +599,7 @@ func (p *pattern) validate(jsonPath string, jsonData jsonData, rootSchemaId stri
 
 type format string
 
-func (f *format) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (f *format) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
 	if v, ok := jsonData.value.(string); ok {
 		switch string(*f) {
 		case FORMAT_DATE_TIME:
@@ -577,7 +735,7 @@ func (f *format) validate(jsonPath string, jsonData jsonData, rootSchemaId strin
 
 type multipleOf float64
 
-func (mo *multipleOf) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (mo *multipleOf) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
 	// If jsonData is float64, validate it. Else, return KeywordValidationError
 	if v, ok := jsonData.value.(float64); ok {
 		if math.Mod(v, float64(*mo)) == 0 {
@@ -598,7 +756,7 @@ func (mo *multipleOf) validate(jsonPath string, jsonData jsonData, rootSchemaId
 
 type minimum float64
 
-func (m *minimum) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (m *minimum) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
 	// If jsonData is float64, validate it. Else, return KeywordValidationError
 	if v, ok := jsonData.value.(float64); ok {
 		if v >= float64(*m) {
@@ -619,7 +777,7 @@ func (m *minimum) validate(jsonPath string, jsonData jsonData, rootSchemaId stri
 
 type maximum float64
 
-func (m *maximum) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (m *maximum) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
 	// If jsonData is float64, validate it. Else, return KeywordValidationError
 	if v, ok := jsonData.value.(float64); ok {
 		if v <= float64(*m) {
@@ -640,7 +798,7 @@ func (m *maximum) validate(jsonPath string, jsonData jsonData, rootSchemaId stri
 
 type exclusiveMinimum float64
 
-func (em *exclusiveMinimum) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (em *exclusiveMinimum) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
 	// If jsonData is float64, validate it. Else, return KeywordValidationError
 	if v, ok := jsonData.value.(float64); ok {
 		if v > float64(*em) {
@@ -661,7 +819,7 @@ func (em *exclusiveMinimum) validate(jsonPath string, jsonData jsonData, rootSch
 
 type exclusiveMaximum float64
 
-func (em *exclusiveMaximum) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (em *exclusiveMaximum) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
 	// If jsonData is float64, validate it. Else, return KeywordValidationError
 	if v, ok := jsonData.value.(float64); ok {
 		if v < float64(*em) {
@@ -686,25 +844,36 @@ func (em *exclusiveMaximum) validate(jsonPath string, jsonData jsonData, rootSch
 
 type properties map[string]*JsonSchema
 
-func (p properties) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (p properties) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	var errs ValidationErrors
+
 	// First, we need to verify that jsonData is a json object
 	if object, ok := jsonData.value.(map[string]interface{}); ok {
 		// For each "property" validate it according to its JsonSchema.
-		for key, value := range p {
+		for _, key := range sortedSchemaKeys(p) {
 			// Before we try to validate the data against the schema,
 			// we make sure that the data actually contains the property.
 			if _, ok := object[key]; ok {
-				err := value.validateJsonData(jsonPath+"/"+key, jsonData.raw, rootSchemaId)
+				ctx.evaluation.addProperty(key)
+
+				err := p[key].validateValue(jsonPath+"/"+jsonwalker.EscapeToken(key), newJsonData(object[key]), ctx)
 				if err != nil {
-					return err
+					errs, err = errs.add(err, "/properties/"+key)
+					if err != nil {
+						return err
+					}
+
+					if !ctx.exhaustive {
+						break
+					}
 				}
 			}
 		}
 	}
 
-	// If we arrived here, the validation of all the properties
+	// If the list is empty, the validation of all the properties
 	// succeeded.
-	return nil
+	return errs.result()
 }
 
 type additionalProperties struct {
@@ -713,11 +882,13 @@ type additionalProperties struct {
 	siblingPatternProperties *patternProperties
 }
 
-func (ap *additionalProperties) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (ap *additionalProperties) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	var errs ValidationErrors
+
 	// First we need to verify that jsonData is a json object.
 	if object, isObject := jsonData.value.(map[string]interface{}); isObject {
 		// Iterate over the properties of the inspected object.
-		for property := range object {
+		for _, property := range sortedKeys(object) {
 			validatedByProperties := false
 			validatedByPatternProperties := false
 
@@ -733,7 +904,7 @@ func (ap *additionalProperties) validate(jsonPath string, jsonData jsonData, roo
 				// Iterate over the patterns in "patternProperties" field.
 				for pattern := range *ap.siblingPatternProperties {
 					// Check if the inspected property matches to the pattern.
-					match, err := regexp.MatchString(pattern, property)
+					match, err := matchPattern(pattern, property)
 
 					// The pattern or the value is not in the right format (string)
 					if err != nil {
@@ -752,38 +923,47 @@ func (ap *additionalProperties) validate(jsonPath string, jsonData jsonData, roo
 			}
 
 			if !validatedByProperties && !validatedByPatternProperties {
-				err := (*ap).validateJsonData(jsonPath+"/"+property, jsonData.raw, rootSchemaId)
+				ctx.evaluation.addProperty(property)
+
+				err := (*ap).validateValue(jsonPath+"/"+jsonwalker.EscapeToken(property), newJsonData(object[property]), ctx)
 
-				// If the validation fails, return an error.
+				// If the validation fails, collect the failure.
 				if err != nil {
-					return KeywordValidationError{
-						"additionalProperties",
-						"property \"" +
-							property +
-							"\" failed in validation: \n" + err.Error(),
+					errs, err = errs.add(err, "/additionalProperties")
+					if err != nil {
+						return err
+					}
+
+					if !ctx.exhaustive {
+						break
 					}
 				}
 			}
 		}
 	}
 
-	// If we arrived here, none of the properties failed in validation,
-	// and we return true.
-	return nil
+	// If the list is empty, none of the properties failed in validation.
+	return errs.result()
 }
 
 type required []string
 
-func (r required) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (r required) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
 	// First, we must verify that jsonData is a json object.
 	if object, ok := jsonData.value.(map[string]interface{}); ok {
+		var missingProperties []string
+
 		// For each property in the required list, check if it exists.
 		for _, property := range r {
 			if object[property] == nil {
-				return KeywordValidationError{
-					"required",
-					"Missing required property - " + property,
-				}
+				missingProperties = append(missingProperties, property)
+			}
+		}
+
+		if len(missingProperties) > 0 {
+			return KeywordValidationError{
+				"required",
+				"Missing required property - " + strings.Join(missingProperties, ", "),
 			}
 		}
 	}
@@ -796,24 +976,34 @@ type propertyNames struct {
 	JsonSchema
 }
 
-func (pn *propertyNames) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (pn *propertyNames) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	var failures []string
+
 	// First, we need to verify that jsonData is a json object
 	if object, ok := jsonData.value.(map[string]interface{}); ok {
 		// Iterate over the object's properties.
-		for property := range object {
+		for _, property := range sortedKeys(object) {
 			// Validate the property name against the schema stored in "propertyNames" field
-			err := pn.validateJsonData("", []byte("\""+property+"\""), rootSchemaId)
+			err := pn.validateValue("", newJsonData(property), ctx.firstFailure())
 
-			// If the property name could be validated against the scheme return an error
+			// If the property name could be validated against the scheme collect the failure
 			if err != nil {
-				return KeywordValidationError{
-					"propertyNames",
-					"property name \"" + property + "\" failed in validation: " + err.Error(),
+				failures = append(failures, "property name \""+property+"\" failed in validation: "+err.Error())
+
+				if !ctx.exhaustive {
+					break
 				}
 			}
 		}
 	}
 
+	if len(failures) > 0 {
+		return KeywordValidationError{
+			"propertyNames",
+			strings.Join(failures, "; "),
+		}
+	}
+
 	// If we arrived here it means that all the property names validated successfully against
 	// the schema stored in "propertyNames".
 	return nil
@@ -821,56 +1011,46 @@ func (pn *propertyNames) validate(jsonPath string, jsonData jsonData, rootSchema
 
 type dependencies map[string]interface{}
 
-func (d dependencies) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (d dependencies) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	var errs ValidationErrors
+
 	// First we need to verify that jsonData is a json object.
 	if object, ok := jsonData.value.(map[string]interface{}); ok {
 
 		// Iterate over the dependencies object from the schema.
-		for propertyName, dependency := range d {
+		for _, propertyName := range sortedKeys(d) {
+			// Dependencies of properties that are not present in the
+			// instance are ignored.
+			if _, ok := object[propertyName]; !ok {
+				continue
+			}
+
+			var err error
+
 			// A dependency may be a json array (consist of strings) of a json
 			// object which is a json schema that the inspected value need to
 			// validated against.
-			switch v := dependency.(type) {
+			switch v := d[propertyName].(type) {
 
-			// In this case the dependency is a sub-schema.
+			// In this case the dependency is a sub-schema, so we validate the
+			// whole instance against it.
 			case *JsonSchema:
 				{
-					// Check if the propertyName (which is the key in the "dependencies" object)
-					// is present in the data. If it is, validate the whole instance against the
-					// sub-schema.
-					if _, ok := object[propertyName]; ok {
-						// Validate the whole data against the given sub-schema.
-						err := v.validateJsonData("", jsonData.raw, rootSchemaId)
-						if err != nil {
-							return KeywordValidationError{
-								"dependencies",
-								"inspected value failed in validation against sub-schema given in \"" +
-									propertyName +
-									"\" dependency: " +
-									err.Error(),
-							}
-						}
-					}
+					err = v.validateValue(jsonPath, jsonData, ctx)
 				}
 			// In this case the dependency is a list of required property names.
 			case []interface{}:
 				{
+					var missingProperties []string
+
 					// Iterate over the items in the dependency array.
 					for index, value := range v {
 						// Verify that the value is actually a string.
 						// If not, return an error
 						if requiredProperty, ok := value.(string); ok {
-							// Check if the required property name is missing. If it is,
-							// return an error.
+							// Check if the required property name is missing.
 							if _, ok := object[requiredProperty]; !ok {
-								return KeywordValidationError{
-									"dependencies",
-									"missing property \"" +
-										requiredProperty +
-										"\" although it is required according to \"" +
-										propertyName +
-										"\" dependency",
-								}
+								missingProperties = append(missingProperties, requiredProperty)
 							}
 						} else {
 							return KeywordValidationError{
@@ -881,6 +1061,19 @@ func (d dependencies) validate(jsonPath string, jsonData jsonData, rootSchemaId
 							}
 						}
 					}
+
+					if len(missingProperties) > 0 {
+						err = SchemaValidationError{
+							jsonPath,
+							"",
+							"dependencies",
+							"missing property \"" +
+								strings.Join(missingProperties, "\", \"") +
+								"\" although it is required according to \"" +
+								propertyName +
+								"\" dependency",
+						}
+					}
 				}
 			default:
 				{
@@ -890,24 +1083,37 @@ func (d dependencies) validate(jsonPath string, jsonData jsonData, rootSchemaId
 					}
 				}
 			}
+
+			if err != nil {
+				errs, err = errs.add(err, "/dependencies/"+propertyName)
+				if err != nil {
+					return err
+				}
+
+				if !ctx.exhaustive {
+					break
+				}
+			}
 		}
 	}
 
-	// If we arrived here it means that all the validations succeeded.
-	return nil
+	// If the list is empty it means that all the validations succeeded.
+	return errs.result()
 }
 
 type patternProperties map[string]*JsonSchema
 
-func (pp patternProperties) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (pp patternProperties) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	var errs ValidationErrors
+
 	// First we need to verify that jsonData is a json object.
 	if object, ok := jsonData.value.(map[string]interface{}); ok {
 		// Iterate over the given patterns.
-		for pattern, subSchema := range pp {
+		for _, pattern := range sortedSchemaKeys(pp) {
 			// Iterate over the properties in the inspected value.
-			for property := range object {
+			for _, property := range sortedKeys(object) {
 				// Check if the property matches to the pattern.
-				match, err := regexp.MatchString(pattern, property)
+				match, err := matchPattern(pattern, property)
 
 				// The pattern or the value is not in the right format (string)
 				if err != nil {
@@ -920,17 +1126,19 @@ func (pp patternProperties) validate(jsonPath string, jsonData jsonData, rootSch
 				// If there is a match, validate the value of the property against
 				// the given schema.
 				if match {
-					err := subSchema.validateJsonData(jsonPath+"/"+property, jsonData.raw, rootSchemaId)
+					ctx.evaluation.addProperty(property)
+
+					err := pp[pattern].validateValue(jsonPath+"/"+jsonwalker.EscapeToken(property), newJsonData(object[property]), ctx)
 
-					// If the validation fails, return an error.
+					// If the validation fails, collect the failure.
 					if err != nil {
-						return KeywordValidationError{
-							"patternProperties",
-							"property \"" +
-								property +
-								"\" that matches the pattern \"" +
-								pattern +
-								"\" failed in validation: \n" + err.Error(),
+						errs, err = errs.add(err, "/patternProperties/"+pattern)
+						if err != nil {
+							return err
+						}
+
+						if !ctx.exhaustive {
+							return errs.result()
 						}
 					}
 				}
@@ -938,14 +1146,136 @@ func (pp patternProperties) validate(jsonPath string, jsonData jsonData, rootSch
 		}
 	}
 
-	// If we arrived here it means that none of the properties failed in
+	// If the list is empty it means that none of the properties failed in
 	// validation against any of the given schemas.
-	return nil
+	return errs.result()
+}
+
+type dependentRequired map[string][]string
+
+func (dr dependentRequired) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	var errs ValidationErrors
+
+	// First we need to verify that jsonData is a json object.
+	if object, ok := jsonData.value.(map[string]interface{}); ok {
+		propertyNames := make([]string, 0, len(dr))
+		for propertyName := range dr {
+			propertyNames = append(propertyNames, propertyName)
+		}
+
+		sort.Strings(propertyNames)
+
+		for _, propertyName := range propertyNames {
+			// Dependencies of properties that are not present in the
+			// instance are ignored.
+			if _, ok := object[propertyName]; !ok {
+				continue
+			}
+
+			var missingProperties []string
+
+			for _, requiredProperty := range dr[propertyName] {
+				if _, ok := object[requiredProperty]; !ok {
+					missingProperties = append(missingProperties, requiredProperty)
+				}
+			}
+
+			if len(missingProperties) > 0 {
+				errs = append(errs, SchemaValidationError{
+					jsonPath,
+					"/dependentRequired/" + propertyName,
+					"dependentRequired",
+					"missing property \"" +
+						strings.Join(missingProperties, "\", \"") +
+						"\" although it is required when \"" +
+						propertyName +
+						"\" is present",
+				})
+
+				if !ctx.exhaustive {
+					break
+				}
+			}
+		}
+	}
+
+	// If the list is empty it means that all the dependencies are present.
+	return errs.result()
+}
+
+type dependentSchemas map[string]*JsonSchema
+
+func (ds dependentSchemas) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	var errs ValidationErrors
+
+	// First we need to verify that jsonData is a json object.
+	if object, ok := jsonData.value.(map[string]interface{}); ok {
+		for _, propertyName := range sortedSchemaKeys(ds) {
+			// Dependencies of properties that are not present in the
+			// instance are ignored.
+			if _, ok := object[propertyName]; !ok {
+				continue
+			}
+
+			// The whole instance is validated against the dependency.
+			err := ds[propertyName].validateValue(jsonPath, jsonData, ctx)
+			if err != nil {
+				errs, err = errs.add(err, "/dependentSchemas/"+propertyName)
+				if err != nil {
+					return err
+				}
+
+				if !ctx.exhaustive {
+					break
+				}
+			}
+		}
+	}
+
+	// If the list is empty it means that all the validations succeeded.
+	return errs.result()
+}
+
+type unevaluatedProperties struct {
+	JsonSchema
+}
+
+func (up *unevaluatedProperties) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	var errs ValidationErrors
+
+	// First we need to verify that jsonData is a json object.
+	if object, ok := jsonData.value.(map[string]interface{}); ok {
+		for _, property := range sortedKeys(object) {
+			// The properties that the other keywords evaluated are ignored.
+			if ctx.evaluation.isPropertyEvaluated(property) {
+				continue
+			}
+
+			err := up.validateValue(jsonPath+"/"+jsonwalker.EscapeToken(property), newJsonData(object[property]), ctx)
+			if err != nil {
+				errs, err = errs.add(err, "/unevaluatedProperties")
+				if err != nil {
+					return err
+				}
+
+				if !ctx.exhaustive {
+					break
+				}
+
+				continue
+			}
+
+			ctx.evaluation.addProperty(property)
+		}
+	}
+
+	// If the list is empty, all the unevaluated properties are valid.
+	return errs.result()
 }
 
 type minProperties int
 
-func (mp *minProperties) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (mp *minProperties) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
 	// First, we must verify that jsonData is a json object.
 	// If it is not a json object, we return an error.
 	if v, ok := jsonData.value.(map[string]interface{}); ok {
@@ -967,7 +1297,7 @@ func (mp *minProperties) validate(jsonPath string, jsonData jsonData, rootSchema
 
 type maxProperties int
 
-func (mp *maxProperties) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (mp *maxProperties) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
 	// First, we must verify that jsonData is a json object.
 	// If it is not a json object, we return an error.
 	if v, ok := jsonData.value.(map[string]interface{}); ok {
@@ -978,7 +1308,7 @@ func (mp *maxProperties) validate(jsonPath string, jsonData jsonData, rootSchema
 			return nil
 		} else {
 			return KeywordValidationError{
-				"minProperties",
+				"maxProperties",
 				"inspected value may contains at most " +
 					strconv.Itoa(int(*mp)) +
 					" properties",
@@ -993,104 +1323,137 @@ func (mp *maxProperties) validate(jsonPath string, jsonData jsonData, rootSchema
 /** Array Keywords **/
 /********************/
 
-type items json.RawMessage
+// items holds either a single schema for all the items of an array, or an
+// array of schemas for the items at the same positions.
+type items struct {
+	schema  *JsonSchema
+	schemas []*JsonSchema
+
+	// array is true if "items" is an array of schemas.
+	array bool
+
+	siblingPrefixItems *prefixItems
+}
+
+func (i *items) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	var errs ValidationErrors
 
-func (i items) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
 	// First, we need to verify that json Data is an array
-	if array, ok := jsonData.value.([]interface{}); ok {
-		var data interface{}
+	array, ok := jsonData.value.([]interface{})
+	if !ok {
+		return nil
+	}
 
-		// Unmarshal the value in items in order to figure out if it is a
-		// json object or json array
-		err := json.Unmarshal(i, &data)
-		if err != nil {
-			return err
+	// If "items" is an array of schemas, we validate each item in the
+	// inspected array against the schema at the same position.
+	if i.array {
+		if len(i.schemas) > len(array) {
+			return KeywordValidationError{
+				"items",
+				"when \"items\" field contains a list of Json Schema objects, the " +
+					"inspected array must contain at least the same amount of items",
+			}
 		}
 
-		// Handle the value in items according to its json type.
-		switch itemsField := data.(type) {
-		// If jsonData is a json object, which means that is holds a single schema,
-		// we validate the all the items in the inspected array against the given
-		// schema.
-		case map[string]interface{}:
-			{
-				// This is the JsonSchema instance that should hold the schema in
-				// "items" field.
-				var schema JsonSchema
+		ctx.evaluation.addItems(len(i.schemas))
 
-				// Unmarshal the rawSchema into the JsonSchema struct.
-				err = json.Unmarshal(i, &schema)
+		// Iterate over the schemas in "items" field.
+		for index, schema := range i.schemas {
+			// Validate the item against the schema at the same position.
+			err := schema.validateValue(jsonPath+"/"+strconv.Itoa(index), newJsonData(array[index]), ctx)
+			if err != nil {
+				errs, err = errs.add(err, "/items/"+strconv.Itoa(index))
 				if err != nil {
 					return err
 				}
 
-				// Iterate over the items in the inspected array and validate each
-				// item against the schema in "items" field.
-				for index := 0; index < len(array); index++ {
-					err := schema.validateJsonData(jsonPath+"/"+strconv.Itoa(index), jsonData.raw, rootSchemaId)
-					if err != nil {
-						return err
-					}
+				if !ctx.exhaustive {
+					break
 				}
 			}
-		// If jsonData is a json array, which means that is holds multiple json schema objects,
-		// we validate each item in the inspected array against the schema at the same position.
-		case []interface{}:
-			{
-				if len(itemsField) > len(array) {
-					return KeywordValidationError{
-						"items",
-						"when \"items\" field contains a list of Json Schema objects, the " +
-							"inspected array must contain at least the same amount of items",
-					}
-				}
+		}
 
-				// Iterate over the schemas in "items" field.
-				for index, schemaFromItems := range itemsField {
-					// Marshal the current schema in "items" field in order to Unmarshal it
-					// into JsonSchema instance.
-					rawSchema, err := json.Marshal(schemaFromItems)
-					if err != nil {
-						return err
-					}
+		return errs.result()
+	}
 
-					// This is the JsonSchema instance that should hold the current
-					// working schema.
-					var schema JsonSchema
+	// In draft 2020-12 "items" applies only to the items that "prefixItems"
+	// did not.
+	start := 0
+	if i.siblingPrefixItems != nil && ctx.evaluates("prefixItems") {
+		start = len(*i.siblingPrefixItems)
+	}
 
-					// Unmarshal the rawSchema into the JsonSchema struct.
-					err = json.Unmarshal(rawSchema, &schema)
-					if err != nil {
-						return err
-					}
+	ctx.evaluation.addAllItems()
 
-					// Validate the item against the schema at the same position.
-					err = schema.validateJsonData(jsonPath+"/"+strconv.Itoa(index), jsonData.raw, rootSchemaId)
-					if err != nil {
-						return err
-					}
-				}
+	// Iterate over the items in the inspected array and validate each
+	// item against the schema in "items" field.
+	for index := start; index < len(array); index++ {
+		err := i.schema.validateValue(jsonPath+"/"+strconv.Itoa(index), newJsonData(array[index]), ctx)
+		if err != nil {
+			errs, err = errs.add(err, "/items")
+			if err != nil {
+				return err
 			}
-		// The default case indicates that the value in items field is not a json schema or
-		// a list of json schema.
-		default:
-			{
-				return KeywordValidationError{
-					"items",
-					"\"items\" field value in schema must be a valid Json Schema or an array of Json Schema",
-				}
+
+			if !ctx.exhaustive {
+				break
 			}
 		}
 	}
 
-	// If we arrived here it means that all the items in the inspected array
+	// If the list is empty it means that all the items in the inspected array
 	// validated successfully against the given schema.
-	return nil
+	return errs.result()
 }
 
 func (i *items) UnmarshalJSON(data []byte) error {
-	*i = data
-	return nil
+	var value interface{}
+
+	// Unmarshal the value in items in order to figure out if it is a
+	// json schema or an array of json schemas.
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := value.([]interface{}); ok {
+		i.array = true
+		return json.Unmarshal(data, &i.schemas)
+	}
+
+	i.schema = new(JsonSchema)
+
+	return json.Unmarshal(data, i.schema)
+}
+
+type prefixItems []*JsonSchema
+
+func (pi prefixItems) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	var errs ValidationErrors
+
+	// First, we need to verify that json Data is an array
+	if array, ok := jsonData.value.([]interface{}); ok {
+		ctx.evaluation.addItems(len(pi))
+
+		// Validate each item against the schema at the same position. An
+		// array may be shorter than "prefixItems".
+		for index := 0; index < len(pi) && index < len(array); index++ {
+			err := pi[index].validateValue(jsonPath+"/"+strconv.Itoa(index), newJsonData(array[index]), ctx)
+			if err != nil {
+				errs, err = errs.add(err, "/prefixItems/"+strconv.Itoa(index))
+				if err != nil {
+					return err
+				}
+
+				if !ctx.exhaustive {
+					break
+				}
+			}
+		}
+	}
+
+	// If the list is empty all the items are valid against their schemas.
+	return errs.result()
 }
 
 type additionalItems struct {
@@ -1098,74 +1461,171 @@ type additionalItems struct {
 	siblingItems *items
 }
 
-func (ai *additionalItems) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
-	// Unmarshal the sibling field "items" in order to check it's json type.
-	var siblingItems interface{}
-	err := json.Unmarshal(*ai.siblingItems, &siblingItems)
-	if err != nil {
-		return err
-	}
+func (ai *additionalItems) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	var errs ValidationErrors
 
 	// If "items" is a json array, "additionalItems" needs to verify the items
 	// that the schema in "items" field did not validate.
-	if itemsArray, ok := siblingItems.([]interface{}); ok {
+	if ai.siblingItems != nil && ai.siblingItems.array {
 		// Check if jsonData is a json array.
 		if array, ok := jsonData.value.([]interface{}); ok {
+			ctx.evaluation.addAllItems()
+
 			// Iterate over the inspected array from the position that items stopped
 			// validating.
-			for index := range array[len(itemsArray):] {
+			for index := len(ai.siblingItems.schemas); index < len(array); index++ {
 				// Validate the inspected item against the schema given in "additionalItems".
-				err := ai.validateJsonData(jsonPath+"/"+strconv.Itoa(index), jsonData.raw, rootSchemaId)
+				err := ai.validateValue(jsonPath+"/"+strconv.Itoa(index), newJsonData(array[index]), ctx)
 				if err != nil {
-					return KeywordValidationError{
-						"additionalItems",
-						"item at position " +
-							strconv.Itoa(index) +
-							" failed in validation: " +
-							err.Error(),
+					errs, err = errs.add(err, "/additionalItems")
+					if err != nil {
+						return err
+					}
+
+					if !ctx.exhaustive {
+						break
 					}
 				}
 			}
-
-			// If we arrived here it means that no item failed in validation.
-			return nil
 		}
 	}
 
 	// If "items" field is not an array of json schema, additionalItems
-	// is meaningless so we return true.
-	return nil
+	// is meaningless so the list is empty.
+	return errs.result()
 }
 
 type contains struct {
 	JsonSchema
+	siblingMinContains *minContains
+	siblingMaxContains *maxContains
+}
+
+func (c *contains) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	// In draft-07 the array only needs to contain a single valid item.
+	if ctx.isLegacyDraft() {
+		// First, we need to verify that jsonData is a json array.
+		if array, ok := jsonData.value.([]interface{}); ok {
+			// Go over all the items in the array in order to inspect them.
+			for index := range array {
+				// If the item is valid against the given schema, which means that
+				// the array contains the required value.
+				err := (*c).validateValue(jsonPath+"/"+strconv.Itoa(index), newJsonData(array[index]), ctx.firstFailure())
+				if err == nil {
+					return nil
+				}
+			}
+		}
+
+		// If we arrived here it means that we could not validate any of the array's
+		// items against the given schema.
+		return KeywordValidationError{
+			"contains",
+			"could validate any of the inspected array's items against the given schema",
+		}
+	}
+
+	// Since draft 2019-09 only arrays are inspected.
+	array, ok := jsonData.value.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	// The valid items are counted, and in draft 2020-12 they count as
+	// evaluated items.
+	matches := 0
+	for index := range array {
+		err := (*c).validateValue(jsonPath+"/"+strconv.Itoa(index), newJsonData(array[index]), ctx.firstFailure())
+		if err == nil {
+			matches++
+
+			if ctx.draft == DRAFT_2020_12 {
+				ctx.evaluation.addIndex(index)
+			}
+		}
+	}
+
+	min := 1
+	if c.siblingMinContains != nil && ctx.evaluates("minContains") {
+		min = int(*c.siblingMinContains)
+	}
+
+	if matches < min {
+		// A failure of the default minimum belongs to "contains" itself.
+		if min == 1 && (c.siblingMinContains == nil || !ctx.evaluates("minContains")) {
+			return KeywordValidationError{
+				"contains",
+				"could validate any of the inspected array's items against the given schema",
+			}
+		}
+
+		return KeywordValidationError{
+			"minContains",
+			"inspected array must contain at least " +
+				strconv.Itoa(min) +
+				" items that are valid against \"contains\", found " +
+				strconv.Itoa(matches),
+		}
+	}
+
+	if c.siblingMaxContains != nil && ctx.evaluates("maxContains") && matches > int(*c.siblingMaxContains) {
+		return KeywordValidationError{
+			"maxContains",
+			"inspected array must contain at most " +
+				strconv.Itoa(int(*c.siblingMaxContains)) +
+				" items that are valid against \"contains\", found " +
+				strconv.Itoa(matches),
+		}
+	}
+
+	return nil
+}
+
+// minContains and maxContains are validated by their sibling "contains".
+type minContains int
+type maxContains int
+
+type unevaluatedItems struct {
+	JsonSchema
 }
 
-func (c *contains) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (ui *unevaluatedItems) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	var errs ValidationErrors
+
 	// First, we need to verify that jsonData is a json array.
 	if array, ok := jsonData.value.([]interface{}); ok {
-		// Go over all the items in the array in order to inspect them.
 		for index := range array {
-			// If the item is valid against the given schema, which means that
-			// the array contains the required value.
-			err := (*c).validateJsonData(jsonPath+"/"+strconv.Itoa(index), jsonData.raw, rootSchemaId)
-			if err == nil {
-				return nil
+			// The items that the other keywords evaluated are ignored.
+			if ctx.evaluation.isItemEvaluated(index) {
+				continue
+			}
+
+			err := ui.validateValue(jsonPath+"/"+strconv.Itoa(index), newJsonData(array[index]), ctx)
+			if err != nil {
+				errs, err = errs.add(err, "/unevaluatedItems")
+				if err != nil {
+					return err
+				}
+
+				if !ctx.exhaustive {
+					break
+				}
 			}
 		}
-	}
 
-	// If we arrived here it means that we could not validate any of the array's
-	// items against the given schema.
-	return KeywordValidationError{
-		"contains",
-		"could validate any of the inspected array's items against the given schema",
+		// Once all the items are valid, all of them were evaluated.
+		if len(errs) == 0 {
+			ctx.evaluation.addAllItems()
+		}
 	}
+
+	// If the list is empty, all the unevaluated items are valid.
+	return errs.result()
 }
 
 type minItems int
 
-func (mi *minItems) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (mi *minItems) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
 	// First, we need to verify that jsonData is an array.
 	if v, ok := jsonData.value.([]interface{}); ok {
 		// Check that the number of items in the array is equal to
@@ -1185,7 +1645,7 @@ func (mi *minItems) validate(jsonPath string, jsonData jsonData, rootSchemaId st
 
 type maxItems int
 
-func (mi *maxItems) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (mi *maxItems) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
 	// First, we need to verify that jsonData is an array.
 	if v, ok := jsonData.value.([]interface{}); ok {
 		// Check that the number of items in the array is equal to
@@ -1205,7 +1665,7 @@ func (mi *maxItems) validate(jsonPath string, jsonData jsonData, rootSchemaId st
 
 type uniqueItems bool
 
-func (ui *uniqueItems) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (ui *uniqueItems) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
 	// First, we need to verify that jsonData is an array.
 	if array, ok := jsonData.value.([]interface{}); ok {
 		// Create a map that will help us to check if we already met the
@@ -1258,15 +1718,27 @@ type contentEncoding string
 
 type anyOf []*JsonSchema
 
-func (af anyOf) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
-	// Validate jsonData.raw against each of the schemas until on of them succeeds.
+func (af anyOf) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	valid := false
+
+	// Validate jsonData against each of the schemas until on of them succeeds.
+	// Since draft 2019-09 all of them are evaluated, in order to collect the
+	// annotations of every valid schema.
 	for _, schema := range af {
-		err := schema.validateJsonData("", jsonData.raw, rootSchemaId)
+		err := schema.validateValue(jsonPath, jsonData, ctx.firstFailure())
 		if err == nil {
-			return nil
+			valid = true
+
+			if ctx.isLegacyDraft() {
+				break
+			}
 		}
 	}
 
+	if valid {
+		return nil
+	}
+
 	// If we arrived here, the validation of jsonData failed against all schemas.
 	return KeywordValidationError{
 		"anyOf",
@@ -1276,32 +1748,38 @@ func (af anyOf) validate(jsonPath string, jsonData jsonData, rootSchemaId string
 
 type allOf []*JsonSchema
 
-func (af allOf) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
-	// Validate jsonData.raw against each of the schemas.
-	// If one of them fails, return error.
-	for _, schema := range af {
-		err := schema.validateJsonData("", jsonData.raw, rootSchemaId)
+func (af allOf) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	var errs ValidationErrors
+
+	// Validate jsonData against each of the schemas and collect the
+	// failures.
+	for index, schema := range af {
+		err := schema.validateValue(jsonPath, jsonData, ctx)
 		if err != nil {
-			return KeywordValidationError{
-				"allOf",
-				"inspected value could not be validated against all of the given schemas",
+			errs, err = errs.add(err, "/allOf/"+strconv.Itoa(index))
+			if err != nil {
+				return err
+			}
+
+			if !ctx.exhaustive {
+				break
 			}
 		}
 	}
 
-	// If we arrived here, the validation of jsonData succeeded against all
+	// If the list is empty, the validation of jsonData succeeded against all
 	// given schemas.
-	return nil
+	return errs.result()
 }
 
 type oneOf []*JsonSchema
 
-func (of oneOf) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (of oneOf) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
 	var oneValidationAlreadySucceeded bool
 
-	// Validate jsonData.raw against each of the schemas until on of them succeeds.
+	// Validate jsonData against each of the schemas until on of them succeeds.
 	for _, schema := range of {
-		err := schema.validateJsonData("", jsonData.raw, rootSchemaId)
+		err := schema.validateValue(jsonPath, jsonData, ctx.firstFailure())
 		if err == nil {
 			if oneValidationAlreadySucceeded {
 				return KeywordValidationError{
@@ -1329,8 +1807,9 @@ type not struct {
 	JsonSchema
 }
 
-func (n *not) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
-	err := (*n).validateJsonData(jsonPath, jsonData.raw, rootSchemaId)
+func (n *not) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
+	// The annotations of "not" are always dropped.
+	err := (*n).validateValue(jsonPath, jsonData, ctx.firstFailure().withEvaluation(nil))
 	if err != nil {
 		return nil
 	} else {
@@ -1347,20 +1826,36 @@ type _if struct {
 	siblingElse *_else
 }
 
-func (i *_if) validate(jsonPath string, jsonData jsonData, rootSchemaId string) error {
+func (i *_if) validate(jsonPath string, jsonData jsonData, ctx *validationContext) error {
 	// Validate the data against the given schema in "if".
-	err := (*i).validateJsonData("", jsonData.raw, rootSchemaId)
+	err := (*i).validateValue(jsonPath, jsonData, ctx.firstFailure())
 
 	// If the validation succeeded, validate the data against the given schema
 	// in "then".
 	// Else, validate the data against the given schema in "else".
 	if err == nil {
 		if (*i).siblingThen != nil {
-			return (*i).siblingThen.validateJsonData(jsonPath, jsonData.raw, rootSchemaId)
+			err = (*i).siblingThen.validateValue(jsonPath, jsonData, ctx)
+			if err != nil {
+				errs, err := ValidationErrors{}.add(err, "/then")
+				if err != nil {
+					return err
+				}
+
+				return errs.result()
+			}
 		}
 	} else {
 		if (*i).siblingElse != nil {
-			return (*i).siblingElse.validateJsonData(jsonPath, jsonData.raw, rootSchemaId)
+			err = (*i).siblingElse.validateValue(jsonPath, jsonData, ctx)
+			if err != nil {
+				errs, err := ValidationErrors{}.add(err, "/else")
+				if err != nil {
+					return err
+				}
+
+				return errs.result()
+			}
 		}
 	}
 
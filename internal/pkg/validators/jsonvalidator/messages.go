@@ -0,0 +1,50 @@
+package jsonvalidator
+
+import (
+	"github.com/pkg/errors"
+)
+
+// LoadMessageSchema handles addition of a schema that describes the json
+// WebSocket messages that clients send to an endpoint. Message schemas are
+// kept apart from the schemas of the request bodies, but they share the
+// registry, so they can reference each other.
+func (jv JsonValidator) LoadMessageSchema(path, method string, rawSchema []byte) error {
+	err := checkMethod(path, method)
+	if err != nil {
+		return err
+	}
+
+	schema, err := jv.compileSchema("message "+method+" "+path, rawSchema)
+	if err != nil {
+		return err
+	}
+
+	jv.mutex.Lock()
+	defer jv.mutex.Unlock()
+
+	if jv.messagesDict[path] == nil {
+		jv.messagesDict[path] = make(map[string]*RootJsonSchema)
+	}
+
+	jv.messagesDict[path][method] = schema
+
+	return nil
+}
+
+// ValidateMessage validates a WebSocket message against the schema that was
+// loaded for the messages of the endpoint.
+func (jv JsonValidator) ValidateMessage(path, method string, message []byte) error {
+	jv.mutex.RLock()
+	schema, ok := jv.messagesDict[path][method]
+	jv.mutex.RUnlock()
+
+	if !ok {
+		return errors.New("could not validate message: no message schema exist for path \"" +
+			path +
+			"\" and method \"" +
+			method +
+			"\"")
+	}
+
+	return schema.validateBytes(message, jv.exhaustive)
+}
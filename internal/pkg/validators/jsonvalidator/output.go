@@ -0,0 +1,211 @@
+package jsonvalidator
+
+import (
+	"strings"
+)
+
+// Output formats defined by json schema draft 2019-09
+const (
+	OUTPUT_FLAG     = "flag"
+	OUTPUT_BASIC    = "basic"
+	OUTPUT_DETAILED = "detailed"
+)
+
+// OutputUnit is a struct that describes the result of a validation in one
+// of the output formats that are defined by json schema draft 2019-09.
+type OutputUnit struct {
+	Valid            bool          `json:"valid"`
+	KeywordLocation  string        `json:"keywordLocation"`
+	InstanceLocation string        `json:"instanceLocation"`
+	Error            string        `json:"error,omitempty"`
+	Errors           []*OutputUnit `json:"errors,omitempty"`
+}
+
+// Output gets the error that was returned from a validation and describes it
+// in the requested output format.
+// It returns an error if the format is unknown or if the validation error
+// is not a validation failure (for example, if the data is not a valid json).
+func Output(validationErr error, format string) (*OutputUnit, error) {
+	var errs ValidationErrors
+
+	if validationErr != nil {
+		var err error
+
+		errs, err = ValidationErrors{}.add(validationErr, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	root := &OutputUnit{
+		Valid: len(errs) == 0,
+	}
+
+	switch format {
+	case OUTPUT_FLAG:
+		return root, nil
+	case OUTPUT_BASIC:
+		for _, err := range errs {
+			root.Errors = append(root.Errors, newErrorOutputUnit(err))
+		}
+
+		return root, nil
+	case OUTPUT_DETAILED:
+		if len(errs) == 0 {
+			return root, nil
+		}
+
+		return newDetailedOutputUnit(buildOutputTree(errs)), nil
+	default:
+		return nil, InvalidOutputFormatError(format)
+	}
+}
+
+// FormatOutput describes the error that was returned from Validate in the
+// requested output format.
+func (jv JsonValidator) FormatOutput(validationErr error, format string) (interface{}, error) {
+	return Output(validationErr, format)
+}
+
+// newErrorOutputUnit creates an output unit that describes a single failure.
+func newErrorOutputUnit(err SchemaValidationError) *OutputUnit {
+	reason := err.reason
+	if err.keyword != "" {
+		reason = KeywordValidationError{err.keyword, err.reason}.Error()
+	}
+
+	return &OutputUnit{
+		Valid:            false,
+		KeywordLocation:  err.schemaPath,
+		InstanceLocation: err.path,
+		Error:            reason,
+	}
+}
+
+// outputNode is a node in a tree of keyword locations that is used in order
+// to arrange the failures according to the structure of the schema.
+type outputNode struct {
+	tokens   []string
+	failures []SchemaValidationError
+	children []*outputNode
+}
+
+// buildOutputTree arranges the failures in a tree where each node represents
+// a location in the schema.
+func buildOutputTree(errs ValidationErrors) *outputNode {
+	root := &outputNode{}
+
+	for _, err := range errs {
+		node := root
+		tokens := splitLocation(err.schemaPath)
+
+		// The last token is the keyword that failed, so it belongs to the
+		// failure itself rather than to a schema location.
+		for index := 0; index < len(tokens)-1; index++ {
+			node = node.child(tokens[:index+1])
+		}
+
+		node.failures = append(node.failures, err)
+	}
+
+	return root
+}
+
+// child returns the child of the node that represents the location, and
+// creates it if it does not exist.
+func (n *outputNode) child(tokens []string) *outputNode {
+	// All the children share the location of the node, so they differ
+	// only in their last token.
+	for _, child := range n.children {
+		if child.tokens[len(child.tokens)-1] == tokens[len(tokens)-1] {
+			return child
+		}
+	}
+
+	child := &outputNode{tokens: append([]string{}, tokens...)}
+	n.children = append(n.children, child)
+
+	return child
+}
+
+// newDetailedOutputUnit creates an output unit for a node in the tree.
+// Nodes that hold a single failure or a single child are collapsed, as
+// described by the "detailed" output format.
+func newDetailedOutputUnit(node *outputNode) *OutputUnit {
+	if len(node.children) == 0 && len(node.failures) == 1 {
+		return newErrorOutputUnit(node.failures[0])
+	}
+
+	if len(node.failures) == 0 && len(node.children) == 1 && len(node.tokens) > 0 {
+		return newDetailedOutputUnit(node.children[0])
+	}
+
+	unit := &OutputUnit{
+		Valid:            false,
+		KeywordLocation:  locationOf(node.tokens),
+		InstanceLocation: commonInstanceLocation(node),
+	}
+
+	for _, child := range node.children {
+		unit.Errors = append(unit.Errors, newDetailedOutputUnit(child))
+	}
+
+	for _, failure := range node.failures {
+		unit.Errors = append(unit.Errors, newErrorOutputUnit(failure))
+	}
+
+	return unit
+}
+
+// commonInstanceLocation returns the deepest instance location that all the
+// failures under a node share.
+func commonInstanceLocation(node *outputNode) string {
+	var common []string
+	first := true
+
+	var visit func(n *outputNode)
+	visit = func(n *outputNode) {
+		for _, failure := range n.failures {
+			tokens := splitLocation(failure.path)
+
+			if first {
+				common = tokens
+				first = false
+				continue
+			}
+
+			length := 0
+			for length < len(common) && length < len(tokens) && common[length] == tokens[length] {
+				length++
+			}
+
+			common = common[:length]
+		}
+
+		for _, child := range n.children {
+			visit(child)
+		}
+	}
+
+	visit(node)
+
+	return locationOf(common)
+}
+
+// splitLocation splits a json path into its tokens.
+func splitLocation(location string) []string {
+	if location == "" || location == "/" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimPrefix(location, "/"), "/")
+}
+
+// locationOf joins tokens into a json path.
+func locationOf(tokens []string) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+
+	return "/" + strings.Join(tokens, "/")
+}
@@ -0,0 +1,315 @@
+package jsonvalidator
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/apidome/gateway/internal/pkg/validators"
+	"github.com/pkg/errors"
+)
+
+// LoadParametersSchema handles addition of a schema that describes the
+// parameters in a specific location of a request (path, query or header).
+// The parameters are validated as a json object that maps each parameter
+// name to its value.
+func (jv JsonValidator) LoadParametersSchema(path, method, location string, rawSchema []byte) error {
+	switch location {
+	case validators.ParametersPath, validators.ParametersQuery, validators.ParametersHeader:
+	default:
+		return errors.New("could not load parameters schema to path " +
+			path +
+			": unknown location \"" +
+			location +
+			"\"")
+	}
+
+	err := checkMethod(path, method)
+	if err != nil {
+		return err
+	}
+
+	schema, err := jv.compileSchema(location+" "+method+" "+path, rawSchema)
+	if err != nil {
+		return err
+	}
+
+	jv.mutex.Lock()
+	defer jv.mutex.Unlock()
+
+	// Create the maps of the location and the path if they do not exist.
+	if jv.parametersDict[location] == nil {
+		jv.parametersDict[location] = make(map[string]map[string]*RootJsonSchema)
+	}
+
+	if jv.parametersDict[location][path] == nil {
+		jv.parametersDict[location][path] = make(map[string]*RootJsonSchema)
+	}
+
+	jv.parametersDict[location][path][method] = schema
+
+	return nil
+}
+
+// ValidateParameters validates the parameters in a specific location of a
+// request against the schema that was loaded for it.
+// Since parameters always arrive as strings, each value is converted to
+// the type that the schema expects before it is validated.
+// The paths of the failures start with the location of the parameters,
+// for example "/query/limit".
+func (jv JsonValidator) ValidateParameters(path, method, location string,
+	parameters map[string][]string) error {
+	jv.mutex.RLock()
+	schema, ok := jv.parametersDict[location][path][method]
+	jv.mutex.RUnlock()
+
+	if !ok {
+		return errors.New("could not validate parameters: no " +
+			location +
+			" parameters schema exist for path \"" +
+			path +
+			"\" and method \"" +
+			method +
+			"\"")
+	}
+
+	bytes, err := json.Marshal(coerceParameters(schema, location, parameters))
+	if err != nil {
+		return errors.Wrap(err, "could not encode the "+location+" parameters")
+	}
+
+	err = schema.validateBytes(bytes, jv.exhaustive)
+
+	return prefixPath(err, "/"+location)
+}
+
+// coerceParameters creates a json object out of the parameters according to
+// the types of the properties in the schema.
+func coerceParameters(schema *RootJsonSchema, location string,
+	parameters map[string][]string) map[string]interface{} {
+	object := make(map[string]interface{})
+
+	for name, values := range parameters {
+		if len(values) == 0 {
+			continue
+		}
+
+		propertyName := name
+		propertySchema := schema.Properties[name]
+
+		// Header names are case-insensitive, and only the headers that the
+		// schema describes are validated, since a request carries many
+		// headers that have nothing to do with the api.
+		if location == validators.ParametersHeader {
+			propertyName, propertySchema = headerProperty(schema, name)
+			if propertyName == "" {
+				continue
+			}
+		}
+
+		object[propertyName] = coerceParameter(schema, propertySchema, values)
+	}
+
+	return object
+}
+
+// headerProperty returns the name and the schema of the property that
+// describes a header, or an empty name if the schema does not describe it.
+func headerProperty(schema *RootJsonSchema, header string) (string, *JsonSchema) {
+	for _, name := range sortedSchemaKeys(schema.Properties) {
+		if strings.EqualFold(name, header) {
+			return name, schema.Properties[name]
+		}
+	}
+
+	return "", nil
+}
+
+// coerceParameter converts the values of a parameter to the types that its
+// schema expects. A parameter is an array only if its schema allows arrays,
+// otherwise only its first value is used.
+// The schemas that the parameter's schema references are resolved against
+// the root schema that contains it.
+func coerceParameter(rootSchema *RootJsonSchema, schema *JsonSchema, values []string) interface{} {
+	resolved := resolveSchemas(rootSchema, schema)
+	types := schemaTypes(resolved)
+
+	for _, t := range types {
+		if t == TYPE_ARRAY {
+			var itemTypes []string
+
+			// Only a single "items" schema describes the type of all
+			// the values.
+			for _, s := range resolved {
+				if s.schema.Items != nil && s.schema.Items.schema != nil {
+					itemTypes = schemaTypes(resolveSchemas(s.root, s.schema.Items.schema))
+					break
+				}
+			}
+
+			array := make([]interface{}, len(values))
+
+			for index, value := range values {
+				array[index] = coerceValue(value, itemTypes)
+			}
+
+			return array
+		}
+	}
+
+	return coerceValue(values[0], types)
+}
+
+// coerceValue converts a value to the first type in the list that it can
+// be converted to. If it cannot be converted to any of them, it is left as
+// a string so the validation will report the mismatch.
+func coerceValue(value string, types []string) interface{} {
+	for _, t := range types {
+		switch t {
+		case TYPE_STRING:
+			return value
+		case TYPE_INTEGER, TYPE_NUMBER:
+			// "integer" is checked by the "type" keyword, so both
+			// types are parsed as a number.
+			// "NaN" and "Inf" are parsed as well, but they are not
+			// json numbers.
+			number, err := strconv.ParseFloat(value, 64)
+			if err == nil && !math.IsNaN(number) && !math.IsInf(number, 0) {
+				return number
+			}
+		case TYPE_BOOLEAN:
+			if value == "true" || value == "false" {
+				return value == "true"
+			}
+		case TYPE_NULL:
+			if value == "" || value == "null" {
+				return nil
+			}
+		}
+	}
+
+	return value
+}
+
+// scopedSchema is a schema with the root schema that its references are
+// resolved against.
+type scopedSchema struct {
+	root   *RootJsonSchema
+	schema *JsonSchema
+}
+
+// resolveSchemas returns a schema followed by the schemas that apply to the
+// same value through its "$ref" and "allOf" keywords, recursively.
+// References that cannot be resolved are skipped, since the validation
+// reports them.
+func resolveSchemas(rootSchema *RootJsonSchema, schema *JsonSchema) []scopedSchema {
+	var resolved []scopedSchema
+
+	seen := make(map[*JsonSchema]bool)
+	pending := []scopedSchema{{rootSchema, schema}}
+
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+
+		if current.schema == nil || seen[current.schema] {
+			continue
+		}
+
+		seen[current.schema] = true
+		resolved = append(resolved, current)
+
+		if current.schema.Ref != nil {
+			if referenced, ok := resolveRef(current.root, string(*current.schema.Ref)); ok {
+				pending = append(pending, referenced)
+			}
+		}
+
+		for _, subSchema := range current.schema.AllOf {
+			pending = append(pending, scopedSchema{current.root, subSchema})
+		}
+	}
+
+	return resolved
+}
+
+// resolveRef returns the schema that an absolute reference points to in the
+// registry of a root schema.
+func resolveRef(rootSchema *RootJsonSchema, reference string) (scopedSchema, bool) {
+	if rootSchema == nil {
+		return scopedSchema{}, false
+	}
+
+	schemaURI, fragment := splitReference(reference)
+	if schemaURI == "" {
+		schemaURI = rootSchema.uri
+	}
+
+	referencedRoot := rootSchema.registry.lookup(schemaURI)
+	if referencedRoot == nil {
+		return scopedSchema{}, false
+	}
+
+	if fragment == "" {
+		return scopedSchema{referencedRoot, &referencedRoot.JsonSchema}, true
+	}
+
+	subSchema, ok := referencedRoot.subSchemaMap[fragment]
+
+	return scopedSchema{referencedRoot, subSchema}, ok
+}
+
+// schemaTypes returns the types that the "type" keywords of schemas allow,
+// in order and without duplicates.
+func schemaTypes(schemas []scopedSchema) []string {
+	var types []string
+
+	added := make(map[string]bool)
+	add := func(t string) {
+		if !added[t] {
+			added[t] = true
+			types = append(types, t)
+		}
+	}
+
+	for _, s := range schemas {
+		if s.schema.Type == nil {
+			continue
+		}
+
+		// "type" is either a single type or a list of types.
+		switch t := s.schema.Type.decoded.(type) {
+		case string:
+			add(t)
+		case []interface{}:
+			for _, item := range t {
+				if single, ok := item.(string); ok {
+					add(single)
+				}
+			}
+		}
+	}
+
+	return types
+}
+
+// prefixPath prefixes the json paths of the failures that err holds.
+// If err is not a validation failure, it is returned as is.
+func prefixPath(err error, prefix string) error {
+	switch v := err.(type) {
+	case SchemaValidationError:
+		v.path = prefix + v.path
+		return v
+	case ValidationErrors:
+		errs := make(ValidationErrors, len(v))
+		for index, schemaValidationError := range v {
+			schemaValidationError.path = prefix + schemaValidationError.path
+			errs[index] = schemaValidationError
+		}
+
+		return errs
+	default:
+		return err
+	}
+}
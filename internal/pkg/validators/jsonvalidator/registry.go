@@ -0,0 +1,260 @@
+package jsonvalidator
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/apidome/gateway/internal/pkg/jsonwalker"
+)
+
+// DEFAULT_BASE_URI is the base URI that the "$id"s and references of a
+// schema are resolved against when the schema does not declare an absolute
+// "$id". A schema without "$id" is identified by a hash of its content under
+// this base, so different schemas never share an URI.
+const DEFAULT_BASE_URI = "apidome:///schemas/"
+
+// metaSchemaRegistry holds the meta-schemas of the supported drafts. It is
+// the parent of the registries of all the validators, so every schema can
+// reference the meta-schemas without loading them again.
+var metaSchemaRegistry = newSchemaRegistry(nil)
+
+// schemaRegistry holds the schema resources that references can point to,
+// by their absolute URIs.
+// It is safe for concurrent use: schemas are looked up during validations
+// while other schemas are loaded or reloaded.
+type schemaRegistry struct {
+	mutex     sync.RWMutex
+	resources map[string]*registeredResource
+
+	// parent is consulted for the URIs that the registry does not hold.
+	parent *schemaRegistry
+}
+
+// registeredResource is a schema resource in the registry, with the loaded
+// schemas that contain it.
+type registeredResource struct {
+	schema *RootJsonSchema
+
+	// owners holds the keys of the loaded schemas that contain the resource
+	// (for example the body schema of an endpoint), so the resource is
+	// removed only when none of them holds it anymore.
+	owners map[string]bool
+}
+
+// newSchemaRegistry creates an empty registry.
+func newSchemaRegistry(parent *schemaRegistry) *schemaRegistry {
+	return &schemaRegistry{
+		resources: make(map[string]*registeredResource),
+		parent:    parent,
+	}
+}
+
+// lookup returns the schema resource with the given absolute URI, or nil if
+// it is not registered. An empty fragment in the URI is ignored.
+func (r *schemaRegistry) lookup(uri string) *RootJsonSchema {
+	if r == nil {
+		return nil
+	}
+
+	uri = strings.TrimSuffix(uri, "#")
+
+	r.mutex.RLock()
+	resource, ok := r.resources[uri]
+	r.mutex.RUnlock()
+
+	if ok {
+		return resource.schema
+	}
+
+	return r.parent.lookup(uri)
+}
+
+// register adds a root schema and the schema resources that it embeds to the
+// registry on behalf of owner, and removes the resources that owner
+// registered before, so reloading a schema replaces it.
+// A resource whose URI is already held by another owner with a different
+// content is a conflict, in which case nothing is registered.
+func (r *schemaRegistry) register(owner string, rootSchema *RootJsonSchema) error {
+	resources := append([]*RootJsonSchema{rootSchema}, rootSchema.resources...)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Check all the resources before changing anything.
+	declared := make(map[string]bool, len(resources))
+	for _, resource := range resources {
+		if declared[resource.uri] {
+			return ConflictingIdError{resource.uri, "the schema declares it more than once"}
+		}
+
+		declared[resource.uri] = true
+
+		if parentResource := r.parent.lookup(resource.uri); parentResource != nil &&
+			parentResource.canonical != resource.canonical {
+			return ConflictingIdError{resource.uri, "it belongs to a meta-schema"}
+		}
+
+		existing, ok := r.resources[resource.uri]
+		if !ok || existing.schema.canonical == resource.canonical {
+			continue
+		}
+
+		for existingOwner := range existing.owners {
+			if existingOwner != owner {
+				return ConflictingIdError{resource.uri, "a different schema is already registered by " + existingOwner}
+			}
+		}
+	}
+
+	// Release the resources that the owner registered before.
+	for uri, existing := range r.resources {
+		delete(existing.owners, owner)
+
+		if len(existing.owners) == 0 {
+			delete(r.resources, uri)
+		}
+	}
+
+	for _, resource := range resources {
+		existing, ok := r.resources[resource.uri]
+
+		// Identical resources are shared by their owners.
+		if !ok || existing.schema.canonical != resource.canonical {
+			existing = &registeredResource{resource, make(map[string]bool)}
+			r.resources[resource.uri] = existing
+		}
+
+		existing.owners[owner] = true
+	}
+
+	return nil
+}
+
+// schemas returns the root schemas whose resources are registered in the
+// registry (without its parent).
+func (r *schemaRegistry) schemas() []*RootJsonSchema {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var schemas []*RootJsonSchema
+	for _, resource := range r.resources {
+		// Embedded resources have no references of their own, since the
+		// references are collected by the root schemas.
+		if resource.schema.references != nil {
+			schemas = append(schemas, resource.schema)
+		}
+	}
+
+	return schemas
+}
+
+// resolves returns true if an absolute reference points to a registered
+// schema resource and to an existing sub-schema in it.
+func (r *schemaRegistry) resolves(reference string) bool {
+	uri, fragment := splitReference(reference)
+
+	resource := r.lookup(uri)
+	if resource == nil {
+		return false
+	}
+
+	if fragment == "" {
+		return true
+	}
+
+	_, ok := resource.subSchemaMap[fragment]
+
+	return ok
+}
+
+// resolveReference resolves a URI reference (like an "$id" or a "$ref")
+// against a base URI.
+func resolveReference(base, reference string) (string, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	referenceURL, err := url.Parse(reference)
+	if err != nil {
+		return "", err
+	}
+
+	return baseURL.ResolveReference(referenceURL).String(), nil
+}
+
+// splitReference splits an absolute reference into the URI of the schema
+// resource and the fragment that points into it.
+func splitReference(reference string) (string, string) {
+	referenceURL, err := url.Parse(reference)
+	if err != nil {
+		splittedRef := strings.SplitN(reference, "#", 2)
+		if len(splittedRef) == 1 {
+			return splittedRef[0], ""
+		}
+
+		return splittedRef[0], splittedRef[1]
+	}
+
+	fragment := referenceURL.Fragment
+	referenceURL.Fragment = ""
+
+	return referenceURL.String(), fragment
+}
+
+// canonicalJson encodes a decoded json value in a canonical form, which is
+// equal for equal values regardless of the formatting and the order of the
+// properties of the original document.
+func canonicalJson(value interface{}) string {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+
+	return string(bytes)
+}
+
+// contentURI returns the URI of a schema that does not declare an "$id".
+func contentURI(canonical string) string {
+	hash := sha1.Sum([]byte(canonical))
+
+	return DEFAULT_BASE_URI + hex.EncodeToString(hash[:])
+}
+
+// valueAt returns the value that a json pointer points to in a decoded json
+// document.
+func valueAt(document interface{}, pointer string) (interface{}, bool) {
+	tokens, err := jsonwalker.NewJsonPointer(pointer)
+	if err != nil {
+		return nil, false
+	}
+
+	value := document
+	for _, token := range tokens {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			child, ok := v[token]
+			if !ok {
+				return nil, false
+			}
+
+			value = child
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil, false
+			}
+
+			value = v[index]
+		default:
+			return nil, false
+		}
+	}
+
+	return value, true
+}
@@ -0,0 +1,210 @@
+package jsonvalidator
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The status of a response schema that applies to any status code that no
+// other response schema describes
+const RESPONSE_STATUS_DEFAULT = "default"
+
+// responseSchema is the schema of the responses with a specific status and
+// content type.
+type responseSchema struct {
+	status      string
+	contentType string
+	schema      *RootJsonSchema
+}
+
+// LoadResponseSchema handles addition of a schema that describes the body of
+// the responses to an endpoint. The status is either a status code ("200"),
+// a range of status codes ("2XX") or "default", and an empty content type
+// means that the schema applies to any content type.
+func (jv JsonValidator) LoadResponseSchema(path, method, status, contentType string,
+	rawSchema []byte) error {
+	status = strings.ToUpper(status)
+	if strings.EqualFold(status, RESPONSE_STATUS_DEFAULT) {
+		status = RESPONSE_STATUS_DEFAULT
+	}
+
+	if !isValidResponseStatus(status) {
+		return errors.New("could not load response schema to path " +
+			path +
+			": invalid status \"" +
+			status +
+			"\"")
+	}
+
+	contentType, err := normalizeMediaType(contentType)
+	if err != nil {
+		return errors.Wrap(err, "could not load response schema to path "+path)
+	}
+
+	err = checkMethod(path, method)
+	if err != nil {
+		return err
+	}
+
+	jv.mutex.Lock()
+	defer jv.mutex.Unlock()
+
+	// Two schemas for the same response would make one of them useless.
+	for _, existing := range jv.responsesDict[path][method] {
+		if existing.status == status && existing.contentType == contentType {
+			return errors.New("could not load response schema to path " +
+				path +
+				": a schema for status \"" +
+				status +
+				"\" and content type \"" +
+				contentType +
+				"\" already exists")
+		}
+	}
+
+	schema, err := jv.compileSchema("response "+status+" "+contentType+" "+method+" "+path,
+		rawSchema)
+	if err != nil {
+		return err
+	}
+
+	if jv.responsesDict[path] == nil {
+		jv.responsesDict[path] = make(map[string][]*responseSchema)
+	}
+
+	jv.responsesDict[path][method] = append(jv.responsesDict[path][method],
+		&responseSchema{status, contentType, schema})
+
+	return nil
+}
+
+// ValidateResponse validates the body of a response against the schema that
+// describes its status and content type.
+// A response that no schema describes is considered valid, and so is a
+// response without a body.
+func (jv JsonValidator) ValidateResponse(path, method string, status int,
+	contentType string, body []byte) error {
+	if !hasResponseBody(method, status, body) {
+		return nil
+	}
+
+	schema := jv.findResponseSchema(path, method, status, contentType)
+	if schema == nil {
+		return nil
+	}
+
+	return schema.validateBytes(body, jv.exhaustive)
+}
+
+// findResponseSchema returns the most specific schema that describes a
+// response: a status code is preferred over a range of status codes, which
+// is preferred over "default", and for the same status a schema with a
+// content type is preferred over a schema without one.
+func (jv JsonValidator) findResponseSchema(path, method string, status int,
+	contentType string) *RootJsonSchema {
+	var best *responseSchema
+	bestScore := 0
+
+	// A malformed content type can only match schemas without one.
+	contentType, _ = normalizeMediaType(contentType)
+	code := strconv.Itoa(status)
+
+	jv.mutex.RLock()
+	defer jv.mutex.RUnlock()
+
+	for _, candidate := range jv.responsesDict[path][method] {
+		score := 0
+
+		switch {
+		case candidate.status == code:
+			score = 6
+		case candidate.status == code[:1]+"XX":
+			score = 4
+		case candidate.status == RESPONSE_STATUS_DEFAULT:
+			score = 2
+		default:
+			continue
+		}
+
+		if candidate.contentType != "" {
+			if !mediaTypeMatches(candidate.contentType, contentType) {
+				continue
+			}
+
+			score++
+		}
+
+		if score > bestScore {
+			best = candidate
+			bestScore = score
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+
+	return best.schema
+}
+
+// hasResponseBody returns true if a response has a body to validate. The
+// responses to HEAD requests and the responses whose status (1xx, 204 or
+// 304) does not allow a body have none, even if the target sent one.
+func hasResponseBody(method string, status int, body []byte) bool {
+	if len(body) == 0 || method == http.MethodHead {
+		return false
+	}
+
+	return status >= http.StatusOK &&
+		status != http.StatusNoContent &&
+		status != http.StatusNotModified
+}
+
+// isValidResponseStatus returns true if the status is a status code, a
+// range of status codes or "default".
+func isValidResponseStatus(status string) bool {
+	if status == RESPONSE_STATUS_DEFAULT {
+		return true
+	}
+
+	if len(status) != 3 || status[0] < '1' || status[0] > '5' {
+		return false
+	}
+
+	if status[1:] == "XX" {
+		return true
+	}
+
+	_, err := strconv.Atoi(status[1:])
+
+	return err == nil
+}
+
+// normalizeMediaType removes the parameters of a content type and lower-cases
+// it, so content types can be compared.
+func normalizeMediaType(contentType string) (string, error) {
+	if contentType == "" {
+		return "", nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", err
+	}
+
+	return mediaType, nil
+}
+
+// mediaTypeMatches returns true if a media type matches a pattern, which is
+// either a media type or a range like "application/*".
+func mediaTypeMatches(pattern, mediaType string) bool {
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*"))
+	}
+
+	return pattern == mediaType
+}
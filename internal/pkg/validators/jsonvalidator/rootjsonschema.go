@@ -3,26 +3,79 @@ package jsonvalidator
 import (
 	"encoding/json"
 	"fmt"
-)
 
-// This is a package-level dictionary that contains all the reference-able
-// root schema instances.
-var rootSchemaPool = map[string]*RootJsonSchema{}
+	"github.com/pkg/errors"
+)
 
 // RootJsonSchema is struct that contains a JsonSchema embedded into it
 // (and therefore inherits all JsonSchema's methods) and a map of json path and
 // a pointer to JsonSchema instance called subSchemaMap.
 // subSchemaMap holds a record for each sub-schema that the root-schema contains.
+// Anchors ("$anchor" and "$dynamicAnchor") are mapped in subSchemaMap by their
+// names as well.
 type RootJsonSchema struct {
 	JsonSchema
 	subSchemaMap map[string]*JsonSchema
+
+	// dynamicAnchors holds the sub-schemas that "$dynamicRef" can point to
+	// by their "$dynamicAnchor".
+	dynamicAnchors map[string]*JsonSchema
+
+	// draft and vocabularies describe the dialect that the schema is
+	// written in.
+	draft        string
+	vocabularies vocabularies
+
+	// registry holds the schemas that the references of the schema are
+	// resolved against.
+	registry *schemaRegistry
+
+	// uri is the absolute URI of the schema, which is the base URI of its
+	// references, and canonical is its content in a canonical form.
+	uri       string
+	canonical string
+
+	// resources holds the schema resources that the schema embeds (its
+	// sub-schemas with an "$id").
+	resources []*RootJsonSchema
+
+	// references holds the absolute references ("$ref", "$recursiveRef" and
+	// "$dynamicRef") of the schema and its embedded resources.
+	references []string
 }
 
 // NewJsonSchema creates a new RootJsonSchema instance, Unmarshals the byte array
 // into the instance, and returns a pointer to the instance.
+// A schema that does not declare its draft in "$schema" is considered a
+// draft-07 schema. The references of the schema can only point to the schema
+// itself and to the meta-schemas of the drafts.
 func NewRootJsonSchema(bytes []byte) (*RootJsonSchema, error) {
-	var rootSchemaId string
+	registry := newSchemaRegistry(metaSchemaRegistry)
+
+	rootSchema, err := newRootJsonSchema(bytes, DRAFT_07, registry, "")
+	if err != nil {
+		return nil, err
+	}
+
+	err = registry.register("root", rootSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	return rootSchema, nil
+}
+
+// newRootJsonSchema creates a new RootJsonSchema instance of a schema that is
+// written in defaultDraft unless it declares otherwise. The references of the
+// schema are resolved against the registry, but the schema is not registered
+// in it.
+// baseURI is the URI that the schema was retrieved from, which identifies
+// the schema if it does not declare an "$id". If it is empty, the schema is
+// identified by its content.
+func newRootJsonSchema(bytes []byte, defaultDraft string, registry *schemaRegistry,
+	baseURI string) (*RootJsonSchema, error) {
 	var rootSchema *RootJsonSchema
+	var document interface{}
 
 	// Check if the string s is a valid json.
 	err := json.Unmarshal(bytes, &rootSchema)
@@ -30,23 +83,47 @@ func NewRootJsonSchema(bytes []byte) (*RootJsonSchema, error) {
 		return nil, err
 	}
 
-	// Allocate space for the map in memory.
+	err = json.Unmarshal(bytes, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	declaredDialect, err := schemaDialect(bytes, defaultDraft, registry)
+	if err != nil {
+		return nil, err
+	}
+
+	rootSchema.draft = declaredDialect.draft
+	rootSchema.vocabularies = declaredDialect.vocabularies
+	rootSchema.registry = registry
+	rootSchema.canonical = canonicalJson(document)
+
+	// Allocate space for the maps in memory.
 	rootSchema.subSchemaMap = make(map[string]*JsonSchema)
+	rootSchema.dynamicAnchors = make(map[string]*JsonSchema)
 
-	// If the field $id in the rootSchema exists, add the rootSchema to the
-	// rootSchemaPool
-	if rootSchema.Id != nil {
-		rootSchemaId = string(*rootSchema.Id)
+	// A schema without an "$id" is identified by the URI it was retrieved
+	// from or by its content, and a relative "$id" is resolved against the
+	// same base URI.
+	rootSchema.uri = baseURI
+	if baseURI == "" {
+		baseURI = DEFAULT_BASE_URI
+		rootSchema.uri = contentURI(rootSchema.canonical)
 	}
-	//else {
-	//	fmt.Println("[RootJsonSchema DEBUG] created a RootJsonSchema instance with no $id")
-	//}
 
-	if _, ok := rootSchemaPool[rootSchemaId]; !ok {
-		rootSchemaPool[rootSchemaId] = rootSchema
+	if rootSchema.Id != nil {
+		uri, err := resolveReference(baseURI, string(*rootSchema.Id))
+		if err != nil {
+			return nil, SchemaCompilationError{"/$id", err.Error()}
+		}
+
+		rootSchema.uri, _ = splitReference(uri)
 	}
 
-	err = rootSchema.scanSchema("", rootSchemaId)
+	err = rootSchema.scanSchema(schemaLocation{
+		[]schemaScope{{rootSchema, ""}},
+		document,
+	})
 	if err != nil {
 		fmt.Println("[RootJsonSchema DEBUG] scanSchema() " +
 			"failed: " + err.Error())
@@ -56,15 +133,50 @@ func NewRootJsonSchema(bytes []byte) (*RootJsonSchema, error) {
 	return rootSchema, nil
 }
 
-// validate calls RootJsonSchema.validateJsonData() with an empty jsonPath
-// (represents root), and the root-schema id if exists.
-func (rs *RootJsonSchema) validateBytes(bytes []byte) error {
-	var id string
-	if rs.Id != nil {
-		id = string(*rs.Id)
+// embedResource creates the schema resource of a sub-schema with an "$id",
+// whose id is resolved against the base URI of the resource that contains it.
+func (rs *RootJsonSchema) embedResource(js *JsonSchema, location schemaLocation) (*RootJsonSchema, error) {
+	uri, err := resolveReference(rs.uri, string(*js.Id))
+	if err != nil {
+		return nil, SchemaCompilationError{location.path() + "/$id", err.Error()}
+	}
+
+	embedded := &RootJsonSchema{
+		JsonSchema:     *js,
+		subSchemaMap:   make(map[string]*JsonSchema),
+		dynamicAnchors: make(map[string]*JsonSchema),
+		draft:          rs.draft,
+		vocabularies:   rs.vocabularies,
+		registry:       rs.registry,
+	}
+
+	embedded.uri, _ = splitReference(uri)
+
+	// The content of the resource is compared when another schema declares
+	// the same id.
+	if value, ok := valueAt(location.document, location.path()); ok {
+		embedded.canonical = canonicalJson(value)
 	} else {
-		id = ""
+		embedded.canonical = location.scopes[0].resource.canonical + "#" + location.path()
+	}
+
+	root := location.scopes[0].resource
+	root.resources = append(root.resources, embedded)
+
+	return embedded, nil
+}
+
+// validateBytes decodes the data once and calls RootJsonSchema.validateValue()
+// with an empty jsonPath (represents root).
+// If exhaustive is true, all the failures are collected and returned as
+// ValidationErrors.
+func (rs *RootJsonSchema) validateBytes(bytes []byte, exhaustive bool) error {
+	var value interface{}
+
+	err := json.Unmarshal(bytes, &value)
+	if err != nil {
+		return errors.Wrap(err, "could not decode the data")
 	}
 
-	return rs.validateJsonData("", bytes, id)
+	return rs.validateValue("", jsonData{value}, newValidationContext(rs, exhaustive))
 }
@@ -0,0 +1,189 @@
+package jsonvalidator
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// schemaSources tells a JsonValidator where to find the files of the
+// schemas that references point to.
+type schemaSources struct {
+	// directories are searched, in order, for the files of the references
+	// that were resolved against DEFAULT_BASE_URI.
+	directories []string
+
+	// mappings maps absolute URIs to files, and URI prefixes that end with
+	// "/" to directories.
+	mappings map[string]string
+}
+
+// file returns the file that holds the schema with the given URI, or false
+// if the sources do not know where to find the schema.
+func (s *schemaSources) file(uri string) (string, bool) {
+	if file, ok := s.mappings[uri]; ok {
+		return file, true
+	}
+
+	// The longest prefix is the most specific mapping.
+	var prefix string
+	for candidate := range s.mappings {
+		if strings.HasSuffix(candidate, "/") && strings.HasPrefix(uri, candidate) &&
+			len(candidate) > len(prefix) {
+			prefix = candidate
+		}
+	}
+
+	if prefix != "" {
+		return joinConfined(s.mappings[prefix], strings.TrimPrefix(uri, prefix)), true
+	}
+
+	if !strings.HasPrefix(uri, DEFAULT_BASE_URI) {
+		return "", false
+	}
+
+	for _, directory := range s.directories {
+		file := joinConfined(directory, strings.TrimPrefix(uri, DEFAULT_BASE_URI))
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			return file, true
+		}
+	}
+
+	return "", false
+}
+
+// joinConfined joins a directory and a relative path that was taken from a
+// URI, without letting the path climb out of the directory.
+func joinConfined(directory, relativePath string) string {
+	return filepath.Join(directory, filepath.FromSlash(path.Clean("/"+relativePath)))
+}
+
+// SetSchemaSources tells the validator where to find the schemas that
+// references point to when they were not loaded: directories are searched,
+// in order, for the files of relative references (which are resolved against
+// DEFAULT_BASE_URI), and mappings maps absolute URIs to files, or URI
+// prefixes that end with "/" to directories.
+// The schemas are loaded when a schema that references them is loaded.
+func (jv *JsonValidator) SetSchemaSources(directories []string, mappings map[string]string) {
+	jv.sources = &schemaSources{directories, mappings}
+}
+
+// ResolveReferences loads the schemas that the references of the loaded
+// schemas point to from the schema sources, in case they were not available
+// when the referencing schemas were loaded, and returns an
+// UnresolvedReferencesError that lists the references that still point to
+// nothing.
+// It should be called once all the schemas were loaded.
+func (jv JsonValidator) ResolveReferences() error {
+	loading := make(map[string]bool)
+
+	for _, schema := range jv.registry.schemas() {
+		err := jv.loadReferences(schema, loading)
+		if err != nil {
+			return err
+		}
+	}
+
+	var unresolved UnresolvedReferencesError
+	for _, schema := range jv.registry.schemas() {
+		for _, reference := range schema.references {
+			if !jv.registry.resolves(reference) {
+				unresolved = append(unresolved, "\""+reference+"\" in \""+schema.uri+"\"")
+			}
+		}
+	}
+
+	if len(unresolved) > 0 {
+		sort.Strings(unresolved)
+
+		return unresolved
+	}
+
+	return nil
+}
+
+// loadReferences loads the schemas that the references of a schema point
+// to, if they are not registered yet and the schema sources know where to
+// find them. References that the sources do not know are left for
+// ResolveReferences to report, since another schema may still provide them.
+func (jv JsonValidator) loadReferences(schema *RootJsonSchema, loading map[string]bool) error {
+	for _, reference := range schema.references {
+		uri, _ := splitReference(reference)
+
+		err := jv.loadReference(uri, loading)
+		if err != nil {
+			return errors.Wrap(err, "could not load the schema that \""+
+				reference+
+				"\" in \""+
+				schema.uri+
+				"\" points to")
+		}
+	}
+
+	return nil
+}
+
+// loadReference loads the schema with the given URI from the schema sources.
+func (jv JsonValidator) loadReference(uri string, loading map[string]bool) error {
+	// A reference to a schema that is being loaded closes a cycle, and the
+	// schema will be registered when its loading is done.
+	if uri == "" || loading[uri] || jv.registry.lookup(uri) != nil {
+		return nil
+	}
+
+	// The meta-schemas of the drafts are loaded from their own files.
+	if draft, ok := draftURIs[uri]; ok {
+		_, err := loadMetaSchema(draft)
+		return err
+	}
+
+	file, ok := jv.sources.file(uri)
+	if !ok {
+		return nil
+	}
+
+	loading[uri] = true
+
+	bytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return errors.Wrap(err, "could not read schema file")
+	}
+
+	_, err = jv.compileResource("file "+file, bytes, uri, loading)
+
+	return err
+}
+
+// loadMetaSchemaReference loads the custom meta-schema that a raw schema
+// declares in "$schema" from the schema sources, if it was not loaded yet.
+func (jv JsonValidator) loadMetaSchemaReference(rawSchema []byte, loading map[string]bool) error {
+	var declaration struct {
+		Schema string `json:"$schema"`
+	}
+
+	// Boolean schemas cannot declare a meta-schema.
+	_ = json.Unmarshal(rawSchema, &declaration)
+	if declaration.Schema == "" {
+		return nil
+	}
+
+	uri, err := resolveReference(DEFAULT_BASE_URI, declaration.Schema)
+	if err != nil {
+		return nil
+	}
+
+	uri, _ = splitReference(uri)
+
+	err = jv.loadReference(uri, loading)
+	if err != nil {
+		return errors.Wrap(err, "could not load meta-schema \""+declaration.Schema+"\"")
+	}
+
+	return nil
+}
@@ -0,0 +1,499 @@
+package protovalidator
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// maxDepth limits the nesting of the decoded messages
+const maxDepth = 100
+
+// Well-known types that have a json mapping of their own
+const (
+	typeTimestamp = "google.protobuf.Timestamp"
+	typeDuration  = "google.protobuf.Duration"
+	typeStruct    = "google.protobuf.Struct"
+	typeValue     = "google.protobuf.Value"
+	typeListValue = "google.protobuf.ListValue"
+)
+
+// wrapperTypes are the well-known types that wrap a single scalar, which
+// is their json mapping, by the type of the scalar
+var wrapperTypes = map[string]int{
+	"google.protobuf.DoubleValue": typeDouble,
+	"google.protobuf.FloatValue":  typeFloat,
+	"google.protobuf.Int64Value":  typeInt64,
+	"google.protobuf.UInt64Value": typeUint64,
+	"google.protobuf.Int32Value":  typeInt32,
+	"google.protobuf.UInt32Value": typeUint32,
+	"google.protobuf.BoolValue":   typeBool,
+	"google.protobuf.StringValue": typeString,
+	"google.protobuf.BytesValue":  typeBytes,
+}
+
+// isWellKnownType returns true if a message type is decoded without its
+// descriptor
+func isWellKnownType(typeName string) bool {
+	switch typeName {
+	case typeTimestamp, typeDuration, typeStruct, typeValue, typeListValue:
+		return true
+	}
+
+	_, ok := wrapperTypes[typeName]
+
+	return ok
+}
+
+// decodeMessage decodes an encoded message into its proto3 json mapping.
+// Fields that are not on the wire are not in the json object (like the
+// fields with default values in proto3), and unknown fields are ignored.
+func (d *descriptors) decodeMessage(typeName string, data []byte, depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, errors.New("the message is nested too deeply")
+	}
+
+	if isWellKnownType(typeName) {
+		return decodeWellKnownType(typeName, data, depth)
+	}
+
+	message := d.messages[typeName]
+	object := make(map[string]interface{})
+
+	reader := wireReader{data}
+	for !reader.done() {
+		field, err := reader.next()
+		if err != nil {
+			return nil, err
+		}
+
+		descriptor := message.fields[field.number]
+		if descriptor == nil || field.wireType == wireStartGroup {
+			continue
+		}
+
+		// Map fields are repeated entries with a key and a value
+		if entry := d.messages[descriptor.typeName]; descriptor.repeated && entry != nil && entry.mapEntry {
+			key, value, err := d.decodeMapEntry(entry, field, depth)
+			if err != nil {
+				return nil, errors.New(descriptor.name + ": " + err.Error())
+			}
+
+			entries, _ := object[descriptor.jsonName].(map[string]interface{})
+			if entries == nil {
+				entries = make(map[string]interface{})
+				object[descriptor.jsonName] = entries
+			}
+
+			entries[key] = value
+
+			continue
+		}
+
+		values, err := d.decodeField(descriptor, field, depth)
+		if err != nil {
+			return nil, errors.New(descriptor.name + ": " + err.Error())
+		}
+
+		if !descriptor.repeated {
+			// The last value of a field that is not repeated wins
+			object[descriptor.jsonName] = values[len(values)-1]
+			continue
+		}
+
+		list, _ := object[descriptor.jsonName].([]interface{})
+		object[descriptor.jsonName] = append(list, values...)
+	}
+
+	return object, nil
+}
+
+// decodeField decodes the value of a field. Packed repeated fields hold
+// several values.
+func (d *descriptors) decodeField(descriptor *fieldDescriptor, field wireField,
+	depth int) ([]interface{}, error) {
+	expected := wireTypeOf(descriptor.kind)
+
+	if field.wireType == expected {
+		value, err := d.decodeValue(descriptor.kind, descriptor.typeName, field, depth)
+		if err != nil {
+			return nil, err
+		}
+
+		return []interface{}{value}, nil
+	}
+
+	// Packed repeated scalars are a length-delimited sequence of values
+	if field.wireType != wireBytes || !descriptor.repeated ||
+		expected == wireBytes || expected == wireStartGroup {
+		return nil, errors.New("unexpected wire type " + strconv.Itoa(field.wireType))
+	}
+
+	var values []interface{}
+
+	reader := wireReader{field.bytes}
+	for !reader.done() {
+		element := wireField{number: field.number, wireType: expected}
+
+		var err error
+		switch expected {
+		case wireVarint:
+			element.number64, err = reader.varint()
+		case wireFixed64:
+			element.number64, err = reader.fixed(8)
+		case wireFixed32:
+			element.number64, err = reader.fixed(4)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := d.decodeValue(descriptor.kind, descriptor.typeName, element, depth)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// decodeMapEntry decodes an entry of a map field into its key, which is a
+// string in json, and its value
+func (d *descriptors) decodeMapEntry(entry *messageDescriptor, field wireField,
+	depth int) (string, interface{}, error) {
+	if field.wireType != wireBytes {
+		return "", nil, errors.New("unexpected wire type " + strconv.Itoa(field.wireType))
+	}
+
+	keyDescriptor, valueDescriptor := entry.fields[1], entry.fields[2]
+	if keyDescriptor == nil || valueDescriptor == nil {
+		return "", nil, errors.New("invalid map entry " + entry.fullName)
+	}
+
+	var key, value interface{}
+
+	reader := wireReader{field.bytes}
+	for !reader.done() {
+		entryField, err := reader.next()
+		if err != nil {
+			return "", nil, err
+		}
+
+		var descriptor *fieldDescriptor
+		switch entryField.number {
+		case 1:
+			descriptor = keyDescriptor
+		case 2:
+			descriptor = valueDescriptor
+		default:
+			continue
+		}
+
+		values, err := d.decodeField(descriptor, entryField, depth)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if entryField.number == 1 {
+			key = values[len(values)-1]
+		} else {
+			value = values[len(values)-1]
+		}
+	}
+
+	// Missing keys and values have their default values
+	if key == nil {
+		key = defaultValue(keyDescriptor.kind)
+	}
+
+	if value == nil {
+		value = defaultValue(valueDescriptor.kind)
+		if valueDescriptor.kind == typeMessage {
+			value = map[string]interface{}{}
+		}
+	}
+
+	switch key := key.(type) {
+	case string:
+		return key, value, nil
+	case json.Number:
+		return key.String(), value, nil
+	default:
+		return strconv.FormatBool(key.(bool)), value, nil
+	}
+}
+
+// decodeValue decodes a single value of a field
+func (d *descriptors) decodeValue(kind int, typeName string, field wireField,
+	depth int) (interface{}, error) {
+	n := field.number64
+
+	switch kind {
+	case typeDouble:
+		return floatValue(math.Float64frombits(n), 64), nil
+	case typeFloat:
+		return floatValue(float64(math.Float32frombits(uint32(n))), 32), nil
+	case typeInt32, typeSfixed32:
+		return json.Number(strconv.FormatInt(int64(int32(n)), 10)), nil
+	case typeUint32, typeFixed32:
+		return json.Number(strconv.FormatUint(uint64(uint32(n)), 10)), nil
+	case typeSint32:
+		return json.Number(strconv.FormatInt(int64(int32(zigzag(n))), 10)), nil
+	case typeInt64, typeSfixed64:
+		// 64 bit integers are strings in json, since they may not fit in a
+		// json number
+		return strconv.FormatInt(int64(n), 10), nil
+	case typeUint64, typeFixed64:
+		return strconv.FormatUint(n, 10), nil
+	case typeSint64:
+		return strconv.FormatInt(zigzag(n), 10), nil
+	case typeBool:
+		return n != 0, nil
+	case typeEnum:
+		if enum := d.enums[typeName]; enum != nil {
+			if name, ok := enum.values[int32(n)]; ok {
+				return name, nil
+			}
+		}
+
+		return json.Number(strconv.FormatInt(int64(int32(n)), 10)), nil
+	case typeString:
+		if !utf8.Valid(field.bytes) {
+			return nil, errors.New("the string is not valid UTF-8")
+		}
+
+		return string(field.bytes), nil
+	case typeBytes:
+		return base64.StdEncoding.EncodeToString(field.bytes), nil
+	case typeMessage:
+		return d.decodeMessage(typeName, field.bytes, depth+1)
+	default:
+		return nil, errors.New("unsupported field type " + strconv.Itoa(kind))
+	}
+}
+
+// decodeWellKnownType decodes a well-known type into its special json
+// mapping
+func decodeWellKnownType(typeName string, data []byte, depth int) (interface{}, error) {
+	fields, err := wellKnownFields(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if kind, ok := wrapperTypes[typeName]; ok {
+		field, ok := fields[1]
+		if !ok {
+			return defaultValue(kind), nil
+		}
+
+		return (&descriptors{}).decodeValue(kind, "", field, depth)
+	}
+
+	switch typeName {
+	case typeTimestamp:
+		seconds, nanos := int64(fields[1].number64), int64(int32(fields[2].number64))
+
+		return time.Unix(seconds, nanos).UTC().Format(time.RFC3339Nano), nil
+	case typeDuration:
+		return formatDuration(int64(fields[1].number64), int32(fields[2].number64)), nil
+	case typeStruct:
+		return decodeStruct(data, depth)
+	case typeListValue:
+		return decodeListValue(data, depth)
+	default:
+		return decodeStructValue(fields, depth)
+	}
+}
+
+// wellKnownFields returns the last value of each field of a well-known type
+func wellKnownFields(data []byte) (map[int32]wireField, error) {
+	fields := make(map[int32]wireField)
+
+	reader := wireReader{data}
+	for !reader.done() {
+		field, err := reader.next()
+		if err != nil {
+			return nil, err
+		}
+
+		fields[field.number] = field
+	}
+
+	return fields, nil
+}
+
+// decodeStruct decodes a google.protobuf.Struct into a json object
+func decodeStruct(data []byte, depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, errors.New("the message is nested too deeply")
+	}
+
+	object := make(map[string]interface{})
+
+	reader := wireReader{data}
+	for !reader.done() {
+		field, err := reader.next()
+		if err != nil {
+			return nil, err
+		}
+
+		if field.number != 1 || field.wireType != wireBytes {
+			continue
+		}
+
+		entry, err := wellKnownFields(field.bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := decodeWellKnownType(typeValue, entry[2].bytes, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		object[string(entry[1].bytes)] = value
+	}
+
+	return object, nil
+}
+
+// decodeListValue decodes a google.protobuf.ListValue into a json array
+func decodeListValue(data []byte, depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, errors.New("the message is nested too deeply")
+	}
+
+	list := []interface{}{}
+
+	reader := wireReader{data}
+	for !reader.done() {
+		field, err := reader.next()
+		if err != nil {
+			return nil, err
+		}
+
+		if field.number != 1 || field.wireType != wireBytes {
+			continue
+		}
+
+		value, err := decodeWellKnownType(typeValue, field.bytes, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, value)
+	}
+
+	return list, nil
+}
+
+// decodeStructValue decodes a google.protobuf.Value into a json value
+func decodeStructValue(fields map[int32]wireField, depth int) (interface{}, error) {
+	switch {
+	case fields[2].wireType == wireFixed64 && hasField(fields, 2):
+		return floatValue(math.Float64frombits(fields[2].number64), 64), nil
+	case hasField(fields, 3):
+		return (&descriptors{}).decodeValue(typeString, "", fields[3], depth)
+	case hasField(fields, 4):
+		return fields[4].number64 != 0, nil
+	case hasField(fields, 5):
+		return decodeStruct(fields[5].bytes, depth+1)
+	case hasField(fields, 6):
+		return decodeListValue(fields[6].bytes, depth+1)
+	default:
+		return nil, nil
+	}
+}
+
+// hasField returns true if a field of a well-known type is on the wire
+func hasField(fields map[int32]wireField, number int32) bool {
+	_, ok := fields[number]
+
+	return ok
+}
+
+// wireTypeOf returns the wire type of the values of a field type
+func wireTypeOf(kind int) int {
+	switch kind {
+	case typeDouble, typeFixed64, typeSfixed64:
+		return wireFixed64
+	case typeFloat, typeFixed32, typeSfixed32:
+		return wireFixed32
+	case typeString, typeBytes, typeMessage:
+		return wireBytes
+	case typeGroup:
+		return wireStartGroup
+	default:
+		return wireVarint
+	}
+}
+
+// defaultValue returns the json mapping of the default value of a scalar
+// type
+func defaultValue(kind int) interface{} {
+	switch kind {
+	case typeBool:
+		return false
+	case typeString, typeBytes:
+		return ""
+	case typeInt64, typeUint64, typeFixed64, typeSfixed64, typeSint64:
+		return "0"
+	default:
+		return json.Number("0")
+	}
+}
+
+// floatValue returns the json mapping of a floating point number, in which
+// the special values are strings
+func floatValue(value float64, bitSize int) interface{} {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "Infinity"
+	case math.IsInf(value, -1):
+		return "-Infinity"
+	}
+
+	return json.Number(strconv.FormatFloat(value, 'g', -1, bitSize))
+}
+
+// zigzag decodes a zigzag encoded signed integer
+func zigzag(n uint64) int64 {
+	return int64(n>>1) ^ -int64(n&1)
+}
+
+// formatDuration returns the json mapping of a google.protobuf.Duration,
+// like "1.5s"
+func formatDuration(seconds int64, nanos int32) string {
+	sign := ""
+	if seconds < 0 || nanos < 0 {
+		sign = "-"
+	}
+
+	if seconds < 0 {
+		seconds = -seconds
+	}
+
+	if nanos < 0 {
+		nanos = -nanos
+	}
+
+	duration := sign + strconv.FormatInt(seconds, 10)
+
+	if nanos != 0 {
+		fraction := strconv.Itoa(int(nanos) + 1000000000)[1:]
+		duration += "." + strings.TrimRight(fraction, "0")
+	}
+
+	return duration + "s"
+}
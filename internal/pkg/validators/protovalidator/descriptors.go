@@ -0,0 +1,432 @@
+package protovalidator
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Field types of descriptor.proto
+const (
+	typeDouble   = 1
+	typeFloat    = 2
+	typeInt64    = 3
+	typeUint64   = 4
+	typeInt32    = 5
+	typeFixed64  = 6
+	typeFixed32  = 7
+	typeBool     = 8
+	typeString   = 9
+	typeGroup    = 10
+	typeMessage  = 11
+	typeBytes    = 12
+	typeUint32   = 13
+	typeEnum     = 14
+	typeSfixed32 = 15
+	typeSfixed64 = 16
+	typeSint32   = 17
+	typeSint64   = 18
+)
+
+// labelRepeated is the label of repeated fields in descriptor.proto
+const labelRepeated = 3
+
+// messageDescriptor describes a message type
+type messageDescriptor struct {
+	fullName string
+	fields   map[int32]*fieldDescriptor
+
+	// mapEntry is true for the entries of map fields, whose key is field 1
+	// and whose value is field 2
+	mapEntry bool
+}
+
+// fieldDescriptor describes a field of a message
+type fieldDescriptor struct {
+	name     string
+	jsonName string
+	number   int32
+	repeated bool
+	kind     int
+
+	// typeName is the full name of the type of message and enum fields,
+	// without the leading dot
+	typeName string
+}
+
+// enumDescriptor describes an enum type
+type enumDescriptor struct {
+	values map[int32]string
+}
+
+// methodDescriptor describes a method of a service
+type methodDescriptor struct {
+	inputType       string
+	clientStreaming bool
+	serverStreaming bool
+}
+
+// descriptors holds the types and the methods of the files of a descriptor
+// set
+type descriptors struct {
+	messages map[string]*messageDescriptor
+	enums    map[string]*enumDescriptor
+
+	// methods are keyed by their gRPC paths ("/package.Service/Method")
+	methods map[string]*methodDescriptor
+}
+
+// parseDescriptorSet parses a FileDescriptorSet in the wire format, as
+// written by protoc --descriptor_set_out
+func parseDescriptorSet(set []byte) (*descriptors, error) {
+	d := &descriptors{
+		messages: make(map[string]*messageDescriptor),
+		enums:    make(map[string]*enumDescriptor),
+		methods:  make(map[string]*methodDescriptor),
+	}
+
+	reader := wireReader{set}
+	for !reader.done() {
+		field, err := reader.next()
+		if err != nil {
+			return nil, err
+		}
+
+		// FileDescriptorSet.file
+		if field.number == 1 && field.wireType == wireBytes {
+			err = d.parseFile(field.bytes)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return d, d.check()
+}
+
+// parseFile parses a FileDescriptorProto
+func (d *descriptors) parseFile(file []byte) error {
+	var pkg string
+	var messages, enums, services [][]byte
+
+	reader := wireReader{file}
+	for !reader.done() {
+		field, err := reader.next()
+		if err != nil {
+			return err
+		}
+
+		if field.wireType != wireBytes {
+			continue
+		}
+
+		switch field.number {
+		case 2:
+			pkg = string(field.bytes)
+		case 4:
+			messages = append(messages, field.bytes)
+		case 5:
+			enums = append(enums, field.bytes)
+		case 6:
+			services = append(services, field.bytes)
+		}
+	}
+
+	scope := ""
+	if pkg != "" {
+		scope = pkg + "."
+	}
+
+	for _, message := range messages {
+		err := d.parseMessage(scope, message)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, enum := range enums {
+		err := d.parseEnum(scope, enum)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, service := range services {
+		err := d.parseService(scope, service)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseMessage parses a DescriptorProto and its nested types
+func (d *descriptors) parseMessage(scope string, message []byte) error {
+	descriptor := &messageDescriptor{
+		fields: make(map[int32]*fieldDescriptor),
+	}
+
+	var nested, enums [][]byte
+
+	reader := wireReader{message}
+	for !reader.done() {
+		field, err := reader.next()
+		if err != nil {
+			return err
+		}
+
+		if field.wireType != wireBytes {
+			continue
+		}
+
+		switch field.number {
+		case 1:
+			descriptor.fullName = scope + string(field.bytes)
+		case 2:
+			fd, err := parseField(field.bytes)
+			if err != nil {
+				return err
+			}
+
+			descriptor.fields[fd.number] = fd
+		case 3:
+			nested = append(nested, field.bytes)
+		case 4:
+			enums = append(enums, field.bytes)
+		case 7:
+			descriptor.mapEntry, err = parseMapEntryOption(field.bytes)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	d.messages[descriptor.fullName] = descriptor
+
+	for _, message := range nested {
+		err := d.parseMessage(descriptor.fullName+".", message)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, enum := range enums {
+		err := d.parseEnum(descriptor.fullName+".", enum)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseField parses a FieldDescriptorProto
+func parseField(raw []byte) (*fieldDescriptor, error) {
+	descriptor := &fieldDescriptor{}
+
+	reader := wireReader{raw}
+	for !reader.done() {
+		field, err := reader.next()
+		if err != nil {
+			return nil, err
+		}
+
+		switch field.number {
+		case 1:
+			descriptor.name = string(field.bytes)
+		case 3:
+			descriptor.number = int32(field.number64)
+		case 4:
+			descriptor.repeated = field.number64 == labelRepeated
+		case 5:
+			descriptor.kind = int(field.number64)
+		case 6:
+			descriptor.typeName = strings.TrimPrefix(string(field.bytes), ".")
+		case 10:
+			descriptor.jsonName = string(field.bytes)
+		}
+	}
+
+	// protoc sets the json names of the fields, but other tools may not
+	if descriptor.jsonName == "" {
+		descriptor.jsonName = jsonName(descriptor.name)
+	}
+
+	return descriptor, nil
+}
+
+// parseMapEntryOption returns the map_entry option of a MessageOptions
+func parseMapEntryOption(options []byte) (bool, error) {
+	reader := wireReader{options}
+	for !reader.done() {
+		field, err := reader.next()
+		if err != nil {
+			return false, err
+		}
+
+		if field.number == 7 && field.wireType == wireVarint {
+			return field.number64 != 0, nil
+		}
+	}
+
+	return false, nil
+}
+
+// parseEnum parses an EnumDescriptorProto
+func (d *descriptors) parseEnum(scope string, enum []byte) error {
+	var name string
+	descriptor := &enumDescriptor{
+		values: make(map[int32]string),
+	}
+
+	reader := wireReader{enum}
+	for !reader.done() {
+		field, err := reader.next()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case field.number == 1:
+			name = string(field.bytes)
+		case field.number == 2 && field.wireType == wireBytes:
+			valueName, number, err := parseEnumValue(field.bytes)
+			if err != nil {
+				return err
+			}
+
+			// The first name of an aliased value is its name in json
+			if _, ok := descriptor.values[number]; !ok {
+				descriptor.values[number] = valueName
+			}
+		}
+	}
+
+	d.enums[scope+name] = descriptor
+
+	return nil
+}
+
+// parseEnumValue parses an EnumValueDescriptorProto
+func parseEnumValue(value []byte) (string, int32, error) {
+	var name string
+	var number int32
+
+	reader := wireReader{value}
+	for !reader.done() {
+		field, err := reader.next()
+		if err != nil {
+			return "", 0, err
+		}
+
+		switch field.number {
+		case 1:
+			name = string(field.bytes)
+		case 2:
+			number = int32(field.number64)
+		}
+	}
+
+	return name, number, nil
+}
+
+// parseService parses a ServiceDescriptorProto
+func (d *descriptors) parseService(scope string, service []byte) error {
+	var name string
+	var methods [][]byte
+
+	reader := wireReader{service}
+	for !reader.done() {
+		field, err := reader.next()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case field.number == 1:
+			name = string(field.bytes)
+		case field.number == 2 && field.wireType == wireBytes:
+			methods = append(methods, field.bytes)
+		}
+	}
+
+	for _, method := range methods {
+		var methodName string
+		descriptor := &methodDescriptor{}
+
+		reader := wireReader{method}
+		for !reader.done() {
+			field, err := reader.next()
+			if err != nil {
+				return err
+			}
+
+			switch field.number {
+			case 1:
+				methodName = string(field.bytes)
+			case 2:
+				descriptor.inputType = strings.TrimPrefix(string(field.bytes), ".")
+			case 5:
+				descriptor.clientStreaming = field.number64 != 0
+			case 6:
+				descriptor.serverStreaming = field.number64 != 0
+			}
+		}
+
+		d.methods["/"+scope+name+"/"+methodName] = descriptor
+	}
+
+	return nil
+}
+
+// check makes sure that the types that the fields and the methods refer to
+// are in the descriptor set
+func (d *descriptors) check() error {
+	for _, message := range d.messages {
+		for _, field := range message.fields {
+			switch field.kind {
+			case typeMessage, typeGroup:
+				if d.messages[field.typeName] == nil && !isWellKnownType(field.typeName) {
+					return errors.New("message " + message.fullName + " refers to the unknown message " +
+						field.typeName + " (the descriptor set should include the imported files)")
+				}
+			case typeEnum:
+				if d.enums[field.typeName] == nil {
+					return errors.New("message " + message.fullName + " refers to the unknown enum " +
+						field.typeName + " (the descriptor set should include the imported files)")
+				}
+			}
+		}
+	}
+
+	for path, method := range d.methods {
+		if d.messages[method.inputType] == nil && !isWellKnownType(method.inputType) {
+			return errors.New("method " + path + " refers to the unknown message " + method.inputType)
+		}
+	}
+
+	return nil
+}
+
+// jsonName converts the name of a field to lower camel case, like protoc
+// does for the json names of the fields
+func jsonName(name string) string {
+	var builder strings.Builder
+	upper := false
+
+	for _, r := range name {
+		switch {
+		case r == '_':
+			upper = true
+		case upper && r >= 'a' && r <= 'z':
+			builder.WriteRune(r - 'a' + 'A')
+			upper = false
+		default:
+			builder.WriteRune(r)
+			upper = false
+		}
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,206 @@
+package protovalidator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/apidome/gateway/internal/pkg/validators/jsonvalidator"
+	"github.com/pkg/errors"
+)
+
+// maxMessageSize is the maximum size of a decompressed message, which is
+// the default limit of gRPC
+const maxMessageSize = 4 << 20
+
+// ProtoValidator is a struct that implements the Validator interface and
+// validates the protocol buffers messages of gRPC requests.
+// The messages are decoded with the descriptors of a descriptor set, and
+// their proto3 json mapping is validated against json schemas. The paths of
+// the endpoints are the paths of the gRPC methods
+// ("/package.Service/Method").
+type ProtoValidator struct {
+	descriptors   *descriptors
+	jsonValidator *jsonvalidator.JsonValidator
+}
+
+// NewProtoValidator returns a new instance of ProtoValidator for the
+// services of a descriptor set (a FileDescriptorSet, as written by protoc
+// --include_imports --descriptor_set_out).
+// The draft applies to the json schemas that do not declare their draft in
+// "$schema".
+func NewProtoValidator(descriptorSet []byte, draft string) (*ProtoValidator, error) {
+	descriptors, err := parseDescriptorSet(descriptorSet)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse descriptor set")
+	}
+
+	jsonValidator, err := jsonvalidator.NewJsonValidator(draft)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProtoValidator{
+		descriptors:   descriptors,
+		jsonValidator: &jsonValidator,
+	}, nil
+}
+
+// SetExhaustive determines whether Validate should stop at the first failure
+// or collect all the failures in a message.
+func (pv *ProtoValidator) SetExhaustive(exhaustive bool) {
+	pv.jsonValidator.SetExhaustive(exhaustive)
+}
+
+// SetSchemaSources tells the validator where to find the json schemas that
+// references point to when they were not loaded.
+func (pv *ProtoValidator) SetSchemaSources(directories []string, mappings map[string]string) {
+	pv.jsonValidator.SetSchemaSources(directories, mappings)
+}
+
+// LoadSchema loads a json schema of the json mapping of the request
+// messages of a gRPC method.
+// The requests of the methods whose messages are validated are read as a
+// whole before they are sent to the target, so the methods of
+// bidirectional streams cannot be validated.
+func (pv *ProtoValidator) LoadSchema(path string, method string, schema []byte) error {
+	descriptor, err := pv.method(path, method)
+	if err != nil {
+		return err
+	}
+
+	if descriptor.clientStreaming && descriptor.serverStreaming {
+		return errors.New("could not load schema: the requests of the bidirectional streaming method " +
+			path + " cannot be validated")
+	}
+
+	return pv.jsonValidator.LoadSchema(path, method, schema)
+}
+
+// Validate decodes the messages of a gRPC request body and validates their
+// json mapping.
+// Compressed messages are expected to be compressed with gzip.
+func (pv *ProtoValidator) Validate(path string, method string, body []byte) error {
+	descriptor, err := pv.method(path, method)
+	if err != nil {
+		return err
+	}
+
+	messages, err := readMessages(body)
+	if err != nil {
+		return errors.Wrap(err, "could not read gRPC messages")
+	}
+
+	if !descriptor.clientStreaming && len(messages) != 1 {
+		return errors.New("could not read gRPC messages: the request of " + path +
+			" should hold a single message, got " + strconv.Itoa(len(messages)))
+	}
+
+	for _, message := range messages {
+		value, err := pv.descriptors.decodeMessage(descriptor.inputType, message, 0)
+		if err != nil {
+			return errors.Wrap(err, "could not decode "+descriptor.inputType+" message")
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+
+		err = pv.jsonValidator.Validate(path, method, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ResolveReferences makes sure that the references of the loaded json
+// schemas point to existing schemas.
+func (pv *ProtoValidator) ResolveReferences() error {
+	return pv.jsonValidator.ResolveReferences()
+}
+
+// FormatOutput describes the error that was returned from Validate in one
+// of the json schema output formats.
+func (pv *ProtoValidator) FormatOutput(validationErr error, format string) (interface{}, error) {
+	return pv.jsonValidator.FormatOutput(validationErr, format)
+}
+
+// method returns the descriptor of the gRPC method of an endpoint
+func (pv *ProtoValidator) method(path, method string) (*methodDescriptor, error) {
+	if method != http.MethodPost {
+		return nil, errors.New("gRPC method " + path + " should be called with POST, not " + method)
+	}
+
+	descriptor := pv.descriptors.methods[path]
+	if descriptor == nil {
+		return nil, errors.New("unknown gRPC method \"" + path + "\"")
+	}
+
+	return descriptor, nil
+}
+
+// readMessages splits a gRPC request body into its messages, each of which
+// is prefixed by a compression flag and a 4 bytes length
+func readMessages(body []byte) ([][]byte, error) {
+	var messages [][]byte
+
+	for len(body) > 0 {
+		if len(body) < 5 {
+			return nil, errTruncated
+		}
+
+		compressed := body[0]&1 != 0
+		length := binary.BigEndian.Uint32(body[1:5])
+		body = body[5:]
+
+		if uint64(length) > uint64(len(body)) {
+			return nil, errTruncated
+		}
+
+		message := body[:length]
+		body = body[length:]
+
+		if compressed {
+			var err error
+
+			message, err = decompress(message)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+// decompress decompresses a gzip compressed message
+func decompress(message []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(message))
+	if err != nil {
+		return nil, err
+	}
+
+	defer reader.Close()
+
+	// A small compressed message may hide a huge one
+	data, err := ioutil.ReadAll(io.LimitReader(reader, maxMessageSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > maxMessageSize {
+		return nil, errors.New("a decompressed message exceeds " + strconv.Itoa(maxMessageSize) + " bytes")
+	}
+
+	return data, nil
+}
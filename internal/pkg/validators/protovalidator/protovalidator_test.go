@@ -0,0 +1,404 @@
+package protovalidator_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/apidome/gateway/internal/pkg/validators/protovalidator"
+)
+
+const succeed = "V"
+const failed = "X"
+
+// Helpers that encode messages in the protocol buffers wire format
+
+func varint(value uint64) []byte {
+	var buffer []byte
+
+	for value >= 0x80 {
+		buffer = append(buffer, byte(value)|0x80)
+		value >>= 7
+	}
+
+	return append(buffer, byte(value))
+}
+
+func varintField(number int, value uint64) []byte {
+	return append(varint(uint64(number)<<3), varint(value)...)
+}
+
+func bytesField(number int, value []byte) []byte {
+	field := append(varint(uint64(number)<<3|2), varint(uint64(len(value)))...)
+
+	return append(field, value...)
+}
+
+func stringField(number int, value string) []byte {
+	return bytesField(number, []byte(value))
+}
+
+func doubleField(number int, value float64) []byte {
+	field := varint(uint64(number)<<3 | 1)
+
+	var bits [8]byte
+	binary.LittleEndian.PutUint64(bits[:], math.Float64bits(value))
+
+	return append(field, bits[:]...)
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+// Helpers that encode descriptors
+
+func field(name string, number, label, kind int, typeName string) []byte {
+	return bytesField(2, concat(
+		stringField(1, name),
+		varintField(3, uint64(number)),
+		varintField(4, uint64(label)),
+		varintField(5, uint64(kind)),
+		stringField(6, typeName)))
+}
+
+func method(name, input string, clientStreaming, serverStreaming bool) []byte {
+	streaming := func(value bool) uint64 {
+		if value {
+			return 1
+		}
+
+		return 0
+	}
+
+	return bytesField(2, concat(
+		stringField(1, name),
+		stringField(2, input),
+		stringField(3, ".shop.Item"),
+		varintField(5, streaming(clientStreaming)),
+		varintField(6, streaming(serverStreaming))))
+}
+
+// descriptorSet describes the shop.Orders service
+func descriptorSet() []byte {
+	const optional, repeated = 1, 3
+
+	pricesEntry := bytesField(3, concat(
+		stringField(1, "PricesEntry"),
+		field("key", 1, optional, 9, ""),
+		field("value", 2, optional, 3, ""),
+		bytesField(7, varintField(7, 1))))
+
+	order := bytesField(4, concat(
+		stringField(1, "Order"),
+		field("id", 1, optional, 9, ""),
+		field("quantity", 2, optional, 5, ""),
+		field("tags", 3, repeated, 9, ""),
+		field("prices", 4, repeated, 11, ".shop.Order.PricesEntry"),
+		field("status", 5, optional, 14, ".shop.Status"),
+		field("item", 6, optional, 11, ".shop.Item"),
+		field("created_at", 7, optional, 11, ".google.protobuf.Timestamp"),
+		field("sizes", 8, repeated, 5, ""),
+		pricesEntry))
+
+	item := bytesField(4, concat(
+		stringField(1, "Item"),
+		field("name", 1, optional, 9, ""),
+		field("price", 2, optional, 1, "")))
+
+	status := bytesField(5, concat(
+		stringField(1, "Status"),
+		bytesField(2, concat(stringField(1, "UNKNOWN"), varintField(2, 0))),
+		bytesField(2, concat(stringField(1, "PAID"), varintField(2, 1)))))
+
+	service := bytesField(6, concat(
+		stringField(1, "Orders"),
+		method("Create", ".shop.Order", false, false),
+		method("Upload", ".shop.Order", true, false),
+		method("Chat", ".shop.Order", true, true)))
+
+	file := concat(
+		stringField(1, "shop.proto"),
+		stringField(2, "shop"),
+		order,
+		item,
+		status,
+		service)
+
+	return bytesField(1, file)
+}
+
+const orderSchema = `{
+	"type": "object",
+	"required": ["id", "quantity"],
+	"properties": {
+		"id": {"type": "string", "minLength": 3},
+		"quantity": {"type": "integer", "minimum": 1},
+		"tags": {"type": "array", "maxItems": 2},
+		"prices": {
+			"type": "object",
+			"additionalProperties": {"type": "string", "pattern": "^[0-9]+$"}
+		},
+		"status": {"enum": ["PAID"]},
+		"item": {
+			"type": "object",
+			"properties": {"price": {"type": "number", "exclusiveMinimum": 0}}
+		},
+		"createdAt": {"type": "string", "pattern": "^2020-01-01T"},
+		"sizes": {"type": "array", "items": {"type": "integer", "maximum": 50}}
+	}
+}`
+
+// frame prefixes a message with the gRPC compression flag and length
+func frame(message []byte) []byte {
+	prefix := make([]byte, 5)
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(message)))
+
+	return append(prefix, message...)
+}
+
+func compressedFrame(message []byte) []byte {
+	var buffer bytes.Buffer
+
+	writer := gzip.NewWriter(&buffer)
+	writer.Write(message)
+	writer.Close()
+
+	compressed := frame(buffer.Bytes())
+	compressed[0] = 1
+
+	return compressed
+}
+
+func validOrder() []byte {
+	return concat(
+		stringField(1, "order-1"),
+		varintField(2, 3),
+		stringField(3, "gift"),
+		bytesField(4, concat(stringField(1, "shirt"), varintField(2, 25))),
+		varintField(5, 1),
+		bytesField(6, concat(stringField(1, "shirt"), doubleField(2, 12.5))),
+		bytesField(7, concat(varintField(1, 1577880000), varintField(2, 5))),
+		bytesField(8, concat(varint(38), varint(40))))
+}
+
+func TestNewProtoValidator(t *testing.T) {
+	testCases := []struct {
+		description string
+		set         []byte
+		valid       bool
+	}{
+		{
+			"a descriptor set",
+			descriptorSet(),
+			true,
+		},
+		{
+			"a truncated descriptor set",
+			descriptorSet()[:20],
+			false,
+		},
+		{
+			"a descriptor set whose messages refer to missing types",
+			bytesField(1, concat(
+				stringField(2, "shop"),
+				bytesField(4, concat(
+					stringField(1, "Order"),
+					field("item", 1, 1, 11, ".shop.Missing"))))),
+			false,
+		},
+	}
+
+	t.Log("Given the need to test the creation of a ProtoValidator")
+	{
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When given %s", index, testCase.description)
+			{
+				_, err := protovalidator.NewProtoValidator(testCase.set, "draft-07")
+				if testCase.valid != (err == nil) {
+					t.Errorf("\t%s\tShould return an error only for invalid descriptor sets: %v", failed, err)
+				} else {
+					t.Logf("\t%s\tShould return an error only for invalid descriptor sets", succeed)
+				}
+			}
+		}
+	}
+}
+
+func TestLoadSchema(t *testing.T) {
+	testCases := []struct {
+		description string
+		path        string
+		method      string
+		valid       bool
+	}{
+		{"a unary method", "/shop.Orders/Create", "POST", true},
+		{"a client streaming method", "/shop.Orders/Upload", "POST", true},
+		{"a bidirectional streaming method", "/shop.Orders/Chat", "POST", false},
+		{"an unknown method", "/shop.Orders/Delete", "POST", false},
+		{"a method that is not called with POST", "/shop.Orders/Create", "GET", false},
+	}
+
+	t.Log("Given the need to test the loading of schemas of gRPC methods")
+	{
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When given %s", index, testCase.description)
+			{
+				validator, err := protovalidator.NewProtoValidator(descriptorSet(), "draft-07")
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to get a reference to a ProtoValidator: %v", failed, err)
+				}
+
+				err = validator.LoadSchema(testCase.path, testCase.method, []byte(orderSchema))
+				if testCase.valid != (err == nil) {
+					t.Errorf("\t%s\tShould load schemas only for the methods that can be validated: %v", failed, err)
+				} else {
+					t.Logf("\t%s\tShould load schemas only for the methods that can be validated", succeed)
+				}
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		description string
+		path        string
+		body        []byte
+		valid       bool
+	}{
+		{
+			"a valid message",
+			"/shop.Orders/Create",
+			frame(validOrder()),
+			true,
+		},
+		{
+			"a valid compressed message",
+			"/shop.Orders/Create",
+			compressedFrame(validOrder()),
+			true,
+		},
+		{
+			"a message without a required field",
+			"/shop.Orders/Create",
+			frame(stringField(1, "order-1")),
+			false,
+		},
+		{
+			"a message with a short string",
+			"/shop.Orders/Create",
+			frame(concat(validOrder(), stringField(1, "o"))),
+			false,
+		},
+		{
+			"a message with a negative int32",
+			"/shop.Orders/Create",
+			frame(concat(validOrder(), varintField(2, uint64(math.MaxUint64)))),
+			false,
+		},
+		{
+			"a message with too many repeated values",
+			"/shop.Orders/Create",
+			frame(concat(validOrder(), stringField(3, "a"), stringField(3, "b"))),
+			false,
+		},
+		{
+			"a message with an unknown enum value",
+			"/shop.Orders/Create",
+			frame(concat(validOrder(), varintField(5, 7))),
+			false,
+		},
+		{
+			"a message with an invalid nested message",
+			"/shop.Orders/Create",
+			frame(concat(validOrder(), bytesField(6, doubleField(2, -1)))),
+			false,
+		},
+		{
+			"a message with an invalid packed value",
+			"/shop.Orders/Create",
+			frame(concat(validOrder(), bytesField(8, varint(60)))),
+			false,
+		},
+		{
+			"a message with a timestamp that the schema does not allow",
+			"/shop.Orders/Create",
+			frame(concat(validOrder(), bytesField(7, varintField(1, 0)))),
+			false,
+		},
+		{
+			"a message with an unknown field",
+			"/shop.Orders/Create",
+			frame(concat(validOrder(), stringField(99, "ignored"))),
+			true,
+		},
+		{
+			"a message with a string that is not UTF-8",
+			"/shop.Orders/Create",
+			frame(concat(validOrder(), bytesField(3, []byte{0xff}))),
+			false,
+		},
+		{
+			"a truncated message",
+			"/shop.Orders/Create",
+			frame(validOrder()[:10]),
+			false,
+		},
+		{
+			"a unary request with two messages",
+			"/shop.Orders/Create",
+			concat(frame(validOrder()), frame(validOrder())),
+			false,
+		},
+		{
+			"a client stream of valid messages",
+			"/shop.Orders/Upload",
+			concat(frame(validOrder()), frame(validOrder())),
+			true,
+		},
+		{
+			"a client stream with an invalid message",
+			"/shop.Orders/Upload",
+			concat(frame(validOrder()), frame(stringField(1, "order-2"))),
+			false,
+		},
+	}
+
+	t.Log("Given the need to test the validation of gRPC messages")
+	{
+		validator, err := protovalidator.NewProtoValidator(descriptorSet(), "draft-07")
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to get a reference to a ProtoValidator: %v", failed, err)
+		}
+
+		for _, path := range []string{"/shop.Orders/Create", "/shop.Orders/Upload"} {
+			err = validator.LoadSchema(path, "POST", []byte(orderSchema))
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to load a schema: %v", failed, err)
+			}
+		}
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When given %s", index, testCase.description)
+			{
+				err := validator.Validate(testCase.path, "POST", testCase.body)
+				if testCase.valid {
+					if err != nil {
+						t.Errorf("\t%s\tShould be valid: %v", failed, err)
+					} else {
+						t.Logf("\t%s\tShould be valid", succeed)
+					}
+				} else {
+					if err == nil {
+						t.Errorf("\t%s\tShould not be valid", failed)
+					} else {
+						t.Logf("\t%s\tShould not be valid: %v", succeed, err)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package protovalidator
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// Protocol buffers wire types
+const (
+	wireVarint     = 0
+	wireFixed64    = 1
+	wireBytes      = 2
+	wireStartGroup = 3
+	wireEndGroup   = 4
+	wireFixed32    = 5
+)
+
+// errTruncated is returned when an encoded message ends in the middle of a
+// field
+var errTruncated = errors.New("the message is truncated")
+
+// wireField is a field of an encoded message. Varints and fixed numbers
+// are held in number, and length-delimited values in bytes.
+type wireField struct {
+	number   int32
+	wireType int
+	number64 uint64
+	bytes    []byte
+}
+
+// wireReader reads the fields of an encoded message
+type wireReader struct {
+	buffer []byte
+}
+
+// done returns true if all the fields were read
+func (wr *wireReader) done() bool {
+	return len(wr.buffer) == 0
+}
+
+// next reads the next field. The content of groups, which are deprecated,
+// is skipped.
+func (wr *wireReader) next() (wireField, error) {
+	key, err := wr.varint()
+	if err != nil {
+		return wireField{}, err
+	}
+
+	field := wireField{
+		number:   int32(key >> 3),
+		wireType: int(key & 7),
+	}
+
+	if field.number <= 0 {
+		return wireField{}, errors.New("the message has an invalid field number")
+	}
+
+	switch field.wireType {
+	case wireVarint:
+		field.number64, err = wr.varint()
+	case wireFixed64:
+		field.number64, err = wr.fixed(8)
+	case wireFixed32:
+		field.number64, err = wr.fixed(4)
+	case wireBytes:
+		field.bytes, err = wr.lengthDelimited()
+	case wireStartGroup:
+		err = wr.skipGroup(field.number)
+	default:
+		err = errors.New("the message has an invalid wire type")
+	}
+
+	return field, err
+}
+
+// varint reads a base 128 varint
+func (wr *wireReader) varint() (uint64, error) {
+	var value uint64
+
+	for shift := uint(0); shift < 64; shift += 7 {
+		if len(wr.buffer) == 0 {
+			return 0, errTruncated
+		}
+
+		b := wr.buffer[0]
+		wr.buffer = wr.buffer[1:]
+
+		value |= uint64(b&0x7f) << shift
+
+		if b < 0x80 {
+			return value, nil
+		}
+	}
+
+	return 0, errors.New("the message has a varint that overflows 64 bits")
+}
+
+// fixed reads a little-endian number of 4 or 8 bytes
+func (wr *wireReader) fixed(size int) (uint64, error) {
+	if len(wr.buffer) < size {
+		return 0, errTruncated
+	}
+
+	var value uint64
+	if size == 4 {
+		value = uint64(binary.LittleEndian.Uint32(wr.buffer))
+	} else {
+		value = binary.LittleEndian.Uint64(wr.buffer)
+	}
+
+	wr.buffer = wr.buffer[size:]
+
+	return value, nil
+}
+
+// lengthDelimited reads a length-delimited value
+func (wr *wireReader) lengthDelimited() ([]byte, error) {
+	length, err := wr.varint()
+	if err != nil {
+		return nil, err
+	}
+
+	if length > uint64(len(wr.buffer)) {
+		return nil, errTruncated
+	}
+
+	value := wr.buffer[:length]
+	wr.buffer = wr.buffer[length:]
+
+	return value, nil
+}
+
+// skipGroup skips the fields of a group up to the end of the group
+func (wr *wireReader) skipGroup(number int32) error {
+	for {
+		key, err := wr.varint()
+		if err != nil {
+			return err
+		}
+
+		fieldNumber, wireType := int32(key>>3), int(key&7)
+
+		switch wireType {
+		case wireVarint:
+			_, err = wr.varint()
+		case wireFixed64:
+			_, err = wr.fixed(8)
+		case wireFixed32:
+			_, err = wr.fixed(4)
+		case wireBytes:
+			_, err = wr.lengthDelimited()
+		case wireStartGroup:
+			err = wr.skipGroup(fieldNumber)
+		case wireEndGroup:
+			if fieldNumber != number {
+				return errors.New("the message has a mismatched group")
+			}
+
+			return nil
+		default:
+			return errors.New("the message has an invalid wire type")
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -1,5 +1,12 @@
 package validators
 
+// The locations of the parameters of a request
+const (
+	ParametersPath   = "path"
+	ParametersQuery  = "query"
+	ParametersHeader = "header"
+)
+
 type Validator interface {
 	// LoadSchema Gets a new schema and verifies that the schema is correct.
 	LoadSchema(path string, method string, schema []byte) error
@@ -7,3 +14,101 @@ type Validator interface {
 	// Validate enforces the schema's rules on a piece of data.
 	Validate(path string, method string, body []byte) error
 }
+
+// ParametersValidator is implemented by validators that can also validate
+// the parameters of a request (path variables, query parameters and headers).
+type ParametersValidator interface {
+	Validator
+
+	// LoadParametersSchema gets a schema that describes the parameters in
+	// a specific location of the request.
+	LoadParametersSchema(path string, method string, location string, schema []byte) error
+
+	// ValidateParameters enforces the schema's rules on the parameters in
+	// a specific location of the request.
+	ValidateParameters(path string, method string, location string, parameters map[string][]string) error
+}
+
+// ResponseValidator is implemented by validators that can also validate the
+// responses of the targets.
+type ResponseValidator interface {
+	Validator
+
+	// LoadResponseSchema gets a schema that describes the body of the
+	// responses with a specific status ("200", "2XX" or "default") and
+	// content type (any content type if it is empty).
+	LoadResponseSchema(path string, method string, status string, contentType string, schema []byte) error
+
+	// ValidateResponse enforces the rules of the schema that describes the
+	// response's status and content type on the response body.
+	ValidateResponse(path string, method string, status int, contentType string, body []byte) error
+}
+
+// FormValidator is implemented by validators that can also validate form
+// bodies (application/x-www-form-urlencoded and multipart/form-data).
+type FormValidator interface {
+	Validator
+
+	// ValidateForm enforces the rules of the schema of the request bodies
+	// on the fields of a form body. The fields of file uploads hold the
+	// names of their files.
+	ValidateForm(path string, method string, fields map[string][]string) error
+}
+
+// MessageValidator is implemented by validators that can also validate the
+// messages that clients send over upgraded (WebSocket) connections.
+type MessageValidator interface {
+	Validator
+
+	// LoadMessageSchema gets a schema that describes the messages of an
+	// endpoint.
+	LoadMessageSchema(path string, method string, schema []byte) error
+
+	// ValidateMessage enforces the rules of the schema of an endpoint's
+	// messages on a message.
+	ValidateMessage(path string, method string, message []byte) error
+}
+
+// ReferenceResolver is implemented by validators whose schemas can reference
+// other schemas.
+type ReferenceResolver interface {
+	// ResolveReferences makes sure that the references of the loaded
+	// schemas point to existing schemas. It is called once all the
+	// schemas were loaded.
+	ResolveReferences() error
+}
+
+// ValidationError is implemented by errors that describe why a piece of
+// data failed in validation.
+type ValidationError interface {
+	error
+
+	// Path returns the location of the invalid value inside the data.
+	Path() string
+
+	// SchemaPath returns the location of the rule inside the schema.
+	SchemaPath() string
+
+	// Keyword returns the schema rule that the value failed in.
+	Keyword() string
+
+	// Reason returns a description of the failure.
+	Reason() string
+}
+
+// OutputFormatter is implemented by validators that can describe the
+// result of a validation in a standard output format.
+type OutputFormatter interface {
+	// FormatOutput describes the error that was returned from Validate in
+	// the requested format.
+	FormatOutput(err error, format string) (interface{}, error)
+}
+
+// ValidationErrors is implemented by errors that hold several validation
+// failures.
+type ValidationErrors interface {
+	error
+
+	// Errors returns the failures.
+	Errors() []ValidationError
+}
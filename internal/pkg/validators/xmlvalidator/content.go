@@ -0,0 +1,373 @@
+package xmlvalidator
+
+import (
+	"encoding/xml"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// maxStates bounds the size of the automaton of a content model, since
+// occurrence bounds are expanded into copies of their particles
+const maxStates = 100000
+
+// automaton is a nondeterministic finite automaton that matches the
+// children of an element against a content model
+type automaton struct {
+	states []state
+	start  int
+	final  int
+}
+
+// state is a state of an automaton
+type state struct {
+	epsilon     []int
+	transitions []transition
+}
+
+// transition consumes a child element that an element declaration or a
+// wildcard matches
+type transition struct {
+	element  *elementDecl
+	wildcard *wildcard
+	to       int
+}
+
+// compileAutomaton builds the automaton of a content model
+func compileAutomaton(p *particle) (*automaton, error) {
+	a := &automaton{}
+
+	start, final, err := a.particle(p)
+	if err != nil {
+		return nil, err
+	}
+
+	a.start, a.final = start, final
+
+	return a, nil
+}
+
+// newState adds a state to the automaton
+func (a *automaton) newState() (int, error) {
+	if len(a.states) >= maxStates {
+		return 0, errors.New("the content model is too large")
+	}
+
+	a.states = append(a.states, state{})
+
+	return len(a.states) - 1, nil
+}
+
+// connect adds an epsilon transition between two states
+func (a *automaton) connect(from, to int) {
+	a.states[from].epsilon = append(a.states[from].epsilon, to)
+}
+
+// particle adds the states of a particle and of its occurrences, and
+// returns its start and final states
+func (a *automaton) particle(p *particle) (int, int, error) {
+	start, err := a.newState()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	end := start
+
+	// Required occurrences are chained
+	for index := 0; index < p.min; index++ {
+		termStart, termEnd, err := a.term(p)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		a.connect(end, termStart)
+		end = termEnd
+	}
+
+	if p.max == unbounded {
+		termStart, termEnd, err := a.term(p)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		final, err := a.newState()
+		if err != nil {
+			return 0, 0, err
+		}
+
+		a.connect(end, termStart)
+		a.connect(end, final)
+		a.connect(termEnd, termStart)
+		a.connect(termEnd, final)
+
+		return start, final, nil
+	}
+
+	// Optional occurrences may each be skipped to the final state
+	final, err := a.newState()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for index := p.min; index < p.max; index++ {
+		termStart, termEnd, err := a.term(p)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		a.connect(end, final)
+		a.connect(end, termStart)
+		end = termEnd
+	}
+
+	a.connect(end, final)
+
+	return start, final, nil
+}
+
+// term adds the states of a single occurrence of a particle
+func (a *automaton) term(p *particle) (int, int, error) {
+	start, err := a.newState()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	end, err := a.newState()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	switch {
+	case p.element != nil || p.wildcard != nil:
+		a.states[start].transitions = append(a.states[start].transitions, transition{
+			element:  p.element,
+			wildcard: p.wildcard,
+			to:       end,
+		})
+	case p.compositor == "choice":
+		for _, nested := range p.particles {
+			nestedStart, nestedEnd, err := a.particle(nested)
+			if err != nil {
+				return 0, 0, err
+			}
+
+			a.connect(start, nestedStart)
+			a.connect(nestedEnd, end)
+		}
+	case p.compositor == "all":
+		return 0, 0, errors.New("xs:all may only be the whole content of a type")
+	default:
+		current := start
+
+		for _, nested := range p.particles {
+			nestedStart, nestedEnd, err := a.particle(nested)
+			if err != nil {
+				return 0, 0, err
+			}
+
+			a.connect(current, nestedStart)
+			current = nestedEnd
+		}
+
+		a.connect(current, end)
+	}
+
+	return start, end, nil
+}
+
+// closure adds the states that are reachable from a set of states by
+// epsilon transitions to the set
+func (a *automaton) closure(states []int, seen []bool) []int {
+	for index := 0; index < len(states); index++ {
+		for _, next := range a.states[states[index]].epsilon {
+			if !seen[next] {
+				seen[next] = true
+				states = append(states, next)
+			}
+		}
+	}
+
+	return states
+}
+
+// match is the declaration or the wildcard that a child element matched
+type match struct {
+	element  *elementDecl
+	wildcard *wildcard
+}
+
+// contentError describes why the children of an element do not match a
+// content model: the position of the first child that did not match (the
+// number of children if children were missing) and the elements that were
+// expected instead.
+type contentError struct {
+	position int
+	expected []string
+}
+
+// match matches the children of an element, and returns what each child
+// matched
+func (a *automaton) match(children []*node) ([]match, *contentError) {
+	matches := make([]match, len(children))
+
+	seen := make([]bool, len(a.states))
+	seen[a.start] = true
+	current := a.closure([]int{a.start}, seen)
+
+	for position, child := range children {
+		seen = make([]bool, len(a.states))
+
+		var next []int
+
+		for _, from := range current {
+			for _, t := range a.states[from].transitions {
+				m, ok := t.matches(child.name)
+				if !ok || seen[t.to] {
+					continue
+				}
+
+				// Element declarations take precedence over wildcards
+				if matches[position].element == nil && (m.element != nil || matches[position].wildcard == nil) {
+					matches[position] = m
+				}
+
+				seen[t.to] = true
+				next = append(next, t.to)
+			}
+		}
+
+		if len(next) == 0 {
+			return nil, &contentError{position: position, expected: a.expected(current)}
+		}
+
+		current = a.closure(next, seen)
+	}
+
+	for _, s := range current {
+		if s == a.final {
+			return matches, nil
+		}
+	}
+
+	return nil, &contentError{position: len(children), expected: a.expected(current)}
+}
+
+// expected returns the names of the elements that a set of states expects
+func (a *automaton) expected(states []int) []string {
+	names := make(map[string]bool)
+
+	for _, s := range states {
+		for _, t := range a.states[s].transitions {
+			if t.wildcard != nil {
+				names["any element"] = true
+				continue
+			}
+
+			names[t.element.name.Local] = true
+
+			for name := range t.element.substitutes {
+				names[name.Local] = true
+			}
+		}
+	}
+
+	return sortedNames(names)
+}
+
+// matches returns the declaration or the wildcard that a transition
+// matches an element name with
+func (t transition) matches(name xml.Name) (match, bool) {
+	if t.wildcard != nil {
+		return match{wildcard: t.wildcard}, t.wildcard.allows(name.Space)
+	}
+
+	if t.element.name == name {
+		return match{element: t.element}, true
+	}
+
+	if substitute, ok := t.element.substitutes[name]; ok {
+		return match{element: substitute}, true
+	}
+
+	return match{}, false
+}
+
+// matchAll matches the children of an element against an xs:all group, in
+// which each element may occur at most once in any order
+func matchAll(group *particle, children []*node) ([]match, *contentError) {
+	if len(children) == 0 && group.min == 0 {
+		return nil, nil
+	}
+
+	matches := make([]match, len(children))
+	counts := make([]int, len(group.particles))
+
+	for position, child := range children {
+		matched := false
+
+		for index, p := range group.particles {
+			m, ok := transition{element: p.element, wildcard: p.wildcard}.matches(child.name)
+			if !ok {
+				continue
+			}
+
+			if p.max != unbounded && counts[index] >= p.max {
+				return nil, &contentError{position: position, expected: missingAll(group, counts)}
+			}
+
+			counts[index]++
+			matches[position] = m
+			matched = true
+
+			break
+		}
+
+		if !matched {
+			return nil, &contentError{position: position, expected: missingAll(group, counts)}
+		}
+	}
+
+	for index, p := range group.particles {
+		if counts[index] < p.min {
+			return nil, &contentError{position: len(children), expected: missingAll(group, counts)}
+		}
+	}
+
+	return matches, nil
+}
+
+// missingAll returns the names of the elements of an xs:all group that may
+// still occur
+func missingAll(group *particle, counts []int) []string {
+	names := make(map[string]bool)
+
+	for index, p := range group.particles {
+		if p.element != nil && (p.max == unbounded || counts[index] < p.max) {
+			names[p.element.name.Local] = true
+		}
+	}
+
+	return sortedNames(names)
+}
+
+// sortedNames returns the names in a set in order
+func sortedNames(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// describe formats the elements that were expected for error messages
+func (e *contentError) describe() string {
+	if len(e.expected) == 0 {
+		return "no more elements"
+	}
+
+	return strings.Join(quoteAll(e.expected), ", ")
+}
@@ -0,0 +1,514 @@
+package xmlvalidator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// Default bounds of parsed documents
+const (
+	defaultMaxDepth     = 256
+	defaultMaxExpansion = 1 << 20
+)
+
+// xmlNamespace is the namespace of the "xml" prefix
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// predefinedEntities are the entities that every document may reference
+var predefinedEntities = map[string]string{
+	"lt":   "<",
+	"gt":   ">",
+	"amp":  "&",
+	"apos": "'",
+	"quot": "\"",
+}
+
+// Limits bound the documents that are parsed, so a small document cannot
+// exhaust the memory of the gateway. A zero limit is replaced with its
+// default.
+type Limits struct {
+	// MaxDepth is the maximum nesting of elements (256 by default).
+	MaxDepth int
+
+	// MaxExpansion is the maximum number of bytes that the references to
+	// the entities that a document declares may expand to (1MB by
+	// default).
+	MaxExpansion int
+}
+
+// node is an element of a parsed xml document
+type node struct {
+	name xml.Name
+
+	// attributes are the attributes of the element, without its namespace
+	// declarations, which are held in namespaces by their prefixes ("" for
+	// the default namespace).
+	attributes []xml.Attr
+	namespaces map[string]string
+
+	parent   *node
+	children []*node
+
+	// text is the character data that the element holds directly, and
+	// hasText is true if some of it is not white space.
+	text    string
+	hasText bool
+}
+
+// namespace returns the namespace that a prefix is bound to in the scope of
+// the element
+func (n *node) namespace(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return xmlNamespace, true
+	}
+
+	for current := n; current != nil; current = current.parent {
+		if namespace, ok := current.namespaces[prefix]; ok {
+			return namespace, true
+		}
+	}
+
+	// Names without a prefix are in no namespace unless a default
+	// namespace was declared
+	return "", prefix == ""
+}
+
+// attribute returns the value of an attribute of the element
+func (n *node) attribute(space, local string) (string, bool) {
+	for _, attr := range n.attributes {
+		if attr.Name.Space == space && attr.Name.Local == local {
+			return attr.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// resolveQName resolves a qualified name ("tns:Order") in the scope of the
+// element
+func (n *node) resolveQName(qname string) (xml.Name, error) {
+	qname = strings.TrimSpace(qname)
+
+	prefix, local := "", qname
+	if index := strings.IndexByte(qname, ':'); index >= 0 {
+		prefix, local = qname[:index], qname[index+1:]
+	}
+
+	namespace, ok := n.namespace(prefix)
+	if !ok || local == "" {
+		return xml.Name{}, errors.New("could not resolve the qualified name \"" + qname + "\"")
+	}
+
+	return xml.Name{Space: namespace, Local: local}, nil
+}
+
+// path returns the XPath-style location of the element in its document.
+// The position of an element is added when it has siblings of the same
+// name.
+func (n *node) path() string {
+	if n == nil {
+		return ""
+	}
+
+	step := "/" + n.name.Local
+
+	if n.parent != nil {
+		position, count := 0, 0
+
+		for _, sibling := range n.parent.children {
+			if sibling.name == n.name {
+				count++
+
+				if sibling == n {
+					position = count
+				}
+			}
+		}
+
+		if count > 1 {
+			step += "[" + strconv.Itoa(position) + "]"
+		}
+	}
+
+	return n.parent.path() + step
+}
+
+// parseDocument parses an xml document into a tree of elements.
+// Document type declarations may only declare internal entities, whose
+// references are expanded within the limits, and external entities are
+// refused, so parsing a document never reads other resources.
+func parseDocument(data []byte, limits Limits) (*node, error) {
+	if limits.MaxDepth <= 0 {
+		limits.MaxDepth = defaultMaxDepth
+	}
+
+	if limits.MaxExpansion <= 0 {
+		limits.MaxExpansion = defaultMaxExpansion
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = true
+
+	var (
+		root    *node
+		current *node
+		text    []byte
+		depth   int
+		texts   [][]byte
+	)
+
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, "could not parse xml document")
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if current == nil && root != nil {
+				return nil, errors.New("could not parse xml document: the document has more than one root element")
+			}
+
+			depth++
+			if depth > limits.MaxDepth {
+				return nil, errors.New("could not parse xml document: the document is nested more than " +
+					strconv.Itoa(limits.MaxDepth) + " levels deep")
+			}
+
+			n := &node{name: t.Name, parent: current}
+
+			for _, attr := range t.Attr {
+				switch {
+				case attr.Name.Space == "xmlns":
+					n.declare(attr.Name.Local, attr.Value)
+				case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+					n.declare("", attr.Value)
+				default:
+					n.attributes = append(n.attributes, attr)
+				}
+			}
+
+			if current == nil {
+				root = n
+			} else {
+				current.children = append(current.children, n)
+				texts = append(texts, text)
+			}
+
+			current = n
+			text = nil
+		case xml.EndElement:
+			current.text = string(text)
+			current.hasText = len(bytes.TrimSpace(text)) > 0
+			current = current.parent
+			depth--
+
+			if current != nil {
+				text = texts[len(texts)-1]
+				texts = texts[:len(texts)-1]
+			}
+		case xml.CharData:
+			if current == nil {
+				if len(bytes.TrimSpace(t)) > 0 {
+					return nil, errors.New("could not parse xml document: text outside of the root element")
+				}
+
+				continue
+			}
+
+			text = append(text, t...)
+		case xml.Directive:
+			if root != nil || decoder.Entity != nil {
+				return nil, errors.New("could not parse xml document: unexpected declaration")
+			}
+
+			entities, err := parseDoctype(t, data, limits.MaxExpansion)
+			if err != nil {
+				return nil, errors.Wrap(err, "could not parse xml document")
+			}
+
+			decoder.Entity = entities
+		}
+	}
+
+	if root == nil {
+		return nil, errors.New("could not parse xml document: the document has no root element")
+	}
+
+	return root, nil
+}
+
+// declare binds a prefix to a namespace in the scope of an element
+func (n *node) declare(prefix, namespace string) {
+	if n.namespaces == nil {
+		n.namespaces = make(map[string]string)
+	}
+
+	n.namespaces[prefix] = namespace
+}
+
+// parseDoctype reads the internal entities that a document type declaration
+// declares and expands their values. The references to the entities in the
+// document may not expand to more than maxExpansion bytes.
+func parseDoctype(directive, document []byte, maxExpansion int) (map[string]string, error) {
+	if !bytes.HasPrefix(directive, []byte("DOCTYPE")) {
+		return nil, errors.New("unexpected declaration")
+	}
+
+	entities := make(map[string]string)
+
+	start := bytes.IndexByte(directive, '[')
+	end := bytes.LastIndexByte(directive, ']')
+
+	// The external subset of a declaration is never read
+	if start < 0 || end < start {
+		return entities, nil
+	}
+
+	raw, err := parseInternalSubset(string(directive[start+1 : end]))
+	if err != nil {
+		return nil, err
+	}
+
+	for name := range raw {
+		value, err := expandEntity(name, raw, make(map[string]bool), maxExpansion)
+		if err != nil {
+			return nil, err
+		}
+
+		entities[name] = value
+	}
+
+	// Each reference inserts the whole value of the entity
+	expansion := 0
+	for name, value := range entities {
+		references := bytes.Count(document, []byte("&"+name+";"))
+		if len(value) > 0 && references > (maxExpansion-expansion)/len(value) {
+			return nil, errors.New("the references to entities expand to more than " +
+				strconv.Itoa(maxExpansion) + " bytes")
+		}
+
+		expansion += references * len(value)
+	}
+
+	return entities, nil
+}
+
+// parseInternalSubset returns the raw values of the general entities that
+// the internal subset of a document type declaration declares
+func parseInternalSubset(subset string) (map[string]string, error) {
+	entities := make(map[string]string)
+
+	for position := 0; position < len(subset); {
+		rest := subset[position:]
+
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest, "-->")
+			if end < 0 {
+				return nil, errors.New("unterminated comment in document type declaration")
+			}
+
+			position += end + 3
+		case strings.HasPrefix(rest, "<?"):
+			end := strings.Index(rest, "?>")
+			if end < 0 {
+				return nil, errors.New("unterminated processing instruction in document type declaration")
+			}
+
+			position += end + 2
+		case strings.HasPrefix(rest, "<!ENTITY"):
+			name, value, length, err := parseEntityDeclaration(rest)
+			if err != nil {
+				return nil, err
+			}
+
+			// The first declaration of an entity is binding
+			if _, ok := entities[name]; !ok {
+				entities[name] = value
+			}
+
+			position += length
+		case strings.HasPrefix(rest, "<!"):
+			length, err := skipMarkupDeclaration(rest)
+			if err != nil {
+				return nil, err
+			}
+
+			position += length
+		case rest[0] == '%':
+			return nil, errors.New("parameter entities are not allowed")
+		case rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\r' || rest[0] == '\n':
+			position++
+		default:
+			return nil, errors.New("invalid document type declaration")
+		}
+	}
+
+	return entities, nil
+}
+
+// parseEntityDeclaration parses an entity declaration and returns the
+// entity's name, its raw value and the length of the declaration
+func parseEntityDeclaration(declaration string) (string, string, int, error) {
+	position := len("<!ENTITY")
+	position += len(declaration[position:]) - len(strings.TrimLeft(declaration[position:], " \t\r\n"))
+
+	if strings.HasPrefix(declaration[position:], "%") {
+		return "", "", 0, errors.New("parameter entities are not allowed")
+	}
+
+	nameStart := position
+	for position < len(declaration) && !strings.ContainsRune(" \t\r\n\"'>", rune(declaration[position])) {
+		position++
+	}
+
+	name := declaration[nameStart:position]
+	if name == "" {
+		return "", "", 0, errors.New("invalid entity declaration")
+	}
+
+	position += len(declaration[position:]) - len(strings.TrimLeft(declaration[position:], " \t\r\n"))
+
+	if position >= len(declaration) || (declaration[position] != '"' && declaration[position] != '\'') {
+		return "", "", 0, errors.New("the external entity \"" + name + "\" is not allowed")
+	}
+
+	quote := declaration[position]
+
+	end := strings.IndexByte(declaration[position+1:], quote)
+	if end < 0 {
+		return "", "", 0, errors.New("unterminated value of entity \"" + name + "\"")
+	}
+
+	value := declaration[position+1 : position+1+end]
+	position += end + 2
+
+	close := strings.IndexByte(declaration[position:], '>')
+	if close < 0 || strings.TrimSpace(declaration[position:position+close]) != "" {
+		return "", "", 0, errors.New("invalid declaration of entity \"" + name + "\"")
+	}
+
+	return name, value, position + close + 1, nil
+}
+
+// skipMarkupDeclaration returns the length of a markup declaration
+// (<!ELEMENT>, <!ATTLIST> or <!NOTATION>), whose quoted strings may hold
+// ">"
+func skipMarkupDeclaration(declaration string) (int, error) {
+	var quote byte
+
+	for position := 2; position < len(declaration); position++ {
+		c := declaration[position]
+
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '%':
+			return 0, errors.New("parameter entities are not allowed")
+		case c == '>':
+			return position + 1, nil
+		}
+	}
+
+	return 0, errors.New("unterminated markup declaration")
+}
+
+// expandEntity expands the character and entity references in the value of
+// an entity. Values may not hold markup.
+func expandEntity(name string, raw map[string]string, expanding map[string]bool, maxExpansion int) (string, error) {
+	if expanding[name] {
+		return "", errors.New("the entity \"" + name + "\" references itself")
+	}
+
+	expanding[name] = true
+	defer delete(expanding, name)
+
+	value := raw[name]
+
+	if strings.ContainsRune(value, '<') {
+		return "", errors.New("the value of entity \"" + name + "\" holds markup, which is not supported")
+	}
+
+	var builder strings.Builder
+
+	for len(value) > 0 {
+		index := strings.IndexByte(value, '&')
+		if index < 0 {
+			builder.WriteString(value)
+			break
+		}
+
+		builder.WriteString(value[:index])
+		value = value[index:]
+
+		end := strings.IndexByte(value, ';')
+		if end < 0 {
+			return "", errors.New("invalid reference in the value of entity \"" + name + "\"")
+		}
+
+		reference := value[1:end]
+		value = value[end+1:]
+
+		switch {
+		case strings.HasPrefix(reference, "#"):
+			r, err := characterReference(reference[1:])
+			if err != nil {
+				return "", err
+			}
+
+			builder.WriteRune(r)
+		case predefinedEntities[reference] != "":
+			builder.WriteString(predefinedEntities[reference])
+		default:
+			if _, ok := raw[reference]; !ok {
+				return "", errors.New("the entity \"" + reference + "\" is not declared")
+			}
+
+			expanded, err := expandEntity(reference, raw, expanding, maxExpansion)
+			if err != nil {
+				return "", err
+			}
+
+			builder.WriteString(expanded)
+		}
+
+		if builder.Len() > maxExpansion {
+			return "", errors.New("the entity \"" + name + "\" expands to more than " +
+				strconv.Itoa(maxExpansion) + " bytes")
+		}
+	}
+
+	return builder.String(), nil
+}
+
+// characterReference returns the character of a character reference
+// ("#65" or "#x41", without the "&#" and the ";")
+func characterReference(reference string) (rune, error) {
+	base := 10
+	if strings.HasPrefix(reference, "x") {
+		base = 16
+		reference = reference[1:]
+	}
+
+	value, err := strconv.ParseUint(reference, base, 32)
+	if err != nil || !utf8.ValidRune(rune(value)) {
+		return 0, errors.New("invalid character reference \"&#" + reference + ";\"")
+	}
+
+	return rune(value), nil
+}
@@ -0,0 +1,86 @@
+package xmlvalidator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apidome/gateway/internal/pkg/validators"
+)
+
+// DocumentValidationError describes why an xml document failed in
+// validation against an xml schema
+type DocumentValidationError struct {
+	path       string
+	schemaPath string
+	keyword    string
+	reason     string
+}
+
+func (e DocumentValidationError) Error() string {
+	return fmt.Sprintf("validation failed in path %s: \"%s\" validation failed, reason: %s",
+		e.Path(), e.keyword, e.reason)
+}
+
+// Path returns the XPath-style location of the invalid node in the
+// document ("/Envelope/Body/Order/item[2]/@sku").
+func (e DocumentValidationError) Path() string {
+	if e.path == "" {
+		return "/"
+	}
+
+	return e.path
+}
+
+// SchemaPath returns the name of the schema component that the node
+// failed in ("{urn:shop}OrderType"), if it has one.
+func (e DocumentValidationError) SchemaPath() string {
+	return e.schemaPath
+}
+
+// Keyword returns the schema rule that the node failed in, like a facet
+// ("maxLength") or a content rule ("content").
+func (e DocumentValidationError) Keyword() string {
+	return e.keyword
+}
+
+// Reason returns the reason of the validation failure.
+func (e DocumentValidationError) Reason() string {
+	return e.reason
+}
+
+// ValidationErrors is a list of the failures that were found in a
+// document.
+type ValidationErrors []DocumentValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for index, err := range e {
+		messages[index] = err.Error()
+	}
+
+	return fmt.Sprintf("%d validation failures: %s", len(e), strings.Join(messages, "; "))
+}
+
+// Errors returns the failures in the list.
+func (e ValidationErrors) Errors() []validators.ValidationError {
+	errs := make([]validators.ValidationError, len(e))
+	for index, err := range e {
+		errs[index] = err
+	}
+
+	return errs
+}
+
+// result returns the list as an error: nil if it is empty, the first
+// failure if the validation is not exhaustive or if the list holds a single
+// failure, and the whole list otherwise.
+func (e ValidationErrors) result(exhaustive bool) error {
+	switch {
+	case len(e) == 0:
+		return nil
+	case len(e) == 1 || !exhaustive:
+		return e[0]
+	default:
+		return e
+	}
+}
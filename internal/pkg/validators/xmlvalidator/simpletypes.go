@@ -0,0 +1,665 @@
+package xmlvalidator
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// xsdNamespace is the namespace of xml schemas and of their built-in types
+const xsdNamespace = "http://www.w3.org/2001/XMLSchema"
+
+// Varieties of simple types
+const (
+	varietyAtomic = iota
+	varietyList
+	varietyUnion
+)
+
+// Values of the whiteSpace facet
+const (
+	whiteSpacePreserve = iota
+	whiteSpaceReplace
+	whiteSpaceCollapse
+)
+
+// facets are the constraining facets that a restriction of a simple type
+// declares
+type facets struct {
+	enumeration []string
+	patterns    []*regexp.Regexp
+
+	// patternSources are the patterns as the schema wrote them
+	patternSources []string
+
+	length    *int
+	minLength *int
+	maxLength *int
+
+	minInclusive *string
+	maxInclusive *string
+	minExclusive *string
+	maxExclusive *string
+
+	totalDigits    *int
+	fractionDigits *int
+}
+
+// simpleType is a simple type definition: an atomic type that restricts a
+// primitive type, a list of items of a simple type, or a union of simple
+// types
+type simpleType struct {
+	name    xml.Name
+	variety int
+
+	// primitive is the name of the built-in primitive type that an atomic
+	// type restricts, which defines its lexical space.
+	primitive string
+
+	base        *simpleType
+	facets      facets
+	whiteSpace  int
+	itemType    *simpleType
+	memberTypes []*simpleType
+}
+
+// primitiveSyntax holds the lexical spaces of the primitive types that are
+// described by regular expressions
+var primitiveSyntax = map[string]*regexp.Regexp{
+	"boolean":    regexp.MustCompile(`^(true|false|1|0)$`),
+	"decimal":    regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)$`),
+	"float":      regexp.MustCompile(`^([+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?|[+-]?INF|NaN)$`),
+	"double":     regexp.MustCompile(`^([+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?|[+-]?INF|NaN)$`),
+	"duration":   regexp.MustCompile(`^-?P((\d+Y)?(\d+M)?(\d+D)?)(T(\d+H)?(\d+M)?(\d+(\.\d+)?S)?)?$`),
+	"dateTime":   regexp.MustCompile(`^-?\d{4,}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?` + timezone + `$`),
+	"time":       regexp.MustCompile(`^\d{2}:\d{2}:\d{2}(\.\d+)?` + timezone + `$`),
+	"date":       regexp.MustCompile(`^-?\d{4,}-\d{2}-\d{2}` + timezone + `$`),
+	"gYearMonth": regexp.MustCompile(`^-?\d{4,}-\d{2}` + timezone + `$`),
+	"gYear":      regexp.MustCompile(`^-?\d{4,}` + timezone + `$`),
+	"gMonthDay":  regexp.MustCompile(`^--\d{2}-\d{2}` + timezone + `$`),
+	"gDay":       regexp.MustCompile(`^---\d{2}` + timezone + `$`),
+	"gMonth":     regexp.MustCompile(`^--\d{2}` + timezone + `$`),
+	"hexBinary":  regexp.MustCompile(`^([0-9a-fA-F]{2})*$`),
+	"QName":      regexp.MustCompile(`^(` + ncName + `:)?` + ncName + `$`),
+	"NOTATION":   regexp.MustCompile(`^(` + ncName + `:)?` + ncName + `$`),
+}
+
+// Parts of the lexical spaces of primitive types
+const (
+	timezone = `(Z|[+-]\d{2}:\d{2})?`
+	ncName   = `[\p{L}_][\p{L}\p{N}\p{M}._\-]*`
+)
+
+// builtInType describes a built-in type that is derived from another
+// built-in type
+type builtInType struct {
+	name       string
+	base       string
+	whiteSpace int
+	pattern    string
+	min        string
+	max        string
+	list       bool
+}
+
+// derivedBuiltInTypes are the built-in types that are derived from the
+// primitive types, in the order of their derivation
+var derivedBuiltInTypes = []builtInType{
+	{name: "normalizedString", base: "string", whiteSpace: whiteSpaceReplace},
+	{name: "token", base: "normalizedString", whiteSpace: whiteSpaceCollapse},
+	{name: "language", base: "token", whiteSpace: whiteSpaceCollapse, pattern: `[a-zA-Z]{1,8}(-[a-zA-Z0-9]{1,8})*`},
+	{name: "NMTOKEN", base: "token", whiteSpace: whiteSpaceCollapse, pattern: `[\p{L}\p{N}\p{M}._:\-]+`},
+	{name: "Name", base: "token", whiteSpace: whiteSpaceCollapse, pattern: `[\p{L}_:][\p{L}\p{N}\p{M}._:\-]*`},
+	{name: "NCName", base: "Name", whiteSpace: whiteSpaceCollapse, pattern: ncName},
+	{name: "ID", base: "NCName", whiteSpace: whiteSpaceCollapse},
+	{name: "IDREF", base: "NCName", whiteSpace: whiteSpaceCollapse},
+	{name: "ENTITY", base: "NCName", whiteSpace: whiteSpaceCollapse},
+	{name: "NMTOKENS", base: "NMTOKEN", list: true},
+	{name: "IDREFS", base: "IDREF", list: true},
+	{name: "ENTITIES", base: "ENTITY", list: true},
+	{name: "integer", base: "decimal", whiteSpace: whiteSpaceCollapse, pattern: `[\-+]?[0-9]+`},
+	{name: "nonPositiveInteger", base: "integer", whiteSpace: whiteSpaceCollapse, max: "0"},
+	{name: "negativeInteger", base: "nonPositiveInteger", whiteSpace: whiteSpaceCollapse, max: "-1"},
+	{name: "long", base: "integer", whiteSpace: whiteSpaceCollapse, min: "-9223372036854775808", max: "9223372036854775807"},
+	{name: "int", base: "long", whiteSpace: whiteSpaceCollapse, min: "-2147483648", max: "2147483647"},
+	{name: "short", base: "int", whiteSpace: whiteSpaceCollapse, min: "-32768", max: "32767"},
+	{name: "byte", base: "short", whiteSpace: whiteSpaceCollapse, min: "-128", max: "127"},
+	{name: "nonNegativeInteger", base: "integer", whiteSpace: whiteSpaceCollapse, min: "0"},
+	{name: "unsignedLong", base: "nonNegativeInteger", whiteSpace: whiteSpaceCollapse, max: "18446744073709551615"},
+	{name: "unsignedInt", base: "unsignedLong", whiteSpace: whiteSpaceCollapse, max: "4294967295"},
+	{name: "unsignedShort", base: "unsignedInt", whiteSpace: whiteSpaceCollapse, max: "65535"},
+	{name: "unsignedByte", base: "unsignedShort", whiteSpace: whiteSpaceCollapse, max: "255"},
+	{name: "positiveInteger", base: "nonNegativeInteger", whiteSpace: whiteSpaceCollapse, min: "1"},
+}
+
+// primitiveTypes are the names of the primitive built-in types
+var primitiveTypes = []string{
+	"string", "boolean", "decimal", "float", "double", "duration", "dateTime", "time", "date",
+	"gYearMonth", "gYear", "gMonthDay", "gDay", "gMonth", "hexBinary", "base64Binary", "anyURI",
+	"QName", "NOTATION",
+}
+
+// builtInSimpleTypes returns the built-in simple types by their names
+func builtInSimpleTypes() map[xml.Name]*simpleType {
+	types := make(map[xml.Name]*simpleType)
+
+	anySimpleType := &simpleType{name: xml.Name{Space: xsdNamespace, Local: "anySimpleType"}}
+	types[anySimpleType.name] = anySimpleType
+
+	for _, name := range primitiveTypes {
+		t := &simpleType{
+			name:       xml.Name{Space: xsdNamespace, Local: name},
+			primitive:  name,
+			base:       anySimpleType,
+			whiteSpace: whiteSpaceCollapse,
+		}
+
+		if name == "string" {
+			t.whiteSpace = whiteSpacePreserve
+		}
+
+		types[t.name] = t
+	}
+
+	one := 1
+
+	for _, builtIn := range derivedBuiltInTypes {
+		base := types[xml.Name{Space: xsdNamespace, Local: builtIn.base}]
+		name := xml.Name{Space: xsdNamespace, Local: builtIn.name}
+
+		if builtIn.list {
+			types[name] = &simpleType{
+				name:       name,
+				variety:    varietyList,
+				base:       anySimpleType,
+				whiteSpace: whiteSpaceCollapse,
+				itemType:   base,
+				facets:     facets{minLength: &one},
+			}
+
+			continue
+		}
+
+		t := &simpleType{
+			name:       name,
+			primitive:  base.primitive,
+			base:       base,
+			whiteSpace: builtIn.whiteSpace,
+		}
+
+		if builtIn.pattern != "" {
+			t.facets.patterns = []*regexp.Regexp{regexp.MustCompile(`^(?:` + builtIn.pattern + `)$`)}
+			t.facets.patternSources = []string{builtIn.pattern}
+		}
+
+		if builtIn.min != "" {
+			min := builtIn.min
+			t.facets.minInclusive = &min
+		}
+
+		if builtIn.max != "" {
+			max := builtIn.max
+			t.facets.maxInclusive = &max
+		}
+
+		if builtIn.name == "integer" {
+			zero := 0
+			t.facets.fractionDigits = &zero
+		}
+
+		types[name] = t
+	}
+
+	return types
+}
+
+// validate validates a value against the type, and returns the keyword and
+// the reason of the failure, or empty strings if the value is valid
+func (t *simpleType) validate(value string) (string, string) {
+	value = normalizeWhiteSpace(value, t.whiteSpace)
+
+	itemCount := 0
+
+	switch t.variety {
+	case varietyList:
+		items := strings.Fields(value)
+		itemCount = len(items)
+
+		for _, item := range items {
+			if keyword, reason := t.itemType.validate(item); keyword != "" {
+				return keyword, "item \"" + item + "\": " + reason
+			}
+		}
+	case varietyUnion:
+		valid := false
+
+		for _, member := range t.memberTypes {
+			if keyword, _ := member.validate(value); keyword == "" {
+				valid = true
+				break
+			}
+		}
+
+		if !valid {
+			return "type", "the value \"" + value + "\" matches none of the member types of the union"
+		}
+	default:
+		if t.primitive != "" && !validPrimitive(t.primitive, value) {
+			return "type", "the value \"" + value + "\" is not a valid " + t.primitive
+		}
+	}
+
+	for current := t; current != nil; current = current.base {
+		if keyword, reason := current.checkFacets(t, value, itemCount); keyword != "" {
+			return keyword, reason
+		}
+	}
+
+	return "", ""
+}
+
+// checkFacets validates a value against the facets of a restriction step
+// of a type
+func (current *simpleType) checkFacets(t *simpleType, value string, itemCount int) (string, string) {
+	f := current.facets
+
+	if len(f.patterns) > 0 {
+		matched := false
+
+		for _, pattern := range f.patterns {
+			if pattern.MatchString(value) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return "pattern", "the value \"" + value + "\" does not match the pattern " +
+				strconv.Quote(strings.Join(f.patternSources, "|"))
+		}
+	}
+
+	if len(f.enumeration) > 0 {
+		matched := false
+
+		for _, option := range f.enumeration {
+			if t.equal(value, option) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return "enumeration", "the value \"" + value + "\" is not one of " + strings.Join(quoteAll(f.enumeration), ", ")
+		}
+	}
+
+	if f.length != nil || f.minLength != nil || f.maxLength != nil {
+		length := t.length(value, itemCount)
+
+		switch {
+		case f.length != nil && length != *f.length:
+			return "length", "the length of the value is " + strconv.Itoa(length) + ", expected " + strconv.Itoa(*f.length)
+		case f.minLength != nil && length < *f.minLength:
+			return "minLength", "the length of the value is " + strconv.Itoa(length) +
+				", shorter than the minimum of " + strconv.Itoa(*f.minLength)
+		case f.maxLength != nil && length > *f.maxLength:
+			return "maxLength", "the length of the value is " + strconv.Itoa(length) +
+				", longer than the maximum of " + strconv.Itoa(*f.maxLength)
+		}
+	}
+
+	bounds := []struct {
+		keyword string
+		bound   *string
+		valid   func(int) bool
+		reason  string
+	}{
+		{"minInclusive", f.minInclusive, func(c int) bool { return c >= 0 }, "less than"},
+		{"maxInclusive", f.maxInclusive, func(c int) bool { return c <= 0 }, "greater than"},
+		{"minExclusive", f.minExclusive, func(c int) bool { return c > 0 }, "less than or equal to"},
+		{"maxExclusive", f.maxExclusive, func(c int) bool { return c < 0 }, "greater than or equal to"},
+	}
+
+	for _, b := range bounds {
+		if b.bound == nil {
+			continue
+		}
+
+		comparison, ok := t.compare(value, *b.bound)
+		if ok && !b.valid(comparison) {
+			return b.keyword, "the value " + value + " is " + b.reason + " " + *b.bound
+		}
+	}
+
+	if f.totalDigits != nil || f.fractionDigits != nil {
+		total, fraction := digits(value)
+
+		switch {
+		case f.totalDigits != nil && total > *f.totalDigits:
+			return "totalDigits", "the value " + value + " has more than " + strconv.Itoa(*f.totalDigits) + " digits"
+		case f.fractionDigits != nil && fraction > *f.fractionDigits:
+			return "fractionDigits", "the value " + value + " has more than " +
+				strconv.Itoa(*f.fractionDigits) + " fraction digits"
+		}
+	}
+
+	return "", ""
+}
+
+// length returns the length of a value in the units of its type: items of
+// lists, octets of binary types and characters of the other types
+func (t *simpleType) length(value string, itemCount int) int {
+	switch {
+	case t.variety == varietyList:
+		return itemCount
+	case t.primitive == "hexBinary":
+		return len(value) / 2
+	case t.primitive == "base64Binary":
+		data, _ := base64.StdEncoding.DecodeString(removeWhiteSpace(value))
+		return len(data)
+	default:
+		return utf8.RuneCountInString(value)
+	}
+}
+
+// equal returns true if two values of the type are equal, comparing
+// numbers by their values
+func (t *simpleType) equal(a, b string) bool {
+	if comparison, ok := t.compare(a, b); ok {
+		return comparison == 0
+	}
+
+	return a == normalizeWhiteSpace(b, t.whiteSpace)
+}
+
+// compare compares two values of an ordered type. It returns false if the
+// values cannot be compared.
+func (t *simpleType) compare(a, b string) (int, bool) {
+	switch t.primitive {
+	case "decimal":
+		x, okX := new(big.Rat).SetString(a)
+		y, okY := new(big.Rat).SetString(b)
+
+		if !okX || !okY {
+			return 0, false
+		}
+
+		return x.Cmp(y), true
+	case "float", "double":
+		x, errX := strconv.ParseFloat(strings.Replace(a, "INF", "Inf", 1), 64)
+		y, errY := strconv.ParseFloat(strings.Replace(b, "INF", "Inf", 1), 64)
+
+		if errX != nil || errY != nil || x != x || y != y {
+			return 0, false
+		}
+
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		default:
+			return 0, true
+		}
+	case "dateTime", "date", "time":
+		x, okX := parseTemporal(t.primitive, a)
+		y, okY := parseTemporal(t.primitive, b)
+
+		if !okX || !okY {
+			return 0, false
+		}
+
+		switch {
+		case x.Before(y):
+			return -1, true
+		case x.After(y):
+			return 1, true
+		default:
+			return 0, true
+		}
+	default:
+		return 0, false
+	}
+}
+
+// temporalLayouts are the layouts of the values of the date and time types,
+// with and without a timezone
+var temporalLayouts = map[string][]string{
+	"dateTime": {"2006-01-02T15:04:05.999999999Z07:00", "2006-01-02T15:04:05.999999999"},
+	"date":     {"2006-01-02Z07:00", "2006-01-02"},
+	"time":     {"15:04:05.999999999Z07:00", "15:04:05.999999999"},
+}
+
+// parseTemporal parses a value of a date or time type. Values with years
+// beyond 9999 are not parsed.
+func parseTemporal(primitive, value string) (time.Time, bool) {
+	for _, layout := range temporalLayouts[primitive] {
+		parsed, err := time.Parse(layout, value)
+		if err == nil {
+			return parsed, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// validPrimitive returns true if a value is in the lexical space of a
+// primitive type
+func validPrimitive(primitive, value string) bool {
+	switch primitive {
+	case "string", "anyURI":
+		return true
+	case "base64Binary":
+		_, err := base64.StdEncoding.DecodeString(removeWhiteSpace(value))
+		return err == nil
+	case "hexBinary":
+		_, err := hex.DecodeString(value)
+		return err == nil
+	case "duration":
+		return primitiveSyntax[primitive].MatchString(value) &&
+			!strings.HasSuffix(value, "P") && !strings.HasSuffix(value, "T")
+	case "dateTime", "date", "time":
+		return primitiveSyntax[primitive].MatchString(value) && validTemporal(primitive, value)
+	default:
+		return primitiveSyntax[primitive].MatchString(value)
+	}
+}
+
+// validTemporal checks the ranges of the components of a value of a date
+// or time type, whose syntax is valid
+func validTemporal(primitive, value string) bool {
+	rest := value
+
+	if primitive != "time" {
+		rest = strings.TrimPrefix(rest, "-")
+		dash := strings.IndexByte(rest, '-')
+
+		// The remainder of a year by 400 depends on its last 4 digits only
+		year, _ := strconv.Atoi(rest[dash-4 : dash])
+		month, _ := strconv.Atoi(rest[dash+1 : dash+3])
+		day, _ := strconv.Atoi(rest[dash+4 : dash+6])
+
+		if month < 1 || month > 12 || day < 1 || day > daysIn(month, year) {
+			return false
+		}
+
+		rest = rest[dash+6:]
+
+		if primitive == "date" {
+			return validTimezone(rest)
+		}
+
+		rest = rest[1:]
+	}
+
+	hour, _ := strconv.Atoi(rest[0:2])
+	minute, _ := strconv.Atoi(rest[3:5])
+	second, _ := strconv.Atoi(rest[6:8])
+	rest = rest[8:]
+
+	fraction := ""
+	if strings.HasPrefix(rest, ".") {
+		end := 1
+		for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+			end++
+		}
+
+		fraction, rest = rest[1:end], rest[end:]
+	}
+
+	// 24:00:00 is the end of a day
+	if hour == 24 && (minute != 0 || second != 0 || strings.Trim(fraction, "0") != "") {
+		return false
+	}
+
+	return hour <= 24 && minute <= 59 && second <= 59 && validTimezone(rest)
+}
+
+// validTimezone checks the range of a timezone ("Z", "+02:00" or none)
+func validTimezone(zone string) bool {
+	if zone == "" || zone == "Z" {
+		return true
+	}
+
+	hours, _ := strconv.Atoi(zone[1:3])
+	minutes, _ := strconv.Atoi(zone[4:6])
+
+	return minutes <= 59 && (hours < 14 || (hours == 14 && minutes == 0))
+}
+
+// daysIn returns the number of days in a month of a year
+func daysIn(month, year int) int {
+	switch month {
+	case 2:
+		if year%4 == 0 && (year%100 != 0 || year%400 == 0) {
+			return 29
+		}
+
+		return 28
+	case 4, 6, 9, 11:
+		return 30
+	default:
+		return 31
+	}
+}
+
+// digits returns the number of digits and of fraction digits of a decimal
+// value, without leading and trailing zeros
+func digits(value string) (int, int) {
+	value = strings.TrimLeft(value, "+-")
+
+	integer, fraction := value, ""
+	if index := strings.IndexByte(value, '.'); index >= 0 {
+		integer, fraction = value[:index], value[index+1:]
+	}
+
+	integer = strings.TrimLeft(integer, "0")
+	fraction = strings.TrimRight(fraction, "0")
+
+	return len(integer) + len(fraction), len(fraction)
+}
+
+// normalizeWhiteSpace normalizes the white space of a value by a whiteSpace
+// facet
+func normalizeWhiteSpace(value string, whiteSpace int) string {
+	switch whiteSpace {
+	case whiteSpaceReplace:
+		return strings.Map(func(r rune) rune {
+			if r == '\t' || r == '\n' || r == '\r' {
+				return ' '
+			}
+
+			return r
+		}, value)
+	case whiteSpaceCollapse:
+		return strings.Join(strings.Fields(value), " ")
+	default:
+		return value
+	}
+}
+
+// removeWhiteSpace removes all the white space of a value
+func removeWhiteSpace(value string) string {
+	return strings.Join(strings.Fields(value), "")
+}
+
+// quoteAll quotes a list of values
+func quoteAll(values []string) []string {
+	quoted := make([]string, len(values))
+	for index, value := range values {
+		quoted[index] = strconv.Quote(value)
+	}
+
+	return quoted
+}
+
+// compilePattern translates a regular expression of xml schemas to a go
+// regular expression. The expressions of xml schemas match whole values,
+// "^" and "$" are not anchors, and "\i" and "\c" are the initial and name
+// characters of xml names.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	var builder strings.Builder
+
+	inClass := false
+
+	for index := 0; index < len(pattern); index++ {
+		c := pattern[index]
+
+		switch {
+		case c == '\\' && index+1 < len(pattern):
+			index++
+			escaped := pattern[index]
+
+			classes := map[byte][2]string{
+				'i': {`\p{L}_:`, `[\p{L}_:]`},
+				'I': {``, `[^\p{L}_:]`},
+				'c': {`\p{L}\p{N}\p{M}._:\-`, `[\p{L}\p{N}\p{M}._:\-]`},
+				'C': {``, `[^\p{L}\p{N}\p{M}._:\-]`},
+				'd': {`\p{Nd}`, `\p{Nd}`},
+			}
+
+			class, ok := classes[escaped]
+
+			switch {
+			case ok && inClass && class[0] == "":
+				return nil, errors.New("unsupported escape \\" + string(escaped) + " in a character class")
+			case ok && inClass:
+				builder.WriteString(class[0])
+			case ok:
+				builder.WriteString(class[1])
+			default:
+				builder.WriteByte('\\')
+				builder.WriteByte(escaped)
+			}
+		case c == '[' && !inClass:
+			inClass = true
+			builder.WriteByte(c)
+		case c == '[' && inClass:
+			return nil, errors.New("character class subtraction is not supported")
+		case c == ']' && inClass:
+			inClass = false
+			builder.WriteByte(c)
+		case (c == '^' || c == '$') && !inClass:
+			builder.WriteByte('\\')
+			builder.WriteByte(c)
+		default:
+			builder.WriteByte(c)
+		}
+	}
+
+	compiled, err := regexp.Compile(`^(?:` + builder.String() + `)$`)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid pattern "+strconv.Quote(pattern))
+	}
+
+	return compiled, nil
+}
@@ -0,0 +1,383 @@
+package xmlvalidator
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// validation validates a document against the components of a schema
+type validation struct {
+	schema *schemaSet
+	errs   ValidationErrors
+}
+
+// Namespaces of SOAP 1.1 and SOAP 1.2 envelopes
+var soapNamespaces = map[string]bool{
+	"http://schemas.xmlsoap.org/soap/envelope/": true,
+	"http://www.w3.org/2003/05/soap-envelope":   true,
+}
+
+// validateDocument returns the failures of a document. SOAP envelopes that
+// the schema does not declare are unwrapped: the entries of their bodies
+// are validated instead.
+func validateDocument(s *schemaSet, root *node) ValidationErrors {
+	v := &validation{schema: s}
+
+	if _, ok := s.elements[root.name]; !ok && root.name.Local == "Envelope" && soapNamespaces[root.name.Space] {
+		v.validateEnvelope(root)
+		return v.errs
+	}
+
+	v.validateRoot(root)
+
+	return v.errs
+}
+
+// validateRoot validates an element against the global element declaration
+// of its name
+func (v *validation) validateRoot(n *node) {
+	decl, ok := v.schema.elements[n.name]
+	if !ok {
+		v.report(n.path(), "", "element", "no global element declaration matches %s", formatName(n.name))
+		return
+	}
+
+	v.validateElement(n, decl)
+}
+
+// validateEnvelope validates the entries of the body of a SOAP envelope
+func (v *validation) validateEnvelope(envelope *node) {
+	for _, child := range envelope.children {
+		if child.name == (xml.Name{Space: envelope.name.Space, Local: "Body"}) {
+			for _, entry := range child.children {
+				v.validateRoot(entry)
+			}
+
+			return
+		}
+	}
+
+	v.report(envelope.path(), "", "content", "the SOAP envelope has no body")
+}
+
+// report adds a failure at a location of the document
+func (v *validation) report(path, schemaPath, keyword, format string, args ...interface{}) {
+	v.errs = append(v.errs, DocumentValidationError{
+		path:       path,
+		schemaPath: schemaPath,
+		keyword:    keyword,
+		reason:     fmt.Sprintf(format, args...),
+	})
+}
+
+// validateElement validates an element against its declaration
+func (v *validation) validateElement(n *node, decl *elementDecl) {
+	schemaPath := formatName(decl.name)
+
+	if decl.abstract {
+		v.report(n.path(), schemaPath, "abstract", "the element %s is abstract", n.name.Local)
+		return
+	}
+
+	t := decl.typ
+
+	if qname, ok := n.attribute(xsiNamespace, "type"); ok {
+		instanceType, reason := v.instanceType(n, qname, t)
+		if reason != "" {
+			v.report(n.path()+"/@type", schemaPath, "type", "%s", reason)
+			return
+		}
+
+		t = instanceType
+	}
+
+	if value, ok := n.attribute(xsiNamespace, "nil"); ok && isTrue(value) {
+		if !decl.nillable {
+			v.report(n.path()+"/@nil", schemaPath, "nillable", "the element %s is not nillable", n.name.Local)
+			return
+		}
+
+		if len(n.children) > 0 || n.hasText {
+			v.report(n.path(), schemaPath, "nillable", "the nil element %s should be empty", n.name.Local)
+		}
+
+		if complex, ok := t.(*complexType); ok {
+			v.validateAttributes(n, complex)
+		}
+
+		return
+	}
+
+	switch typed := t.(type) {
+	case *simpleType:
+		for _, attr := range n.attributes {
+			if attr.Name.Space != xsiNamespace {
+				v.report(n.path()+"/@"+attr.Name.Local, schemaPath, "attribute",
+					"the attribute %s is not allowed", formatName(attr.Name))
+			}
+		}
+
+		if len(n.children) > 0 {
+			v.report(n.path(), schemaPath, "content", "the element %s may not hold elements", n.name.Local)
+			return
+		}
+
+		v.validateText(n, decl, typed)
+	case *complexType:
+		if typed.name.Local != "" && typed.name != decl.name {
+			schemaPath = formatName(typed.name)
+		}
+
+		v.validateComplex(n, decl, typed, schemaPath)
+	}
+}
+
+// instanceType returns the type that an xsi:type attribute names, or the
+// reason why it cannot replace the declared type
+func (v *validation) instanceType(n *node, qname string, declared interface{}) (interface{}, string) {
+	name, err := n.resolveQName(qname)
+	if err != nil {
+		return nil, err.Error()
+	}
+
+	var t interface{}
+
+	if simple, ok := v.schema.simpleTypes[name]; ok {
+		t = simple
+	} else if complex, ok := v.schema.complexTypes[name]; ok {
+		t = complex
+	} else {
+		return nil, "unknown type " + formatName(name)
+	}
+
+	if !derivesFrom(t, declared) {
+		return nil, "the type " + formatName(name) + " is not derived from the declared type"
+	}
+
+	return t, ""
+}
+
+// derivesFrom returns true if a type is a base type or is derived from it
+func derivesFrom(t, base interface{}) bool {
+	if complex, ok := base.(*complexType); ok && complex.anyType {
+		return true
+	}
+
+	for current := t; current != nil; {
+		if current == base {
+			return true
+		}
+
+		switch typed := current.(type) {
+		case *complexType:
+			if typed.base == nil {
+				return false
+			}
+
+			current = typed.base
+		case *simpleType:
+			if typed.base == nil {
+				return false
+			}
+
+			current = typed.base
+		default:
+			return false
+		}
+	}
+
+	return false
+}
+
+// validateText validates the text of an element of a simple type or with
+// simple content
+func (v *validation) validateText(n *node, decl *elementDecl, t *simpleType) {
+	schemaPath := formatName(decl.name)
+
+	value := n.text
+	if value == "" && decl.defaultValue != nil {
+		value = *decl.defaultValue
+	}
+
+	if value == "" && decl.fixed != nil {
+		value = *decl.fixed
+	}
+
+	if keyword, reason := t.validate(value); keyword != "" {
+		v.report(n.path(), schemaPath, keyword, "%s", reason)
+		return
+	}
+
+	if decl.fixed != nil && !t.equal(normalizeWhiteSpace(value, t.whiteSpace), normalizeWhiteSpace(*decl.fixed, t.whiteSpace)) {
+		v.report(n.path(), schemaPath, "fixed", "the value \"%s\" should be \"%s\"", value, *decl.fixed)
+	}
+}
+
+// validateComplex validates an element of a complex type
+func (v *validation) validateComplex(n *node, decl *elementDecl, t *complexType, schemaPath string) {
+	if t.anyType {
+		v.validateLax(n.children)
+		return
+	}
+
+	v.validateAttributes(n, t)
+
+	if t.simpleContent != nil {
+		if len(n.children) > 0 {
+			v.report(n.path(), schemaPath, "content", "the element %s may not hold elements", n.name.Local)
+			return
+		}
+
+		v.validateText(n, decl, t.simpleContent)
+
+		return
+	}
+
+	if !t.mixed && n.hasText {
+		v.report(n.path(), schemaPath, "content", "the element %s may not hold text", n.name.Local)
+	}
+
+	if t.content == nil {
+		if len(n.children) > 0 {
+			v.report(n.path(), schemaPath, "content", "the element %s should be empty", n.name.Local)
+		}
+
+		return
+	}
+
+	var matches []match
+	var failure *contentError
+
+	if t.content.compositor == "all" {
+		matches, failure = matchAll(t.content, n.children)
+	} else {
+		matches, failure = t.automaton.match(n.children)
+	}
+
+	if failure != nil {
+		if failure.position < len(n.children) {
+			child := n.children[failure.position]
+			v.report(child.path(), schemaPath, "content", "unexpected element %s, expected %s",
+				formatName(child.name), failure.describe())
+		} else {
+			v.report(n.path(), schemaPath, "content", "the element %s is incomplete, expected %s",
+				n.name.Local, failure.describe())
+		}
+
+		return
+	}
+
+	for index, child := range n.children {
+		m := matches[index]
+
+		switch {
+		case m.element != nil:
+			v.validateElement(child, m.element)
+		case m.wildcard != nil:
+			v.validateWildcard(child, m.wildcard, schemaPath)
+		}
+	}
+}
+
+// validateWildcard validates an element that a wildcard matched by the
+// wildcard's processContents
+func (v *validation) validateWildcard(n *node, w *wildcard, schemaPath string) {
+	switch w.processContents {
+	case "skip":
+		return
+	case "lax":
+		v.validateLax([]*node{n})
+	default:
+		decl, ok := v.schema.elements[n.name]
+		if !ok {
+			v.report(n.path(), schemaPath, "element", "no global element declaration matches %s", formatName(n.name))
+			return
+		}
+
+		v.validateElement(n, decl)
+	}
+}
+
+// validateLax validates the elements that have global declarations, and
+// the children of those that do not
+func (v *validation) validateLax(nodes []*node) {
+	for _, n := range nodes {
+		if decl, ok := v.schema.elements[n.name]; ok {
+			v.validateElement(n, decl)
+			continue
+		}
+
+		v.validateLax(n.children)
+	}
+}
+
+// validateAttributes validates the attributes of an element of a complex
+// type
+func (v *validation) validateAttributes(n *node, t *complexType) {
+	schemaPath := formatName(t.name)
+
+	for _, attr := range n.attributes {
+		if attr.Name.Space == xsiNamespace {
+			continue
+		}
+
+		path := n.path() + "/@" + attr.Name.Local
+
+		if use, ok := t.attributes[attr.Name]; ok && !use.prohibited {
+			v.validateAttribute(path, schemaPath, use.decl, use.fixed, attr.Value)
+			continue
+		}
+
+		if t.anyAttribute != nil && t.anyAttribute.allows(attr.Name.Space) {
+			decl, ok := v.schema.attributes[attr.Name]
+
+			switch {
+			case t.anyAttribute.processContents == "skip":
+			case ok:
+				v.validateAttribute(path, schemaPath, decl, decl.fixed, attr.Value)
+			case t.anyAttribute.processContents != "lax":
+				v.report(path, schemaPath, "attribute", "no global attribute declaration matches %s",
+					formatName(attr.Name))
+			}
+
+			continue
+		}
+
+		v.report(path, schemaPath, "attribute", "the attribute %s is not allowed", formatName(attr.Name))
+	}
+
+	var missing []string
+
+	for name, use := range t.attributes {
+		if _, ok := n.attribute(name.Space, name.Local); use.required && !ok {
+			missing = append(missing, name.Local)
+		}
+	}
+
+	sort.Strings(missing)
+
+	for _, name := range missing {
+		v.report(n.path(), schemaPath, "required", "the attribute %s is required", name)
+	}
+}
+
+// validateAttribute validates the value of an attribute
+func (v *validation) validateAttribute(path, schemaPath string, decl *attributeDecl, fixed *string, value string) {
+	if keyword, reason := decl.typ.validate(value); keyword != "" {
+		v.report(path, schemaPath, keyword, "%s", reason)
+		return
+	}
+
+	t := decl.typ
+	if fixed != nil && !t.equal(normalizeWhiteSpace(value, t.whiteSpace), normalizeWhiteSpace(*fixed, t.whiteSpace)) {
+		v.report(path, schemaPath, "fixed", "the value \"%s\" should be \"%s\"", value, *fixed)
+	}
+}
+
+// isTrue returns true if an xs:boolean value is true
+func isTrue(value string) bool {
+	value = strings.TrimSpace(value)
+	return value == "true" || value == "1"
+}
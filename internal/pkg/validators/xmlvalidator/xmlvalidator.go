@@ -0,0 +1,99 @@
+package xmlvalidator
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// XMLValidator is a struct that implements the Validator interface and
+// validates xml (and SOAP) bodies against XSD schemas.
+// Bodies are parsed safely: they may not reference external entities, and
+// their depth and entity expansion are bounded by limits.
+type XMLValidator struct {
+	schemaDict map[string]map[string]*schemaSet
+	exhaustive bool
+	limits     Limits
+	sources    *schemaSources
+
+	// mutex guards the dictionary, so schemas can be loaded while
+	// requests are validated.
+	mutex *sync.RWMutex
+}
+
+// NewXMLValidator returns a new instance of XMLValidator.
+func NewXMLValidator() *XMLValidator {
+	return &XMLValidator{
+		schemaDict: make(map[string]map[string]*schemaSet),
+		mutex:      &sync.RWMutex{},
+	}
+}
+
+// SetExhaustive determines whether Validate should stop at the first failure
+// or collect all the failures in a document.
+func (xv *XMLValidator) SetExhaustive(exhaustive bool) {
+	xv.exhaustive = exhaustive
+}
+
+// SetLimits bounds the depth and the entity expansion of the documents that
+// are validated.
+func (xv *XMLValidator) SetLimits(limits Limits) {
+	xv.limits = limits
+}
+
+// SetSchemaSources tells the validator where to find the schema documents
+// that schemas include and import: directories are searched, in order, for
+// the files of relative schema locations, and mappings maps schema
+// locations or namespaces to files, or location prefixes that end with "/"
+// to directories.
+// It should be called before the schemas are loaded.
+func (xv *XMLValidator) SetSchemaSources(directories []string, mappings map[string]string) {
+	xv.sources = &schemaSources{directories, mappings}
+}
+
+// LoadSchema gets the XSD schema of an endpoint, loads the schema documents
+// that it includes and imports, and verifies that it is correct.
+func (xv *XMLValidator) LoadSchema(path string, method string, rawSchema []byte) error {
+	s, err := newSchemaSet(rawSchema, xv.sources)
+	if err != nil {
+		return errors.Wrap(err, "could not load schema to path "+path)
+	}
+
+	xv.mutex.Lock()
+	defer xv.mutex.Unlock()
+
+	if xv.schemaDict[path] == nil {
+		xv.schemaDict[path] = make(map[string]*schemaSet)
+	}
+
+	xv.schemaDict[path][method] = s
+
+	return nil
+}
+
+// Validate parses an xml body and validates it against the schema of the
+// endpoint. The root element of the body should match a global element
+// declaration of the schema, unless it is a SOAP envelope that the schema
+// does not declare, whose body entries should match them instead.
+func (xv *XMLValidator) Validate(path string, method string, body []byte) error {
+	xv.mutex.RLock()
+	schemas, isPathExist := xv.schemaDict[path]
+	s, isMethodExist := schemas[method]
+	xv.mutex.RUnlock()
+
+	if !isPathExist {
+		return errors.New("could not validate request: unknown path \"" + path + "\"")
+	}
+
+	if !isMethodExist {
+		return errors.New("could not validate to path " + path +
+			": no schema exist for method \"" + method + "\"")
+	}
+
+	root, err := parseDocument(body, xv.limits)
+	if err != nil {
+		return errors.Wrap(err, "could not parse xml document")
+	}
+
+	return validateDocument(s, root).result(xv.exhaustive)
+}
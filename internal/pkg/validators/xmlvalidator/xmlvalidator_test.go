@@ -0,0 +1,481 @@
+package xmlvalidator_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/apidome/gateway/internal/pkg/validators"
+	"github.com/apidome/gateway/internal/pkg/validators/xmlvalidator"
+)
+
+const succeed = "V"
+const failed = "X"
+
+const shopSchema = `<?xml version="1.0" encoding="UTF-8"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"
+           xmlns:tns="urn:shop"
+           xmlns:c="urn:common"
+           targetNamespace="urn:shop"
+           elementFormDefault="qualified">
+  <xs:import namespace="urn:common" schemaLocation="common.xsd"/>
+  <xs:include schemaLocation="address.xsd"/>
+
+  <xs:element name="order" type="tns:OrderType"/>
+  <xs:element name="note" type="xs:string"/>
+  <xs:element name="payment" type="tns:PaymentType" abstract="true"/>
+  <xs:element name="card" type="tns:CardPayment" substitutionGroup="tns:payment"/>
+  <xs:element name="cash" substitutionGroup="tns:payment"/>
+
+  <xs:complexType name="OrderType">
+    <xs:sequence>
+      <xs:element name="customer" type="xs:string"/>
+      <xs:element name="item" type="tns:ItemType" maxOccurs="unbounded"/>
+      <xs:choice minOccurs="0">
+        <xs:element name="pickup" type="xs:boolean"/>
+        <xs:element name="address" type="tns:Address"/>
+      </xs:choice>
+      <xs:element ref="tns:payment" minOccurs="0"/>
+      <xs:element name="total" type="c:Money" minOccurs="0"/>
+      <xs:element name="comment" type="xs:string" nillable="true" minOccurs="0"/>
+      <xs:any namespace="##other" processContents="lax" minOccurs="0" maxOccurs="unbounded"/>
+    </xs:sequence>
+    <xs:attribute name="id" type="xs:ID" use="required"/>
+    <xs:attribute name="created" type="xs:dateTime"/>
+    <xs:attribute name="version" type="xs:int" fixed="2"/>
+    <xs:attributeGroup ref="tns:Tracking"/>
+  </xs:complexType>
+
+  <xs:attributeGroup name="Tracking">
+    <xs:attribute name="channel">
+      <xs:simpleType>
+        <xs:restriction base="xs:token">
+          <xs:enumeration value="web"/>
+          <xs:enumeration value="phone"/>
+        </xs:restriction>
+      </xs:simpleType>
+    </xs:attribute>
+  </xs:attributeGroup>
+
+  <xs:complexType name="ItemType">
+    <xs:all>
+      <xs:element name="sku" type="tns:Sku"/>
+      <xs:element name="quantity" type="tns:Quantity"/>
+      <xs:element name="tags" type="tns:Tags" minOccurs="0"/>
+    </xs:all>
+  </xs:complexType>
+
+  <xs:simpleType name="Sku">
+    <xs:restriction base="xs:string">
+      <xs:pattern value="[A-Z]{2}-\d{4}"/>
+    </xs:restriction>
+  </xs:simpleType>
+
+  <xs:simpleType name="Quantity">
+    <xs:restriction base="xs:positiveInteger">
+      <xs:maxInclusive value="100"/>
+    </xs:restriction>
+  </xs:simpleType>
+
+  <xs:simpleType name="Tags">
+    <xs:restriction>
+      <xs:simpleType>
+        <xs:list itemType="xs:NCName"/>
+      </xs:simpleType>
+      <xs:maxLength value="3"/>
+    </xs:restriction>
+  </xs:simpleType>
+
+  <xs:complexType name="PaymentType">
+    <xs:sequence>
+      <xs:element name="amount" type="xs:decimal"/>
+    </xs:sequence>
+  </xs:complexType>
+
+  <xs:complexType name="CardPayment">
+    <xs:complexContent>
+      <xs:extension base="tns:PaymentType">
+        <xs:sequence>
+          <xs:element name="number">
+            <xs:simpleType>
+              <xs:restriction base="xs:string">
+                <xs:length value="16"/>
+              </xs:restriction>
+            </xs:simpleType>
+          </xs:element>
+        </xs:sequence>
+      </xs:extension>
+    </xs:complexContent>
+  </xs:complexType>
+</xs:schema>`
+
+// order returns an order document with the given content after its
+// customer and items
+func order(attributes, content string) string {
+	return `<?xml version="1.0"?>
+<order xmlns="urn:shop" id="o-1"` + attributes + `>
+  <customer>Ada</customer>
+  <item><sku>AB-1234</sku><quantity>2</quantity></item>
+  ` + content + `
+</order>`
+}
+
+// loadValidator returns a validator of the shop schema
+func loadValidator(t *testing.T) *xmlvalidator.XMLValidator {
+	validator := xmlvalidator.NewXMLValidator()
+	validator.SetSchemaSources([]string{"testdata"}, nil)
+
+	err := validator.LoadSchema("/orders", "POST", []byte(shopSchema))
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to load a schema: %v", failed, err)
+	}
+
+	return validator
+}
+
+// failureOf returns the keyword and the path of the validation failure of
+// an error, or empty strings if it is not a validation failure
+func failureOf(err error) (string, string) {
+	validationErr, ok := err.(validators.ValidationError)
+	if !ok {
+		return "", ""
+	}
+
+	return validationErr.Keyword(), validationErr.Path()
+}
+
+func TestLoadSchema(t *testing.T) {
+	const header = `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:tns="urn:t" targetNamespace="urn:t">`
+
+	testCases := []struct {
+		description string
+		schema      string
+		valid       bool
+	}{
+		{"a schema", shopSchema, true},
+		{
+			"a schema with recursive types",
+			header + `<xs:element name="node" type="tns:Node"/><xs:complexType name="Node"><xs:sequence>` +
+				`<xs:element ref="tns:node" minOccurs="0" maxOccurs="unbounded"/></xs:sequence></xs:complexType></xs:schema>`,
+			true,
+		},
+		{
+			"a schema with a type whose content derives from it",
+			header + `<xs:element name="node" type="tns:Node"/><xs:complexType name="Node"><xs:sequence>` +
+				`<xs:element name="child" type="tns:Leaf" minOccurs="0"/></xs:sequence>` +
+				`<xs:attribute name="id"/></xs:complexType>` +
+				`<xs:complexType name="Leaf"><xs:complexContent><xs:extension base="tns:Node">` +
+				`<xs:attribute name="weight" type="xs:int"/></xs:extension></xs:complexContent></xs:complexType></xs:schema>`,
+			true,
+		},
+		{"a document that is not a schema", `<schema/>`, false},
+		{"a schema that is not well-formed", header + `<xs:element name="a">`, false},
+		{"a schema with an unknown type", header + `<xs:element name="a" type="tns:Missing"/></xs:schema>`, false},
+		{
+			"a schema with a duplicate type",
+			header + `<xs:simpleType name="A"><xs:restriction base="xs:string"/></xs:simpleType>` +
+				`<xs:complexType name="A"/></xs:schema>`,
+			false,
+		},
+		{
+			"a schema with a circular derivation",
+			header + `<xs:complexType name="A"><xs:complexContent><xs:extension base="tns:B"/></xs:complexContent></xs:complexType>` +
+				`<xs:complexType name="B"><xs:complexContent><xs:extension base="tns:A"/></xs:complexContent></xs:complexType></xs:schema>`,
+			false,
+		},
+		{
+			"a schema with an invalid pattern",
+			header + `<xs:simpleType name="A"><xs:restriction base="xs:string"><xs:pattern value="[a-z"/></xs:restriction></xs:simpleType></xs:schema>`,
+			false,
+		},
+		{
+			"a schema with an invalid occurrence",
+			header + `<xs:complexType name="A"><xs:sequence><xs:element name="a" minOccurs="2" maxOccurs="1"/></xs:sequence></xs:complexType></xs:schema>`,
+			false,
+		},
+		{"a schema that includes a missing document", header + `<xs:include schemaLocation="missing.xsd"/></xs:schema>`, false},
+		{
+			"a schema with a content model that is too large",
+			header + `<xs:complexType name="A"><xs:sequence minOccurs="1000" maxOccurs="1000"><xs:sequence minOccurs="1000" maxOccurs="1000">` +
+				`<xs:element name="a"/></xs:sequence></xs:sequence></xs:complexType></xs:schema>`,
+			false,
+		},
+	}
+
+	t.Log("Given the need to test the loading of XSD schemas")
+	{
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When given %s", index, testCase.description)
+			{
+				validator := xmlvalidator.NewXMLValidator()
+				validator.SetSchemaSources([]string{"testdata"}, nil)
+
+				err := validator.LoadSchema("/orders", "POST", []byte(testCase.schema))
+				if testCase.valid != (err == nil) {
+					t.Errorf("\t%s\tShould return an error only for invalid schemas: %v", failed, err)
+				} else {
+					t.Logf("\t%s\tShould return an error only for invalid schemas", succeed)
+				}
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		description string
+		body        string
+		keyword     string
+		path        string
+		valid       bool
+	}{
+		{"an order", order("", ""), "", "", true},
+		{
+			"an order with every optional element",
+			order(` created="2020-02-29T10:00:00Z" version="2" channel="phone"`,
+				`<item><tags>gift fragile</tags><quantity>1</quantity><sku>CD-0001</sku></item>
+				<address><street>Main</street><zip>12345</zip></address>
+				<card><amount>10.5</amount><number>1234567812345678</number></card>
+				<total currency="EUR">12.50</total>
+				<comment xsi:nil="true" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"/>
+				<ext:trace xmlns:ext="urn:ext">anything</ext:trace>`),
+			"",
+			"",
+			true,
+		},
+		{"an order with a substitute payment", order("", `<cash><amount>5</amount></cash>`), "", "", true},
+		{
+			"an order with entities and comments",
+			`<!DOCTYPE order [<!ENTITY who "Ada">]><order xmlns="urn:shop" id="o-1"><!-- c --><customer>&who;</customer>` +
+				`<item><sku>AB-1234</sku><quantity>2</quantity></item></order>`,
+			"",
+			"",
+			true,
+		},
+		{
+			"a SOAP envelope",
+			`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Header/><soap:Body>` +
+				`<order xmlns="urn:shop" id="o-1"><customer>Ada</customer><item><sku>AB-1234</sku><quantity>2</quantity></item></order>` +
+				`</soap:Body></soap:Envelope>`,
+			"",
+			"",
+			true,
+		},
+		{
+			"a SOAP envelope with an invalid body entry",
+			`<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope"><soap:Body>` +
+				`<order xmlns="urn:shop" id="o-1"><customer>Ada</customer><item><sku>AB-1234</sku><quantity>2</quantity><tags>1</tags></item></order>` +
+				`</soap:Body></soap:Envelope>`,
+			"pattern",
+			"/Envelope/Body/order/item/tags",
+			false,
+		},
+		{
+			"a SOAP envelope without a body",
+			`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"/>`,
+			"content",
+			"/Envelope",
+			false,
+		},
+		{"an unknown root element", `<invoice xmlns="urn:shop"/>`, "element", "/invoice", false},
+		{"a root element in another namespace", `<order id="o-1"/>`, "element", "/order", false},
+		{
+			"a missing required attribute",
+			`<order xmlns="urn:shop"><customer>Ada</customer><item><sku>AB-1234</sku><quantity>2</quantity></item></order>`,
+			"required",
+			"/order",
+			false,
+		},
+		{"an unknown attribute", order(` color="red"`, ""), "attribute", "/order/@color", false},
+		{"an invalid attribute value", order(` created="2020-02-30T10:00:00Z"`, ""), "type", "/order/@created", false},
+		{"a different fixed attribute value", order(` version="3"`, ""), "fixed", "/order/@version", false},
+		{"an attribute value out of an enumeration", order(` channel="mail"`, ""), "enumeration", "/order/@channel", false},
+		{"an unexpected element", order("", `<gift/>`), "content", "/order/gift", false},
+		{
+			"a missing element",
+			`<order xmlns="urn:shop" id="o-1"><customer>Ada</customer></order>`,
+			"content",
+			"/order",
+			false,
+		},
+		{"elements out of order", order("", `<total currency="EUR">1</total><card/>`), "content", "/order/card", false},
+		{
+			"text in an element content",
+			`<order xmlns="urn:shop" id="o-1">hello<customer>Ada</customer><item><sku>AB-1234</sku><quantity>2</quantity></item></order>`,
+			"content",
+			"/order",
+			false,
+		},
+		{"an invalid pattern", order("", `<item><sku>ab</sku><quantity>1</quantity></item>`), "pattern", "/order/item[2]/sku", false},
+		{"a value above a maximum", order("", `<item><sku>AB-1234</sku><quantity>101</quantity></item>`), "maxInclusive", "/order/item[2]/quantity", false},
+		{"a non-positive integer", order("", `<item><sku>AB-1234</sku><quantity>0</quantity></item>`), "minInclusive", "/order/item[2]/quantity", false},
+		{"a list that is too long", order("", `<item><sku>AB-1234</sku><quantity>1</quantity><tags>a b c d</tags></item>`), "maxLength", "/order/item[2]/tags", false},
+		{"a duplicate element of an all group", order("", `<item><sku>AB-1234</sku><sku>AB-1234</sku></item>`), "content", "/order/item[2]/sku[2]", false},
+		{"an incomplete all group", order("", `<item><sku>AB-1234</sku></item>`), "content", "/order/item[2]", false},
+		{"an abstract element", order("", `<payment><amount>1</amount></payment>`), "abstract", "/order/payment", false},
+		{"an invalid length", order("", `<card><amount>1</amount><number>1234</number></card>`), "length", "/order/card/number", false},
+		{"simple content with an invalid attribute", order("", `<total currency="eur">1</total>`), "pattern", "/order/total/@currency", false},
+		{"simple content with an invalid value", order("", `<total currency="EUR">ten</total>`), "type", "/order/total", false},
+		{"an invalid element of an included type", order("", `<address><street>Main</street><zip>1</zip></address>`), "pattern", "/order/address/zip", false},
+		{
+			"a nil element that is not nillable",
+			order("", `<total xsi:nil="true" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"/>`),
+			"nillable",
+			"/order/total/@nil",
+			false,
+		},
+		{
+			"an xsi:type that derives from the declared type",
+			order("", `<card xsi:type="PaymentType" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"><amount>1</amount></card>`),
+			"type",
+			"/order/card/@type",
+			false,
+		},
+		{
+			"a lax wildcard element with a declaration",
+			order("", `<ext xmlns="urn:ext"><note xmlns="urn:shop"><b/></note></ext>`),
+			"content",
+			"/order/ext/note",
+			false,
+		},
+		{"a body that is not well-formed", `<order xmlns="urn:shop" id="o-1">`, "", "", false},
+		{"a body with two root elements", `<order xmlns="urn:shop" id="o-1"/><order/>`, "", "", false},
+		{
+			"a body with an external entity",
+			`<!DOCTYPE order [<!ENTITY xxe SYSTEM "file:///etc/passwd">]><order xmlns="urn:shop" id="o-1"><customer>&xxe;</customer></order>`,
+			"",
+			"",
+			false,
+		},
+		{
+			"a body with a parameter entity",
+			`<!DOCTYPE order [<!ENTITY % p SYSTEM "http://example.com/evil.dtd"> %p;]><order xmlns="urn:shop" id="o-1"/>`,
+			"",
+			"",
+			false,
+		},
+		{
+			"a body with an external document type",
+			`<!DOCTYPE order SYSTEM "http://example.com/order.dtd"><order xmlns="urn:shop" id="o-1"><customer>&ext;</customer></order>`,
+			"",
+			"",
+			false,
+		},
+		{
+			"a body with a recursive entity",
+			`<!DOCTYPE order [<!ENTITY a "&b;"><!ENTITY b "&a;">]><order xmlns="urn:shop" id="o-1"><customer>&a;</customer></order>`,
+			"",
+			"",
+			false,
+		},
+	}
+
+	t.Log("Given the need to test the validation of xml documents")
+	{
+		validator := loadValidator(t)
+
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When given %s", index, testCase.description)
+			{
+				err := validator.Validate("/orders", "POST", []byte(testCase.body))
+				keyword, path := failureOf(err)
+
+				if testCase.valid {
+					if err != nil {
+						t.Errorf("\t%s\tShould be valid: %v", failed, err)
+					} else {
+						t.Logf("\t%s\tShould be valid", succeed)
+					}
+				} else {
+					if err == nil {
+						t.Errorf("\t%s\tShould not be valid", failed)
+					} else if keyword != testCase.keyword || path != testCase.path && testCase.path != "" {
+						t.Errorf("\t%s\tShould fail in \"%s\" at %s: %v", failed, testCase.keyword, testCase.path, err)
+					} else {
+						t.Logf("\t%s\tShould not be valid: %v", succeed, err)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestValidateExhaustive(t *testing.T) {
+	t.Log("Given the need to test the collection of all the failures of a document")
+	{
+		validator := loadValidator(t)
+		validator.SetExhaustive(true)
+
+		body := order(` color="red" channel="mail"`, `<item><sku>ab</sku><quantity>0</quantity></item>`)
+
+		err := validator.Validate("/orders", "POST", []byte(body))
+
+		validationErrs, ok := err.(validators.ValidationErrors)
+		if !ok || len(validationErrs.Errors()) != 4 {
+			t.Errorf("\t%s\tShould return 4 failures: %v", failed, err)
+		} else {
+			t.Logf("\t%s\tShould return 4 failures", succeed)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	laughs := `<!DOCTYPE order [<!ENTITY a "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa">` +
+		`<!ENTITY b "&a;&a;&a;&a;&a;&a;&a;&a;&a;&a;"><!ENTITY c "&b;&b;&b;&b;&b;&b;&b;&b;&b;&b;">` +
+		`<!ENTITY d "&c;&c;&c;&c;&c;&c;&c;&c;&c;&c;"><!ENTITY e "&d;&d;&d;&d;&d;&d;&d;&d;&d;&d;">]>` +
+		`<order xmlns="urn:shop" id="o-1"><customer>&e;&e;&e;&e;&e;&e;&e;&e;&e;&e;&e;</customer></order>`
+
+	testCases := []struct {
+		description string
+		limits      xmlvalidator.Limits
+		body        string
+		valid       bool
+	}{
+		{"a document within the limits", xmlvalidator.Limits{MaxDepth: 3}, order("", ""), true},
+		{"a document that is too deep", xmlvalidator.Limits{MaxDepth: 2}, order("", ""), false},
+		{
+			"a document that is too deep by default",
+			xmlvalidator.Limits{},
+			strings.Repeat("<a>", 300) + strings.Repeat("</a>", 300),
+			false,
+		},
+		{"entities that expand too much", xmlvalidator.Limits{MaxExpansion: 1 << 16}, laughs, false},
+		{"entities that expand too much by default", xmlvalidator.Limits{}, laughs, false},
+	}
+
+	t.Log("Given the need to test the limits of parsed documents")
+	{
+		for index, testCase := range testCases {
+			t.Logf("\tTest %d: When given %s", index, testCase.description)
+			{
+				validator := loadValidator(t)
+				validator.SetLimits(testCase.limits)
+
+				err := validator.Validate("/orders", "POST", []byte(testCase.body))
+				if keyword, _ := failureOf(err); keyword != "" || testCase.valid != (err == nil) {
+					t.Errorf("\t%s\tShould fail only in parsing documents beyond the limits: %v", failed, err)
+				} else {
+					t.Logf("\t%s\tShould fail only in parsing documents beyond the limits: %v", succeed, err)
+				}
+			}
+		}
+	}
+}
+
+func TestUnknownEndpoint(t *testing.T) {
+	t.Log("Given the need to test the validation of requests to unknown endpoints")
+	{
+		validator := loadValidator(t)
+
+		if err := validator.Validate("/missing", "POST", []byte(order("", ""))); err == nil {
+			t.Errorf("\t%s\tShould fail for an unknown path", failed)
+		} else {
+			t.Logf("\t%s\tShould fail for an unknown path", succeed)
+		}
+
+		if err := validator.Validate("/orders", "PUT", []byte(order("", ""))); err == nil {
+			t.Errorf("\t%s\tShould fail for an unknown method", failed)
+		} else {
+			t.Logf("\t%s\tShould fail for an unknown method", succeed)
+		}
+	}
+}
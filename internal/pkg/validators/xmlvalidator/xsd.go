@@ -0,0 +1,1455 @@
+package xmlvalidator
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// xsiNamespace is the namespace of the attributes that instance documents
+// use to talk to schema processors (xsi:type, xsi:nil)
+const xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+// unbounded is the maximum number of occurrences of a particle whose
+// maxOccurs is "unbounded"
+const unbounded = -1
+
+// facetNames are the names of the constraining facets
+var facetNames = map[string]bool{
+	"enumeration": true, "pattern": true, "length": true, "minLength": true, "maxLength": true,
+	"minInclusive": true, "maxInclusive": true, "minExclusive": true, "maxExclusive": true,
+	"whiteSpace": true, "totalDigits": true, "fractionDigits": true,
+}
+
+// Kinds of top-level schema components
+const (
+	kindElement        = "element"
+	kindComplexType    = "complexType"
+	kindSimpleType     = "simpleType"
+	kindGroup          = "group"
+	kindAttributeGroup = "attributeGroup"
+	kindAttribute      = "attribute"
+)
+
+// elementDecl is an element declaration
+type elementDecl struct {
+	name xml.Name
+
+	// typ is a *simpleType or a *complexType
+	typ interface{}
+
+	nillable     bool
+	abstract     bool
+	fixed        *string
+	defaultValue *string
+
+	// substitutes holds the elements that may substitute the element, by
+	// their names.
+	substitutes map[xml.Name]*elementDecl
+}
+
+// complexType is a complex type definition. Its content is empty if
+// content and simpleContent are both nil.
+type complexType struct {
+	name xml.Name
+
+	// base is the *simpleType or *complexType that the type derives from
+	base interface{}
+
+	mixed         bool
+	content       *particle
+	simpleContent *simpleType
+
+	attributes   map[xml.Name]*attributeUse
+	anyAttribute *wildcard
+
+	// anyType is true for xs:anyType, whose elements may hold anything
+	anyType bool
+
+	// automaton matches the children of elements against content
+	automaton *automaton
+
+	// derive merges the content and the attributes of the base of a
+	// derived type into the type, once the base was built
+	derive func() error
+}
+
+// attributeDecl is an attribute declaration
+type attributeDecl struct {
+	name  xml.Name
+	typ   *simpleType
+	fixed *string
+}
+
+// attributeUse is the use of an attribute by a complex type
+type attributeUse struct {
+	decl       *attributeDecl
+	required   bool
+	prohibited bool
+	fixed      *string
+}
+
+// attributeGroup is an attribute group definition
+type attributeGroup struct {
+	attributes   map[xml.Name]*attributeUse
+	anyAttribute *wildcard
+}
+
+// wildcard is an element or attribute wildcard (xs:any, xs:anyAttribute)
+type wildcard struct {
+	any bool
+
+	// other is true for "##other": any namespace but the target namespace
+	// and no namespace
+	other           bool
+	targetNamespace string
+
+	namespaces      map[string]bool
+	processContents string
+}
+
+// allows returns true if the wildcard allows a namespace
+func (w *wildcard) allows(namespace string) bool {
+	switch {
+	case w.any:
+		return true
+	case w.other:
+		return namespace != w.targetNamespace && namespace != ""
+	default:
+		return w.namespaces[namespace]
+	}
+}
+
+// particle is an element declaration, a wildcard or a model group
+// (sequence, choice or all) that may occur between min and max times
+type particle struct {
+	min int
+	max int
+
+	element    *elementDecl
+	wildcard   *wildcard
+	compositor string
+	particles  []*particle
+}
+
+// schemaDocument holds the properties of a schema document that apply to
+// its definitions
+type schemaDocument struct {
+	targetNamespace    string
+	elementQualified   bool
+	attributeQualified bool
+}
+
+// definition is a top-level definition of a schema document
+type definition struct {
+	node     *node
+	document *schemaDocument
+}
+
+// schemaSet holds the components of the schema of an endpoint and of the
+// schema documents that it includes and imports
+type schemaSet struct {
+	definitions map[string]map[xml.Name]definition
+
+	elements        map[xml.Name]*elementDecl
+	complexTypes    map[xml.Name]*complexType
+	simpleTypes     map[xml.Name]*simpleType
+	groups          map[xml.Name]*particle
+	attributeGroups map[xml.Name]*attributeGroup
+	attributes      map[xml.Name]*attributeDecl
+
+	// building holds the definitions that are being built, to detect
+	// circular definitions
+	building map[string]bool
+
+	// heads holds the heads of the substitution groups of global elements
+	heads map[*elementDecl]xml.Name
+
+	// anonymousTypes are the complex types that are defined inside other
+	// definitions
+	anonymousTypes []*complexType
+
+	sources *schemaSources
+	loaded  map[string]bool
+}
+
+// schemaSources tells where to find the schema documents that are included
+// and imported: mappings map schema locations and namespaces to files, or
+// location prefixes that end with "/" to directories, and directories are
+// searched, in order, for the files of relative locations.
+type schemaSources struct {
+	directories []string
+	mappings    map[string]string
+}
+
+// read returns a schema document by its location or its namespace
+func (s *schemaSources) read(location, namespace string) ([]byte, string, error) {
+	if s != nil {
+		for _, key := range []string{location, namespace} {
+			if file, ok := s.mappings[key]; ok && key != "" {
+				data, err := ioutil.ReadFile(file)
+				return data, file, err
+			}
+		}
+
+		for prefix, directory := range s.mappings {
+			if strings.HasSuffix(prefix, "/") && strings.HasPrefix(location, prefix) {
+				file := filepath.Join(directory, filepath.FromSlash(strings.TrimPrefix(location, prefix)))
+
+				data, err := ioutil.ReadFile(file)
+				return data, file, err
+			}
+		}
+
+		if location != "" && !strings.Contains(location, "://") {
+			for _, directory := range s.directories {
+				file := filepath.Join(directory, filepath.FromSlash(location))
+
+				data, err := ioutil.ReadFile(file)
+				if err == nil {
+					return data, file, nil
+				}
+			}
+		}
+	}
+
+	return nil, "", errors.New("could not find schema document \"" + location + "\"")
+}
+
+// newSchemaSet loads a schema document and the documents that it includes
+// and imports, and builds their components
+func newSchemaSet(data []byte, sources *schemaSources) (*schemaSet, error) {
+	s := &schemaSet{
+		definitions:     make(map[string]map[xml.Name]definition),
+		elements:        make(map[xml.Name]*elementDecl),
+		complexTypes:    make(map[xml.Name]*complexType),
+		simpleTypes:     builtInSimpleTypes(),
+		groups:          make(map[xml.Name]*particle),
+		attributeGroups: make(map[xml.Name]*attributeGroup),
+		attributes:      make(map[xml.Name]*attributeDecl),
+		building:        make(map[string]bool),
+		heads:           make(map[*elementDecl]xml.Name),
+		sources:         sources,
+		loaded:          make(map[string]bool),
+	}
+
+	anyTypeName := xml.Name{Space: xsdNamespace, Local: "anyType"}
+	s.complexTypes[anyTypeName] = &complexType{name: anyTypeName, anyType: true}
+
+	for _, name := range []string{"lang", "space", "base", "id"} {
+		attributeName := xml.Name{Space: xmlNamespace, Local: name}
+		s.attributes[attributeName] = &attributeDecl{
+			name: attributeName,
+			typ:  s.simpleTypes[xml.Name{Space: xsdNamespace, Local: "anySimpleType"}],
+		}
+	}
+
+	err := s.loadDocument(data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.build()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// loadDocument parses a schema document, loads the documents that it
+// includes and imports, and registers its top-level definitions. The target
+// namespace of an included document without one is the namespace of the
+// document that includes it.
+func (s *schemaSet) loadDocument(data []byte, includingNamespace *string) error {
+	root, err := parseDocument(data, Limits{})
+	if err != nil {
+		return err
+	}
+
+	if root.name != (xml.Name{Space: xsdNamespace, Local: "schema"}) {
+		return errors.New("the root element of a schema document should be xs:schema")
+	}
+
+	doc := &schemaDocument{}
+	doc.targetNamespace, _ = root.attribute("", "targetNamespace")
+
+	if _, ok := root.attribute("", "targetNamespace"); !ok && includingNamespace != nil {
+		doc.targetNamespace = *includingNamespace
+	}
+
+	elementForm, _ := root.attribute("", "elementFormDefault")
+	attributeForm, _ := root.attribute("", "attributeFormDefault")
+	doc.elementQualified = elementForm == "qualified"
+	doc.attributeQualified = attributeForm == "qualified"
+
+	for _, child := range schemaChildren(root) {
+		location, _ := child.attribute("", "schemaLocation")
+
+		switch child.name.Local {
+		case "include":
+			err = s.loadReferenced(location, "", &doc.targetNamespace, true)
+		case "import":
+			namespace, _ := child.attribute("", "namespace")
+			err = s.loadReferenced(location, namespace, nil, false)
+		case "redefine", "override":
+			err = errors.New("xs:" + child.name.Local + " is not supported")
+		case kindElement, kindComplexType, kindSimpleType, kindGroup, kindAttributeGroup, kindAttribute:
+			err = s.register(child, doc)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadReferenced loads an included or imported schema document once.
+// Imported documents that cannot be found are skipped, since their
+// components may never be used.
+func (s *schemaSet) loadReferenced(location, namespace string, includingNamespace *string, required bool) error {
+	if namespace == xsdNamespace || namespace == xmlNamespace {
+		return nil
+	}
+
+	data, file, err := s.sources.read(location, namespace)
+	if err != nil {
+		if required {
+			return err
+		}
+
+		return nil
+	}
+
+	if s.loaded[file] {
+		return nil
+	}
+
+	s.loaded[file] = true
+
+	return errors.Wrap(s.loadDocument(data, includingNamespace), "could not load schema document "+file)
+}
+
+// register registers a top-level definition by its kind and name
+func (s *schemaSet) register(n *node, doc *schemaDocument) error {
+	local, ok := n.attribute("", "name")
+	if !ok {
+		return errors.New("a top-level xs:" + n.name.Local + " has no name")
+	}
+
+	name := xml.Name{Space: doc.targetNamespace, Local: local}
+	kind := n.name.Local
+
+	// Simple and complex types share their symbol space
+	if kind == kindSimpleType || kind == kindComplexType {
+		if _, ok := s.definitions[kindSimpleType][name]; ok {
+			return errors.New("the type " + formatName(name) + " is defined more than once")
+		}
+
+		if _, ok := s.definitions[kindComplexType][name]; ok {
+			return errors.New("the type " + formatName(name) + " is defined more than once")
+		}
+	}
+
+	if s.definitions[kind] == nil {
+		s.definitions[kind] = make(map[xml.Name]definition)
+	}
+
+	if _, ok := s.definitions[kind][name]; ok {
+		return errors.New("the " + kind + " " + formatName(name) + " is defined more than once")
+	}
+
+	s.definitions[kind][name] = definition{node: n, document: doc}
+
+	return nil
+}
+
+// build builds all the top-level definitions, links the substitution
+// groups and compiles the content models of the complex types
+func (s *schemaSet) build() error {
+	for _, kind := range []string{kindSimpleType, kindComplexType, kindAttribute, kindAttributeGroup, kindGroup, kindElement} {
+		for name := range s.definitions[kind] {
+			var err error
+
+			switch kind {
+			case kindSimpleType, kindComplexType:
+				_, err = s.typeByName(name)
+			case kindAttribute:
+				_, err = s.attribute(name)
+			case kindAttributeGroup:
+				_, err = s.attributeGroup(name)
+			case kindGroup:
+				_, err = s.group(name)
+			case kindElement:
+				_, err = s.element(name)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for decl, headName := range s.heads {
+		for visited := make(map[*elementDecl]bool); ; {
+			head := s.elements[headName]
+			if head == nil {
+				return errors.New("the substitution group head " + formatName(headName) + " is not declared")
+			}
+
+			if visited[head] {
+				return errors.New("the substitution group of " + formatName(decl.name) + " is circular")
+			}
+
+			visited[head] = true
+
+			if head.substitutes == nil {
+				head.substitutes = make(map[xml.Name]*elementDecl)
+			}
+
+			head.substitutes[decl.name] = decl
+
+			next, ok := s.heads[head]
+			if !ok {
+				break
+			}
+
+			headName = next
+		}
+	}
+
+	types := s.anonymousTypes
+	for _, t := range s.complexTypes {
+		types = append(types, t)
+	}
+
+	deriving := make(map[*complexType]bool)
+
+	for _, t := range types {
+		if err := s.finalize(t, deriving); err != nil {
+			return err
+		}
+	}
+
+	for _, t := range types {
+		if t.content == nil || t.content.compositor == "all" {
+			continue
+		}
+
+		var err error
+
+		t.automaton, err = compileAutomaton(t.content)
+		if err != nil {
+			return errors.Wrap(err, "could not compile the content model of "+formatName(t.name))
+		}
+	}
+
+	return nil
+}
+
+// startBuilding marks a definition as being built, and fails if it
+// already is, which means that it is defined by itself
+func (s *schemaSet) startBuilding(kind string, name xml.Name) error {
+	key := kind + " " + formatName(name)
+	if s.building[key] {
+		return errors.New("the " + kind + " " + formatName(name) + " is defined by itself")
+	}
+
+	s.building[key] = true
+
+	return nil
+}
+
+// doneBuilding marks a definition as built
+func (s *schemaSet) doneBuilding(kind string, name xml.Name) {
+	delete(s.building, kind+" "+formatName(name))
+}
+
+// typeByName returns a simple or a complex type by its name
+func (s *schemaSet) typeByName(name xml.Name) (interface{}, error) {
+	if t, ok := s.simpleTypes[name]; ok {
+		return t, nil
+	}
+
+	if t, ok := s.complexTypes[name]; ok {
+		return t, nil
+	}
+
+	if def, ok := s.definitions[kindSimpleType][name]; ok {
+		if err := s.startBuilding(kindSimpleType, name); err != nil {
+			return nil, err
+		}
+
+		defer s.doneBuilding(kindSimpleType, name)
+
+		t, err := s.simpleType(def.node, def.document, name)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid simple type "+formatName(name))
+		}
+
+		s.simpleTypes[name] = t
+
+		return t, nil
+	}
+
+	if def, ok := s.definitions[kindComplexType][name]; ok {
+		if err := s.startBuilding(kindComplexType, name); err != nil {
+			return nil, err
+		}
+
+		defer s.doneBuilding(kindComplexType, name)
+
+		// The type is registered before it is built, since the
+		// declarations of its content may use it
+		t := &complexType{name: name}
+		s.complexTypes[name] = t
+
+		err := s.fillComplexType(t, def.node, def.document)
+		if err != nil {
+			delete(s.complexTypes, name)
+			return nil, errors.Wrap(err, "invalid complex type "+formatName(name))
+		}
+
+		return t, nil
+	}
+
+	return nil, errors.New("unknown type " + formatName(name))
+}
+
+// simpleTypeByName returns a simple type by its name
+func (s *schemaSet) simpleTypeByName(name xml.Name) (*simpleType, error) {
+	t, err := s.typeByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	simple, ok := t.(*simpleType)
+	if !ok {
+		return nil, errors.New("the type " + formatName(name) + " is not a simple type")
+	}
+
+	return simple, nil
+}
+
+// typeAttribute returns the type that an attribute of a definition names,
+// or nil if the definition does not have the attribute
+func (s *schemaSet) typeAttribute(n *node, attribute string) (interface{}, error) {
+	qname, ok := n.attribute("", attribute)
+	if !ok {
+		return nil, nil
+	}
+
+	name, err := n.resolveQName(qname)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.typeByName(name)
+}
+
+// simpleType builds a simple type definition
+func (s *schemaSet) simpleType(n *node, doc *schemaDocument, name xml.Name) (*simpleType, error) {
+	for _, child := range schemaChildren(n) {
+		switch child.name.Local {
+		case "restriction":
+			return s.simpleRestriction(child, doc, name, nil)
+		case "list":
+			t := &simpleType{
+				name:       name,
+				variety:    varietyList,
+				base:       s.simpleTypes[xml.Name{Space: xsdNamespace, Local: "anySimpleType"}],
+				whiteSpace: whiteSpaceCollapse,
+			}
+
+			itemType, err := s.simpleTypeOf(child, doc, "itemType")
+			if err != nil {
+				return nil, err
+			}
+
+			t.itemType = itemType
+
+			return t, nil
+		case "union":
+			t := &simpleType{
+				name:       name,
+				variety:    varietyUnion,
+				base:       s.simpleTypes[xml.Name{Space: xsdNamespace, Local: "anySimpleType"}],
+				whiteSpace: whiteSpaceCollapse,
+			}
+
+			memberTypes, _ := child.attribute("", "memberTypes")
+			for _, qname := range strings.Fields(memberTypes) {
+				memberName, err := child.resolveQName(qname)
+				if err != nil {
+					return nil, err
+				}
+
+				member, err := s.simpleTypeByName(memberName)
+				if err != nil {
+					return nil, err
+				}
+
+				t.memberTypes = append(t.memberTypes, member)
+			}
+
+			for _, inline := range schemaChildren(child) {
+				member, err := s.simpleType(inline, doc, xml.Name{})
+				if err != nil {
+					return nil, err
+				}
+
+				t.memberTypes = append(t.memberTypes, member)
+			}
+
+			return t, nil
+		}
+	}
+
+	return nil, errors.New("a simple type should be a restriction, a list or a union")
+}
+
+// simpleTypeOf returns the simple type that a definition names in an
+// attribute or defines inline
+func (s *schemaSet) simpleTypeOf(n *node, doc *schemaDocument, attribute string) (*simpleType, error) {
+	t, err := s.typeAttribute(n, attribute)
+	if err != nil {
+		return nil, err
+	}
+
+	if t != nil {
+		simple, ok := t.(*simpleType)
+		if !ok {
+			return nil, errors.New("the " + attribute + " of xs:" + n.name.Local + " is not a simple type")
+		}
+
+		return simple, nil
+	}
+
+	for _, child := range schemaChildren(n) {
+		if child.name.Local == kindSimpleType {
+			return s.simpleType(child, doc, xml.Name{})
+		}
+	}
+
+	return nil, errors.New("xs:" + n.name.Local + " has no " + attribute)
+}
+
+// simpleRestriction builds a simple type that restricts a base type with
+// facets. The base type of restrictions of simple content may be given.
+func (s *schemaSet) simpleRestriction(n *node, doc *schemaDocument, name xml.Name, base *simpleType) (*simpleType, error) {
+	if base == nil {
+		var err error
+
+		base, err = s.simpleTypeOf(n, doc, "base")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	t := &simpleType{
+		name:        name,
+		variety:     base.variety,
+		primitive:   base.primitive,
+		base:        base,
+		whiteSpace:  base.whiteSpace,
+		itemType:    base.itemType,
+		memberTypes: base.memberTypes,
+	}
+
+	for _, facet := range schemaChildren(n) {
+		value, _ := facet.attribute("", "value")
+
+		var err error
+
+		switch facet.name.Local {
+		case "enumeration":
+			t.facets.enumeration = append(t.facets.enumeration, value)
+		case "pattern":
+			var pattern *regexp.Regexp
+
+			pattern, err = compilePattern(value)
+			t.facets.patterns = append(t.facets.patterns, pattern)
+			t.facets.patternSources = append(t.facets.patternSources, value)
+		case "length":
+			t.facets.length, err = parseFacetInt(facet.name.Local, value)
+		case "minLength":
+			t.facets.minLength, err = parseFacetInt(facet.name.Local, value)
+		case "maxLength":
+			t.facets.maxLength, err = parseFacetInt(facet.name.Local, value)
+		case "totalDigits":
+			t.facets.totalDigits, err = parseFacetInt(facet.name.Local, value)
+		case "fractionDigits":
+			t.facets.fractionDigits, err = parseFacetInt(facet.name.Local, value)
+		case "minInclusive":
+			t.facets.minInclusive = &value
+		case "maxInclusive":
+			t.facets.maxInclusive = &value
+		case "minExclusive":
+			t.facets.minExclusive = &value
+		case "maxExclusive":
+			t.facets.maxExclusive = &value
+		case "whiteSpace":
+			switch value {
+			case "preserve":
+				t.whiteSpace = whiteSpacePreserve
+			case "replace":
+				t.whiteSpace = whiteSpaceReplace
+			case "collapse":
+				t.whiteSpace = whiteSpaceCollapse
+			default:
+				err = errors.New("invalid whiteSpace facet \"" + value + "\"")
+			}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// parseFacetInt parses the value of a facet that is a non-negative integer
+func parseFacetInt(facet, value string) (*int, error) {
+	number, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || number < 0 {
+		return nil, errors.New("invalid " + facet + " facet \"" + value + "\"")
+	}
+
+	return &number, nil
+}
+
+// anonymousComplexType builds a complex type that is defined inside
+// another definition
+func (s *schemaSet) anonymousComplexType(n *node, doc *schemaDocument, name xml.Name) (*complexType, error) {
+	t := &complexType{name: name}
+
+	err := s.fillComplexType(t, n, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	s.anonymousTypes = append(s.anonymousTypes, t)
+
+	return t, nil
+}
+
+// fillComplexType builds the content and the attributes of a complex type
+func (s *schemaSet) fillComplexType(t *complexType, n *node, doc *schemaDocument) error {
+	t.attributes = make(map[xml.Name]*attributeUse)
+	t.mixed = booleanAttribute(n, "mixed")
+	t.base = s.complexTypes[xml.Name{Space: xsdNamespace, Local: "anyType"}]
+
+	for _, child := range schemaChildren(n) {
+		var err error
+
+		switch child.name.Local {
+		case "simpleContent":
+			err = s.simpleContent(t, child, doc)
+		case "complexContent":
+			if _, ok := child.attribute("", "mixed"); ok {
+				t.mixed = booleanAttribute(child, "mixed")
+			}
+
+			err = s.complexContent(t, child, doc)
+		case "sequence", "choice", "all", kindGroup:
+			t.content, err = s.particle(child, doc)
+		default:
+			err = s.attributeUses(child, doc, t.attributes, &t.anyAttribute)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// simpleContent builds the content of a complex type with simple content,
+// which extends or restricts a simple type or a complex type with simple
+// content
+func (s *schemaSet) simpleContent(t *complexType, n *node, doc *schemaDocument) error {
+	for _, derivation := range schemaChildren(n) {
+		base, err := s.typeAttribute(derivation, "base")
+		if err != nil {
+			return err
+		}
+
+		if base == nil {
+			return errors.New("xs:" + derivation.name.Local + " has no base")
+		}
+
+		t.base = base
+
+		own := make(map[xml.Name]*attributeUse)
+
+		var anyAttribute *wildcard
+
+		for _, child := range schemaChildren(derivation) {
+			if child.name.Local == kindSimpleType || facetNames[child.name.Local] {
+				continue
+			}
+
+			err = s.attributeUses(child, doc, own, &anyAttribute)
+			if err != nil {
+				return err
+			}
+		}
+
+		restriction := derivation
+		if derivation.name.Local != "restriction" {
+			restriction = nil
+		}
+
+		t.derive = func() error {
+			var baseContent *simpleType
+
+			switch b := base.(type) {
+			case *simpleType:
+				baseContent = b
+			case *complexType:
+				if b.simpleContent == nil {
+					return errors.New("the base " + formatName(b.name) + " has no simple content")
+				}
+
+				baseContent = b.simpleContent
+				t.inherit(b)
+			}
+
+			t.simpleContent = baseContent
+
+			if restriction != nil {
+				var err error
+
+				t.simpleContent, err = s.simpleRestriction(restriction, doc, xml.Name{}, baseContent)
+				if err != nil {
+					return err
+				}
+			}
+
+			t.extend(own, anyAttribute)
+
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// complexContent builds the content of a complex type that extends or
+// restricts another complex type. Extensions append their content to the
+// content of their base, and restrictions replace it.
+func (s *schemaSet) complexContent(t *complexType, n *node, doc *schemaDocument) error {
+	for _, derivation := range schemaChildren(n) {
+		base, err := s.typeAttribute(derivation, "base")
+		if err != nil {
+			return err
+		}
+
+		baseType, ok := base.(*complexType)
+		if !ok {
+			return errors.New("the base of complex content should be a complex type")
+		}
+
+		t.base = baseType
+
+		own := make(map[xml.Name]*attributeUse)
+
+		var content *particle
+		var anyAttribute *wildcard
+
+		for _, child := range schemaChildren(derivation) {
+			switch child.name.Local {
+			case "sequence", "choice", "all", kindGroup:
+				content, err = s.particle(child, doc)
+			default:
+				err = s.attributeUses(child, doc, own, &anyAttribute)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		extension := derivation.name.Local == "extension"
+
+		t.derive = func() error {
+			t.inherit(baseType)
+			t.extend(own, anyAttribute)
+
+			switch {
+			case !extension, baseType.anyType, baseType.content == nil:
+				t.content = content
+			case content == nil:
+				t.content = baseType.content
+			default:
+				t.content = &particle{
+					min:        1,
+					max:        1,
+					compositor: "sequence",
+					particles:  []*particle{baseType.content, content},
+				}
+			}
+
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// inherit copies the attributes of the base of a type
+func (t *complexType) inherit(base *complexType) {
+	for name, use := range base.attributes {
+		t.attributes[name] = use
+	}
+
+	t.anyAttribute = base.anyAttribute
+}
+
+// extend adds the attributes that a derivation declares to a type, over
+// the attributes of its base
+func (t *complexType) extend(uses map[xml.Name]*attributeUse, anyAttribute *wildcard) {
+	for name, use := range uses {
+		t.attributes[name] = use
+	}
+
+	if anyAttribute != nil {
+		t.anyAttribute = anyAttribute
+	}
+}
+
+// finalize derives a type from its base once the base was derived, and
+// fails if the type derives from itself
+func (s *schemaSet) finalize(t *complexType, deriving map[*complexType]bool) error {
+	if t.derive == nil {
+		return nil
+	}
+
+	if deriving[t] {
+		return errors.New("the type " + formatName(t.name) + " derives from itself")
+	}
+
+	deriving[t] = true
+
+	if base, ok := t.base.(*complexType); ok {
+		if err := s.finalize(base, deriving); err != nil {
+			return err
+		}
+	}
+
+	err := t.derive()
+	if err != nil {
+		return errors.Wrap(err, "invalid complex type "+formatName(t.name))
+	}
+
+	t.derive = nil
+
+	return nil
+}
+
+// particle builds a particle of a content model
+func (s *schemaSet) particle(n *node, doc *schemaDocument) (*particle, error) {
+	min, max, err := occurrences(n)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &particle{min: min, max: max}
+
+	switch n.name.Local {
+	case kindElement:
+		p.element, err = s.localElement(n, doc)
+	case "any":
+		p.wildcard = newWildcard(n, doc)
+	case kindGroup:
+		var group *particle
+
+		group, err = s.groupRef(n)
+		if err == nil {
+			p.compositor = group.compositor
+			p.particles = group.particles
+		}
+	case "sequence", "choice", "all":
+		p.compositor = n.name.Local
+
+		for _, child := range schemaChildren(n) {
+			var nested *particle
+
+			nested, err = s.particle(child, doc)
+			if err != nil {
+				return nil, err
+			}
+
+			p.particles = append(p.particles, nested)
+		}
+	default:
+		err = errors.New("unexpected xs:" + n.name.Local + " in a content model")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// occurrences reads the minOccurs and maxOccurs of a particle
+func occurrences(n *node) (int, int, error) {
+	min, max := 1, 1
+
+	if value, ok := n.attribute("", "minOccurs"); ok {
+		parsed, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || parsed < 0 {
+			return 0, 0, errors.New("invalid minOccurs \"" + value + "\"")
+		}
+
+		min = parsed
+	}
+
+	if value, ok := n.attribute("", "maxOccurs"); ok {
+		if strings.TrimSpace(value) == "unbounded" {
+			max = unbounded
+		} else {
+			parsed, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || parsed < 0 {
+				return 0, 0, errors.New("invalid maxOccurs \"" + value + "\"")
+			}
+
+			max = parsed
+		}
+	}
+
+	if max != unbounded && min > max {
+		return 0, 0, errors.New("minOccurs is greater than maxOccurs")
+	}
+
+	return min, max, nil
+}
+
+// newWildcard builds an element or attribute wildcard
+func newWildcard(n *node, doc *schemaDocument) *wildcard {
+	w := &wildcard{targetNamespace: doc.targetNamespace, processContents: "strict"}
+
+	if process, ok := n.attribute("", "processContents"); ok {
+		w.processContents = process
+	}
+
+	namespaces, ok := n.attribute("", "namespace")
+	if !ok {
+		namespaces = "##any"
+	}
+
+	w.namespaces = make(map[string]bool)
+
+	for _, namespace := range strings.Fields(namespaces) {
+		switch namespace {
+		case "##any":
+			w.any = true
+		case "##other":
+			w.other = true
+		case "##targetNamespace":
+			w.namespaces[doc.targetNamespace] = true
+		case "##local":
+			w.namespaces[""] = true
+		default:
+			w.namespaces[namespace] = true
+		}
+	}
+
+	return w
+}
+
+// groupRef returns the model group that a group reference points to
+func (s *schemaSet) groupRef(n *node) (*particle, error) {
+	ref, ok := n.attribute("", "ref")
+	if !ok {
+		return nil, errors.New("a local xs:group should have a ref")
+	}
+
+	name, err := n.resolveQName(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.group(name)
+}
+
+// group returns a model group definition by its name
+func (s *schemaSet) group(name xml.Name) (*particle, error) {
+	if group, ok := s.groups[name]; ok {
+		return group, nil
+	}
+
+	def, ok := s.definitions[kindGroup][name]
+	if !ok {
+		return nil, errors.New("unknown group " + formatName(name))
+	}
+
+	if err := s.startBuilding(kindGroup, name); err != nil {
+		return nil, err
+	}
+
+	defer s.doneBuilding(kindGroup, name)
+
+	for _, child := range schemaChildren(def.node) {
+		group, err := s.particle(child, def.document)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid group "+formatName(name))
+		}
+
+		s.groups[name] = group
+
+		return group, nil
+	}
+
+	return nil, errors.New("the group " + formatName(name) + " is empty")
+}
+
+// element returns a global element declaration by its name
+func (s *schemaSet) element(name xml.Name) (*elementDecl, error) {
+	if decl, ok := s.elements[name]; ok {
+		return decl, nil
+	}
+
+	def, ok := s.definitions[kindElement][name]
+	if !ok {
+		return nil, errors.New("unknown element " + formatName(name))
+	}
+
+	// The declaration is registered before its type is built, since its
+	// type may declare it as its own content
+	decl := &elementDecl{name: name}
+	s.elements[name] = decl
+
+	if head, ok := def.node.attribute("", "substitutionGroup"); ok {
+		headName, err := def.node.resolveQName(head)
+		if err != nil {
+			return nil, err
+		}
+
+		s.heads[decl] = headName
+	}
+
+	err := s.fillElement(decl, def.node, def.document)
+	if err != nil {
+		delete(s.elements, name)
+		return nil, errors.Wrap(err, "invalid element "+formatName(name))
+	}
+
+	return decl, nil
+}
+
+// localElement returns the declaration of an element in a content model,
+// which declares a local element or references a global one
+func (s *schemaSet) localElement(n *node, doc *schemaDocument) (*elementDecl, error) {
+	if ref, ok := n.attribute("", "ref"); ok {
+		name, err := n.resolveQName(ref)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.element(name)
+	}
+
+	local, ok := n.attribute("", "name")
+	if !ok {
+		return nil, errors.New("a local xs:element should have a name or a ref")
+	}
+
+	qualified := doc.elementQualified
+	if form, ok := n.attribute("", "form"); ok {
+		qualified = form == "qualified"
+	}
+
+	decl := &elementDecl{name: xml.Name{Local: local}}
+	if qualified {
+		decl.name.Space = doc.targetNamespace
+	}
+
+	err := s.fillElement(decl, n, doc)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid element "+formatName(decl.name))
+	}
+
+	return decl, nil
+}
+
+// fillElement builds the type and the properties of an element declaration
+func (s *schemaSet) fillElement(decl *elementDecl, n *node, doc *schemaDocument) error {
+	decl.nillable = booleanAttribute(n, "nillable")
+	decl.abstract = booleanAttribute(n, "abstract")
+
+	if fixed, ok := n.attribute("", "fixed"); ok {
+		decl.fixed = &fixed
+	}
+
+	if defaultValue, ok := n.attribute("", "default"); ok {
+		decl.defaultValue = &defaultValue
+	}
+
+	t, err := s.typeAttribute(n, "type")
+	if err != nil {
+		return err
+	}
+
+	for _, child := range schemaChildren(n) {
+		switch child.name.Local {
+		case kindComplexType:
+			t, err = s.anonymousComplexType(child, doc, decl.name)
+		case kindSimpleType:
+			t, err = s.simpleType(child, doc, xml.Name{})
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	// An element without a type has the type of the head of its
+	// substitution group, or any type
+	if t == nil {
+		if headName, ok := s.heads[decl]; ok {
+			head, err := s.element(headName)
+			if err != nil {
+				return err
+			}
+
+			if head.typ == nil {
+				return errors.New("the substitution group of " + formatName(decl.name) + " is circular")
+			}
+
+			t = head.typ
+		} else {
+			t = s.complexTypes[xml.Name{Space: xsdNamespace, Local: "anyType"}]
+		}
+	}
+
+	decl.typ = t
+
+	return nil
+}
+
+// attributeUses adds the attributes that an attribute declaration, an
+// attribute group reference or an attribute wildcard of a complex type
+// declares to the type's attributes
+func (s *schemaSet) attributeUses(n *node, doc *schemaDocument, uses map[xml.Name]*attributeUse,
+	anyAttribute **wildcard) error {
+	switch n.name.Local {
+	case kindAttribute:
+		use, err := s.attributeUse(n, doc)
+		if err != nil {
+			return err
+		}
+
+		uses[use.decl.name] = use
+	case kindAttributeGroup:
+		ref, ok := n.attribute("", "ref")
+		if !ok {
+			return errors.New("a local xs:attributeGroup should have a ref")
+		}
+
+		name, err := n.resolveQName(ref)
+		if err != nil {
+			return err
+		}
+
+		group, err := s.attributeGroup(name)
+		if err != nil {
+			return err
+		}
+
+		for attributeName, use := range group.attributes {
+			uses[attributeName] = use
+		}
+
+		if group.anyAttribute != nil {
+			*anyAttribute = group.anyAttribute
+		}
+	case "anyAttribute":
+		*anyAttribute = newWildcard(n, doc)
+	default:
+		return errors.New("unexpected xs:" + n.name.Local)
+	}
+
+	return nil
+}
+
+// attributeUse builds the use of an attribute, which declares a local
+// attribute or references a global one
+func (s *schemaSet) attributeUse(n *node, doc *schemaDocument) (*attributeUse, error) {
+	use := &attributeUse{}
+
+	switch value, _ := n.attribute("", "use"); value {
+	case "required":
+		use.required = true
+	case "prohibited":
+		use.prohibited = true
+	}
+
+	if fixed, ok := n.attribute("", "fixed"); ok {
+		use.fixed = &fixed
+	}
+
+	if ref, ok := n.attribute("", "ref"); ok {
+		name, err := n.resolveQName(ref)
+		if err != nil {
+			return nil, err
+		}
+
+		use.decl, err = s.attribute(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if use.fixed == nil {
+			use.fixed = use.decl.fixed
+		}
+
+		return use, nil
+	}
+
+	local, ok := n.attribute("", "name")
+	if !ok {
+		return nil, errors.New("a local xs:attribute should have a name or a ref")
+	}
+
+	qualified := doc.attributeQualified
+	if form, ok := n.attribute("", "form"); ok {
+		qualified = form == "qualified"
+	}
+
+	name := xml.Name{Local: local}
+	if qualified {
+		name.Space = doc.targetNamespace
+	}
+
+	decl, err := s.attributeDecl(n, doc, name)
+	if err != nil {
+		return nil, err
+	}
+
+	use.decl = decl
+
+	return use, nil
+}
+
+// attribute returns a global attribute declaration by its name
+func (s *schemaSet) attribute(name xml.Name) (*attributeDecl, error) {
+	if decl, ok := s.attributes[name]; ok {
+		return decl, nil
+	}
+
+	def, ok := s.definitions[kindAttribute][name]
+	if !ok {
+		return nil, errors.New("unknown attribute " + formatName(name))
+	}
+
+	decl, err := s.attributeDecl(def.node, def.document, name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.attributes[name] = decl
+
+	return decl, nil
+}
+
+// attributeDecl builds an attribute declaration
+func (s *schemaSet) attributeDecl(n *node, doc *schemaDocument, name xml.Name) (*attributeDecl, error) {
+	decl := &attributeDecl{name: name}
+
+	if fixed, ok := n.attribute("", "fixed"); ok {
+		decl.fixed = &fixed
+	}
+
+	_, hasType := n.attribute("", "type")
+	hasInlineType := false
+
+	for _, child := range schemaChildren(n) {
+		hasInlineType = hasInlineType || child.name.Local == kindSimpleType
+	}
+
+	if !hasType && !hasInlineType {
+		decl.typ = s.simpleTypes[xml.Name{Space: xsdNamespace, Local: "anySimpleType"}]
+		return decl, nil
+	}
+
+	t, err := s.simpleTypeOf(n, doc, "type")
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid attribute "+formatName(name))
+	}
+
+	decl.typ = t
+
+	return decl, nil
+}
+
+// attributeGroup returns an attribute group definition by its name
+func (s *schemaSet) attributeGroup(name xml.Name) (*attributeGroup, error) {
+	if group, ok := s.attributeGroups[name]; ok {
+		return group, nil
+	}
+
+	def, ok := s.definitions[kindAttributeGroup][name]
+	if !ok {
+		return nil, errors.New("unknown attribute group " + formatName(name))
+	}
+
+	if err := s.startBuilding(kindAttributeGroup, name); err != nil {
+		return nil, err
+	}
+
+	defer s.doneBuilding(kindAttributeGroup, name)
+
+	group := &attributeGroup{attributes: make(map[xml.Name]*attributeUse)}
+
+	for _, child := range schemaChildren(def.node) {
+		err := s.attributeUses(child, def.document, group.attributes, &group.anyAttribute)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid attribute group "+formatName(name))
+		}
+	}
+
+	s.attributeGroups[name] = group
+
+	return group, nil
+}
+
+// schemaChildren returns the children of a schema element that are schema
+// elements, without annotations
+func schemaChildren(n *node) []*node {
+	var children []*node
+
+	for _, child := range n.children {
+		if child.name.Space == xsdNamespace && child.name.Local != "annotation" {
+			children = append(children, child)
+		}
+	}
+
+	return children
+}
+
+// booleanAttribute returns the value of a boolean attribute of a schema
+// element
+func booleanAttribute(n *node, name string) bool {
+	value, _ := n.attribute("", name)
+	return isTrue(value)
+}
+
+// formatName formats a qualified name as "{namespace}local"
+func formatName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+
+	return "{" + name.Space + "}" + name.Local
+}